@@ -0,0 +1,19 @@
+-- Email verification and password recovery
+
+ALTER TABLE ACCOUNT
+    ADD COLUMN email_verified BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE ACCOUNT_TOKEN (
+    token_id   INT          NOT NULL AUTO_INCREMENT,
+    acc_id     INT          NOT NULL,
+    purpose    VARCHAR(32)  NOT NULL,
+    token_hash CHAR(64)     NOT NULL,
+    email      VARCHAR(255) NOT NULL DEFAULT '',
+    expires_at DATETIME     NOT NULL,
+    used_at    DATETIME     NULL,
+    created_at DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (token_id),
+    UNIQUE KEY uq_account_token_hash (token_hash),
+    KEY idx_account_token_acc (acc_id, purpose),
+    CONSTRAINT fk_account_token_acc FOREIGN KEY (acc_id) REFERENCES ACCOUNT (acc_id) ON DELETE CASCADE
+);
@@ -0,0 +1,21 @@
+-- TOTP two-factor authentication
+
+CREATE TABLE ACCOUNT_TOTP (
+    acc_id     INT         NOT NULL,
+    secret     VARCHAR(64) NOT NULL,
+    confirmed  BOOLEAN     NOT NULL DEFAULT FALSE,
+    last_step  BIGINT      NOT NULL DEFAULT 0,
+    created_at DATETIME    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (acc_id),
+    CONSTRAINT fk_account_totp_acc FOREIGN KEY (acc_id) REFERENCES ACCOUNT (acc_id) ON DELETE CASCADE
+);
+
+CREATE TABLE RECOVERY_CODE (
+    code_id   INT      NOT NULL AUTO_INCREMENT,
+    acc_id    INT      NOT NULL,
+    code_hash CHAR(64) NOT NULL,
+    used_at   DATETIME NULL,
+    PRIMARY KEY (code_id),
+    UNIQUE KEY uq_recovery_code (acc_id, code_hash),
+    CONSTRAINT fk_recovery_code_acc FOREIGN KEY (acc_id) REFERENCES ACCOUNT (acc_id) ON DELETE CASCADE
+);
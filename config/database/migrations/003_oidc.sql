@@ -0,0 +1,27 @@
+-- External identities from OpenID Connect providers
+
+CREATE TABLE ACCOUNT_IDENTITY (
+    identity_id INT          NOT NULL AUTO_INCREMENT,
+    acc_id      INT          NOT NULL,
+    provider    VARCHAR(64)  NOT NULL,
+    subject     VARCHAR(255) NOT NULL,
+    email       VARCHAR(255) NOT NULL DEFAULT '',
+    created_at  DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_login  DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (identity_id),
+    UNIQUE KEY uq_identity_subject (provider, subject),
+    CONSTRAINT fk_identity_acc FOREIGN KEY (acc_id) REFERENCES ACCOUNT (acc_id) ON DELETE CASCADE
+);
+
+-- Pending authorization requests. acc_id is set when an identity is
+-- being linked to an existing account
+CREATE TABLE OIDC_STATE (
+    state      CHAR(64)     NOT NULL,
+    provider   VARCHAR(64)  NOT NULL,
+    nonce      CHAR(64)     NOT NULL,
+    verifier   VARCHAR(128) NOT NULL,
+    acc_id     INT          NULL,
+    expires_at DATETIME     NOT NULL,
+    PRIMARY KEY (state),
+    CONSTRAINT fk_oidc_state_acc FOREIGN KEY (acc_id) REFERENCES ACCOUNT (acc_id) ON DELETE CASCADE
+);
@@ -0,0 +1,17 @@
+-- Personal API keys for scripts and integrations
+
+CREATE TABLE API_KEY (
+    key_id     INT          NOT NULL AUTO_INCREMENT,
+    acc_id     INT          NOT NULL,
+    name       VARCHAR(100) NOT NULL,
+    prefix     CHAR(12)     NOT NULL,
+    key_hash   CHAR(64)     NOT NULL,
+    scopes     VARCHAR(255) NOT NULL,
+    expires_at DATETIME     NULL,
+    last_used  DATETIME     NULL,
+    created_at DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (key_id),
+    UNIQUE KEY uq_api_key_hash (key_hash),
+    UNIQUE KEY uq_api_key_name (acc_id, name),
+    CONSTRAINT fk_api_key_acc FOREIGN KEY (acc_id) REFERENCES ACCOUNT (acc_id) ON DELETE CASCADE
+);
@@ -0,0 +1,11 @@
+-- Failed login tracking per account and per IP
+
+CREATE TABLE LOGIN_FAILURE (
+    scope        VARCHAR(16)  NOT NULL,
+    subject      VARCHAR(255) NOT NULL,
+    failures     INT          NOT NULL DEFAULT 0,
+    last_failure DATETIME     NOT NULL,
+    locked_until DATETIME     NULL,
+    PRIMARY KEY (scope, subject),
+    KEY idx_login_failure_locked (locked_until)
+);
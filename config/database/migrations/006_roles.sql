@@ -0,0 +1,15 @@
+-- Roles per account. Accounts without a row are regular users
+
+CREATE TABLE ACCOUNT_ROLE (
+    acc_id     INT         NOT NULL,
+    role       VARCHAR(32) NOT NULL,
+    granted_by INT         NULL,
+    granted_at DATETIME    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (acc_id),
+    KEY idx_account_role_role (role),
+    CONSTRAINT fk_account_role_acc FOREIGN KEY (acc_id) REFERENCES ACCOUNT (acc_id) ON DELETE CASCADE,
+    CONSTRAINT fk_account_role_granted_by FOREIGN KEY (granted_by) REFERENCES ACCOUNT (acc_id) ON DELETE SET NULL
+);
+
+-- The "deleted user" account keeps the decks of removed accounts
+INSERT INTO ACCOUNT_ROLE (acc_id, role) VALUES (1, 'system');
@@ -0,0 +1,37 @@
+-- Suspended and banned accounts, decks hidden by staff and the audit log
+
+CREATE TABLE ACCOUNT_SUSPENSION (
+    acc_id     INT          NOT NULL,
+    reason     VARCHAR(500) NOT NULL,
+    until      DATETIME     NULL, -- NULL means banned
+    created_by INT          NULL,
+    created_at DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (acc_id),
+    CONSTRAINT fk_account_suspension_acc FOREIGN KEY (acc_id) REFERENCES ACCOUNT (acc_id) ON DELETE CASCADE,
+    CONSTRAINT fk_account_suspension_created_by FOREIGN KEY (created_by) REFERENCES ACCOUNT (acc_id) ON DELETE SET NULL
+);
+
+-- Owners can't make these decks visible again until staff unhides them
+CREATE TABLE DECK_HIDDEN (
+    deck_id   INT          NOT NULL,
+    reason    VARCHAR(500) NOT NULL,
+    hidden_by INT          NULL,
+    hidden_at DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (deck_id),
+    CONSTRAINT fk_deck_hidden_deck FOREIGN KEY (deck_id) REFERENCES DECK (deck_id) ON DELETE CASCADE,
+    CONSTRAINT fk_deck_hidden_by FOREIGN KEY (hidden_by) REFERENCES ACCOUNT (acc_id) ON DELETE SET NULL
+);
+
+CREATE TABLE AUDIT_LOG (
+    audit_id   INT          NOT NULL AUTO_INCREMENT,
+    actor_id   INT          NULL,
+    action     VARCHAR(128) NOT NULL,
+    target     VARCHAR(255) NOT NULL DEFAULT '',
+    details    TEXT         NULL,
+    ip         VARCHAR(45)  NOT NULL DEFAULT '',
+    created_at DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (audit_id),
+    KEY idx_audit_log_actor (actor_id, created_at),
+    KEY idx_audit_log_created (created_at),
+    CONSTRAINT fk_audit_log_actor FOREIGN KEY (actor_id) REFERENCES ACCOUNT (acc_id) ON DELETE SET NULL
+);
@@ -0,0 +1,45 @@
+-- Reports of decks, cards and profiles and the warnings sent to owners
+
+CREATE TABLE REPORT (
+    report_id   INT          NOT NULL AUTO_INCREMENT,
+    target_type VARCHAR(16)  NOT NULL,
+    target_id   INT          NOT NULL,
+    deck_id     INT          NULL, -- Deck of a reported card
+    owner_id    INT          NULL, -- Account responsible for the content
+    reporter_id INT          NULL,
+    reason      VARCHAR(32)  NOT NULL,
+    comment     VARCHAR(1000) NOT NULL DEFAULT '',
+    status      VARCHAR(16)  NOT NULL DEFAULT 'open',
+    -- 1 while the report isn't closed, NULL afterwards, so the same
+    -- account can only have one pending report per target
+    pending     TINYINT      NULL DEFAULT 1,
+    assigned_to INT          NULL,
+    actions     VARCHAR(255) NOT NULL DEFAULT '',
+    note        VARCHAR(1000) NOT NULL DEFAULT '',
+    handled_by  INT          NULL,
+    handled_at  DATETIME     NULL,
+    created_at  DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at  DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    PRIMARY KEY (report_id),
+    UNIQUE KEY uq_report_pending (reporter_id, target_type, target_id, pending),
+    KEY idx_report_status (status, created_at),
+    KEY idx_report_target (target_type, target_id),
+    CONSTRAINT fk_report_owner FOREIGN KEY (owner_id) REFERENCES ACCOUNT (acc_id) ON DELETE SET NULL,
+    CONSTRAINT fk_report_reporter FOREIGN KEY (reporter_id) REFERENCES ACCOUNT (acc_id) ON DELETE SET NULL,
+    CONSTRAINT fk_report_assigned FOREIGN KEY (assigned_to) REFERENCES ACCOUNT (acc_id) ON DELETE SET NULL,
+    CONSTRAINT fk_report_handled FOREIGN KEY (handled_by) REFERENCES ACCOUNT (acc_id) ON DELETE SET NULL
+);
+
+CREATE TABLE ACCOUNT_WARNING (
+    warning_id INT          NOT NULL AUTO_INCREMENT,
+    acc_id     INT          NOT NULL,
+    report_id  INT          NULL,
+    message    VARCHAR(1000) NOT NULL,
+    created_by INT          NULL,
+    created_at DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (warning_id),
+    KEY idx_account_warning_acc (acc_id),
+    CONSTRAINT fk_account_warning_acc FOREIGN KEY (acc_id) REFERENCES ACCOUNT (acc_id) ON DELETE CASCADE,
+    CONSTRAINT fk_account_warning_report FOREIGN KEY (report_id) REFERENCES REPORT (report_id) ON DELETE SET NULL,
+    CONSTRAINT fk_account_warning_created_by FOREIGN KEY (created_by) REFERENCES ACCOUNT (acc_id) ON DELETE SET NULL
+);
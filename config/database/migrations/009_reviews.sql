@@ -0,0 +1,18 @@
+-- Ratings can carry a written review, an owner reply and helpful votes
+
+ALTER TABLE RATING
+    ADD COLUMN title      VARCHAR(120) NULL,
+    ADD COLUMN body       TEXT         NULL,
+    ADD COLUMN created_at DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    ADD COLUMN edited_at  DATETIME     NULL,
+    ADD COLUMN reply      TEXT         NULL,
+    ADD COLUMN replied_at DATETIME     NULL;
+
+CREATE TABLE REVIEW_VOTE (
+    rating_id  INT      NOT NULL,
+    acc_id     INT      NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (rating_id, acc_id),
+    CONSTRAINT fk_review_vote_rating FOREIGN KEY (rating_id) REFERENCES RATING (rating_id) ON DELETE CASCADE,
+    CONSTRAINT fk_review_vote_acc FOREIGN KEY (acc_id) REFERENCES ACCOUNT (acc_id) ON DELETE CASCADE
+);
@@ -0,0 +1,22 @@
+-- Threaded comments on decks and cards
+
+CREATE TABLE COMMENT (
+    comment_id INT          NOT NULL AUTO_INCREMENT,
+    deck_id    INT          NOT NULL,
+    card_id    INT          NULL, -- NULL for the deck thread
+    parent_id  INT          NULL,
+    acc_id     INT          NULL,
+    body       TEXT         NOT NULL, -- Markdown as written
+    html       TEXT         NOT NULL, -- Rendered and sanitised
+    mentions   VARCHAR(1000) NOT NULL DEFAULT '',
+    pinned     BOOLEAN      NOT NULL DEFAULT FALSE,
+    status     VARCHAR(16)  NOT NULL DEFAULT 'visible',
+    created_at DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    edited_at  DATETIME     NULL,
+    PRIMARY KEY (comment_id),
+    KEY idx_comment_thread (deck_id, card_id, created_at),
+    CONSTRAINT fk_comment_deck FOREIGN KEY (deck_id) REFERENCES DECK (deck_id) ON DELETE CASCADE,
+    CONSTRAINT fk_comment_card FOREIGN KEY (card_id) REFERENCES CARD (card_id) ON DELETE CASCADE,
+    CONSTRAINT fk_comment_parent FOREIGN KEY (parent_id) REFERENCES COMMENT (comment_id) ON DELETE CASCADE,
+    CONSTRAINT fk_comment_acc FOREIGN KEY (acc_id) REFERENCES ACCOUNT (acc_id) ON DELETE SET NULL
+);
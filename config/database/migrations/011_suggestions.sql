@@ -0,0 +1,26 @@
+-- Card edits and new cards proposed by subscribers
+
+CREATE TABLE SUGGESTION (
+    suggestion_id INT          NOT NULL AUTO_INCREMENT,
+    deck_id       INT          NOT NULL,
+    card_id       INT          NULL, -- NULL when proposing a new card
+    acc_id        INT          NULL,
+    title         VARCHAR(255) NULL, -- NULL fields are left unchanged
+    front         TEXT         NULL,
+    back          TEXT         NULL,
+    question      TEXT         NULL,
+    answer        TEXT         NULL,
+    wrong         TEXT         NOT NULL, -- JSON list of wrong answers
+    message       VARCHAR(1000) NOT NULL DEFAULT '', -- Why the change is needed
+    status        VARCHAR(16)  NOT NULL DEFAULT 'pending',
+    comment       VARCHAR(1000) NOT NULL DEFAULT '', -- Left by the owner when reviewing
+    result_card   INT          NULL, -- Card created when a new card is accepted
+    reviewed_at   DATETIME     NULL,
+    created_at    DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (suggestion_id),
+    KEY idx_suggestion_deck (deck_id, status),
+    CONSTRAINT fk_suggestion_deck FOREIGN KEY (deck_id) REFERENCES DECK (deck_id) ON DELETE CASCADE,
+    CONSTRAINT fk_suggestion_card FOREIGN KEY (card_id) REFERENCES CARD (card_id) ON DELETE CASCADE,
+    CONSTRAINT fk_suggestion_acc FOREIGN KEY (acc_id) REFERENCES ACCOUNT (acc_id) ON DELETE SET NULL,
+    CONSTRAINT fk_suggestion_result FOREIGN KEY (result_card) REFERENCES CARD (card_id) ON DELETE SET NULL
+);
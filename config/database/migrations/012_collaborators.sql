@@ -0,0 +1,16 @@
+-- Accounts working on a deck with its owner. Invitations are rows
+-- without accepted_at
+
+CREATE TABLE DECK_COLLABORATOR (
+    deck_id     INT         NOT NULL,
+    acc_id      INT         NOT NULL,
+    role        VARCHAR(16) NOT NULL,
+    invited_by  INT         NULL,
+    invited_at  DATETIME    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    accepted_at DATETIME    NULL,
+    PRIMARY KEY (deck_id, acc_id),
+    KEY idx_collaborator_acc (acc_id),
+    CONSTRAINT fk_collaborator_deck FOREIGN KEY (deck_id) REFERENCES DECK (deck_id) ON DELETE CASCADE,
+    CONSTRAINT fk_collaborator_acc FOREIGN KEY (acc_id) REFERENCES ACCOUNT (acc_id) ON DELETE CASCADE,
+    CONSTRAINT fk_collaborator_invited_by FOREIGN KEY (invited_by) REFERENCES ACCOUNT (acc_id) ON DELETE SET NULL
+);
@@ -0,0 +1,21 @@
+-- Deck ownership handed over to another account. The recipient has to
+-- accept before expires_at
+
+CREATE TABLE DECK_TRANSFER (
+    transfer_id INT         NOT NULL AUTO_INCREMENT,
+    deck_id     INT         NOT NULL,
+    from_acc    INT         NULL,
+    to_acc      INT         NOT NULL,
+    keep_role   VARCHAR(16) NOT NULL DEFAULT '', -- Role left to the previous owner, empty to leave the deck
+    status      VARCHAR(16) NOT NULL DEFAULT 'pending',
+    pending     TINYINT     NULL DEFAULT 1, -- NULL once resolved, allows one pending transfer per deck
+    expires_at  DATETIME    NOT NULL,
+    created_at  DATETIME    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    resolved_at DATETIME    NULL,
+    PRIMARY KEY (transfer_id),
+    UNIQUE KEY uq_transfer_pending (deck_id, pending),
+    KEY idx_transfer_to (to_acc, pending),
+    CONSTRAINT fk_transfer_deck FOREIGN KEY (deck_id) REFERENCES DECK (deck_id) ON DELETE CASCADE,
+    CONSTRAINT fk_transfer_from FOREIGN KEY (from_acc) REFERENCES ACCOUNT (acc_id) ON DELETE SET NULL,
+    CONSTRAINT fk_transfer_to FOREIGN KEY (to_acc) REFERENCES ACCOUNT (acc_id) ON DELETE CASCADE
+);
@@ -0,0 +1,20 @@
+-- Links that give access to a deck without publishing it. Revoked links
+-- are kept so the owner can see how they were used
+
+CREATE TABLE SHARE_LINK (
+    share_id   INT         NOT NULL AUTO_INCREMENT,
+    deck_id    INT         NOT NULL,
+    slug       VARCHAR(32) NOT NULL,
+    permission VARCHAR(16) NOT NULL DEFAULT 'view', -- view or subscribe
+    max_uses   INT         NULL, -- Unlimited when NULL
+    uses       INT         NOT NULL DEFAULT 0,
+    expires_at DATETIME    NULL, -- Never expires when NULL
+    created_by INT         NULL,
+    created_at DATETIME    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    revoked_at DATETIME    NULL,
+    PRIMARY KEY (share_id),
+    UNIQUE KEY uq_share_slug (slug),
+    KEY idx_share_deck (deck_id),
+    CONSTRAINT fk_share_deck FOREIGN KEY (deck_id) REFERENCES DECK (deck_id) ON DELETE CASCADE,
+    CONSTRAINT fk_share_account FOREIGN KEY (created_by) REFERENCES ACCOUNT (acc_id) ON DELETE SET NULL
+);
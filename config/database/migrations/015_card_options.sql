@@ -0,0 +1,50 @@
+-- Answers become an ordered list of options where any of them can be
+-- correct. Replaces CARD.answer and WRONG_ANSWER
+
+CREATE TABLE CARD_OPTION (
+    option_id INT     NOT NULL AUTO_INCREMENT,
+    card_id   INT     NOT NULL,
+    position  INT     NOT NULL,
+    answer    TEXT    NOT NULL,
+    correct   BOOLEAN NOT NULL DEFAULT FALSE,
+    PRIMARY KEY (option_id),
+    UNIQUE KEY uq_option_position (card_id, position),
+    CONSTRAINT fk_option_card FOREIGN KEY (card_id) REFERENCES CARD (card_id) ON DELETE CASCADE
+);
+
+ALTER TABLE CARD ADD COLUMN answer_type VARCHAR(16) NOT NULL DEFAULT 'single' AFTER question;
+
+-- Wrong answers keep their ids, pending suggestions point to them. The
+-- right answer goes first and the wrong ones follow in their order
+INSERT INTO CARD_OPTION (option_id, card_id, position, answer, correct)
+SELECT wrong_id, card_id, ROW_NUMBER() OVER (PARTITION BY card_id ORDER BY wrong_id), answer, FALSE
+FROM WRONG_ANSWER;
+
+INSERT INTO CARD_OPTION (card_id, position, answer, correct)
+SELECT card_id, 0, answer, TRUE FROM CARD;
+
+-- Suggestions propose options too. Wrong answers become options and the
+-- proposed answer the correct one, the current one for edits
+ALTER TABLE SUGGESTION ADD COLUMN answer_type VARCHAR(16) NULL AFTER question;
+
+UPDATE SUGGESTION s
+SET s.wrong = (
+    SELECT COALESCE(JSON_ARRAYAGG(JSON_OBJECT('option_id', w.wrong_id, 'answer', w.answer, 'correct', FALSE)), JSON_ARRAY())
+    FROM JSON_TABLE(s.wrong, '$[*]' COLUMNS (
+        wrong_id INT  PATH '$.wrong_id',
+        answer   TEXT PATH '$.answer'
+    )) w
+);
+
+UPDATE SUGGESTION s
+SET s.wrong = JSON_ARRAY_INSERT(s.wrong, '$[0]', JSON_OBJECT(
+    'option_id', (SELECT o.option_id FROM CARD_OPTION o WHERE o.card_id = s.card_id AND o.correct ORDER BY o.position LIMIT 1),
+    'answer', s.answer,
+    'correct', TRUE))
+WHERE s.answer IS NOT NULL;
+
+ALTER TABLE SUGGESTION RENAME COLUMN wrong TO options;
+ALTER TABLE SUGGESTION DROP COLUMN answer;
+
+ALTER TABLE CARD DROP COLUMN answer;
+DROP TABLE WRONG_ANSWER;
@@ -0,0 +1,11 @@
+-- Cards get a type with its own payload. Cloze and image occlusion cards
+-- generate a sibling for each deletion or mask after the first, which
+-- the source card asks about itself
+
+ALTER TABLE CARD ADD COLUMN card_type VARCHAR(16) NOT NULL DEFAULT 'basic' AFTER deck_id;
+ALTER TABLE CARD ADD COLUMN payload JSON NULL AFTER answer_type;
+ALTER TABLE CARD ADD COLUMN parent_id INT NULL AFTER payload;
+ALTER TABLE CARD ADD COLUMN sibling INT NOT NULL DEFAULT 0 AFTER parent_id;
+
+ALTER TABLE CARD ADD CONSTRAINT fk_card_parent FOREIGN KEY (parent_id) REFERENCES CARD (card_id) ON DELETE CASCADE;
+ALTER TABLE CARD ADD UNIQUE KEY uq_card_sibling (parent_id, sibling);
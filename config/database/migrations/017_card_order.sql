@@ -0,0 +1,32 @@
+-- Cards keep an explicit order in their deck and can be grouped in named
+-- sections. Decks are studied in sections order, cards outside of any
+-- section last, and each section in the order of its cards
+
+CREATE TABLE SECTION (
+    section_id INT          NOT NULL AUTO_INCREMENT,
+    deck_id    INT          NOT NULL,
+    name       VARCHAR(100) NOT NULL,
+    position   INT          NOT NULL,
+    created_at DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (section_id),
+    UNIQUE KEY uq_section_position (deck_id, position),
+    CONSTRAINT fk_section_deck FOREIGN KEY (deck_id) REFERENCES DECK (deck_id) ON DELETE CASCADE
+);
+
+-- Siblings take the position and section of their source card
+ALTER TABLE CARD ADD COLUMN section_id INT NULL AFTER deck_id;
+ALTER TABLE CARD ADD COLUMN position INT NOT NULL DEFAULT 0 AFTER section_id;
+
+ALTER TABLE CARD ADD CONSTRAINT fk_card_section FOREIGN KEY (section_id) REFERENCES SECTION (section_id) ON DELETE SET NULL;
+ALTER TABLE CARD ADD KEY idx_card_order (deck_id, position);
+
+-- Cards were shown in the order they were created
+UPDATE CARD c
+JOIN (SELECT card_id, ROW_NUMBER() OVER (PARTITION BY deck_id ORDER BY card_id) - 1 AS position
+      FROM CARD WHERE parent_id IS NULL) o ON c.card_id = o.card_id
+SET c.position = o.position;
+
+UPDATE CARD c JOIN CARD src ON c.parent_id = src.card_id SET c.position = src.position;
+
+-- How the study queue introduces new cards, shuffled or in deck order
+ALTER TABLE DECK ADD COLUMN new_cards VARCHAR(16) NOT NULL DEFAULT 'random';
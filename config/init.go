@@ -3,44 +3,160 @@ package config
 import (
 	"database/sql"
 	"learn-swiping-api/internal/account"
+	"learn-swiping-api/internal/admin"
+	"learn-swiping-api/internal/anki"
+	"learn-swiping-api/internal/apikey"
+	"learn-swiping-api/internal/audit"
 	"learn-swiping-api/internal/card"
+	"learn-swiping-api/internal/collaborator"
+	"learn-swiping-api/internal/comment"
 	"learn-swiping-api/internal/deck"
+	"learn-swiping-api/internal/export"
+	"learn-swiping-api/internal/importer"
+	"learn-swiping-api/internal/lockout"
+	"learn-swiping-api/internal/mailer"
+	"learn-swiping-api/internal/oidc"
 	"learn-swiping-api/internal/picture"
 	"learn-swiping-api/internal/progress"
+	"learn-swiping-api/internal/ratelimit"
+	"learn-swiping-api/internal/report"
+	"learn-swiping-api/internal/role"
+	"learn-swiping-api/internal/share"
+	"learn-swiping-api/internal/suggestion"
+	"learn-swiping-api/internal/transfer"
+	"log"
+	"os"
+	"time"
 )
 
 type Initialization struct {
-	UserCtrl     account.AccountController
-	DeckCtrl     deck.DeckController
-	CardCtrl     card.CardController
-	ProgressCtrl progress.ProgressController
-	PictureCtrl  picture.PictureController
+	UserCtrl         account.AccountController
+	DeckCtrl         deck.DeckController
+	CardCtrl         card.CardController
+	CommentCtrl      comment.CommentController
+	SuggestionCtrl   suggestion.SuggestionController
+	CollaboratorCtrl collaborator.CollaboratorController
+	TransferCtrl     transfer.TransferController
+	ShareCtrl        share.ShareController
+	AnkiCtrl         anki.AnkiController
+	ImporterCtrl     importer.ImporterController
+	ExportCtrl       export.ExportController
+	ProgressCtrl     progress.ProgressController
+	PictureCtrl      picture.PictureController
+	APIKeyCtrl       apikey.APIKeyController
+	LockoutCtrl      lockout.LockoutController
+	RoleCtrl         role.RoleController
+	AdminCtrl        admin.AdminController
+	AuditCtrl        audit.AuditController
+	ReportCtrl       report.ReportController
+	Limiter          *ratelimit.Limiter
 }
 
 func NewInitialization(db *sql.DB) *Initialization {
+	mailer := mailer.NewMailer()
+	providers := oidc.NewProvidersFromEnv()
+
+	lockoutRepo := lockout.NewLockoutRepository(db)
+	lockoutSrvc := lockout.NewLockoutService(lockoutRepo)
+	lockoutCtrl := lockout.NewLockoutController(lockoutSrvc)
+
 	userRepo := account.NewAccountRepository(db)
-	userSrvc := account.NewAccountService(userRepo)
+	userSrvc := account.NewAccountService(userRepo, mailer, providers, lockoutSrvc)
 	userCtrl := account.NewAccountController(userSrvc)
 
+	collaboratorRepo := collaborator.NewCollaboratorRepository(db)
+	collaboratorSrvc := collaborator.NewCollaboratorService(collaboratorRepo, mailer)
+	collaboratorCtrl := collaborator.NewCollaboratorController(collaboratorSrvc)
+
+	transferRepo := transfer.NewTransferRepository(db)
+	transferSrvc := transfer.NewTransferService(transferRepo, collaboratorSrvc, mailer)
+	transferCtrl := transfer.NewTransferController(transferSrvc)
+
 	deckRepo := deck.NewDeckRepository(db)
-	deckSrvc := deck.NewDeckService(deckRepo)
+	deckSrvc := deck.NewDeckService(deckRepo, collaboratorSrvc)
 	deckCtrl := deck.NewDeckController(deckSrvc)
 
+	commentRepo := comment.NewCommentRepository(db)
+	commentSrvc := comment.NewCommentService(commentRepo, collaboratorSrvc, mailer)
+	commentCtrl := comment.NewCommentController(commentSrvc)
+
 	cardRepo := card.NewCardRepository(db)
-	cardSrvc := card.NewCardService(cardRepo)
+	cardSrvc := card.NewCardService(cardRepo, commentSrvc, collaboratorSrvc)
 	cardCtrl := card.NewCardController(cardSrvc)
 
+	suggestionRepo := suggestion.NewSuggestionRepository(db)
+	suggestionSrvc := suggestion.NewSuggestionService(suggestionRepo, cardSrvc, collaboratorSrvc, mailer)
+	suggestionCtrl := suggestion.NewSuggestionController(suggestionSrvc)
+
+	shareRepo := share.NewShareRepository(db)
+	shareSrvc := share.NewShareService(shareRepo, deckSrvc, cardSrvc, collaboratorSrvc)
+	shareCtrl := share.NewShareController(shareSrvc)
+
+	ankiRepo := anki.NewAnkiRepository(db)
+	ankiSrvc := anki.NewAnkiService(ankiRepo, collaboratorSrvc)
+	ankiCtrl := anki.NewAnkiController(ankiSrvc)
+
+	importerRepo := importer.NewImporterRepository(db)
+	importerSrvc := importer.NewImporterService(importerRepo, collaboratorSrvc)
+	importerCtrl := importer.NewImporterController(importerSrvc)
+
+	exportRepo := export.NewExportRepository(db)
+	exportSrvc := export.NewExportService(exportRepo, collaboratorSrvc)
+	exportCtrl := export.NewExportController(exportSrvc)
+
 	progressRepo := progress.NewProgressRepository(db)
 	progressSrvc := progress.NewProgressService(progressRepo)
 	progressCtrl := progress.NewProgressController(progressSrvc)
 
 	pictureCtrl := picture.NewPictureController()
 
+	apiKeyRepo := apikey.NewAPIKeyRepository(db)
+	apiKeySrvc := apikey.NewAPIKeyService(apiKeyRepo)
+	apiKeyCtrl := apikey.NewAPIKeyController(apiKeySrvc)
+
+	roleRepo := role.NewRoleRepository(db)
+	roleSrvc := role.NewRoleService(roleRepo)
+	roleCtrl := role.NewRoleController(roleSrvc)
+
+	// BOOTSTRAP_ADMIN is made admin on startup while there are none
+	if err := roleSrvc.Bootstrap(os.Getenv("BOOTSTRAP_ADMIN")); err != nil {
+		log.Println(err)
+	}
+
+	adminRepo := admin.NewAdminRepository(db)
+	adminSrvc := admin.NewAdminService(adminRepo, userSrvc)
+	adminCtrl := admin.NewAdminController(adminSrvc)
+
+	auditRepo := audit.NewAuditRepository(db)
+	auditSrvc := audit.NewAuditService(auditRepo)
+	auditCtrl := audit.NewAuditController(auditSrvc)
+
+	reportRepo := report.NewReportRepository(db)
+	reportSrvc := report.NewReportService(reportRepo, adminSrvc, mailer)
+	reportCtrl := report.NewReportController(reportSrvc)
+
+	limiter := ratelimit.NewLimiter(ratelimit.NewMemoryStore(time.Minute), ratelimit.NewSQLResolver(db))
+
 	return &Initialization{
-		UserCtrl:     userCtrl,
-		DeckCtrl:     deckCtrl,
-		CardCtrl:     cardCtrl,
-		ProgressCtrl: progressCtrl,
-		PictureCtrl:  pictureCtrl,
+		UserCtrl:         userCtrl,
+		DeckCtrl:         deckCtrl,
+		CardCtrl:         cardCtrl,
+		CommentCtrl:      commentCtrl,
+		SuggestionCtrl:   suggestionCtrl,
+		CollaboratorCtrl: collaboratorCtrl,
+		TransferCtrl:     transferCtrl,
+		ShareCtrl:        shareCtrl,
+		AnkiCtrl:         ankiCtrl,
+		ImporterCtrl:     importerCtrl,
+		ExportCtrl:       exportCtrl,
+		ProgressCtrl:     progressCtrl,
+		PictureCtrl:      pictureCtrl,
+		APIKeyCtrl:       apiKeyCtrl,
+		LockoutCtrl:      lockoutCtrl,
+		RoleCtrl:         roleCtrl,
+		AdminCtrl:        adminCtrl,
+		AuditCtrl:        auditCtrl,
+		ReportCtrl:       reportCtrl,
+		Limiter:          limiter,
 	}
 }
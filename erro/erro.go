@@ -2,6 +2,7 @@ package erro
 
 import (
 	"errors"
+	"time"
 )
 
 var (
@@ -15,15 +16,121 @@ var (
 	ErrDeckExists   = errors.New("deck already exists")
 
 	ErrRatingNotFound = errors.New("rating not found")
+	ErrAlreadyVoted   = errors.New("review already voted")
+	ErrVoteNotFound   = errors.New("vote not found")
+	ErrOwnDeckRating  = errors.New("owners can't rate their own decks")
+	ErrNotEligible    = errors.New("subscribe and study some cards of the deck before rating it")
 
-	ErrCardNotFound  = errors.New("card not found")
-	ErrWrongNotFound = errors.New("wrong answer not found")
-	ErrCardExists    = errors.New("card already exists")
+	ErrCardNotFound    = errors.New("card not found")
+	ErrOptionNotFound  = errors.New("answer option not found")
+	ErrCardExists      = errors.New("card already exists")
+	ErrCardGenerated   = errors.New("card was generated from another one, change that one instead")
+	ErrBatchInvalid    = errors.New("some operations can't be applied, nothing was changed")
+	ErrSectionNotFound = errors.New("section not found")
+
+	ErrCommentNotFound = errors.New("comment not found")
+	ErrCommentParent   = errors.New("replies must belong to the same thread")
+	ErrNotPinnable     = errors.New("only top level comments can be pinned")
+
+	ErrSuggestionNotFound = errors.New("suggestion not found")
+	ErrSuggestionClosed   = errors.New("suggestion already reviewed")
+	ErrOwnDeckSuggestion  = errors.New("you can edit the cards of this deck directly")
+	ErrNotSubscribed      = errors.New("subscribe to the deck first")
+
+	ErrCollaboratorExists   = errors.New("account already collaborates on the deck")
+	ErrCollaboratorNotFound = errors.New("collaborator not found")
+	ErrInvitationNotFound   = errors.New("invitation not found")
+
+	ErrTransferNotFound = errors.New("transfer not found")
+	ErrTransferExists   = errors.New("deck already has a pending transfer")
+	ErrTransferExpired  = errors.New("transfer expired")
+
+	ErrShareLinkNotFound = errors.New("share link not found")
+	ErrShareLinkExpired  = errors.New("share link expired or used up")
+	ErrShareViewOnly     = errors.New("share link doesn't allow subscribing")
+
+	ErrApkgInvalid     = errors.New("not a valid anki package")
+	ErrApkgUnsupported = errors.New("collection format not supported, export it with support for older anki versions")
+	ErrApkgEmpty       = errors.New("the package has no notes that can be imported")
+
+	ErrImportFormat   = errors.New("unknown import format, use csv, tsv or json")
+	ErrImportFile     = errors.New("file couldn't be read in the given format")
+	ErrImportMapping  = errors.New("column mapping doesn't match the file")
+	ErrImportOutdated = errors.New("file or mapping changed since the preview")
+	ErrImportInvalid  = errors.New("some rows have errors")
+	ErrImportEmpty    = errors.New("no rows left to import")
 
 	ErrProgressNotFound = errors.New("progress not found")
 	ErrProgressExists   = errors.New("progress already exists")
 
 	ErrBadField     = errors.New("field is empty or invalid")
+	ErrFileTooLarge = errors.New("file is too large")
 	ErrInvalidToken = errors.New("invalid token")
 	ErrInvalidEmail = errors.New("invalid email")
+	ErrTokenExpired = errors.New("token expired")
+
+	ErrAlreadyVerified = errors.New("email already verified")
+
+	ErrInvalidCode    = errors.New("invalid code")
+	ErrTOTPEnabled    = errors.New("two-factor authentication already enabled")
+	ErrTOTPNotEnabled = errors.New("two-factor authentication not enabled")
+
+	ErrProviderNotFound = errors.New("identity provider not found")
+	ErrIdentityNotFound = errors.New("identity not found")
+	ErrIdentityExists   = errors.New("identity already linked to an account")
+
+	ErrInvalidAPIKey  = errors.New("invalid api key")
+	ErrAPIKeyNotFound = errors.New("api key not found")
+	ErrAPIKeyExists   = errors.New("api key already exists")
+	ErrInvalidScope   = errors.New("invalid scope")
+	ErrMissingScope   = errors.New("api key lacks the required scope")
+
+	ErrTooManyAttempts = errors.New("too many failed attempts, try again later")
+	ErrLockoutNotFound = errors.New("lockout not found")
+	ErrForbidden       = errors.New("forbidden")
+	ErrRateLimited     = errors.New("rate limit exceeded")
+
+	ErrAccountSuspended = errors.New("account suspended")
+	ErrAccountBanned    = errors.New("account banned")
+	ErrNotSuspended     = errors.New("account not suspended")
+	ErrDeckHidden       = errors.New("deck hidden by an administrator")
+
+	ErrReportNotFound = errors.New("report not found")
+	ErrReportExists   = errors.New("content already reported")
+	ErrReportClosed   = errors.New("report already closed")
 )
+
+// Returned while logins are blocked. It matches ErrTooManyAttempts with
+// errors.Is and carries when the block ends
+type LockedError struct {
+	Until time.Time
+}
+
+func (e *LockedError) Error() string {
+	return ErrTooManyAttempts.Error()
+}
+
+func (e *LockedError) Is(target error) bool {
+	return target == ErrTooManyAttempts
+}
+
+// Returned when a suspended or banned account tries to log in. It matches
+// ErrAccountSuspended or ErrAccountBanned with errors.Is
+type SuspendedError struct {
+	Reason string
+	Until  *time.Time // Nil when banned
+}
+
+func (e *SuspendedError) Error() string {
+	if e.Until == nil {
+		return ErrAccountBanned.Error()
+	}
+	return ErrAccountSuspended.Error()
+}
+
+func (e *SuspendedError) Is(target error) bool {
+	if e.Until == nil {
+		return target == ErrAccountBanned
+	}
+	return target == ErrAccountSuspended
+}
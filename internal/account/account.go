@@ -3,14 +3,74 @@ package account
 import "time"
 
 type Account struct {
-	ID           int64     `json:"acc_id"`
-	Username     string    `json:"username"`
-	Password     string    `json:"-"`
-	Email        string    `json:"email,omitempty"`
-	Name         string    `json:"name"`
-	PicID        string    `json:"pic_id"`
-	Token        string    `json:"token"`
-	TokenExpires time.Time `json:"token_expires"`
-	LastSeen     time.Time `json:"last_seen"`
-	Since        time.Time `json:"since"`
+	ID            int64     `json:"acc_id"`
+	Username      string    `json:"username"`
+	Password      string    `json:"-"`
+	Email         string    `json:"email,omitempty"`
+	EmailVerified bool      `json:"email_verified"`
+	Name          string    `json:"name"`
+	PicID         string    `json:"pic_id"`
+	Token         string    `json:"token"`
+	TokenExpires  time.Time `json:"token_expires"`
+	LastSeen      time.Time `json:"last_seen"`
+	Since         time.Time `json:"since"`
+}
+
+// Purposes an AccountToken can be issued for
+const (
+	PurposeVerifyEmail   = "verify_email"
+	PurposeResetPassword = "reset_password"
+	PurposeLoginTOTP     = "login_totp"
+)
+
+// Single-use token sent by email. Only the SHA-256 hash of the
+// token is stored so a database leak doesn't expose usable links.
+type AccountToken struct {
+	ID        int64
+	AccID     int64
+	Purpose   string
+	Hash      string
+	Email     string // Address being verified, empty for password resets
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+// TOTP secret of an account. It isn't enforced on login until confirmed
+type TOTP struct {
+	AccID     int64
+	Secret    string
+	Confirmed bool
+	LastStep  int64 // Last accepted time step, used to reject replayed codes
+	CreatedAt time.Time
+}
+
+// Login from an external OpenID Connect provider linked to an account
+type Identity struct {
+	ID        int64     `json:"identity_id"`
+	AccID     int64     `json:"acc_id"`
+	Provider  string    `json:"provider"`
+	Subject   string    `json:"subject"`
+	Email     string    `json:"email,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	LastLogin time.Time `json:"last_login"`
+}
+
+// Authorization request waiting for the provider callback
+type OIDCState struct {
+	State     string
+	Provider  string
+	Nonce     string
+	Verifier  string
+	AccID     *int64 // Set when linking to an existing account
+	ExpiresAt time.Time
+}
+
+// Set by an administrator. A ban is a suspension without an end date
+type Suspension struct {
+	AccID     int64      `json:"acc_id"`
+	Reason    string     `json:"reason"`
+	Until     *time.Time `json:"until"`
+	CreatedBy *int64     `json:"created_by"`
+	CreatedAt time.Time  `json:"created_at"`
 }
@@ -4,7 +4,10 @@ import (
 	"errors"
 	"learn-swiping-api/erro"
 	account "learn-swiping-api/internal/account/dto"
+	"math"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -12,12 +15,29 @@ import (
 type AccountController interface {
 	Register(*gin.Context)      // POST
 	Login(*gin.Context)         // POST
+	LoginTOTP(*gin.Context)     // POST
 	Token(*gin.Context)         // POST
 	Logout(*gin.Context)        // POST
 	Account(*gin.Context)       // GET
 	AccountPublic(*gin.Context) // GET
 	Update(*gin.Context)        // PUT
 	Delete(*gin.Context)        // DELETE
+
+	VerifyEmail(*gin.Context)        // POST
+	ResendVerification(*gin.Context) // POST
+	ForgotPassword(*gin.Context)     // POST
+	ResetPassword(*gin.Context)      // POST
+
+	EnrolTOTP(*gin.Context)               // POST
+	ConfirmTOTP(*gin.Context)             // POST
+	DisableTOTP(*gin.Context)             // DELETE
+	RegenerateRecoveryCodes(*gin.Context) // POST
+
+	OIDCLogin(*gin.Context)      // GET
+	OIDCCallback(*gin.Context)   // GET
+	LinkIdentity(*gin.Context)   // POST
+	Identities(*gin.Context)     // GET
+	UnlinkIdentity(*gin.Context) // DELETE
 }
 
 type AccountControllerImpl struct {
@@ -65,8 +85,13 @@ func (c *AccountControllerImpl) Login(ctx *gin.Context) {
 		return
 	}
 
-	account, err := c.service.Login(request)
+	request.IP = ctx.ClientIP()
+
+	acc, challenge, err := c.service.Login(request)
 	if err != nil {
+		if tooManyAttempts(ctx, err) || suspended(ctx, err) {
+			return
+		}
 		if errors.Is(err, erro.ErrAccountNotFound) {
 			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 			return
@@ -75,7 +100,44 @@ func (c *AccountControllerImpl) Login(ctx *gin.Context) {
 		return
 	}
 
-	ctx.JSON(http.StatusOK, account)
+	// Password was right but a TOTP code is still needed
+	if challenge != "" {
+		ctx.JSON(http.StatusAccepted, account.LoginChallenge{TwoFactorRequired: true, Challenge: challenge})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, acc)
+}
+
+// Finishes a login started with Login using a TOTP or recovery code
+// Method: POST
+func (c *AccountControllerImpl) LoginTOTP(ctx *gin.Context) {
+	var request account.LoginTOTPRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrBadField.Error()})
+		return
+	}
+
+	request.IP = ctx.ClientIP()
+
+	acc, err := c.service.LoginTOTP(request)
+	if err != nil {
+		if tooManyAttempts(ctx, err) || suspended(ctx, err) {
+			return
+		}
+		if errors.Is(err, erro.ErrInvalidToken) || errors.Is(err, erro.ErrTokenExpired) || errors.Is(err, erro.ErrBadField) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, erro.ErrInvalidCode) {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, acc)
 }
 
 // Retrieves an account if token is correct
@@ -196,6 +258,10 @@ func (c *AccountControllerImpl) Update(ctx *gin.Context) {
 			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
+		if errors.Is(err, erro.ErrAccountExists) {
+			ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -224,3 +290,347 @@ func (c *AccountControllerImpl) Delete(ctx *gin.Context) {
 
 	ctx.JSON(http.StatusOK, gin.H{})
 }
+
+// Confirms an email address with the token sent by email
+// Method: POST
+func (c *AccountControllerImpl) VerifyEmail(ctx *gin.Context) {
+	var request account.VerifyRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrBadField.Error()})
+		return
+	}
+
+	if err := c.service.VerifyEmail(request); err != nil {
+		if errors.Is(err, erro.ErrInvalidToken) || errors.Is(err, erro.ErrTokenExpired) || errors.Is(err, erro.ErrBadField) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, erro.ErrAccountExists) {
+			ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{})
+}
+
+// Sends again the verification email of the account
+// Method: POST
+func (c *AccountControllerImpl) ResendVerification(ctx *gin.Context) {
+	token := ctx.GetHeader("Token")
+	if token == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrInvalidToken.Error()})
+		return
+	}
+
+	if err := c.service.ResendVerification(token); err != nil {
+		if errors.Is(err, erro.ErrInvalidToken) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, erro.ErrAlreadyVerified) {
+			ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{})
+}
+
+// Sends a password reset link. Always answers OK when the request is
+// well formed so it can't be used to guess accounts
+// Method: POST
+func (c *AccountControllerImpl) ForgotPassword(ctx *gin.Context) {
+	var request account.ForgotRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrBadField.Error()})
+		return
+	}
+
+	if err := c.service.ForgotPassword(request); err != nil {
+		if errors.Is(err, erro.ErrBadField) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{})
+}
+
+// Sets a new password with the token sent by email
+// Method: POST
+func (c *AccountControllerImpl) ResetPassword(ctx *gin.Context) {
+	var request account.ResetRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrBadField.Error()})
+		return
+	}
+
+	if err := c.service.ResetPassword(request); err != nil {
+		if errors.Is(err, erro.ErrInvalidToken) || errors.Is(err, erro.ErrTokenExpired) || errors.Is(err, erro.ErrBadField) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{})
+}
+
+// Starts the 2FA enrolment returning the secret and provisioning URI
+// Method: POST
+func (c *AccountControllerImpl) EnrolTOTP(ctx *gin.Context) {
+	token := ctx.GetHeader("Token")
+	if token == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrInvalidToken.Error()})
+		return
+	}
+
+	enrolment, err := c.service.EnrolTOTP(token)
+	if err != nil {
+		if errors.Is(err, erro.ErrInvalidToken) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, erro.ErrTOTPEnabled) {
+			ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, enrolment)
+}
+
+// Enables 2FA with a first valid code and returns the recovery codes
+// Method: POST
+func (c *AccountControllerImpl) ConfirmTOTP(ctx *gin.Context) {
+	token := ctx.GetHeader("Token")
+	if token == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrInvalidToken.Error()})
+		return
+	}
+
+	var request account.TOTPRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrBadField.Error()})
+		return
+	}
+
+	codes, err := c.service.ConfirmTOTP(token, request)
+	if err != nil {
+		totpError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, codes)
+}
+
+// Disables 2FA, needs a TOTP or recovery code
+// Method: DELETE
+func (c *AccountControllerImpl) DisableTOTP(ctx *gin.Context) {
+	token := ctx.GetHeader("Token")
+	if token == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrInvalidToken.Error()})
+		return
+	}
+
+	var request account.TOTPRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrBadField.Error()})
+		return
+	}
+
+	if err := c.service.DisableTOTP(token, request); err != nil {
+		totpError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{})
+}
+
+// Replaces the recovery codes, needs a TOTP or recovery code
+// Method: POST
+func (c *AccountControllerImpl) RegenerateRecoveryCodes(ctx *gin.Context) {
+	token := ctx.GetHeader("Token")
+	if token == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrInvalidToken.Error()})
+		return
+	}
+
+	var request account.TOTPRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrBadField.Error()})
+		return
+	}
+
+	codes, err := c.service.RegenerateRecoveryCodes(token, request)
+	if err != nil {
+		totpError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, codes)
+}
+
+// Writes the response when an administrator blocked the account
+func suspended(ctx *gin.Context, err error) bool {
+	var suspension *erro.SuspendedError
+	if !errors.As(err, &suspension) {
+		return false
+	}
+
+	ctx.JSON(http.StatusForbidden, gin.H{"error": err.Error(), "reason": suspension.Reason, "until": suspension.Until})
+	return true
+}
+
+// Answers with 429 and a Retry-After header if logins are blocked
+func tooManyAttempts(ctx *gin.Context, err error) bool {
+	var locked *erro.LockedError
+	if !errors.As(err, &locked) {
+		return false
+	}
+
+	retry := int(math.Ceil(time.Until(locked.Until).Seconds()))
+	ctx.Header("Retry-After", strconv.Itoa(max(retry, 1)))
+	ctx.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+	return true
+}
+
+// Writes the response for errors shared by the 2FA management endpoints
+func totpError(ctx *gin.Context, err error) {
+	if errors.Is(err, erro.ErrInvalidToken) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, erro.ErrInvalidCode) {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, erro.ErrTOTPEnabled) || errors.Is(err, erro.ErrTOTPNotEnabled) {
+		ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
+
+// Redirects to the provider to start a "sign in with" login
+// Method: GET
+func (c *AccountControllerImpl) OIDCLogin(ctx *gin.Context) {
+	url, err := c.service.OIDCAuthURL(ctx.Param("provider"), "")
+	if err != nil {
+		oidcError(ctx, err)
+		return
+	}
+
+	ctx.Redirect(http.StatusFound, url)
+}
+
+// Finishes the provider login, registering the account on first login
+// Method: GET
+func (c *AccountControllerImpl) OIDCCallback(ctx *gin.Context) {
+	if providerErr := ctx.Query("error"); providerErr != "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": providerErr})
+		return
+	}
+
+	acc, challenge, err := c.service.OIDCCallback(ctx.Param("provider"), ctx.Query("code"), ctx.Query("state"))
+	if err != nil {
+		oidcError(ctx, err)
+		return
+	}
+
+	if challenge != "" {
+		ctx.JSON(http.StatusAccepted, account.LoginChallenge{TwoFactorRequired: true, Challenge: challenge})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, acc)
+}
+
+// Returns the provider URL to link an external identity to the account
+// Method: POST
+func (c *AccountControllerImpl) LinkIdentity(ctx *gin.Context) {
+	token := ctx.GetHeader("Token")
+	if token == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrInvalidToken.Error()})
+		return
+	}
+
+	url, err := c.service.OIDCAuthURL(ctx.Param("provider"), token)
+	if err != nil {
+		oidcError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"url": url})
+}
+
+// Lists the external identities linked to the account
+// Method: GET
+func (c *AccountControllerImpl) Identities(ctx *gin.Context) {
+	token := ctx.GetHeader("Token")
+	if token == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrInvalidToken.Error()})
+		return
+	}
+
+	identities, err := c.service.Identities(token)
+	if err != nil {
+		oidcError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, identities)
+}
+
+// Removes an external identity from the account
+// Method: DELETE
+func (c *AccountControllerImpl) UnlinkIdentity(ctx *gin.Context) {
+	token := ctx.GetHeader("Token")
+	if token == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrInvalidToken.Error()})
+		return
+	}
+
+	identityID, err := strconv.Atoi(ctx.Param("identityID"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrBadField.Error()})
+		return
+	}
+
+	if err := c.service.UnlinkIdentity(token, int64(identityID)); err != nil {
+		oidcError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{})
+}
+
+// Writes the response for errors shared by the OpenID Connect endpoints
+func oidcError(ctx *gin.Context, err error) {
+	if suspended(ctx, err) {
+		return
+	}
+	if errors.Is(err, erro.ErrInvalidToken) || errors.Is(err, erro.ErrTokenExpired) || errors.Is(err, erro.ErrBadField) || errors.Is(err, erro.ErrInvalidEmail) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, erro.ErrProviderNotFound) || errors.Is(err, erro.ErrIdentityNotFound) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, erro.ErrAccountExists) || errors.Is(err, erro.ErrIdentityExists) {
+		ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
@@ -0,0 +1,7 @@
+package account
+
+// Either the username or the email is needed
+type ForgotRequest struct {
+	Username string `json:"username"`
+	Email    string `json:"email"`
+}
@@ -1,6 +1,7 @@
 package account
 
 type LoginRequest struct {
+	IP       string // Gathered from the request
 	Username string `json:"username" binding:"required"`
 	Password string `json:"password" binding:"required"`
 }
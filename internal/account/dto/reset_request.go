@@ -0,0 +1,6 @@
+package account
+
+type ResetRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
@@ -0,0 +1,12 @@
+package account
+
+type TOTPRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// Second step of the login, the code can also be a recovery code
+type LoginTOTPRequest struct {
+	IP        string // Gathered from the request
+	Challenge string `json:"challenge" binding:"required"`
+	Code      string `json:"code" binding:"required"`
+}
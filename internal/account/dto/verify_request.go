@@ -0,0 +1,5 @@
+package account
+
+type VerifyRequest struct {
+	Token string `json:"token" binding:"required"`
+}
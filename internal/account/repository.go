@@ -18,6 +18,31 @@ type AccountRepository interface {
 	ByToken(token string) (Account, error)
 	Update(id int64, account Account) error
 	Delete(token string) error
+
+	ByEmail(email string) (Account, error)
+	VerifyEmail(id int64, email string) error
+	CreateToken(AccountToken) (int64, error)
+	TokenByHash(hash string, purpose string) (AccountToken, error)
+	UseToken(tokenID int64) error
+	InvalidateTokens(accID int64, purpose string) error
+
+	TOTP(accID int64) (TOTP, error)
+	SaveTOTP(accID int64, secret string) error
+	ConfirmTOTP(accID int64, step int64) error
+	UseTOTPStep(accID int64, step int64) error
+	DeleteTOTP(accID int64) error
+	ReplaceRecoveryCodes(accID int64, hashes []string) error
+	UseRecoveryCode(accID int64, hash string) error
+
+	CreateOIDCState(OIDCState) error
+	TakeOIDCState(state string) (OIDCState, error)
+	Identity(provider string, subject string) (Identity, error)
+	Identities(accID int64) ([]Identity, error)
+	CreateIdentity(Identity) (int64, error)
+	TouchIdentity(identityID int64) error
+	DeleteIdentity(identityID int64, accID int64) error
+
+	Suspension(accID int64) (Suspension, error)
 }
 
 type AccountRepositoryImpl struct {
@@ -27,6 +52,28 @@ type AccountRepositoryImpl struct {
 	ByUsernameStmt  *sql.Stmt
 	DeleteStmt      *sql.Stmt
 	UnlinkDecksStmt *sql.Stmt
+
+	ByEmailStmt          *sql.Stmt
+	VerifyEmailStmt      *sql.Stmt
+	CreateTokenStmt      *sql.Stmt
+	TokenByHashStmt      *sql.Stmt
+	UseTokenStmt         *sql.Stmt
+	InvalidateTokensStmt *sql.Stmt
+
+	TOTPStmt            *sql.Stmt
+	SaveTOTPStmt        *sql.Stmt
+	ConfirmTOTPStmt     *sql.Stmt
+	UseTOTPStepStmt     *sql.Stmt
+	UseRecoveryCodeStmt *sql.Stmt
+
+	CreateOIDCStateStmt *sql.Stmt
+	IdentityStmt        *sql.Stmt
+	IdentitiesStmt      *sql.Stmt
+	CreateIdentityStmt  *sql.Stmt
+	TouchIdentityStmt   *sql.Stmt
+	DeleteIdentityStmt  *sql.Stmt
+
+	SuspensionStmt *sql.Stmt
 }
 
 func NewAccountRepository(db *sql.DB) *AccountRepositoryImpl {
@@ -60,10 +107,118 @@ func (r *AccountRepositoryImpl) InitStatements() error {
 		return err
 	}
 
+	// Public decks, and the ones waiting for their new owner to accept a
+	// transfer, go to the account with the system role ("deleted user")
 	r.UnlinkDecksStmt, err = r.db.Prepare(`UPDATE DECK d 
 											LEFT JOIN ACCOUNT a ON d.acc_id = a.acc_id
-											SET d.acc_id = 1
-											WHERE d.visible = 1 AND a.token = ?`)
+											SET d.acc_id = (SELECT acc_id FROM ACCOUNT_ROLE WHERE role = 'system' ORDER BY acc_id LIMIT 1)
+											WHERE a.token = ? AND (d.visible = 1 OR EXISTS (
+												SELECT 1 FROM DECK_TRANSFER t WHERE t.deck_id = d.deck_id AND t.pending = 1 AND t.expires_at > NOW()))`)
+	if err != nil {
+		return err
+	}
+
+	r.ByEmailStmt, err = r.db.Prepare("SELECT * FROM ACCOUNT WHERE email = ?")
+	if err != nil {
+		return err
+	}
+
+	r.VerifyEmailStmt, err = r.db.Prepare("UPDATE ACCOUNT SET email = ?, email_verified = TRUE WHERE acc_id = ?")
+	if err != nil {
+		return err
+	}
+
+	r.CreateTokenStmt, err = r.db.Prepare("INSERT INTO ACCOUNT_TOKEN (acc_id, purpose, token_hash, email, expires_at) VALUES (?, ?, ?, ?, ?)")
+	if err != nil {
+		return err
+	}
+
+	r.TokenByHashStmt, err = r.db.Prepare(`SELECT token_id, acc_id, purpose, token_hash, email, expires_at, used_at, created_at
+											FROM ACCOUNT_TOKEN
+											WHERE token_hash = ? AND purpose = ?`)
+	if err != nil {
+		return err
+	}
+
+	// Checking used_at in the same query makes the token single-use even
+	// with concurrent requests
+	r.UseTokenStmt, err = r.db.Prepare("UPDATE ACCOUNT_TOKEN SET used_at = NOW() WHERE token_id = ? AND used_at IS NULL")
+	if err != nil {
+		return err
+	}
+
+	r.InvalidateTokensStmt, err = r.db.Prepare("UPDATE ACCOUNT_TOKEN SET used_at = NOW() WHERE acc_id = ? AND purpose = ? AND used_at IS NULL")
+	if err != nil {
+		return err
+	}
+
+	r.TOTPStmt, err = r.db.Prepare("SELECT acc_id, secret, confirmed, last_step, created_at FROM ACCOUNT_TOTP WHERE acc_id = ?")
+	if err != nil {
+		return err
+	}
+
+	// A confirmed secret is never overwritten, it has to be deleted first
+	r.SaveTOTPStmt, err = r.db.Prepare(`INSERT INTO ACCOUNT_TOTP (acc_id, secret) VALUES (?, ?)
+											ON DUPLICATE KEY UPDATE
+												secret = IF(confirmed, secret, VALUES(secret)),
+												created_at = IF(confirmed, created_at, NOW())`)
+	if err != nil {
+		return err
+	}
+
+	r.ConfirmTOTPStmt, err = r.db.Prepare("UPDATE ACCOUNT_TOTP SET confirmed = TRUE, last_step = ? WHERE acc_id = ? AND confirmed = FALSE")
+	if err != nil {
+		return err
+	}
+
+	// Only moving forward makes each code usable once
+	r.UseTOTPStepStmt, err = r.db.Prepare("UPDATE ACCOUNT_TOTP SET last_step = ? WHERE acc_id = ? AND last_step < ?")
+	if err != nil {
+		return err
+	}
+
+	r.UseRecoveryCodeStmt, err = r.db.Prepare("UPDATE RECOVERY_CODE SET used_at = NOW() WHERE acc_id = ? AND code_hash = ? AND used_at IS NULL")
+	if err != nil {
+		return err
+	}
+
+	r.CreateOIDCStateStmt, err = r.db.Prepare("INSERT INTO OIDC_STATE (state, provider, nonce, verifier, acc_id, expires_at) VALUES (?, ?, ?, ?, ?, ?)")
+	if err != nil {
+		return err
+	}
+
+	r.IdentityStmt, err = r.db.Prepare(`SELECT identity_id, acc_id, provider, subject, email, created_at, last_login
+											FROM ACCOUNT_IDENTITY
+											WHERE provider = ? AND subject = ?`)
+	if err != nil {
+		return err
+	}
+
+	r.IdentitiesStmt, err = r.db.Prepare(`SELECT identity_id, acc_id, provider, subject, email, created_at, last_login
+											FROM ACCOUNT_IDENTITY
+											WHERE acc_id = ?`)
+	if err != nil {
+		return err
+	}
+
+	r.CreateIdentityStmt, err = r.db.Prepare("INSERT INTO ACCOUNT_IDENTITY (acc_id, provider, subject, email) VALUES (?, ?, ?, ?)")
+	if err != nil {
+		return err
+	}
+
+	r.TouchIdentityStmt, err = r.db.Prepare("UPDATE ACCOUNT_IDENTITY SET last_login = NOW() WHERE identity_id = ?")
+	if err != nil {
+		return err
+	}
+
+	r.DeleteIdentityStmt, err = r.db.Prepare("DELETE FROM ACCOUNT_IDENTITY WHERE identity_id = ? AND acc_id = ?")
+	if err != nil {
+		return err
+	}
+
+	r.SuspensionStmt, err = r.db.Prepare(`SELECT acc_id, reason, until, created_by, created_at
+											FROM ACCOUNT_SUSPENSION
+											WHERE acc_id = ? AND (until IS NULL OR until > NOW())`)
 	if err != nil {
 		return err
 	}
@@ -95,8 +250,9 @@ func (r *AccountRepositoryImpl) ByUsername(Username string) (Account, error) {
 
 func (r *AccountRepositoryImpl) ByToken(token string) (Account, error) {
 	// Checking token expire date on repository just for simplicity as is strange this is going to change
-	// or cause problems
-	stmt, err := r.db.Prepare("SELECT * FROM ACCOUNT WHERE token = ? AND token_expire >= NOW();")
+	// or cause problems. Suspended accounts aren't logged in either
+	stmt, err := r.db.Prepare(`SELECT * FROM ACCOUNT WHERE token = ? AND token_expire >= NOW()
+									AND acc_id NOT IN (SELECT acc_id FROM ACCOUNT_SUSPENSION WHERE until IS NULL OR until > NOW());`)
 	if err != nil {
 		return Account{}, err
 	}
@@ -148,9 +304,10 @@ func (r *AccountRepositoryImpl) Update(id int64, account Account) error {
 
 func (r *AccountRepositoryImpl) Delete(token string) error {
 	// Necessary to not to delete decks when account is removed
-	// deck's owner now is account 1 (deleted user)
-	// Note that only public decks are saved into the
-	// auxiliar account, the hidden ones are removed
+	// deck's owner now is the system account (deleted user)
+	// Note that only public decks and the ones with a pending
+	// transfer are saved into the auxiliar account, the hidden
+	// ones are removed
 	_, err := r.UnlinkDecksStmt.Exec(token)
 	if err != nil {
 		return err
@@ -175,6 +332,375 @@ func (r *AccountRepositoryImpl) Delete(token string) error {
 	return nil
 }
 
+func (r *AccountRepositoryImpl) ByEmail(email string) (Account, error) {
+	row := r.ByEmailStmt.QueryRow(email)
+	return scanaccount(row)
+}
+
+// Replaces the account email with a verified one
+func (r *AccountRepositoryImpl) VerifyEmail(id int64, email string) error {
+	result, err := r.VerifyEmailStmt.Exec(email, id)
+	if err != nil {
+		if mysqlErr, ok := err.(*mysql.MySQLError); ok && mysqlErr.Number == 1062 {
+			return erro.ErrAccountExists
+		}
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return erro.ErrAccountNotFound
+	}
+
+	return nil
+}
+
+func (r *AccountRepositoryImpl) CreateToken(token AccountToken) (int64, error) {
+	result, err := r.CreateTokenStmt.Exec(token.AccID, token.Purpose, token.Hash, token.Email, token.ExpiresAt)
+	if err != nil {
+		if mysqlErr, ok := err.(*mysql.MySQLError); ok && mysqlErr.Number == 1452 {
+			return 0, erro.ErrAccountNotFound
+		}
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+func (r *AccountRepositoryImpl) TokenByHash(hash string, purpose string) (AccountToken, error) {
+	row := r.TokenByHashStmt.QueryRow(hash, purpose)
+
+	var token AccountToken
+	err := row.Scan(
+		&token.ID,
+		&token.AccID,
+		&token.Purpose,
+		&token.Hash,
+		&token.Email,
+		&token.ExpiresAt,
+		&token.UsedAt,
+		&token.CreatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return AccountToken{}, erro.ErrInvalidToken
+		}
+		return AccountToken{}, err
+	}
+
+	return token, nil
+}
+
+// Marks a token as used. Fails with ErrInvalidToken if it was already used
+func (r *AccountRepositoryImpl) UseToken(tokenID int64) error {
+	result, err := r.UseTokenStmt.Exec(tokenID)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return erro.ErrInvalidToken
+	}
+
+	return nil
+}
+
+// Marks every pending token of an account for a purpose as used
+func (r *AccountRepositoryImpl) InvalidateTokens(accID int64, purpose string) error {
+	_, err := r.InvalidateTokensStmt.Exec(accID, purpose)
+	return err
+}
+
+func (r *AccountRepositoryImpl) TOTP(accID int64) (TOTP, error) {
+	row := r.TOTPStmt.QueryRow(accID)
+
+	var totp TOTP
+	err := row.Scan(
+		&totp.AccID,
+		&totp.Secret,
+		&totp.Confirmed,
+		&totp.LastStep,
+		&totp.CreatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return TOTP{}, erro.ErrTOTPNotEnabled
+		}
+		return TOTP{}, err
+	}
+
+	return totp, nil
+}
+
+// Stores a pending secret, replacing any previous unconfirmed one
+func (r *AccountRepositoryImpl) SaveTOTP(accID int64, secret string) error {
+	_, err := r.SaveTOTPStmt.Exec(accID, secret)
+	if err != nil {
+		if mysqlErr, ok := err.(*mysql.MySQLError); ok && mysqlErr.Number == 1452 {
+			return erro.ErrAccountNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+func (r *AccountRepositoryImpl) ConfirmTOTP(accID int64, step int64) error {
+	result, err := r.ConfirmTOTPStmt.Exec(step, accID)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return erro.ErrTOTPEnabled
+	}
+
+	return nil
+}
+
+// Records the time step of an accepted code. Fails with ErrInvalidCode if
+// that step or a later one was already used
+func (r *AccountRepositoryImpl) UseTOTPStep(accID int64, step int64) error {
+	result, err := r.UseTOTPStepStmt.Exec(step, accID, step)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return erro.ErrInvalidCode
+	}
+
+	return nil
+}
+
+// Removes the secret and every recovery code of the account
+func (r *AccountRepositoryImpl) DeleteTOTP(accID int64) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	result, err := tx.Exec("DELETE FROM ACCOUNT_TOTP WHERE acc_id = ?", accID)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if affected == 0 {
+		tx.Rollback()
+		return erro.ErrTOTPNotEnabled
+	}
+
+	if _, err := tx.Exec("DELETE FROM RECOVERY_CODE WHERE acc_id = ?", accID); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Invalidates the previous recovery codes and stores the new ones
+func (r *AccountRepositoryImpl) ReplaceRecoveryCodes(accID int64, hashes []string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec("DELETE FROM RECOVERY_CODE WHERE acc_id = ?", accID); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	// Cannot use globally prepared statements here because of the transaction
+	stmt, err := tx.Prepare("INSERT INTO RECOVERY_CODE (acc_id, code_hash) VALUES (?, ?)")
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	for _, hash := range hashes {
+		if _, err := stmt.Exec(accID, hash); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (r *AccountRepositoryImpl) UseRecoveryCode(accID int64, hash string) error {
+	result, err := r.UseRecoveryCodeStmt.Exec(accID, hash)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return erro.ErrInvalidCode
+	}
+
+	return nil
+}
+
+func (r *AccountRepositoryImpl) CreateOIDCState(state OIDCState) error {
+	_, err := r.CreateOIDCStateStmt.Exec(state.State, state.Provider, state.Nonce, state.Verifier, state.AccID, state.ExpiresAt)
+	return err
+}
+
+// Retrieves and removes a pending authorization request so each state
+// can only be used once
+func (r *AccountRepositoryImpl) TakeOIDCState(state string) (OIDCState, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return OIDCState{}, err
+	}
+
+	row := tx.QueryRow("SELECT state, provider, nonce, verifier, acc_id, expires_at FROM OIDC_STATE WHERE state = ? FOR UPDATE", state)
+
+	var pending OIDCState
+	err = row.Scan(
+		&pending.State,
+		&pending.Provider,
+		&pending.Nonce,
+		&pending.Verifier,
+		&pending.AccID,
+		&pending.ExpiresAt,
+	)
+	if err != nil {
+		tx.Rollback()
+		if err == sql.ErrNoRows {
+			return OIDCState{}, erro.ErrInvalidToken
+		}
+		return OIDCState{}, err
+	}
+
+	// Expired states from abandoned logins are cleaned up on the way
+	if _, err := tx.Exec("DELETE FROM OIDC_STATE WHERE state = ? OR expires_at < NOW()", state); err != nil {
+		tx.Rollback()
+		return OIDCState{}, err
+	}
+
+	return pending, tx.Commit()
+}
+
+func (r *AccountRepositoryImpl) Identity(provider string, subject string) (Identity, error) {
+	row := r.IdentityStmt.QueryRow(provider, subject)
+
+	var identity Identity
+	err := row.Scan(
+		&identity.ID,
+		&identity.AccID,
+		&identity.Provider,
+		&identity.Subject,
+		&identity.Email,
+		&identity.CreatedAt,
+		&identity.LastLogin,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return Identity{}, erro.ErrIdentityNotFound
+		}
+		return Identity{}, err
+	}
+
+	return identity, nil
+}
+
+func (r *AccountRepositoryImpl) Identities(accID int64) ([]Identity, error) {
+	rows, err := r.IdentitiesStmt.Query(accID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	identities := []Identity{}
+	var identity Identity
+	for rows.Next() {
+		err := rows.Scan(
+			&identity.ID,
+			&identity.AccID,
+			&identity.Provider,
+			&identity.Subject,
+			&identity.Email,
+			&identity.CreatedAt,
+			&identity.LastLogin,
+		)
+		if err != nil {
+			return nil, err
+		}
+		identities = append(identities, identity)
+	}
+
+	return identities, nil
+}
+
+func (r *AccountRepositoryImpl) CreateIdentity(identity Identity) (int64, error) {
+	result, err := r.CreateIdentityStmt.Exec(identity.AccID, identity.Provider, identity.Subject, identity.Email)
+	if err != nil {
+		if mysqlErr, ok := err.(*mysql.MySQLError); ok {
+			if mysqlErr.Number == 1062 {
+				return 0, erro.ErrIdentityExists
+			}
+			if mysqlErr.Number == 1452 {
+				return 0, erro.ErrAccountNotFound
+			}
+		}
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+func (r *AccountRepositoryImpl) TouchIdentity(identityID int64) error {
+	_, err := r.TouchIdentityStmt.Exec(identityID)
+	return err
+}
+
+func (r *AccountRepositoryImpl) DeleteIdentity(identityID int64, accID int64) error {
+	result, err := r.DeleteIdentityStmt.Exec(identityID, accID)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return erro.ErrIdentityNotFound
+	}
+
+	return nil
+}
+
 func updateField(query *strings.Builder, args *[]any, field string, value any) {
 	// Just checking if it's a date and it isn't empty
 	if _, ok := value.(time.Time); ok && value.(time.Time).IsZero() {
@@ -193,6 +719,25 @@ func updateField(query *strings.Builder, args *[]any, field string, value any) {
 	*args = append(*args, value)
 }
 
+// Active suspension or ban of the account
+func (r *AccountRepositoryImpl) Suspension(accID int64) (Suspension, error) {
+	var suspension Suspension
+	err := r.SuspensionStmt.QueryRow(accID).Scan(
+		&suspension.AccID,
+		&suspension.Reason,
+		&suspension.Until,
+		&suspension.CreatedBy,
+		&suspension.CreatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return Suspension{}, erro.ErrNotSuspended
+		}
+		return Suspension{}, err
+	}
+	return suspension, nil
+}
+
 func scanaccount(row *sql.Row) (Account, error) {
 	var account Account
 	err := row.Scan(
@@ -206,6 +751,7 @@ func scanaccount(row *sql.Row) (Account, error) {
 		&account.TokenExpires,
 		&account.LastSeen,
 		&account.Since,
+		&account.EmailVerified,
 	)
 	if err != nil {
 		if err == sql.ErrNoRows {
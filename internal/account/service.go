@@ -2,17 +2,28 @@ package account
 
 import (
 	"bytes"
+	"context"
+	crand "crypto/rand"
+	"crypto/sha256"
 	"database/sql"
+	"encoding/base32"
 	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
 	"learn-swiping-api/erro"
 	account "learn-swiping-api/internal/account/dto"
+	"learn-swiping-api/internal/lockout"
+	"learn-swiping-api/internal/mailer"
+	"learn-swiping-api/internal/oidc"
 	"learn-swiping-api/internal/picture"
+	"learn-swiping-api/internal/totp"
+	"log"
 	"math/rand"
 	"net/mail"
+	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"golang.org/x/crypto/bcrypt"
@@ -20,7 +31,8 @@ import (
 
 type AccountService interface {
 	Register(account.RegisterRequest) (Account, error)
-	Login(account.LoginRequest) (Account, error)
+	Login(account.LoginRequest) (Account, string, error) // Returns a challenge instead if 2FA is enabled
+	LoginTOTP(account.LoginTOTPRequest) (Account, error)
 	Token(token string) (Account, error) // Login with token
 	Logout(token string) error
 	Account(token string) (Account, error)
@@ -31,14 +43,51 @@ type AccountService interface {
 	generateToken() (string, error)
 	hashPassword(password string) (string, error)
 	checkPasswordHash(password, hash string) bool
+
+	VerifyEmail(account.VerifyRequest) error
+	ResendVerification(token string) error
+	ForgotPassword(account.ForgotRequest) error
+	ResetPassword(account.ResetRequest) error
+	sendVerification(acc Account, email string) error
+	issueToken(accID int64, purpose string, email string, ttl time.Duration) (string, error)
+	redeemToken(secret string, purpose string) (AccountToken, error)
+
+	EnrolTOTP(token string) (account.TOTPEnrolment, error)
+	ConfirmTOTP(token string, request account.TOTPRequest) (account.RecoveryCodes, error)
+	DisableTOTP(token string, request account.TOTPRequest) error
+	RegenerateRecoveryCodes(token string, request account.TOTPRequest) (account.RecoveryCodes, error)
+	checkSecondFactor(accID int64, code string) error
+	newRecoveryCodes(accID int64) (account.RecoveryCodes, error)
+	session(acc Account) (Account, error)
+	finishLogin(acc Account) (Account, string, error)
+	failLogin(username string, ip string, acc *Account)
+	checkSuspension(accID int64) error
+
+	OIDCAuthURL(provider string, token string) (string, error)
+	OIDCCallback(provider string, code string, state string) (Account, string, error)
+	Identities(token string) ([]Identity, error)
+	UnlinkIdentity(token string, identityID int64) error
+	registerExternal(provider string, claims oidc.Claims) (Account, error)
 }
 
+const (
+	verifyEmailTTL    = 48 * time.Hour
+	resetPasswordTTL  = time.Hour
+	loginChallengeTTL = 5 * time.Minute
+	oidcStateTTL      = 10 * time.Minute
+	oidcTimeout       = 10 * time.Second
+	recoveryCodeCount = 10
+)
+
 type AccountServiceImpl struct {
 	repository AccountRepository
+	mailer     mailer.Mailer
+	providers  *oidc.Providers
+	lockout    lockout.LockoutService
 }
 
-func NewAccountService(repository AccountRepository) AccountService {
-	return &AccountServiceImpl{repository: repository}
+func NewAccountService(repository AccountRepository, mailer mailer.Mailer, providers *oidc.Providers, lockout lockout.LockoutService) AccountService {
+	return &AccountServiceImpl{repository: repository, mailer: mailer, providers: providers, lockout: lockout}
 }
 
 func (s *AccountServiceImpl) Register(request account.RegisterRequest) (Account, error) {
@@ -74,27 +123,141 @@ func (s *AccountServiceImpl) Register(request account.RegisterRequest) (Account,
 		return Account{}, err
 	}
 
-	return s.repository.ById(id)
+	created, err := s.repository.ById(id)
+	if err != nil {
+		return Account{}, err
+	}
+
+	// Registration shouldn't fail because of the mail server, the user
+	// can ask for another verification email later
+	if err := s.sendVerification(created, created.Email); err != nil {
+		log.Println(err)
+	}
+
+	return created, nil
+}
+
+func (s *AccountServiceImpl) Login(request account.LoginRequest) (Account, string, error) {
+	if err := s.lockout.Check(request.Username, request.IP); err != nil {
+		return Account{}, "", err
+	}
+
+	acc, err := s.repository.ByUsername(request.Username)
+	if err != nil {
+		if errors.Is(err, erro.ErrAccountNotFound) {
+			s.failLogin(request.Username, request.IP, nil)
+		}
+		return Account{}, "", err
+	}
+
+	if !s.checkPasswordHash(request.Password, acc.Password) {
+		s.failLogin(request.Username, request.IP, &acc)
+		return Account{}, "", erro.ErrAccountNotFound
+	}
+
+	if err := s.lockout.Succeed(acc.Username); err != nil {
+		log.Println(err)
+	}
+
+	return s.finishLogin(acc)
+}
+
+// Counts a failed login and lets the owner know when the account gets
+// locked. Failing to record it shouldn't change the login response
+func (s *AccountServiceImpl) failLogin(username string, ip string, acc *Account) {
+	locked, err := s.lockout.Fail(username, ip)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	if !locked || acc == nil {
+		return
+	}
+
+	err = s.mailer.Send(mailer.Message{
+		To:      acc.Email,
+		Subject: "Your account has been temporarily locked",
+		Body: fmt.Sprintf("Hi %s,\n\nThere were too many failed attempts to log into your account, "+
+			"the last one from %s. Logins are blocked for a while.\n\n"+
+			"If it wasn't you, consider resetting your password.\n",
+			acc.Name, ip),
+	})
+	if err != nil {
+		log.Println(err)
+	}
 }
 
-func (s *AccountServiceImpl) Login(request account.LoginRequest) (Account, error) {
-	account, err := s.repository.ByUsername(request.Username)
+// Issues the session once the first factor is verified. With 2FA enabled
+// the session token is only given after LoginTOTP
+func (s *AccountServiceImpl) finishLogin(acc Account) (Account, string, error) {
+	if err := s.checkSuspension(acc.ID); err != nil {
+		return Account{}, "", err
+	}
+
+	factor, err := s.repository.TOTP(acc.ID)
+	if err != nil && !errors.Is(err, erro.ErrTOTPNotEnabled) {
+		return Account{}, "", err
+	}
+	if err == nil && factor.Confirmed {
+		challenge, err := s.issueToken(acc.ID, PurposeLoginTOTP, "", loginChallengeTTL)
+		if err != nil {
+			return Account{}, "", err
+		}
+		return Account{}, challenge, nil
+	}
+
+	acc, err = s.session(acc)
+	return acc, "", err
+}
+
+// Second step of the login for accounts with 2FA enabled. The challenge
+// is consumed even if the code is wrong, so a failed attempt needs the
+// password again
+func (s *AccountServiceImpl) LoginTOTP(request account.LoginTOTPRequest) (Account, error) {
+	challenge, err := s.redeemToken(request.Challenge, PurposeLoginTOTP)
+	if err != nil {
+		return Account{}, err
+	}
+
+	acc, err := s.repository.ById(challenge.AccID)
 	if err != nil {
 		return Account{}, err
 	}
 
-	if s.checkPasswordHash(request.Password, account.Password) {
-		if time.Now().After(account.TokenExpires) {
-			token, err := s.updateToken(account)
-			if err != nil {
-				return Account{}, err
-			}
-			account.Token = token
+	if err := s.lockout.Check(acc.Username, request.IP); err != nil {
+		return Account{}, err
+	}
+
+	if err := s.checkSecondFactor(challenge.AccID, request.Code); err != nil {
+		if errors.Is(err, erro.ErrInvalidCode) {
+			s.failLogin(acc.Username, request.IP, &acc)
 		}
-		return account, nil
+		return Account{}, err
+	}
+
+	if err := s.lockout.Succeed(acc.Username); err != nil {
+		log.Println(err)
 	}
 
-	return Account{}, erro.ErrAccountNotFound
+	if err := s.checkSuspension(acc.ID); err != nil {
+		return Account{}, err
+	}
+
+	return s.session(acc)
+}
+
+// Returns a *erro.SuspendedError if an administrator suspended or banned
+// the account
+func (s *AccountServiceImpl) checkSuspension(accID int64) error {
+	suspension, err := s.repository.Suspension(accID)
+	if err != nil {
+		if errors.Is(err, erro.ErrNotSuspended) {
+			return nil
+		}
+		return err
+	}
+	return &erro.SuspendedError{Reason: suspension.Reason, Until: suspension.Until}
 }
 
 // Same as login function but using a token instead of account and password
@@ -167,11 +330,14 @@ func (s *AccountServiceImpl) Update(request account.UpdateRequest) error {
 		return erro.ErrInvalidToken
 	}
 
-	// Check if email is valid
-	if request.Email != "" {
+	// Check if email is valid and not in use by another account
+	if request.Email != "" && request.Email != account.Email {
 		if _, err := mail.ParseAddress(request.Email); err != nil {
 			return erro.ErrInvalidEmail
 		}
+		if _, err := s.repository.ByEmail(request.Email); err == nil {
+			return erro.ErrAccountExists
+		}
 	}
 
 	var updateAcc Account
@@ -208,9 +374,9 @@ func (s *AccountServiceImpl) Update(request account.UpdateRequest) error {
 	}
 
 	// If a value is empty it the repository won't update
-	// it anyway
+	// it anyway. The email isn't updated here, it's replaced
+	// once the new address is verified
 	updateAcc.Username = request.Username
-	updateAcc.Email = request.Email
 	updateAcc.Name = request.Name
 	updateAcc.Token = request.Token
 
@@ -220,6 +386,10 @@ func (s *AccountServiceImpl) Update(request account.UpdateRequest) error {
 		return err
 	}
 
+	if request.Email != "" && request.Email != account.Email {
+		return s.sendVerification(account, request.Email)
+	}
+
 	return nil
 }
 
@@ -271,3 +441,539 @@ func (s *AccountServiceImpl) generateToken() (string, error) {
 	}
 	return hex.EncodeToString(b), nil
 }
+
+// Confirms the email address bound to a verification token
+func (s *AccountServiceImpl) VerifyEmail(request account.VerifyRequest) error {
+	token, err := s.redeemToken(request.Token, PurposeVerifyEmail)
+	if err != nil {
+		return err
+	}
+
+	return s.repository.VerifyEmail(token.AccID, token.Email)
+}
+
+// Sends a new verification email to the current address of the account
+func (s *AccountServiceImpl) ResendVerification(token string) error {
+	acc, err := s.repository.ByToken(token)
+	if err != nil {
+		return erro.ErrInvalidToken
+	}
+
+	if acc.EmailVerified {
+		return erro.ErrAlreadyVerified
+	}
+
+	return s.sendVerification(acc, acc.Email)
+}
+
+// Sends a password reset link to the account owner. Unknown accounts
+// don't return an error so this can't be used to find registered emails
+func (s *AccountServiceImpl) ForgotPassword(request account.ForgotRequest) error {
+	if request.Username == "" && request.Email == "" {
+		return erro.ErrBadField
+	}
+
+	var acc Account
+	var err error
+	if request.Email != "" {
+		acc, err = s.repository.ByEmail(request.Email)
+	} else {
+		acc, err = s.repository.ByUsername(request.Username)
+	}
+	if err != nil {
+		if errors.Is(err, erro.ErrAccountNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	secret, err := s.issueToken(acc.ID, PurposeResetPassword, "", resetPasswordTTL)
+	if err != nil {
+		return err
+	}
+
+	return s.mailer.Send(mailer.Message{
+		To:      acc.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hi %s,\n\nSomeone asked to reset the password of your account. "+
+			"If it was you, open the following link within the next hour:\n\n%s\n\n"+
+			"If it wasn't you, you can ignore this email.\n",
+			acc.Name, link("reset-password", secret)),
+	})
+}
+
+// Sets a new password using a reset token. The session token is rotated
+// so every device logged in with the old password is logged out
+func (s *AccountServiceImpl) ResetPassword(request account.ResetRequest) error {
+	token, err := s.redeemToken(request.Token, PurposeResetPassword)
+	if err != nil {
+		return err
+	}
+
+	hash, err := s.hashPassword(request.Password)
+	if err != nil {
+		return err
+	}
+
+	session, err := s.generateToken()
+	if err != nil {
+		return err
+	}
+
+	return s.repository.Update(token.AccID, Account{
+		Password:     hash,
+		Token:        session,
+		TokenExpires: time.Now().AddDate(0, 0, 7),
+	})
+}
+
+func (s *AccountServiceImpl) sendVerification(acc Account, email string) error {
+	secret, err := s.issueToken(acc.ID, PurposeVerifyEmail, email, verifyEmailTTL)
+	if err != nil {
+		return err
+	}
+
+	return s.mailer.Send(mailer.Message{
+		To:      email,
+		Subject: "Verify your email",
+		Body: fmt.Sprintf("Hi %s,\n\nPlease confirm this is your email address by opening the following link:\n\n%s\n\n"+
+			"The link expires in 48 hours.\n",
+			acc.Name, link("verify-email", secret)),
+	})
+}
+
+// Stores a new single-use token and returns the secret to send to the
+// user. Previous pending tokens for the same purpose stop being valid
+func (s *AccountServiceImpl) issueToken(accID int64, purpose string, email string, ttl time.Duration) (string, error) {
+	secret, err := RandomHex(32)
+	if err != nil {
+		return "", err
+	}
+
+	if err := s.repository.InvalidateTokens(accID, purpose); err != nil {
+		return "", err
+	}
+
+	_, err = s.repository.CreateToken(AccountToken{
+		AccID:     accID,
+		Purpose:   purpose,
+		Hash:      hashSecret(secret),
+		Email:     email,
+		ExpiresAt: time.Now().Add(ttl),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return secret, nil
+}
+
+// Checks a token secret and marks it as used
+func (s *AccountServiceImpl) redeemToken(secret string, purpose string) (AccountToken, error) {
+	if secret == "" {
+		return AccountToken{}, erro.ErrBadField
+	}
+
+	token, err := s.repository.TokenByHash(hashSecret(secret), purpose)
+	if err != nil {
+		return AccountToken{}, err
+	}
+
+	if token.UsedAt != nil {
+		return AccountToken{}, erro.ErrInvalidToken
+	}
+
+	if time.Now().After(token.ExpiresAt) {
+		return AccountToken{}, erro.ErrTokenExpired
+	}
+
+	if err := s.repository.UseToken(token.ID); err != nil {
+		return AccountToken{}, err
+	}
+
+	return token, nil
+}
+
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// Builds a link to the frontend for the given action
+func link(action string, secret string) string {
+	base := os.Getenv("APP_URL")
+	if base == "" {
+		base = "http://localhost:9999"
+	}
+	return fmt.Sprintf("%s/%s?token=%s", base, action, secret)
+}
+
+// Starts the 2FA enrolment. The secret isn't enforced until confirmed
+func (s *AccountServiceImpl) EnrolTOTP(token string) (account.TOTPEnrolment, error) {
+	acc, err := s.repository.ByToken(token)
+	if err != nil {
+		return account.TOTPEnrolment{}, erro.ErrInvalidToken
+	}
+
+	current, err := s.repository.TOTP(acc.ID)
+	if err == nil && current.Confirmed {
+		return account.TOTPEnrolment{}, erro.ErrTOTPEnabled
+	}
+	if err != nil && !errors.Is(err, erro.ErrTOTPNotEnabled) {
+		return account.TOTPEnrolment{}, err
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return account.TOTPEnrolment{}, err
+	}
+
+	if err := s.repository.SaveTOTP(acc.ID, secret); err != nil {
+		return account.TOTPEnrolment{}, err
+	}
+
+	issuer := os.Getenv("TOTP_ISSUER")
+	if issuer == "" {
+		issuer = "Learn Swiping"
+	}
+
+	return account.TOTPEnrolment{
+		Secret: secret,
+		URI:    totp.URI(issuer, acc.Username, secret),
+	}, nil
+}
+
+// Enables 2FA once the user proves the authenticator app works and
+// returns the recovery codes. They are only shown this time
+func (s *AccountServiceImpl) ConfirmTOTP(token string, request account.TOTPRequest) (account.RecoveryCodes, error) {
+	acc, err := s.repository.ByToken(token)
+	if err != nil {
+		return account.RecoveryCodes{}, erro.ErrInvalidToken
+	}
+
+	pending, err := s.repository.TOTP(acc.ID)
+	if err != nil {
+		return account.RecoveryCodes{}, err
+	}
+	if pending.Confirmed {
+		return account.RecoveryCodes{}, erro.ErrTOTPEnabled
+	}
+
+	step, ok := totp.Validate(pending.Secret, request.Code, time.Now())
+	if !ok {
+		return account.RecoveryCodes{}, erro.ErrInvalidCode
+	}
+
+	if err := s.repository.ConfirmTOTP(acc.ID, step); err != nil {
+		return account.RecoveryCodes{}, err
+	}
+
+	return s.newRecoveryCodes(acc.ID)
+}
+
+func (s *AccountServiceImpl) DisableTOTP(token string, request account.TOTPRequest) error {
+	acc, err := s.repository.ByToken(token)
+	if err != nil {
+		return erro.ErrInvalidToken
+	}
+
+	if err := s.checkSecondFactor(acc.ID, request.Code); err != nil {
+		return err
+	}
+
+	return s.repository.DeleteTOTP(acc.ID)
+}
+
+func (s *AccountServiceImpl) RegenerateRecoveryCodes(token string, request account.TOTPRequest) (account.RecoveryCodes, error) {
+	acc, err := s.repository.ByToken(token)
+	if err != nil {
+		return account.RecoveryCodes{}, erro.ErrInvalidToken
+	}
+
+	if err := s.checkSecondFactor(acc.ID, request.Code); err != nil {
+		return account.RecoveryCodes{}, err
+	}
+
+	return s.newRecoveryCodes(acc.ID)
+}
+
+// Accepts either a TOTP code or an unused recovery code
+func (s *AccountServiceImpl) checkSecondFactor(accID int64, code string) error {
+	current, err := s.repository.TOTP(accID)
+	if err != nil {
+		return err
+	}
+	if !current.Confirmed {
+		return erro.ErrTOTPNotEnabled
+	}
+
+	if step, ok := totp.Validate(current.Secret, code, time.Now()); ok {
+		return s.repository.UseTOTPStep(accID, step)
+	}
+
+	return s.repository.UseRecoveryCode(accID, hashSecret(normalizeRecoveryCode(code)))
+}
+
+func (s *AccountServiceImpl) newRecoveryCodes(accID int64) (account.RecoveryCodes, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		b := make([]byte, 5)
+		if _, err := crand.Read(b); err != nil {
+			return account.RecoveryCodes{}, err
+		}
+		code := strings.ToLower(base32.StdEncoding.EncodeToString(b))
+		codes = append(codes, code[:4]+"-"+code[4:])
+		hashes = append(hashes, hashSecret(code))
+	}
+
+	if err := s.repository.ReplaceRecoveryCodes(accID, hashes); err != nil {
+		return account.RecoveryCodes{}, err
+	}
+
+	return account.RecoveryCodes{Codes: codes}, nil
+}
+
+// Refreshes the session token if it has expired
+func (s *AccountServiceImpl) session(acc Account) (Account, error) {
+	if time.Now().After(acc.TokenExpires) {
+		token, err := s.updateToken(acc)
+		if err != nil {
+			return Account{}, err
+		}
+		acc.Token = token
+	}
+	return acc, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	code = strings.ReplaceAll(code, "-", "")
+	code = strings.ReplaceAll(code, " ", "")
+	return strings.ToLower(code)
+}
+
+// Starts an authorization code flow with PKCE. With a token the external
+// identity is linked to that account instead of being used to log in
+func (s *AccountServiceImpl) OIDCAuthURL(provider string, token string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), oidcTimeout)
+	defer cancel()
+
+	p, err := s.providers.Get(ctx, provider)
+	if err != nil {
+		return "", err
+	}
+
+	state := OIDCState{
+		Provider:  provider,
+		Verifier:  oidc.GenerateVerifier(),
+		ExpiresAt: time.Now().Add(oidcStateTTL),
+	}
+
+	if token != "" {
+		acc, err := s.repository.ByToken(token)
+		if err != nil {
+			return "", erro.ErrInvalidToken
+		}
+		state.AccID = &acc.ID
+	}
+
+	if state.State, err = RandomHex(32); err != nil {
+		return "", err
+	}
+	if state.Nonce, err = RandomHex(32); err != nil {
+		return "", err
+	}
+
+	if err := s.repository.CreateOIDCState(state); err != nil {
+		return "", err
+	}
+
+	return p.AuthURL(state.State, state.Nonce, state.Verifier), nil
+}
+
+// Handles the provider redirect. Unknown identities are registered as new
+// accounts, unless the flow was started to link an existing one
+func (s *AccountServiceImpl) OIDCCallback(provider string, code string, state string) (Account, string, error) {
+	if code == "" || state == "" {
+		return Account{}, "", erro.ErrBadField
+	}
+
+	pending, err := s.repository.TakeOIDCState(state)
+	if err != nil {
+		return Account{}, "", err
+	}
+	if pending.Provider != provider {
+		return Account{}, "", erro.ErrInvalidToken
+	}
+	if time.Now().After(pending.ExpiresAt) {
+		return Account{}, "", erro.ErrTokenExpired
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), oidcTimeout)
+	defer cancel()
+
+	p, err := s.providers.Get(ctx, provider)
+	if err != nil {
+		return Account{}, "", err
+	}
+
+	claims, err := p.Exchange(ctx, code, pending.Verifier, pending.Nonce)
+	if err != nil {
+		return Account{}, "", fmt.Errorf("%w: %v", erro.ErrInvalidToken, err)
+	}
+
+	if pending.AccID != nil {
+		_, err := s.repository.CreateIdentity(Identity{
+			AccID:    *pending.AccID,
+			Provider: provider,
+			Subject:  claims.Subject,
+			Email:    claims.Email,
+		})
+		if err != nil {
+			return Account{}, "", err
+		}
+		acc, err := s.repository.ById(*pending.AccID)
+		return acc, "", err
+	}
+
+	identity, err := s.repository.Identity(provider, claims.Subject)
+	if err == nil {
+		if err := s.repository.TouchIdentity(identity.ID); err != nil {
+			return Account{}, "", err
+		}
+		acc, err := s.repository.ById(identity.AccID)
+		if err != nil {
+			return Account{}, "", err
+		}
+		return s.finishLogin(acc)
+	}
+	if !errors.Is(err, erro.ErrIdentityNotFound) {
+		return Account{}, "", err
+	}
+
+	acc, err := s.registerExternal(provider, claims)
+	if err != nil {
+		return Account{}, "", err
+	}
+	return acc, "", nil
+}
+
+func (s *AccountServiceImpl) Identities(token string) ([]Identity, error) {
+	acc, err := s.repository.ByToken(token)
+	if err != nil {
+		return nil, erro.ErrInvalidToken
+	}
+	return s.repository.Identities(acc.ID)
+}
+
+func (s *AccountServiceImpl) UnlinkIdentity(token string, identityID int64) error {
+	acc, err := s.repository.ByToken(token)
+	if err != nil {
+		return erro.ErrInvalidToken
+	}
+	return s.repository.DeleteIdentity(identityID, acc.ID)
+}
+
+// Creates an account for a first login with an external identity. Existing
+// accounts with the same email aren't linked automatically, the owner has
+// to log in and link the identity to prove it's theirs
+func (s *AccountServiceImpl) registerExternal(provider string, claims oidc.Claims) (Account, error) {
+	if claims.Email == "" {
+		return Account{}, erro.ErrInvalidEmail
+	}
+	if _, err := s.repository.ByEmail(claims.Email); err == nil {
+		return Account{}, erro.ErrAccountExists
+	}
+
+	// The password can't be guessed, a password login is only possible
+	// after a reset
+	secret, err := RandomHex(32)
+	if err != nil {
+		return Account{}, err
+	}
+	hash, err := s.hashPassword(secret)
+	if err != nil {
+		return Account{}, err
+	}
+
+	token, err := s.generateToken()
+	if err != nil {
+		return Account{}, err
+	}
+
+	name := claims.Name
+	if name == "" {
+		name = claims.PreferredUsername
+	}
+
+	base := usernameFrom(claims)
+	acc := Account{
+		Password:     hash,
+		Email:        claims.Email,
+		Name:         name,
+		PicID:        fmt.Sprintf("default_profile_%d.png", (rand.Intn(6) + 1)),
+		Token:        token,
+		TokenExpires: time.Now().AddDate(0, 0, 7),
+	}
+
+	// Retrying with a suffix while the username is taken
+	var id int64
+	for attempt := 0; attempt < 5; attempt++ {
+		acc.Username = base
+		if attempt > 0 {
+			acc.Username = fmt.Sprintf("%s%d", base, rand.Intn(10000))
+		}
+		id, err = s.repository.Create(acc)
+		if !errors.Is(err, erro.ErrAccountExists) {
+			break
+		}
+	}
+	if err != nil {
+		return Account{}, err
+	}
+
+	if _, err := s.repository.CreateIdentity(Identity{AccID: id, Provider: provider, Subject: claims.Subject, Email: claims.Email}); err != nil {
+		return Account{}, err
+	}
+
+	if claims.EmailVerified {
+		err = s.repository.VerifyEmail(id, claims.Email)
+	} else {
+		created, _ := s.repository.ById(id)
+		err = s.sendVerification(created, claims.Email)
+	}
+	if err != nil {
+		log.Println(err)
+	}
+
+	return s.repository.ById(id)
+}
+
+// Hex encoded n random bytes, for secrets and one time tokens
+func RandomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := crand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// Picks a username from the claims keeping only safe characters
+func usernameFrom(claims oidc.Claims) string {
+	candidate := claims.PreferredUsername
+	if candidate == "" {
+		candidate, _, _ = strings.Cut(claims.Email, "@")
+	}
+
+	var b strings.Builder
+	for _, r := range strings.ToLower(candidate) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '_' || r == '.' {
+			b.WriteRune(r)
+		}
+	}
+
+	if b.Len() < 3 {
+		return "user"
+	}
+	return b.String()
+}
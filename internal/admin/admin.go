@@ -0,0 +1,19 @@
+package admin
+
+import (
+	"learn-swiping-api/internal/account"
+	"time"
+)
+
+// Account as seen by administrators
+type Account struct {
+	ID            int64               `json:"acc_id"`
+	Username      string              `json:"username"`
+	Email         string              `json:"email"`
+	EmailVerified bool                `json:"email_verified"`
+	Name          string              `json:"name"`
+	Role          string              `json:"role"`
+	LastSeen      time.Time           `json:"last_seen"`
+	Since         time.Time           `json:"since"`
+	Suspension    *account.Suspension `json:"suspension,omitempty"`
+}
@@ -0,0 +1,232 @@
+package admin
+
+import (
+	"errors"
+	"learn-swiping-api/erro"
+	admin "learn-swiping-api/internal/admin/dto"
+	"learn-swiping-api/internal/audit"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Every route is behind the role middleware, which stores the caller's
+// acc_id in the context, and the audit middleware
+type AdminController interface {
+	Accounts(*gin.Context)          // GET
+	Account(*gin.Context)           // GET
+	Suspend(*gin.Context)           // POST
+	Ban(*gin.Context)               // POST
+	Unsuspend(*gin.Context)         // DELETE
+	Logout(*gin.Context)            // POST
+	ResetPassword(*gin.Context)     // POST
+	SetDeckVisibility(*gin.Context) // PUT
+	TransferDeck(*gin.Context)      // PUT
+}
+
+type AdminControllerImpl struct {
+	service AdminService
+}
+
+func NewAdminController(service AdminService) AdminController {
+	return &AdminControllerImpl{service: service}
+}
+
+// Searches accounts by username, email or name
+// Method: GET
+func (c *AdminControllerImpl) Accounts(ctx *gin.Context) {
+	request := admin.SearchRequest{Query: ctx.Query("q")}
+
+	var err error
+	if page := ctx.Query("page"); page != "" {
+		if request.Page, err = strconv.Atoi(page); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrBadField.Error()})
+			return
+		}
+	}
+	if size := ctx.Query("size"); size != "" {
+		if request.Size, err = strconv.Atoi(size); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrBadField.Error()})
+			return
+		}
+	}
+
+	accounts, err := c.service.Accounts(request)
+	if err != nil {
+		adminError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, accounts)
+}
+
+// Retrieves an account with its role and suspension
+// Method: GET
+func (c *AdminControllerImpl) Account(ctx *gin.Context) {
+	acc, err := c.service.Account(ctx.Param("username"))
+	if err != nil {
+		adminError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, acc)
+}
+
+// Blocks an account until a date
+// Method: POST
+func (c *AdminControllerImpl) Suspend(ctx *gin.Context) {
+	var request admin.SuspendRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrBadField.Error()})
+		return
+	}
+
+	username := ctx.Param("username")
+	if err := c.service.Suspend(ctx.GetInt64("acc_id"), username, request); err != nil {
+		adminError(ctx, err)
+		return
+	}
+
+	audit.Action(ctx, "account.suspend", "account:"+username)
+	audit.Detail(ctx, "reason", request.Reason)
+	audit.Detail(ctx, "until", request.Until)
+	ctx.JSON(http.StatusOK, gin.H{})
+}
+
+// Blocks an account forever
+// Method: POST
+func (c *AdminControllerImpl) Ban(ctx *gin.Context) {
+	var request admin.SuspendRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrBadField.Error()})
+		return
+	}
+
+	username := ctx.Param("username")
+	if err := c.service.Ban(ctx.GetInt64("acc_id"), username, request); err != nil {
+		adminError(ctx, err)
+		return
+	}
+
+	audit.Action(ctx, "account.ban", "account:"+username)
+	audit.Detail(ctx, "reason", request.Reason)
+	ctx.JSON(http.StatusOK, gin.H{})
+}
+
+// Lifts a suspension or ban
+// Method: DELETE
+func (c *AdminControllerImpl) Unsuspend(ctx *gin.Context) {
+	username := ctx.Param("username")
+	if err := c.service.Unsuspend(ctx.GetInt64("acc_id"), username); err != nil {
+		adminError(ctx, err)
+		return
+	}
+
+	audit.Action(ctx, "account.unsuspend", "account:"+username)
+	ctx.JSON(http.StatusOK, gin.H{})
+}
+
+// Invalidates the session token of an account
+// Method: POST
+func (c *AdminControllerImpl) Logout(ctx *gin.Context) {
+	username := ctx.Param("username")
+	if err := c.service.Logout(ctx.GetInt64("acc_id"), username); err != nil {
+		adminError(ctx, err)
+		return
+	}
+
+	audit.Action(ctx, "account.logout", "account:"+username)
+	ctx.JSON(http.StatusOK, gin.H{})
+}
+
+// Invalidates the password of an account and mails a reset link to it
+// Method: POST
+func (c *AdminControllerImpl) ResetPassword(ctx *gin.Context) {
+	username := ctx.Param("username")
+	err := c.service.ResetPassword(ctx.GetInt64("acc_id"), username)
+
+	// The password is already invalidated even if the mail couldn't be sent
+	if err == nil || !errors.Is(err, erro.ErrForbidden) && !errors.Is(err, erro.ErrAccountNotFound) {
+		audit.Action(ctx, "account.reset_password", "account:"+username)
+	}
+	if err != nil {
+		adminError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{})
+}
+
+// Hides a deck so its owner can't publish it again, or unhides it
+// Method: PUT
+func (c *AdminControllerImpl) SetDeckVisibility(ctx *gin.Context) {
+	deckID, err := strconv.Atoi(ctx.Param("deckID"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrBadField.Error()})
+		return
+	}
+
+	var request admin.VisibilityRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrBadField.Error()})
+		return
+	}
+
+	if err := c.service.SetDeckVisibility(ctx.GetInt64("acc_id"), int64(deckID), request); err != nil {
+		adminError(ctx, err)
+		return
+	}
+
+	target := "deck:" + strconv.Itoa(deckID)
+	if *request.Visible {
+		audit.Action(ctx, "deck.unhide", target)
+	} else {
+		audit.Action(ctx, "deck.hide", target)
+		audit.Detail(ctx, "reason", request.Reason)
+	}
+	ctx.JSON(http.StatusOK, gin.H{})
+}
+
+// Gives a deck to another account
+// Method: PUT
+func (c *AdminControllerImpl) TransferDeck(ctx *gin.Context) {
+	deckID, err := strconv.Atoi(ctx.Param("deckID"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrBadField.Error()})
+		return
+	}
+
+	var request admin.TransferRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrBadField.Error()})
+		return
+	}
+
+	previous, err := c.service.TransferDeck(ctx.GetInt64("acc_id"), int64(deckID), request)
+	if err != nil {
+		adminError(ctx, err)
+		return
+	}
+
+	audit.Action(ctx, "deck.transfer", "deck:"+strconv.Itoa(deckID))
+	audit.Detail(ctx, "from_acc_id", previous)
+	audit.Detail(ctx, "to", request.Username)
+	ctx.JSON(http.StatusOK, gin.H{})
+}
+
+func adminError(ctx *gin.Context, err error) {
+	if errors.Is(err, erro.ErrBadField) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, erro.ErrForbidden) {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, erro.ErrAccountNotFound) || errors.Is(err, erro.ErrDeckNotFound) || errors.Is(err, erro.ErrNotSuspended) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
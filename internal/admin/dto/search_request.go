@@ -0,0 +1,7 @@
+package admin
+
+type SearchRequest struct {
+	Query string // Matches username, email or name
+	Page  int
+	Size  int
+}
@@ -0,0 +1,8 @@
+package admin
+
+import "time"
+
+type SuspendRequest struct {
+	Reason string     `json:"reason" binding:"required"`
+	Until  *time.Time `json:"until"` // Required to suspend, ignored when banning
+}
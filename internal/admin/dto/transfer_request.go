@@ -0,0 +1,5 @@
+package admin
+
+type TransferRequest struct {
+	Username string `json:"username" binding:"required"` // New owner
+}
@@ -0,0 +1,6 @@
+package admin
+
+type VisibilityRequest struct {
+	Visible *bool  `json:"visible" binding:"required"` // pointer so false isn't taken as missing
+	Reason  string `json:"reason"`                     // Required to hide
+}
@@ -0,0 +1,361 @@
+package admin
+
+import (
+	"database/sql"
+	"learn-swiping-api/erro"
+	"learn-swiping-api/internal/account"
+	"log"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+type AdminRepository interface {
+	Search(query string, page int, size int) ([]Account, error)
+	ByUsername(username string) (Account, error)
+	Suspend(suspension account.Suspension, token string) error
+	Unsuspend(accID int64) error
+	Logout(accID int64, token string) error
+	ResetPassword(accID int64, hash string, token string) error
+	DeckOwner(deckID int64) (int64, error)
+	HideDeck(deckID int64, reason string, hiddenBy int64) error
+	UnhideDeck(deckID int64) error
+	TransferDeck(deckID int64, accID int64) error
+}
+
+type AdminRepositoryImpl struct {
+	db                *sql.DB
+	SearchStmt        *sql.Stmt
+	ByUsernameStmt    *sql.Stmt
+	UnsuspendStmt     *sql.Stmt
+	LogoutStmt        *sql.Stmt
+	ResetPasswordStmt *sql.Stmt
+	DeckOwnerStmt     *sql.Stmt
+}
+
+func NewAdminRepository(db *sql.DB) *AdminRepositoryImpl {
+	repo := &AdminRepositoryImpl{db: db}
+	err := repo.InitStatements()
+	if err != nil {
+		log.Fatalln(err)
+	}
+	return repo
+}
+
+// Columns read by scanAccount
+const accountColumns = `a.acc_id, a.username, a.email, a.email_verified, a.name, COALESCE(r.role, 'user'),
+							a.last_seen, a.since, s.acc_id, s.reason, s.until, s.created_by, s.created_at
+						FROM ACCOUNT a
+						LEFT JOIN ACCOUNT_ROLE r ON a.acc_id = r.acc_id
+						LEFT JOIN ACCOUNT_SUSPENSION s ON a.acc_id = s.acc_id AND (s.until IS NULL OR s.until > NOW())`
+
+func (r *AdminRepositoryImpl) InitStatements() error {
+	var err error
+	r.SearchStmt, err = r.db.Prepare(`SELECT ` + accountColumns + `
+										WHERE a.username LIKE ? OR a.email LIKE ? OR a.name LIKE ?
+										ORDER BY a.username
+										LIMIT ? OFFSET ?`)
+	if err != nil {
+		return err
+	}
+
+	r.ByUsernameStmt, err = r.db.Prepare(`SELECT ` + accountColumns + ` WHERE a.username = ?`)
+	if err != nil {
+		return err
+	}
+
+	r.UnsuspendStmt, err = r.db.Prepare("DELETE FROM ACCOUNT_SUSPENSION WHERE acc_id = ? AND (until IS NULL OR until > NOW())")
+	if err != nil {
+		return err
+	}
+
+	// The new token is never given to anyone, it only replaces the current one
+	r.LogoutStmt, err = r.db.Prepare("UPDATE ACCOUNT SET token = ?, token_expire = NOW() WHERE acc_id = ?")
+	if err != nil {
+		return err
+	}
+
+	r.ResetPasswordStmt, err = r.db.Prepare("UPDATE ACCOUNT SET passwd = ?, token = ?, token_expire = NOW() WHERE acc_id = ?")
+	if err != nil {
+		return err
+	}
+
+	r.DeckOwnerStmt, err = r.db.Prepare("SELECT acc_id FROM DECK WHERE deck_id = ?")
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *AdminRepositoryImpl) Search(query string, page int, size int) ([]Account, error) {
+	pattern := "%" + query + "%"
+	rows, err := r.SearchStmt.Query(pattern, pattern, pattern, size, (page-1)*size)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	accounts := []Account{}
+	for rows.Next() {
+		acc, err := scanAccount(rows)
+		if err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, acc)
+	}
+
+	return accounts, nil
+}
+
+func (r *AdminRepositoryImpl) ByUsername(username string) (Account, error) {
+	acc, err := scanAccount(r.ByUsernameStmt.QueryRow(username))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return Account{}, erro.ErrAccountNotFound
+		}
+		return Account{}, err
+	}
+	return acc, nil
+}
+
+// Stores the suspension, replacing any previous one, and logs the
+// account out
+func (r *AdminRepositoryImpl) Suspend(suspension account.Suspension, token string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`INSERT INTO ACCOUNT_SUSPENSION (acc_id, reason, until, created_by) VALUES (?, ?, ?, ?)
+						ON DUPLICATE KEY UPDATE
+							reason = VALUES(reason),
+							until = VALUES(until),
+							created_by = VALUES(created_by),
+							created_at = NOW()`,
+		suspension.AccID, suspension.Reason, suspension.Until, suspension.CreatedBy)
+	if err != nil {
+		tx.Rollback()
+		if mysqlErr, ok := err.(*mysql.MySQLError); ok && mysqlErr.Number == 1452 {
+			return erro.ErrAccountNotFound
+		}
+		return err
+	}
+
+	if _, err := tx.Exec("UPDATE ACCOUNT SET token = ?, token_expire = NOW() WHERE acc_id = ?", token, suspension.AccID); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *AdminRepositoryImpl) Unsuspend(accID int64) error {
+	result, err := r.UnsuspendStmt.Exec(accID)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return erro.ErrNotSuspended
+	}
+
+	return nil
+}
+
+func (r *AdminRepositoryImpl) Logout(accID int64, token string) error {
+	return r.exec(r.LogoutStmt, erro.ErrAccountNotFound, token, accID)
+}
+
+// Replaces the password and logs the account out
+func (r *AdminRepositoryImpl) ResetPassword(accID int64, hash string, token string) error {
+	return r.exec(r.ResetPasswordStmt, erro.ErrAccountNotFound, hash, token, accID)
+}
+
+func (r *AdminRepositoryImpl) DeckOwner(deckID int64) (int64, error) {
+	var accID int64
+	if err := r.DeckOwnerStmt.QueryRow(deckID).Scan(&accID); err != nil {
+		if err == sql.ErrNoRows {
+			return 0, erro.ErrDeckNotFound
+		}
+		return 0, err
+	}
+	return accID, nil
+}
+
+// Makes the deck private and stops the owner from publishing it again
+func (r *AdminRepositoryImpl) HideDeck(deckID int64, reason string, hiddenBy int64) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	// A missing deck is caught by the foreign key of DECK_HIDDEN, the
+	// update alone can't tell it apart from an already hidden one
+	if _, err := tx.Exec("UPDATE DECK SET visible = 0 WHERE deck_id = ?", deckID); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	_, err = tx.Exec(`INSERT INTO DECK_HIDDEN (deck_id, reason, hidden_by) VALUES (?, ?, ?)
+						ON DUPLICATE KEY UPDATE
+							reason = VALUES(reason),
+							hidden_by = VALUES(hidden_by),
+							hidden_at = NOW()`,
+		deckID, reason, hiddenBy)
+	if err != nil {
+		tx.Rollback()
+		if mysqlErr, ok := err.(*mysql.MySQLError); ok && mysqlErr.Number == 1452 {
+			return erro.ErrDeckNotFound
+		}
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *AdminRepositoryImpl) UnhideDeck(deckID int64) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec("DELETE FROM DECK_HIDDEN WHERE deck_id = ?", deckID); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	result, err := tx.Exec("UPDATE DECK SET visible = 1, updated_at = ? WHERE deck_id = ?", time.Now(), deckID)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if affected == 0 {
+		tx.Rollback()
+		return erro.ErrDeckNotFound
+	}
+
+	return tx.Commit()
+}
+
+// Changes the deck owner. The new owner is subscribed like on Create and
+// the previous one keeps its subscription
+func (r *AdminRepositoryImpl) TransferDeck(deckID int64, accID int64) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	result, err := tx.Exec("UPDATE DECK SET acc_id = ?, updated_at = ? WHERE deck_id = ?", accID, time.Now(), deckID)
+	if err != nil {
+		tx.Rollback()
+		if mysqlErr, ok := err.(*mysql.MySQLError); ok && mysqlErr.Number == 1452 {
+			return erro.ErrAccountNotFound
+		}
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if affected == 0 {
+		tx.Rollback()
+		return erro.ErrDeckNotFound
+	}
+
+	if _, err := tx.Exec("INSERT IGNORE INTO ACC_DECK (acc_id, deck_id) VALUES (?, ?)", accID, deckID); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	// The new owner doesn't need a collaborator role anymore
+	if _, err := tx.Exec("DELETE FROM DECK_COLLABORATOR WHERE deck_id = ? AND acc_id = ?", deckID, accID); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	// A transfer started by the previous owner no longer applies
+	if _, err := tx.Exec(`UPDATE DECK_TRANSFER SET status = 'cancelled', pending = NULL, resolved_at = NOW()
+							WHERE deck_id = ? AND pending = 1`, deckID); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Runs an update that must change a row
+func (r *AdminRepositoryImpl) exec(stmt *sql.Stmt, notFound error, args ...any) error {
+	result, err := stmt.Exec(args...)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return notFound
+	}
+
+	return nil
+}
+
+// Scans from either *sql.Row or *sql.Rows
+func scanAccount(row interface{ Scan(...any) error }) (Account, error) {
+	var acc Account
+	var suspension struct {
+		AccID     sql.NullInt64
+		Reason    sql.NullString
+		Until     *time.Time
+		CreatedBy *int64
+		CreatedAt sql.NullTime
+	}
+	err := row.Scan(
+		&acc.ID,
+		&acc.Username,
+		&acc.Email,
+		&acc.EmailVerified,
+		&acc.Name,
+		&acc.Role,
+		&acc.LastSeen,
+		&acc.Since,
+		&suspension.AccID,
+		&suspension.Reason,
+		&suspension.Until,
+		&suspension.CreatedBy,
+		&suspension.CreatedAt,
+	)
+	if err != nil {
+		return Account{}, err
+	}
+
+	if suspension.AccID.Valid {
+		acc.Suspension = &account.Suspension{
+			AccID:     suspension.AccID.Int64,
+			Reason:    suspension.Reason.String,
+			Until:     suspension.Until,
+			CreatedBy: suspension.CreatedBy,
+			CreatedAt: suspension.CreatedAt.Time,
+		}
+	}
+
+	return acc, nil
+}
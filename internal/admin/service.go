@@ -0,0 +1,196 @@
+package admin
+
+import (
+	"learn-swiping-api/erro"
+	"learn-swiping-api/internal/account"
+	accountdto "learn-swiping-api/internal/account/dto"
+	admin "learn-swiping-api/internal/admin/dto"
+	"learn-swiping-api/internal/role"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+type AdminService interface {
+	Accounts(admin.SearchRequest) ([]Account, error)
+	Account(username string) (Account, error)
+	Suspend(actorID int64, username string, request admin.SuspendRequest) error
+	Ban(actorID int64, username string, request admin.SuspendRequest) error
+	Unsuspend(actorID int64, username string) error
+	Logout(actorID int64, username string) error
+	ResetPassword(actorID int64, username string) error
+	SetDeckVisibility(actorID int64, deckID int64, request admin.VisibilityRequest) error
+	TransferDeck(actorID int64, deckID int64, request admin.TransferRequest) (int64, error) // Returns the previous owner
+	target(actorID int64, username string) (Account, error)
+	suspend(actorID int64, username string, reason string, until *time.Time) error
+}
+
+type AdminServiceImpl struct {
+	repository AdminRepository
+	accounts   account.AccountService
+}
+
+func NewAdminService(repository AdminRepository, accounts account.AccountService) AdminService {
+	return &AdminServiceImpl{repository: repository, accounts: accounts}
+}
+
+func (s *AdminServiceImpl) Accounts(request admin.SearchRequest) ([]Account, error) {
+	if request.Page < 1 {
+		request.Page = 1
+	}
+	if request.Size < 1 {
+		request.Size = defaultPageSize
+	}
+	if request.Size > maxPageSize {
+		return nil, erro.ErrBadField
+	}
+
+	return s.repository.Search(strings.TrimSpace(request.Query), request.Page, request.Size)
+}
+
+func (s *AdminServiceImpl) Account(username string) (Account, error) {
+	return s.repository.ByUsername(username)
+}
+
+func (s *AdminServiceImpl) Suspend(actorID int64, username string, request admin.SuspendRequest) error {
+	if request.Until == nil || request.Until.Before(time.Now()) {
+		return erro.ErrBadField
+	}
+	return s.suspend(actorID, username, request.Reason, request.Until)
+}
+
+func (s *AdminServiceImpl) Ban(actorID int64, username string, request admin.SuspendRequest) error {
+	return s.suspend(actorID, username, request.Reason, nil)
+}
+
+func (s *AdminServiceImpl) Unsuspend(actorID int64, username string) error {
+	acc, err := s.target(actorID, username)
+	if err != nil {
+		return err
+	}
+	return s.repository.Unsuspend(acc.ID)
+}
+
+func (s *AdminServiceImpl) Logout(actorID int64, username string) error {
+	acc, err := s.target(actorID, username)
+	if err != nil {
+		return err
+	}
+
+	token, err := account.RandomHex(16)
+	if err != nil {
+		return err
+	}
+
+	return s.repository.Logout(acc.ID, token)
+}
+
+// Replaces the password with a random one nobody knows, logs the account
+// out and mails the owner a reset link
+func (s *AdminServiceImpl) ResetPassword(actorID int64, username string) error {
+	acc, err := s.target(actorID, username)
+	if err != nil {
+		return err
+	}
+
+	password, err := account.RandomHex(32)
+	if err != nil {
+		return err
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), 8)
+	if err != nil {
+		return err
+	}
+
+	token, err := account.RandomHex(16)
+	if err != nil {
+		return err
+	}
+
+	if err := s.repository.ResetPassword(acc.ID, string(hash), token); err != nil {
+		return err
+	}
+
+	return s.accounts.ForgotPassword(accountdto.ForgotRequest{Username: acc.Username})
+}
+
+func (s *AdminServiceImpl) SetDeckVisibility(actorID int64, deckID int64, request admin.VisibilityRequest) error {
+	if *request.Visible {
+		return s.repository.UnhideDeck(deckID)
+	}
+
+	reason := strings.TrimSpace(request.Reason)
+	if reason == "" {
+		return erro.ErrBadField
+	}
+	return s.repository.HideDeck(deckID, reason, actorID)
+}
+
+func (s *AdminServiceImpl) TransferDeck(actorID int64, deckID int64, request admin.TransferRequest) (int64, error) {
+	previous, err := s.repository.DeckOwner(deckID)
+	if err != nil {
+		return 0, err
+	}
+
+	acc, err := s.repository.ByUsername(request.Username)
+	if err != nil {
+		return 0, err
+	}
+
+	if acc.Suspension != nil || acc.Role == role.System {
+		return 0, erro.ErrForbidden
+	}
+
+	if acc.ID == previous {
+		return previous, nil
+	}
+
+	return previous, s.repository.TransferDeck(deckID, acc.ID)
+}
+
+// Loads an account an administrator wants to act on. Administrators can't
+// act on themselves, on system accounts or on each other, an admin has to
+// be demoted first
+func (s *AdminServiceImpl) target(actorID int64, username string) (Account, error) {
+	acc, err := s.repository.ByUsername(username)
+	if err != nil {
+		return Account{}, err
+	}
+
+	if acc.ID == actorID || acc.Role == role.System || role.Can(acc.Role, role.ManageAccounts) {
+		return Account{}, erro.ErrForbidden
+	}
+
+	return acc, nil
+}
+
+func (s *AdminServiceImpl) suspend(actorID int64, username string, reason string, until *time.Time) error {
+	reason = strings.TrimSpace(reason)
+	if reason == "" || len(reason) > 500 {
+		return erro.ErrBadField
+	}
+
+	acc, err := s.target(actorID, username)
+	if err != nil {
+		return err
+	}
+
+	token, err := account.RandomHex(16)
+	if err != nil {
+		return err
+	}
+
+	return s.repository.Suspend(account.Suspension{
+		AccID:     acc.ID,
+		Reason:    reason,
+		Until:     until,
+		CreatedBy: &actorID,
+	}, token)
+}
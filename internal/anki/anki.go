@@ -0,0 +1,82 @@
+package anki
+
+import (
+	"learn-swiping-api/internal/card"
+	"learn-swiping-api/internal/progress"
+	"time"
+)
+
+// Kinds of Anki note types
+const (
+	modelStandard = 0
+	modelCloze    = 1
+)
+
+// Note type as stored in the models column of the collection
+type model struct {
+	Name   string `json:"name"`
+	Type   int    `json:"type"`
+	Fields []struct {
+		Name string `json:"name"`
+		Ord  int    `json:"ord"`
+	} `json:"flds"`
+	Templates []struct {
+		Name string `json:"name"`
+		Qfmt string `json:"qfmt"`
+		Afmt string `json:"afmt"`
+	} `json:"tmpls"`
+}
+
+type note struct {
+	ID      int64
+	ModelID int64
+	Fields  []string
+}
+
+// Scheduling of the first card of a note, with its review history
+type schedule struct {
+	DeckID   int64
+	Type     int // 0 new, 1 learning, 2 review, 3 relearning
+	Queue    int // Negative when suspended or buried
+	Due      int64
+	Interval int // Days, negative seconds while learning
+	Factor   int // Ease in permille
+	Reps     int
+	Lapses   int
+	Answers  int
+	Correct  int
+}
+
+type collection struct {
+	Created   time.Time
+	Models    map[int64]model
+	Decks     map[int64]string
+	Notes     []note
+	Schedules map[int64]schedule // By note id
+}
+
+// Outcome of an import
+type Report struct {
+	DeckID      int64             `json:"deck_id"`
+	Title       string            `json:"title"`
+	Cards       int               `json:"cards"`
+	Media       int               `json:"media"`
+	Progress    int               `json:"progress"`
+	Unsupported []UnsupportedType `json:"unsupported_note_types"`
+	// Files referenced by the notes that couldn't be stored
+	SkippedMedia []string `json:"skipped_media"`
+	Warnings     []string `json:"warnings"`
+}
+
+type UnsupportedType struct {
+	Name   string `json:"name"`
+	Reason string `json:"reason"`
+	Notes  int    `json:"notes"`
+}
+
+// Card ready to be inserted, with the scheduling of the caller when the
+// review history is imported
+type imported struct {
+	card     card.Card
+	progress *progress.Progress
+}
@@ -0,0 +1,232 @@
+package anki
+
+import (
+	"archive/zip"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"learn-swiping-api/erro"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+const (
+	maxCollection = 256 << 20 // Uncompressed size of the collection
+	maxMedia      = 10 << 20  // Uncompressed size of a media file
+)
+
+// Contents of an .apkg file. Media is keyed by the file name used in the
+// notes
+type archive struct {
+	collection collection
+	media      map[string]*zip.File
+	mediaMap   bool // Whether the media map could be read
+}
+
+func readPackage(r io.ReaderAt, size int64) (archive, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return archive{}, erro.ErrApkgInvalid
+	}
+
+	files := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+
+	// Newer Anki versions write a zstd compressed collection and leave a
+	// placeholder in collection.anki2 unless asked to support older versions
+	entry := files["collection.anki21"]
+	if entry == nil {
+		if files["collection.anki21b"] != nil {
+			return archive{}, erro.ErrApkgUnsupported
+		}
+		entry = files["collection.anki2"]
+	}
+	if entry == nil {
+		return archive{}, erro.ErrApkgInvalid
+	}
+
+	col, err := readCollection(entry)
+	if err != nil {
+		return archive{}, err
+	}
+
+	pkg := archive{collection: col, media: map[string]*zip.File{}}
+	if f := files["media"]; f != nil {
+		names := map[string]string{}
+		if err := readJSON(f, &names); err == nil {
+			pkg.mediaMap = true
+			for number, name := range names {
+				if file := files[number]; file != nil {
+					pkg.media[name] = file
+				}
+			}
+		}
+	}
+
+	return pkg, nil
+}
+
+// SQLite needs a file, so the collection is copied to a temporary one
+func readCollection(entry *zip.File) (collection, error) {
+	src, err := entry.Open()
+	if err != nil {
+		return collection{}, erro.ErrApkgInvalid
+	}
+	defer src.Close()
+
+	tmp, err := os.CreateTemp("", "collection-*.anki2")
+	if err != nil {
+		return collection{}, err
+	}
+	defer os.Remove(tmp.Name())
+
+	n, err := io.Copy(tmp, io.LimitReader(src, maxCollection+1))
+	tmp.Close()
+	if err != nil {
+		return collection{}, erro.ErrApkgInvalid
+	}
+	if n > maxCollection {
+		return collection{}, erro.ErrBadField
+	}
+
+	db, err := sql.Open("sqlite", "file:"+tmp.Name()+"?mode=ro")
+	if err != nil {
+		return collection{}, err
+	}
+	defer db.Close()
+
+	col, err := queryCollection(db)
+	if err != nil {
+		if err == erro.ErrApkgUnsupported {
+			return collection{}, err
+		}
+		return collection{}, erro.ErrApkgInvalid
+	}
+	return col, nil
+}
+
+func queryCollection(db *sql.DB) (collection, error) {
+	var crt int64
+	var models, decks string
+	if err := db.QueryRow("SELECT crt, models, decks FROM col").Scan(&crt, &models, &decks); err != nil {
+		return collection{}, err
+	}
+
+	// Since schema 15 note types live in their own tables
+	if models == "" || models == "{}" {
+		return collection{}, erro.ErrApkgUnsupported
+	}
+
+	col := collection{
+		Created:   time.Unix(crt, 0),
+		Models:    map[int64]model{},
+		Decks:     map[int64]string{},
+		Schedules: map[int64]schedule{},
+	}
+
+	var rawModels map[string]model
+	if err := json.Unmarshal([]byte(models), &rawModels); err != nil {
+		return collection{}, err
+	}
+	for id, m := range rawModels {
+		modelID, err := strconv.ParseInt(id, 10, 64)
+		if err != nil {
+			return collection{}, err
+		}
+		col.Models[modelID] = m
+	}
+
+	var rawDecks map[string]struct {
+		Name string `json:"name"`
+	}
+	if err := json.Unmarshal([]byte(decks), &rawDecks); err != nil {
+		return collection{}, err
+	}
+	for id, d := range rawDecks {
+		deckID, err := strconv.ParseInt(id, 10, 64)
+		if err != nil {
+			return collection{}, err
+		}
+		col.Decks[deckID] = d.Name
+	}
+
+	rows, err := db.Query("SELECT id, mid, flds FROM notes ORDER BY id")
+	if err != nil {
+		return collection{}, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var n note
+		var fields string
+		if err := rows.Scan(&n.ID, &n.ModelID, &fields); err != nil {
+			return collection{}, err
+		}
+		n.Fields = strings.Split(fields, "\x1f")
+		col.Notes = append(col.Notes, n)
+	}
+	if err := rows.Err(); err != nil {
+		return collection{}, err
+	}
+
+	// Only the first card of each note is imported
+	cards, err := db.Query(`SELECT c.nid, c.did, c.type, c.queue, c.due, c.ivl, c.factor, c.reps, c.lapses,
+								COUNT(r.id), COALESCE(SUM(r.ease > 1), 0)
+							FROM cards c
+							LEFT JOIN revlog r ON r.cid = c.id
+							GROUP BY c.id
+							ORDER BY c.nid, c.ord`)
+	if err != nil {
+		return collection{}, err
+	}
+	defer cards.Close()
+
+	for cards.Next() {
+		var noteID int64
+		var s schedule
+		err := cards.Scan(&noteID, &s.DeckID, &s.Type, &s.Queue, &s.Due, &s.Interval, &s.Factor,
+			&s.Reps, &s.Lapses, &s.Answers, &s.Correct)
+		if err != nil {
+			return collection{}, err
+		}
+		if _, ok := col.Schedules[noteID]; !ok {
+			col.Schedules[noteID] = s
+		}
+	}
+
+	return col, cards.Err()
+}
+
+func readJSON(f *zip.File, v any) error {
+	r, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	return json.NewDecoder(io.LimitReader(r, maxMedia)).Decode(v)
+}
+
+// Reads a media file, failing on the ones over maxMedia
+func readMedia(f *zip.File) ([]byte, error) {
+	r, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	data, err := io.ReadAll(io.LimitReader(r, maxMedia+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxMedia {
+		return nil, erro.ErrBadField
+	}
+	return data, nil
+}
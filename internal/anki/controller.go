@@ -0,0 +1,131 @@
+package anki
+
+import (
+	"errors"
+	"learn-swiping-api/erro"
+	anki "learn-swiping-api/internal/anki/dto"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AnkiController interface {
+	Import(*gin.Context) // POST
+	Export(*gin.Context) // GET
+}
+
+type AnkiControllerImpl struct {
+	service AnkiService
+}
+
+func NewAnkiController(service AnkiService) AnkiController {
+	return &AnkiControllerImpl{service: service}
+}
+
+// Creates a deck from an uploaded Anki package
+// Method: POST
+func (c *AnkiControllerImpl) Import(ctx *gin.Context) {
+	token := ctx.GetHeader("Token")
+	if token == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrInvalidToken.Error()})
+		return
+	}
+
+	// The body is read while binding, so it's cut before
+	ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, maxPackage+maxForm)
+
+	var request anki.ImportRequest
+	if err := ctx.ShouldBind(&request); err != nil {
+		if tooLarge(ctx, err) {
+			return
+		}
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrBadField.Error()})
+		return
+	}
+	request.Token = token
+
+	report, err := c.service.Import(request)
+	if err != nil {
+		// The report tells why nothing could be imported
+		if errors.Is(err, erro.ErrApkgEmpty) {
+			ctx.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error(), "report": report})
+			return
+		}
+		ankiError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, report)
+}
+
+// Downloads a deck as an Anki package, with the caller's scheduling
+// when ?progress=true
+// Method: GET
+func (c *AnkiControllerImpl) Export(ctx *gin.Context) {
+	token := ctx.GetHeader("Token")
+	if token == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrInvalidToken.Error()})
+		return
+	}
+
+	deckID, err := strconv.Atoi(ctx.Param("deckID"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrBadField.Error()})
+		return
+	}
+
+	withProgress := false
+	if value := ctx.Query("progress"); value != "" {
+		withProgress, err = strconv.ParseBool(value)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrBadField.Error()})
+			return
+		}
+	}
+
+	pkg, name, err := c.service.Export(int64(deckID), token, withProgress)
+	if err != nil {
+		ankiError(ctx, err)
+		return
+	}
+
+	ctx.Header("Content-Disposition", `attachment; filename="`+name+`"`)
+	ctx.Data(http.StatusOK, "application/octet-stream", pkg)
+}
+
+func ankiError(ctx *gin.Context, err error) {
+	if errors.Is(err, erro.ErrBadField) || errors.Is(err, erro.ErrInvalidToken) ||
+		errors.Is(err, erro.ErrApkgInvalid) || errors.Is(err, erro.ErrApkgUnsupported) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, erro.ErrFileTooLarge) {
+		ctx.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, erro.ErrForbidden) {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, erro.ErrDeckNotFound) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, erro.ErrDeckExists) {
+		ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
+
+// Writes the response when the upload was cut at the size limit
+func tooLarge(ctx *gin.Context, err error) bool {
+	var limit *http.MaxBytesError
+	if !errors.As(err, &limit) {
+		return false
+	}
+
+	ctx.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": erro.ErrFileTooLarge.Error()})
+	return true
+}
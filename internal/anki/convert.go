@@ -0,0 +1,129 @@
+package anki
+
+import (
+	"html"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/microcosm-cc/bluemonday"
+)
+
+var (
+	fieldRefRegexp = regexp.MustCompile(`{{([^{}]+)}}`)
+	imageRegexp    = regexp.MustCompile(`(?i)<img[^>]*?\ssrc\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s>]+))[^>]*>`)
+	soundRegexp    = regexp.MustCompile(`\[sound:[^\]]*\]`)
+	breakRegexp    = regexp.MustCompile(`(?i)<br\s*/?>|</(?:div|p|li)>`)
+	spaceRegexp    = regexp.MustCompile(`[ \t\f\v\x{00A0}]+`)
+
+	htmlPolicy = bluemonday.UGCPolicy()
+	textPolicy = bluemonday.StrictPolicy()
+)
+
+// Splits the fields of a note type into the ones shown on the question
+// and the ones only revealed with the answer, following the first
+// template. Note types with unusual templates fall back to the first
+// field on the front and the rest on the back
+func sides(m model) ([]int, []int) {
+	index := make(map[string]int, len(m.Fields))
+	for i, f := range m.Fields {
+		index[f.Name] = i
+	}
+
+	var front, back []int
+	if len(m.Templates) > 0 {
+		front = fieldRefs(m.Templates[0].Qfmt, index, nil)
+		back = fieldRefs(m.Templates[0].Afmt, index, front)
+	}
+
+	if len(front) == 0 || len(back) == 0 {
+		front, back = []int{0}, nil
+		for i := 1; i < len(m.Fields); i++ {
+			back = append(back, i)
+		}
+	}
+	return front, back
+}
+
+// Fields used by a template, in order and leaving out the excluded ones
+func fieldRefs(template string, index map[string]int, exclude []int) []int {
+	var refs []int
+	for _, match := range fieldRefRegexp.FindAllStringSubmatch(template, -1) {
+		name := strings.TrimSpace(match[1])
+		// Conditionals only decide whether something is shown
+		if strings.HasPrefix(name, "#") || strings.HasPrefix(name, "^") || strings.HasPrefix(name, "/") {
+			continue
+		}
+		// Filters like type: or hint: go before the name
+		if i := strings.LastIndex(name, ":"); i >= 0 {
+			name = name[i+1:]
+		}
+
+		i, ok := index[name]
+		if !ok || slices.Contains(refs, i) || slices.Contains(exclude, i) {
+			continue
+		}
+		refs = append(refs, i)
+	}
+	return refs
+}
+
+// Media files shown in a field
+func images(field string) []string {
+	var names []string
+	for _, match := range imageRegexp.FindAllStringSubmatch(field, -1) {
+		if name := imageName(match); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func imageName(match []string) string {
+	src := match[1] + match[2] + match[3]
+	if name, err := url.PathUnescape(src); err == nil {
+		return name
+	}
+	return src
+}
+
+// Sanitized HTML of a field. Images point to the stored pictures and the
+// ones that couldn't be stored are dropped, like sounds
+func toHTML(field string, pictures map[string]string) string {
+	field = soundRegexp.ReplaceAllString(field, "")
+	field = imageRegexp.ReplaceAllStringFunc(field, func(tag string) string {
+		picID, ok := pictures[imageName(imageRegexp.FindStringSubmatch(tag))]
+		if !ok {
+			return ""
+		}
+		return `<img src="/pics/` + picID + `">`
+	})
+	return strings.TrimSpace(htmlPolicy.Sanitize(field))
+}
+
+// Plain text of a field, keeping its line breaks
+func toText(field string) string {
+	field = soundRegexp.ReplaceAllString(field, "")
+	field = breakRegexp.ReplaceAllString(field, "\n")
+	field = html.UnescapeString(textPolicy.Sanitize(field))
+
+	lines := strings.Split(field, "\n")
+	kept := lines[:0]
+	for _, line := range lines {
+		line = strings.TrimSpace(spaceRegexp.ReplaceAllString(line, " "))
+		if line != "" {
+			kept = append(kept, line)
+		}
+	}
+	return strings.Join(kept, "\n")
+}
+
+// Cuts the text to a number of characters
+func truncate(text string, max int) string {
+	runes := []rune(strings.ReplaceAll(text, "\n", " "))
+	if len(runes) <= max {
+		return string(runes)
+	}
+	return strings.TrimSpace(string(runes[:max-1])) + "…"
+}
@@ -0,0 +1,12 @@
+package anki
+
+import "mime/multipart"
+
+type ImportRequest struct {
+	Token       string
+	File        *multipart.FileHeader `form:"file" binding:"required"`
+	Title       string                `form:"title"` // Name of the Anki deck if empty
+	Description string                `form:"description"`
+	Visible     bool                  `form:"visible"`  // Default hidden
+	Progress    bool                  `form:"progress"` // Imports the review history of the caller
+}
@@ -0,0 +1,432 @@
+package anki
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha1"
+	"database/sql"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"html"
+	"learn-swiping-api/internal/card"
+	"learn-swiping-api/internal/progress"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Legacy collection layout, the one every Anki version can import
+const collectionSchema = `
+CREATE TABLE col (
+	id integer primary key, crt integer not null, mod integer not null, scm integer not null,
+	ver integer not null, dty integer not null, usn integer not null, ls integer not null,
+	conf text not null, models text not null, decks text not null, dconf text not null, tags text not null
+);
+CREATE TABLE notes (
+	id integer primary key, guid text not null, mid integer not null, mod integer not null,
+	usn integer not null, tags text not null, flds text not null, sfld integer not null,
+	csum integer not null, flags integer not null, data text not null
+);
+CREATE TABLE cards (
+	id integer primary key, nid integer not null, did integer not null, ord integer not null,
+	mod integer not null, usn integer not null, type integer not null, queue integer not null,
+	due integer not null, ivl integer not null, factor integer not null, reps integer not null,
+	lapses integer not null, left integer not null, odue integer not null, odid integer not null,
+	flags integer not null, data text not null
+);
+CREATE TABLE revlog (
+	id integer primary key, cid integer not null, usn integer not null, ease integer not null,
+	ivl integer not null, lastIvl integer not null, factor integer not null, time integer not null,
+	type integer not null
+);
+CREATE TABLE graves (usn integer not null, oid integer not null, type integer not null);
+CREATE INDEX ix_notes_usn ON notes (usn);
+CREATE INDEX ix_cards_usn ON cards (usn);
+CREATE INDEX ix_revlog_usn ON revlog (usn);
+CREATE INDEX ix_cards_nid ON cards (nid);
+CREATE INDEX ix_cards_sched ON cards (did, queue, due);
+CREATE INDEX ix_revlog_cid ON revlog (cid);
+CREATE INDEX ix_notes_csum ON notes (csum);`
+
+const cardCSS = `.card { font-family: arial; font-size: 20px; text-align: center; color: black; background-color: white; }
+ol.choices { display: inline-block; text-align: left; }`
+
+var (
+	// Pictures of the app, either by path or by full url
+	pictureRegexp = regexp.MustCompile(`(["'=])(?:https?://[^"'\s>]*)?/pics/([A-Za-z0-9_-]+\.(?:png|jpeg|webp))`)
+	tagRegexp     = regexp.MustCompile(`<[a-zA-Z/][^>]*>`)
+)
+
+// What goes into an exported package
+type exportDeck struct {
+	Title       string
+	Description string
+	Cards       []card.Card
+	Progress    map[int64]progress.Progress // By card id, empty without scheduling
+	Pictures    map[string][]byte           // By picture id
+}
+
+// Picture ids used by the cards of a deck
+func pictureIDs(cards []card.Card) []string {
+	seen := map[string]bool{}
+	var ids []string
+	for _, c := range cards {
+		fields := []string{c.Front, c.Back, c.Question}
+		for _, o := range c.Options {
+			fields = append(fields, o.Answer)
+		}
+		if c.Cloze != nil {
+			fields = append(fields, c.Cloze.Text)
+		}
+		if c.Occlusion != nil && !seen[c.Occlusion.PicID] {
+			seen[c.Occlusion.PicID] = true
+			ids = append(ids, c.Occlusion.PicID)
+		}
+		for _, field := range fields {
+			for _, match := range pictureRegexp.FindAllStringSubmatch(field, -1) {
+				if !seen[match[2]] {
+					seen[match[2]] = true
+					ids = append(ids, match[2])
+				}
+			}
+		}
+	}
+	return ids
+}
+
+// Anki fields are HTML. Cards written in the app are plain text and the
+// imported ones already HTML
+func fieldHTML(value string) string {
+	if tagRegexp.MatchString(value) {
+		value = htmlPolicy.Sanitize(value)
+		// Media is referenced by file name inside the package
+		return pictureRegexp.ReplaceAllString(value, "$1$2")
+	}
+	return strings.ReplaceAll(html.EscapeString(value), "\n", "<br>")
+}
+
+// Builds the .apkg file
+func writePackage(deck exportDeck) ([]byte, error) {
+	tmp, err := os.CreateTemp("", "export-*.anki2")
+	if err != nil {
+		return nil, err
+	}
+	tmp.Close()
+	defer os.Remove(tmp.Name())
+
+	if err := writeCollection(tmp.Name(), deck); err != nil {
+		return nil, err
+	}
+
+	collection, err := os.ReadFile(tmp.Name())
+	if err != nil {
+		return nil, err
+	}
+
+	buf := &bytes.Buffer{}
+	zw := zip.NewWriter(buf)
+	w, err := zw.Create("collection.anki2")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(collection); err != nil {
+		return nil, err
+	}
+
+	// Media files are numbered, the map gives them their names back
+	media := map[string]string{}
+	number := 0
+	for picID, data := range deck.Pictures {
+		name := strconv.Itoa(number)
+		w, err := zw.Create(name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := w.Write(data); err != nil {
+			return nil, err
+		}
+		media[name] = picID
+		number++
+	}
+
+	w, err = zw.Create("media")
+	if err != nil {
+		return nil, err
+	}
+	if err := json.NewEncoder(w).Encode(media); err != nil {
+		return nil, err
+	}
+
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func writeCollection(path string, deck exportDeck) error {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if _, err := db.Exec(collectionSchema); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	year, month, day := now.Date()
+	created := time.Date(year, month, day, 0, 0, 0, 0, now.Location())
+
+	// Anki ids are millisecond timestamps
+	base := now.UnixMilli()
+	ids := modelIDs{basic: base, choice: base + 1, cloze: base + 3, typed: base + 4}
+	deckID := base + 2
+
+	models, err := json.Marshal(map[string]any{
+		strconv.FormatInt(ids.basic, 10):  basicModel(ids.basic, deckID, now),
+		strconv.FormatInt(ids.choice, 10): choiceModel(ids.choice, deckID, now),
+		strconv.FormatInt(ids.cloze, 10):  clozeModel(ids.cloze, deckID, now),
+		strconv.FormatInt(ids.typed, 10):  typedModel(ids.typed, deckID, now),
+	})
+	if err != nil {
+		return err
+	}
+
+	decks, err := json.Marshal(map[string]any{
+		"1":                           deckJSON(1, "Default", "", now),
+		strconv.FormatInt(deckID, 10): deckJSON(deckID, deck.Title, deck.Description, now),
+	})
+	if err != nil {
+		return err
+	}
+
+	conf, err := json.Marshal(map[string]any{
+		"nextPos": len(deck.Cards) + 1, "estTimes": true, "activeDecks": []int64{deckID}, "sortType": "noteFld",
+		"timeLim": 0, "sortBackwards": false, "addToCur": true, "curDeck": deckID, "newSpread": 0,
+		"dueCounts": true, "curModel": strconv.FormatInt(ids.choice, 10), "collapseTime": 1200,
+	})
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("INSERT INTO col VALUES (1, ?, ?, ?, 11, 0, 0, 0, ?, ?, ?, ?, '{}')",
+		created.Unix(), base, base, string(conf), string(models), string(decks), dconfJSON)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	noteID, cardID := base, base+int64(len(deck.Cards))
+	for i, c := range deck.Cards {
+		modelID, fields, ords := noteOf(c, ids)
+
+		sortField := toText(fields[0])
+		_, err := tx.Exec("INSERT INTO notes VALUES (?, ?, ?, ?, 0, '', ?, ?, ?, 0, '')",
+			noteID, fmt.Sprintf("lsw-%d", c.CardID), modelID, now.Unix(), strings.Join(fields, "\x1f"), sortField, checksum(sortField))
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+
+		for k, ord := range ords {
+			s := newCard(i + 1)
+			// The progress of the app follows the flashcard side, or the
+			// deletion the card itself asks about
+			if p, ok := deck.Progress[c.CardID]; ok && k == 0 {
+				s = scheduled(p)
+			}
+
+			_, err := tx.Exec("INSERT INTO cards VALUES (?, ?, ?, ?, ?, 0, ?, ?, ?, ?, ?, ?, ?, 0, 0, 0, 0, '')",
+				cardID, noteID, deckID, ord, now.Unix(), s.Type, s.Queue, s.Due, s.Interval, s.Factor, s.Reps, s.Lapses)
+			if err != nil {
+				tx.Rollback()
+				return err
+			}
+			cardID++
+		}
+		noteID++
+	}
+
+	return tx.Commit()
+}
+
+// Ids of the note types of the collection
+type modelIDs struct {
+	basic, choice, cloze, typed int64
+}
+
+// Note type, fields and card templates of a card. Cloze cards get an
+// Anki card for each deletion, the siblings of the app, and occlusion
+// ones a single card with the picture and its labels
+func noteOf(c card.Card, ids modelIDs) (int64, []string, []int) {
+	switch {
+	case c.CardType == card.TypeCloze && c.Cloze != nil:
+		// Deletions are written the same way in Anki
+		text := fieldHTML(c.Cloze.Text)
+		if strings.TrimSpace(c.Front) != "" {
+			text += "<br><br>" + fieldHTML(c.Front)
+		}
+		ords := []int{}
+		for _, number := range c.Siblings() {
+			ords = append(ords, number-1)
+		}
+		return ids.cloze, []string{text, fieldHTML(c.Back)}, ords
+	case c.CardType == card.TypeTyped && c.Typed != nil && len(c.Typed.Answers) > 0:
+		// Anki checks a single answer, the rest are shown after it
+		back := fieldHTML(c.Back)
+		if others := c.Typed.Answers[1:]; len(others) > 0 {
+			accepted := make([]string, 0, len(others))
+			for _, answer := range others {
+				accepted = append(accepted, fieldHTML(answer))
+			}
+			back += "<br><br>Also accepted: " + strings.Join(accepted, ", ")
+		}
+		return ids.typed, []string{fieldHTML(c.Front), back, fieldHTML(c.Question), fieldHTML(c.Typed.Answers[0])}, []int{0}
+	case c.CardType == card.TypeOcclusion && c.Occlusion != nil:
+		picture := `<img src="` + html.EscapeString(c.Occlusion.PicID) + `">`
+		var labels strings.Builder
+		labels.WriteString("<ol>")
+		for _, m := range c.Occlusion.Masks {
+			labels.WriteString("<li>" + fieldHTML(m.Label) + "</li>")
+		}
+		labels.WriteString("</ol>")
+		return ids.basic, []string{fieldHTML(c.Front) + "<br>" + picture, picture + labels.String() + fieldHTML(c.Back)}, []int{0}
+	}
+
+	fields := []string{fieldHTML(c.Front), fieldHTML(c.Back)}
+	if correct, _ := c.Answers(); c.Question != "" && len(correct) > 0 {
+		answers := make([]string, 0, len(correct))
+		for _, answer := range correct {
+			answers = append(answers, fieldHTML(answer))
+		}
+		fields = append(fields, fieldHTML(c.Question), choices(c), strings.Join(answers, "<br>"))
+		return ids.choice, fields, []int{0, 1}
+	}
+	return ids.basic, fields, []int{0}
+}
+
+// New cards are shown in the order of the deck
+func newCard(position int) schedule {
+	return schedule{Type: 0, Queue: 0, Due: int64(position)}
+}
+
+// Review state of a card the account already studied. Due days count
+// from the collection creation, which is today
+func scheduled(p progress.Progress) schedule {
+	s := schedule{
+		Type:     2,
+		Queue:    2,
+		Due:      int64(p.DaysHidden),
+		Interval: max(p.Interval, 1),
+		Factor:   int(p.Ease * 1000),
+		Reps:     p.WatchCount,
+		Lapses:   max(p.AnswerCount-p.CorrectCount, 0),
+	}
+	if s.Factor < 1300 {
+		s.Factor = 2500
+	}
+	if p.IsRelearning {
+		s.Type = 3
+	}
+	if p.IsBuried {
+		s.Queue = -2
+	}
+	return s
+}
+
+// Options of the card in their order
+func choices(c card.Card) string {
+	var b strings.Builder
+	b.WriteString(`<ol class="choices" type="A">`)
+	for _, option := range c.Options {
+		b.WriteString("<li>" + fieldHTML(option.Answer) + "</li>")
+	}
+	b.WriteString("</ol>")
+	return b.String()
+}
+
+// First 8 hex digits of the sha1 of the sort field, used by Anki to find
+// duplicates
+func checksum(field string) int64 {
+	sum := sha1.Sum([]byte(field))
+	return int64(binary.BigEndian.Uint32(sum[:4]))
+}
+
+func field(name string, ord int) map[string]any {
+	return map[string]any{"name": name, "ord": ord, "sticky": false, "rtl": false, "font": "Arial", "size": 20, "media": []string{}}
+}
+
+func template(name string, ord int, qfmt string, afmt string) map[string]any {
+	return map[string]any{"name": name, "ord": ord, "qfmt": qfmt, "afmt": afmt, "did": nil, "bqfmt": "", "bafmt": ""}
+}
+
+func modelJSON(id int64, deckID int64, name string, fields []map[string]any, templates []map[string]any, req [][]any, now time.Time) map[string]any {
+	return map[string]any{
+		"id": id, "name": name, "type": modelStandard, "mod": now.Unix(), "usn": 0, "sortf": 0, "did": deckID,
+		"flds": fields, "tmpls": templates, "req": req, "css": cardCSS, "tags": []string{}, "vers": []int{},
+		"latexPre":  "\\documentclass[12pt]{article}\n\\special{papersize=3in,5in}\n\\usepackage{amssymb,amsmath}\n\\pagestyle{empty}\n\\setlength{\\parindent}{0in}\n\\begin{document}\n",
+		"latexPost": "\\end{document}", "latexsvg": false,
+	}
+}
+
+// Front and back, for cards without a quiz
+func basicModel(id int64, deckID int64, now time.Time) map[string]any {
+	return modelJSON(id, deckID, "Learn Swiping Basic",
+		[]map[string]any{field("Front", 0), field("Back", 1)},
+		[]map[string]any{template("Card", 0, "{{Front}}", "{{FrontSide}}\n\n<hr id=answer>\n\n{{Back}}")},
+		[][]any{{0, "any", []int{0}}}, now)
+}
+
+// Front and back plus a multiple choice card with the quiz
+func choiceModel(id int64, deckID int64, now time.Time) map[string]any {
+	return modelJSON(id, deckID, "Learn Swiping Multiple Choice",
+		[]map[string]any{field("Front", 0), field("Back", 1), field("Question", 2), field("Choices", 3), field("Answer", 4)},
+		[]map[string]any{
+			template("Card", 0, "{{Front}}", "{{FrontSide}}\n\n<hr id=answer>\n\n{{Back}}"),
+			template("Multiple choice", 1, "{{Question}}<br>{{Choices}}", "{{FrontSide}}\n\n<hr id=answer>\n\n{{Answer}}"),
+		},
+		[][]any{{0, "any", []int{0}}, {1, "all", []int{2}}}, now)
+}
+
+// Text with deletions, a card for each of them
+func clozeModel(id int64, deckID int64, now time.Time) map[string]any {
+	m := modelJSON(id, deckID, "Learn Swiping Cloze",
+		[]map[string]any{field("Text", 0), field("Back Extra", 1)},
+		[]map[string]any{template("Cloze", 0, "{{cloze:Text}}", "{{cloze:Text}}<br>\n{{Back Extra}}")},
+		[][]any{{0, "any", []int{0}}}, now)
+	m["type"] = modelCloze
+	return m
+}
+
+// Answer typed by hand
+func typedModel(id int64, deckID int64, now time.Time) map[string]any {
+	return modelJSON(id, deckID, "Learn Swiping Typed",
+		[]map[string]any{field("Front", 0), field("Back", 1), field("Question", 2), field("Answer", 3)},
+		[]map[string]any{template("Card", 0, "{{Front}}<br>{{Question}}\n\n{{type:Answer}}",
+			"{{Front}}<br>{{Question}}\n\n<hr id=answer>\n\n{{type:Answer}}<br>{{Back}}")},
+		[][]any{{0, "any", []int{2}}}, now)
+}
+
+func deckJSON(id int64, name string, description string, now time.Time) map[string]any {
+	return map[string]any{
+		"id": id, "name": name, "desc": description, "mod": now.Unix(), "usn": 0, "collapsed": false,
+		"browserCollapsed": false, "dyn": 0, "conf": 1, "extendNew": 10, "extendRev": 50,
+		"newToday": []int{0, 0}, "revToday": []int{0, 0}, "lrnToday": []int{0, 0}, "timeToday": []int{0, 0},
+	}
+}
+
+// Default options group
+const dconfJSON = `{"1": {"id": 1, "name": "Default", "mod": 0, "usn": 0, "maxTaken": 60, "autoplay": true, "timer": 0,
+	"replayq": true, "dyn": false,
+	"new": {"delays": [1, 10], "ints": [1, 4, 7], "initialFactor": 2500, "order": 1, "perDay": 20, "bury": false, "separate": true},
+	"rev": {"perDay": 200, "ease4": 1.3, "ivlFct": 1, "maxIvl": 36500, "bury": false, "hardFactor": 1.2, "fuzz": 0.05, "minSpace": 1},
+	"lapse": {"delays": [10], "mult": 0, "minInt": 1, "leechFails": 8, "leechAction": 0}}}`
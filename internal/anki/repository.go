@@ -0,0 +1,246 @@
+package anki
+
+import (
+	"database/sql"
+	"learn-swiping-api/erro"
+	"learn-swiping-api/internal/card"
+	"learn-swiping-api/internal/progress"
+	"log"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+type AnkiRepository interface {
+	ByToken(token string) (int64, error)
+	Import(accID int64, title string, description string, visible bool, cards []imported) (int64, error)
+	Deck(deckID int64) (string, string, error) // Title and description
+	Cards(deckID int64) ([]card.Card, error)
+	Progress(deckID int64, accID int64) (map[int64]progress.Progress, error)
+}
+
+type AnkiRepositoryImpl struct {
+	db           *sql.DB
+	ByTokenStmt  *sql.Stmt
+	DeckStmt     *sql.Stmt
+	CardsStmt    *sql.Stmt
+	OptionsStmt  *sql.Stmt
+	ProgressStmt *sql.Stmt
+}
+
+func NewAnkiRepository(db *sql.DB) *AnkiRepositoryImpl {
+	repo := &AnkiRepositoryImpl{db: db}
+	err := repo.InitStatements()
+	if err != nil {
+		log.Fatalln(err)
+	}
+	return repo
+}
+
+func (r *AnkiRepositoryImpl) InitStatements() error {
+	var err error
+	r.ByTokenStmt, err = r.db.Prepare("SELECT acc_id FROM ACCOUNT WHERE token = ? AND token_expire >= NOW()")
+	if err != nil {
+		return err
+	}
+
+	r.DeckStmt, err = r.db.Prepare("SELECT title, description FROM DECK WHERE deck_id = ?")
+	if err != nil {
+		return err
+	}
+
+	// Siblings become cards of the note of their source
+	r.CardsStmt, err = r.db.Prepare("SELECT " + card.CardColumns + " " + card.CardFrom +
+		" WHERE c.deck_id = ? AND c.parent_id IS NULL ORDER BY " + card.CardOrder)
+	if err != nil {
+		return err
+	}
+
+	r.OptionsStmt, err = r.db.Prepare(`SELECT o.option_id, o.card_id, o.answer, o.correct FROM CARD_OPTION o
+										JOIN CARD c ON o.card_id = c.card_id
+										WHERE c.deck_id = ?
+										ORDER BY o.card_id, o.position`)
+	if err != nil {
+		return err
+	}
+
+	r.ProgressStmt, err = r.db.Prepare(`SELECT p.card_id, p.ease, p.` + "`interval`" + `, p.days_hidden, p.watch_count,
+											p.answer_count, p.correct_count, p.is_relearning, p.is_buried
+										FROM PROGRESS p
+										JOIN CARD c ON p.card_id = c.card_id
+										WHERE c.deck_id = ? AND p.acc_id = ?`)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *AnkiRepositoryImpl) ByToken(token string) (int64, error) {
+	var accID int64
+	if err := r.ByTokenStmt.QueryRow(token).Scan(&accID); err != nil {
+		if err == sql.ErrNoRows {
+			return 0, erro.ErrInvalidToken
+		}
+		return 0, err
+	}
+	return accID, nil
+}
+
+// Creates the deck with all its cards, or nothing at all. The owner is
+// subscribed like with any other new deck
+func (r *AnkiRepositoryImpl) Import(accID int64, title string, description string, visible bool, cards []imported) (int64, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := tx.Exec("INSERT INTO DECK (acc_id, title, description, visible) VALUES (?, ?, ?, ?)", accID, title, description, visible)
+	if err != nil {
+		tx.Rollback()
+		if mysqlErr, ok := err.(*mysql.MySQLError); ok && mysqlErr.Number == 1062 {
+			return 0, erro.ErrDeckExists
+		}
+		return 0, err
+	}
+
+	deckID, err := result.LastInsertId()
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	if _, err := tx.Exec("INSERT INTO ACC_DECK (acc_id, deck_id) VALUES (?, ?)", accID, deckID); err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	// Cannot use globally prepared statements here because of the transaction
+	cardStmt, err := tx.Prepare("INSERT INTO CARD (deck_id, position, title, front, back, question, answer_type) VALUES (?, ?, ?, ?, ?, ?, ?)")
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	defer cardStmt.Close()
+
+	optionStmt, err := tx.Prepare("INSERT INTO CARD_OPTION (card_id, position, answer, correct) VALUES (?, ?, ?, ?)")
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	defer optionStmt.Close()
+
+	progressStmt, err := tx.Prepare(`INSERT INTO PROGRESS (acc_id, card_id, ease, ` + "`interval`" + `, days_hidden, watch_count,
+											answer_count, correct_count, is_relearning, is_buried)
+										VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	defer progressStmt.Close()
+
+	for position, c := range cards {
+		result, err := cardStmt.Exec(deckID, position, c.card.Title, c.card.Front, c.card.Back, c.card.Question, c.card.AnswerType)
+		if err != nil {
+			tx.Rollback()
+			return 0, err
+		}
+
+		cardID, err := result.LastInsertId()
+		if err != nil {
+			tx.Rollback()
+			return 0, err
+		}
+
+		for position, option := range c.card.Options {
+			if _, err := optionStmt.Exec(cardID, position, option.Answer, option.Correct); err != nil {
+				tx.Rollback()
+				return 0, err
+			}
+		}
+
+		if p := c.progress; p != nil {
+			_, err := progressStmt.Exec(accID, cardID, p.Ease, p.Interval, p.DaysHidden, p.WatchCount,
+				p.AnswerCount, p.CorrectCount, p.IsRelearning, p.IsBuried)
+			if err != nil {
+				tx.Rollback()
+				return 0, err
+			}
+		}
+	}
+
+	return deckID, tx.Commit()
+}
+
+func (r *AnkiRepositoryImpl) Deck(deckID int64) (string, string, error) {
+	var title, description string
+	if err := r.DeckStmt.QueryRow(deckID).Scan(&title, &description); err != nil {
+		if err == sql.ErrNoRows {
+			return "", "", erro.ErrDeckNotFound
+		}
+		return "", "", err
+	}
+	return title, description, nil
+}
+
+// Cards of a deck with their options, in the order of the deck
+func (r *AnkiRepositoryImpl) Cards(deckID int64) ([]card.Card, error) {
+	rows, err := r.CardsStmt.Query(deckID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	cards := []card.Card{}
+	index := map[int64]int{}
+	for rows.Next() {
+		c, err := card.ScanCard(rows)
+		if err != nil {
+			return nil, err
+		}
+		index[c.CardID] = len(cards)
+		cards = append(cards, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	options, err := r.OptionsStmt.Query(deckID)
+	if err != nil {
+		return nil, err
+	}
+	defer options.Close()
+
+	for options.Next() {
+		var o card.Option
+		if err := options.Scan(&o.OptionID, &o.CardID, &o.Answer, &o.Correct); err != nil {
+			return nil, err
+		}
+		if i, ok := index[o.CardID]; ok {
+			cards[i].Options = append(cards[i].Options, o)
+		}
+	}
+
+	return cards, options.Err()
+}
+
+// Progress of the account on the cards of a deck, by card id
+func (r *AnkiRepositoryImpl) Progress(deckID int64, accID int64) (map[int64]progress.Progress, error) {
+	rows, err := r.ProgressStmt.Query(deckID, accID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	progresses := map[int64]progress.Progress{}
+	for rows.Next() {
+		p := progress.Progress{AccID: accID}
+		err := rows.Scan(&p.CardID, &p.Ease, &p.Interval, &p.DaysHidden, &p.WatchCount,
+			&p.AnswerCount, &p.CorrectCount, &p.IsRelearning, &p.IsBuried)
+		if err != nil {
+			return nil, err
+		}
+		progresses[p.CardID] = p
+	}
+
+	return progresses, rows.Err()
+}
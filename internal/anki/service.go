@@ -0,0 +1,411 @@
+package anki
+
+import (
+	"fmt"
+	"learn-swiping-api/erro"
+	anki "learn-swiping-api/internal/anki/dto"
+	"learn-swiping-api/internal/card"
+	"learn-swiping-api/internal/collaborator"
+	"learn-swiping-api/internal/picture"
+	"learn-swiping-api/internal/progress"
+	"log"
+	"math/rand"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	maxPackage   = 200 << 20
+	maxForm      = 1 << 20 // Fields of the upload besides the package
+	maxNotes     = 20000
+	maxTitle     = 60
+	wrongAnswers = 3  // Like cards created from the app
+	maxListed    = 50 // Entries of each list in the report
+	imageText    = "(image)"
+)
+
+// Extensions the picture package takes for each image extension found
+var pictureExtensions = map[string]string{
+	".png":  ".png",
+	".jpg":  ".jpeg",
+	".jpeg": ".jpeg",
+	".webp": ".webp",
+}
+
+type AnkiService interface {
+	Import(anki.ImportRequest) (Report, error)
+	Export(deckID int64, token string, withProgress bool) ([]byte, string, error) // Package and file name
+	convert(pkg archive, withProgress bool, report *Report) ([]imported, []string)
+	storeMedia(pkg archive, notes []note, report *Report) map[string]string
+}
+
+type AnkiServiceImpl struct {
+	repository    AnkiRepository
+	collaborators collaborator.CollaboratorService
+}
+
+func NewAnkiService(repository AnkiRepository, collaborators collaborator.CollaboratorService) AnkiService {
+	return &AnkiServiceImpl{repository: repository, collaborators: collaborators}
+}
+
+// Creates a deck from an .apkg file. Notes of unsupported types are
+// left out and listed in the report
+func (s *AnkiServiceImpl) Import(request anki.ImportRequest) (Report, error) {
+	if !strings.EqualFold(filepath.Ext(request.File.Filename), ".apkg") {
+		return Report{}, erro.ErrBadField
+	}
+	if request.File.Size > maxPackage {
+		return Report{}, erro.ErrFileTooLarge
+	}
+
+	accID, err := s.repository.ByToken(request.Token)
+	if err != nil {
+		return Report{}, err
+	}
+
+	file, err := request.File.Open()
+	if err != nil {
+		return Report{}, erro.ErrBadField
+	}
+	defer file.Close()
+
+	pkg, err := readPackage(file, request.File.Size)
+	if err != nil {
+		return Report{}, err
+	}
+	if len(pkg.collection.Notes) > maxNotes {
+		return Report{}, erro.ErrBadField
+	}
+
+	report := Report{Unsupported: []UnsupportedType{}, SkippedMedia: []string{}, Warnings: []string{}}
+	cards, pictures := s.convert(pkg, request.Progress, &report)
+	if len(cards) == 0 {
+		return report, erro.ErrApkgEmpty
+	}
+
+	report.Title = strings.TrimSpace(request.Title)
+	if report.Title == "" {
+		report.Title = deckName(pkg.collection)
+	}
+
+	report.DeckID, err = s.repository.Import(accID, report.Title, request.Description, request.Visible, cards)
+	if err != nil {
+		for _, picID := range pictures {
+			if err := picture.Remove(picID); err != nil {
+				log.Println(err)
+			}
+		}
+		return Report{}, err
+	}
+
+	return report, nil
+}
+
+// Builds an .apkg file with the cards of a deck anyone who can read it
+// can download. The scheduling is the one of the caller
+func (s *AnkiServiceImpl) Export(deckID int64, token string, withProgress bool) ([]byte, string, error) {
+	access, err := s.collaborators.Authorize(deckID, token, collaborator.ReadDeck)
+	if err != nil {
+		return nil, "", err
+	}
+
+	deck := exportDeck{Pictures: map[string][]byte{}}
+	deck.Title, deck.Description, err = s.repository.Deck(deckID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	deck.Cards, err = s.repository.Cards(deckID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if withProgress {
+		deck.Progress, err = s.repository.Progress(deckID, access.AccID)
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	// A missing picture shouldn't prevent the export, Anki shows it as broken
+	for _, picID := range pictureIDs(deck.Cards) {
+		data, err := picture.Picture(picID)
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+		deck.Pictures[picID] = data
+	}
+
+	pkg, err := writePackage(deck)
+	if err != nil {
+		return nil, "", err
+	}
+	return pkg, fileName(deck.Title) + ".apkg", nil
+}
+
+// Maps the notes to cards. Returns them with the pictures stored for them
+func (s *AnkiServiceImpl) convert(pkg archive, withProgress bool, report *Report) ([]imported, []string) {
+	type layout struct{ front, back []int }
+	layouts := map[int64]layout{}
+	unsupported := map[int64]int{} // Position in report.Unsupported
+
+	var notes []note
+	for _, n := range pkg.collection.Notes {
+		m, ok := pkg.collection.Models[n.ModelID]
+		reason := ""
+		switch {
+		case !ok:
+			reason = "note type missing from the collection"
+		case m.Type == modelCloze:
+			reason = "cloze deletions are not supported"
+		case len(m.Fields) < 2 || len(n.Fields) < len(m.Fields):
+			reason = "needs a front and a back field"
+		}
+
+		if reason != "" {
+			i, seen := unsupported[n.ModelID]
+			if !seen {
+				i = len(report.Unsupported)
+				unsupported[n.ModelID] = i
+				name := m.Name
+				if !ok {
+					name = fmt.Sprintf("unknown (%d)", n.ModelID)
+				}
+				report.Unsupported = append(report.Unsupported, UnsupportedType{Name: name, Reason: reason})
+			}
+			report.Unsupported[i].Notes++
+			continue
+		}
+
+		if _, ok := layouts[n.ModelID]; !ok {
+			front, back := sides(m)
+			layouts[n.ModelID] = layout{front, back}
+		}
+		notes = append(notes, n)
+	}
+
+	pictures := s.storeMedia(pkg, notes, report)
+
+	cards := make([]imported, 0, len(notes))
+	today := int64(time.Since(pkg.collection.Created).Hours() / 24)
+	for _, n := range notes {
+		l := layouts[n.ModelID]
+		front, back := join(n.Fields, l.front, pictures), join(n.Fields, l.back, pictures)
+		if front == "" || back == "" {
+			report.Warnings = appendListed(report.Warnings, fmt.Sprintf("note %d has an empty side and was skipped", n.ID))
+			continue
+		}
+		question, answer := textOf(front), textOf(back)
+
+		c := imported{card: card.Card{
+			Title:      truncate(question, maxTitle),
+			Front:      front,
+			Back:       back,
+			Question:   question,
+			AnswerType: card.AnswerSingle,
+			Options:    []card.Option{{Answer: answer, Correct: true}},
+		}}
+
+		if sched, ok := pkg.collection.Schedules[n.ID]; ok && withProgress && sched.Type != 0 {
+			c.progress = toProgress(sched, today)
+			report.Progress++
+		}
+		cards = append(cards, c)
+	}
+
+	// Anki cards only have one answer, the wrong ones come from other cards
+	short := 0
+	for i := range cards {
+		wrong := distractors(cards, i)
+		if len(wrong) < wrongAnswers {
+			short++
+		}
+		cards[i].card.Options = append(cards[i].card.Options, wrong...)
+	}
+	if short > 0 {
+		report.Warnings = append(report.Warnings, fmt.Sprintf("%d cards have less than %d wrong answers, there weren't enough different answers in the deck", short, wrongAnswers))
+	}
+
+	report.Cards = len(cards)
+	picIDs := make([]string, 0, len(pictures))
+	for _, picID := range pictures {
+		picIDs = append(picIDs, picID)
+	}
+	return cards, picIDs
+}
+
+// Stores the images used by the notes. Returns the picture id of each
+// file name
+func (s *AnkiServiceImpl) storeMedia(pkg archive, notes []note, report *Report) map[string]string {
+	pictures := map[string]string{}
+	skipped := map[string]bool{}
+	skip := func(name string, reason string) {
+		if !skipped[name] {
+			skipped[name] = true
+			report.SkippedMedia = appendListed(report.SkippedMedia, name+": "+reason)
+		}
+	}
+
+	for _, n := range notes {
+		for _, field := range n.Fields {
+			for _, name := range images(field) {
+				if _, ok := pictures[name]; ok || skipped[name] {
+					continue
+				}
+
+				f, ok := pkg.media[name]
+				if !ok {
+					skip(name, "missing from the package")
+					continue
+				}
+
+				extension, ok := pictureExtensions[strings.ToLower(filepath.Ext(name))]
+				if !ok {
+					skip(name, "unsupported format")
+					continue
+				}
+
+				data, err := readMedia(f)
+				if err != nil {
+					skip(name, "too large or unreadable")
+					continue
+				}
+
+				picID, err := picture.Store(extension, data)
+				if err != nil {
+					skip(name, "couldn't be stored")
+					continue
+				}
+				pictures[name] = picID
+			}
+		}
+	}
+
+	if !pkg.mediaMap && len(skipped) > 0 {
+		report.Warnings = append(report.Warnings, "the media map of the package couldn't be read")
+	}
+	report.Media = len(pictures)
+	return pictures
+}
+
+// HTML of some fields of a note, one per line
+func join(fields []string, indexes []int, pictures map[string]string) string {
+	parts := make([]string, 0, len(indexes))
+	for _, i := range indexes {
+		if part := toHTML(fields[i], pictures); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, "<br>")
+}
+
+// Plain text of a side, which can be made of pictures only
+func textOf(side string) string {
+	if text := toText(side); text != "" {
+		return text
+	}
+	return imageText
+}
+
+// Up to three different answers of other cards, taken from their first
+// option. A few random picks are usually enough, decks with many repeated
+// answers are scanned
+func distractors(cards []imported, i int) []card.Option {
+	wrong := []card.Option{}
+	seen := map[string]bool{cards[i].card.Options[0].Answer: true}
+	add := func(j int) bool {
+		if answer := cards[j].card.Options[0].Answer; !seen[answer] {
+			seen[answer] = true
+			wrong = append(wrong, card.Option{Answer: answer})
+		}
+		return len(wrong) == wrongAnswers
+	}
+
+	for try := 0; try < 4*wrongAnswers; try++ {
+		if add(rand.Intn(len(cards))) {
+			return wrong
+		}
+	}
+
+	offset := rand.Intn(len(cards))
+	for k := range cards {
+		if add((offset + k) % len(cards)) {
+			break
+		}
+	}
+	return wrong
+}
+
+// Translates the Anki scheduling to the progress of the app
+func toProgress(s schedule, today int64) *progress.Progress {
+	p := &progress.Progress{
+		Ease:         2.5,
+		WatchCount:   s.Reps,
+		AnswerCount:  s.Answers,
+		CorrectCount: s.Correct,
+		IsRelearning: s.Type == 3,
+		IsBuried:     s.Queue < 0,
+	}
+
+	if s.Factor > 0 {
+		p.Ease = float32(s.Factor) / 1000
+	}
+	if s.Interval > 0 {
+		p.Interval = s.Interval
+	}
+	// Review cards are due on a day counted from the collection creation,
+	// learning ones on a timestamp that is always close
+	if (s.Queue == 2 || s.Queue == 3) && s.Due > today {
+		p.DaysHidden = int(s.Due - today)
+	}
+	// Collections trimmed of their history still know how many times
+	// the card was answered
+	if p.AnswerCount == 0 {
+		p.AnswerCount = s.Reps
+		p.CorrectCount = max(s.Reps-s.Lapses, 0)
+	}
+	return p
+}
+
+// Name of the deck most of the cards are in
+func deckName(col collection) string {
+	counts := map[int64]int{}
+	best := int64(0)
+	for _, s := range col.Schedules {
+		counts[s.DeckID]++
+		if counts[s.DeckID] > counts[best] {
+			best = s.DeckID
+		}
+	}
+
+	name := col.Decks[best]
+	if name == "" {
+		return "Anki import"
+	}
+	// Subdecks are separated with ::
+	parts := strings.Split(name, "::")
+	return truncate(parts[len(parts)-1], maxTitle)
+}
+
+// Title safe to use in a Content-Disposition header
+func fileName(title string) string {
+	name := strings.Map(func(r rune) rune {
+		if r < ' ' || r == '"' || r == '\\' || r == '/' || r > '~' {
+			return '_'
+		}
+		return r
+	}, strings.TrimSpace(title))
+	if name == "" {
+		return "deck"
+	}
+	return name
+}
+
+func appendListed(list []string, entry string) []string {
+	if len(list) >= maxListed {
+		return list
+	}
+	return append(list, entry)
+}
@@ -0,0 +1,34 @@
+package apikey
+
+import "time"
+
+// Scopes a key can be granted
+const (
+	DecksRead     = "decks:read"
+	DecksWrite    = "decks:write"
+	ProgressRead  = "progress:read"
+	ProgressWrite = "progress:write"
+)
+
+var Scopes = []string{DecksRead, DecksWrite, ProgressRead, ProgressWrite}
+
+type APIKey struct {
+	ID        int64      `json:"key_id"`
+	AccID     int64      `json:"acc_id"`
+	Name      string     `json:"name"`
+	Prefix    string     `json:"prefix"` // First characters of the key so users can tell them apart
+	Hash      string     `json:"-"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	LastUsed  *time.Time `json:"last_used,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+func (k APIKey) HasScope(scope string) bool {
+	for _, s := range k.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
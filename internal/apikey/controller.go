@@ -0,0 +1,134 @@
+package apikey
+
+import (
+	"errors"
+	"learn-swiping-api/erro"
+	apikey "learn-swiping-api/internal/apikey/dto"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type APIKeyController interface {
+	Create(*gin.Context) // POST
+	Keys(*gin.Context)   // GET
+	Delete(*gin.Context) // DELETE
+	Scope(scope string) gin.HandlerFunc
+}
+
+type APIKeyControllerImpl struct {
+	service APIKeyService
+}
+
+func NewAPIKeyController(service APIKeyService) APIKeyController {
+	return &APIKeyControllerImpl{service: service}
+}
+
+// Creates an API key, the key itself is only returned here
+// Method: POST
+func (c *APIKeyControllerImpl) Create(ctx *gin.Context) {
+	token := ctx.GetHeader("Token")
+	if token == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrInvalidToken.Error()})
+		return
+	}
+
+	var request apikey.CreateRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrBadField.Error()})
+		return
+	}
+	request.Token = token
+
+	key, err := c.service.Create(request)
+	if err != nil {
+		if errors.Is(err, erro.ErrBadField) || errors.Is(err, erro.ErrInvalidScope) || errors.Is(err, erro.ErrInvalidToken) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, erro.ErrAPIKeyExists) {
+			ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, key)
+}
+
+// Lists the API keys of the account
+// Method: GET
+func (c *APIKeyControllerImpl) Keys(ctx *gin.Context) {
+	token := ctx.GetHeader("Token")
+	if token == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrInvalidToken.Error()})
+		return
+	}
+
+	keys, err := c.service.Keys(token)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, keys)
+}
+
+// Revokes an API key
+// Method: DELETE
+func (c *APIKeyControllerImpl) Delete(ctx *gin.Context) {
+	token := ctx.GetHeader("Token")
+	if token == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrInvalidToken.Error()})
+		return
+	}
+
+	keyID, err := strconv.Atoi(ctx.Param("keyID"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrBadField.Error()})
+		return
+	}
+
+	if err := c.service.Delete(int64(keyID), token); err != nil {
+		if errors.Is(err, erro.ErrAPIKeyNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{})
+}
+
+// Middleware that lets a route be called with an Api-Key header instead
+// of a Token, as long as the key has the given scope. Routes without it
+// don't accept API keys at all
+func (c *APIKeyControllerImpl) Scope(scope string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		key := ctx.GetHeader("Api-Key")
+		if key == "" {
+			ctx.Next()
+			return
+		}
+
+		token, err := c.service.Authenticate(key, scope)
+		if err != nil {
+			if errors.Is(err, erro.ErrInvalidAPIKey) || errors.Is(err, erro.ErrAccountNotFound) {
+				ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": erro.ErrInvalidAPIKey.Error()})
+				return
+			}
+			if errors.Is(err, erro.ErrMissingScope) {
+				ctx.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": err.Error()})
+				return
+			}
+			ctx.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		ctx.Request.Header.Set("Token", token)
+		ctx.Next()
+	}
+}
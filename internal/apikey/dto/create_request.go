@@ -0,0 +1,10 @@
+package apikey
+
+import "time"
+
+type CreateRequest struct {
+	Token     string
+	Name      string     `json:"name" binding:"required"`
+	Scopes    []string   `json:"scopes" binding:"required"`
+	ExpiresAt *time.Time `json:"expires_at"` // Never expires if empty
+}
@@ -0,0 +1,7 @@
+package apikey
+
+// The full key is only returned once, on creation
+type CreateResponse struct {
+	KeyID int64  `json:"key_id"`
+	Key   string `json:"key"`
+}
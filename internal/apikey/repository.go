@@ -0,0 +1,205 @@
+package apikey
+
+import (
+	"database/sql"
+	"learn-swiping-api/erro"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+type APIKeyRepository interface {
+	Create(token string, key APIKey) (int64, error)
+	ByToken(token string) ([]APIKey, error)
+	ByHash(hash string) (APIKey, error)
+	Delete(keyID int64, token string) error
+	Touch(keyID int64) error
+	Session(accID int64) (string, time.Time, error)
+	RefreshSession(accID int64, token string, expires time.Time) error
+}
+
+type APIKeyRepositoryImpl struct {
+	db                 *sql.DB
+	CreateStmt         *sql.Stmt
+	ByTokenStmt        *sql.Stmt
+	ByHashStmt         *sql.Stmt
+	DeleteStmt         *sql.Stmt
+	TouchStmt          *sql.Stmt
+	SessionStmt        *sql.Stmt
+	RefreshSessionStmt *sql.Stmt
+}
+
+func NewAPIKeyRepository(db *sql.DB) *APIKeyRepositoryImpl {
+	repo := &APIKeyRepositoryImpl{db: db}
+	err := repo.InitStatements()
+	if err != nil {
+		log.Fatalln(err)
+	}
+	return repo
+}
+
+func (r *APIKeyRepositoryImpl) InitStatements() error {
+	var err error
+	r.CreateStmt, err = r.db.Prepare(`INSERT INTO API_KEY (acc_id, name, prefix, key_hash, scopes, expires_at)
+										VALUES ((SELECT acc_id FROM ACCOUNT WHERE token = ?), ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+
+	r.ByTokenStmt, err = r.db.Prepare(`SELECT k.key_id, k.acc_id, k.name, k.prefix, k.key_hash, k.scopes, k.expires_at, k.last_used, k.created_at
+										FROM API_KEY k
+										LEFT JOIN ACCOUNT a ON k.acc_id = a.acc_id
+										WHERE a.token = ?
+										ORDER BY k.created_at`)
+	if err != nil {
+		return err
+	}
+
+	r.ByHashStmt, err = r.db.Prepare(`SELECT key_id, acc_id, name, prefix, key_hash, scopes, expires_at, last_used, created_at
+										FROM API_KEY WHERE key_hash = ?`)
+	if err != nil {
+		return err
+	}
+
+	r.DeleteStmt, err = r.db.Prepare(`DELETE k FROM API_KEY k
+										LEFT JOIN ACCOUNT a ON k.acc_id = a.acc_id
+										WHERE k.key_id = ? AND a.token = ?`)
+	if err != nil {
+		return err
+	}
+
+	// Written at most once a minute to not hit the database on every request
+	r.TouchStmt, err = r.db.Prepare(`UPDATE API_KEY SET last_used = NOW()
+										WHERE key_id = ? AND (last_used IS NULL OR last_used < NOW() - INTERVAL 1 MINUTE)`)
+	if err != nil {
+		return err
+	}
+
+	// Keys of suspended accounts stop working too
+	r.SessionStmt, err = r.db.Prepare(`SELECT token, token_expire FROM ACCOUNT
+											WHERE acc_id = ?
+												AND acc_id NOT IN (SELECT acc_id FROM ACCOUNT_SUSPENSION WHERE until IS NULL OR until > NOW())`)
+	if err != nil {
+		return err
+	}
+
+	r.RefreshSessionStmt, err = r.db.Prepare("UPDATE ACCOUNT SET token = ?, token_expire = ? WHERE acc_id = ?")
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *APIKeyRepositoryImpl) Create(token string, key APIKey) (int64, error) {
+	result, err := r.CreateStmt.Exec(token, key.Name, key.Prefix, key.Hash, strings.Join(key.Scopes, " "), key.ExpiresAt)
+	if err != nil {
+		if mysqlErr, ok := err.(*mysql.MySQLError); ok {
+			if mysqlErr.Number == 1048 {
+				return 0, erro.ErrInvalidToken
+			}
+			if mysqlErr.Number == 1062 {
+				return 0, erro.ErrAPIKeyExists
+			}
+		}
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+func (r *APIKeyRepositoryImpl) ByToken(token string) ([]APIKey, error) {
+	rows, err := r.ByTokenStmt.Query(token)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []APIKey{}
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	return keys, nil
+}
+
+func (r *APIKeyRepositoryImpl) ByHash(hash string) (APIKey, error) {
+	key, err := scanAPIKey(r.ByHashStmt.QueryRow(hash))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return APIKey{}, erro.ErrInvalidAPIKey
+		}
+		return APIKey{}, err
+	}
+	return key, nil
+}
+
+func (r *APIKeyRepositoryImpl) Delete(keyID int64, token string) error {
+	result, err := r.DeleteStmt.Exec(keyID, token)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return erro.ErrAPIKeyNotFound
+	}
+
+	return nil
+}
+
+func (r *APIKeyRepositoryImpl) Touch(keyID int64) error {
+	_, err := r.TouchStmt.Exec(keyID)
+	return err
+}
+
+// Session token of the key owner and its expiration date
+func (r *APIKeyRepositoryImpl) Session(accID int64) (string, time.Time, error) {
+	var token string
+	var expires time.Time
+	err := r.SessionStmt.QueryRow(accID).Scan(&token, &expires)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", time.Time{}, erro.ErrAccountNotFound
+		}
+		return "", time.Time{}, err
+	}
+	return token, expires, nil
+}
+
+func (r *APIKeyRepositoryImpl) RefreshSession(accID int64, token string, expires time.Time) error {
+	_, err := r.RefreshSessionStmt.Exec(token, expires, accID)
+	return err
+}
+
+// Scans from either *sql.Row or *sql.Rows
+func scanAPIKey(row interface{ Scan(...any) error }) (APIKey, error) {
+	var key APIKey
+	var scopes string
+	err := row.Scan(
+		&key.ID,
+		&key.AccID,
+		&key.Name,
+		&key.Prefix,
+		&key.Hash,
+		&scopes,
+		&key.ExpiresAt,
+		&key.LastUsed,
+		&key.CreatedAt,
+	)
+	if err != nil {
+		return APIKey{}, err
+	}
+
+	key.Scopes = strings.Fields(scopes)
+	return key, nil
+}
@@ -0,0 +1,127 @@
+package apikey
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"learn-swiping-api/erro"
+	apikey "learn-swiping-api/internal/apikey/dto"
+	"log"
+	"slices"
+	"strings"
+	"time"
+)
+
+const keyPrefix = "lsk_"
+
+type APIKeyService interface {
+	Create(apikey.CreateRequest) (apikey.CreateResponse, error)
+	Keys(token string) ([]APIKey, error)
+	Delete(keyID int64, token string) error
+	Authenticate(key string, scope string) (string, error) // Returns the session token of the key owner
+}
+
+type APIKeyServiceImpl struct {
+	repository APIKeyRepository
+}
+
+func NewAPIKeyService(repository APIKeyRepository) APIKeyService {
+	return &APIKeyServiceImpl{repository: repository}
+}
+
+func (s *APIKeyServiceImpl) Create(request apikey.CreateRequest) (apikey.CreateResponse, error) {
+	name := strings.TrimSpace(request.Name)
+	if name == "" || len(name) > 100 || len(request.Scopes) == 0 {
+		return apikey.CreateResponse{}, erro.ErrBadField
+	}
+
+	for _, scope := range request.Scopes {
+		if !slices.Contains(Scopes, scope) {
+			return apikey.CreateResponse{}, erro.ErrInvalidScope
+		}
+	}
+
+	if request.ExpiresAt != nil && request.ExpiresAt.Before(time.Now()) {
+		return apikey.CreateResponse{}, erro.ErrBadField
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return apikey.CreateResponse{}, err
+	}
+	secret := keyPrefix + hex.EncodeToString(b)
+
+	scopes := slices.Clone(request.Scopes)
+	slices.Sort(scopes)
+
+	id, err := s.repository.Create(request.Token, APIKey{
+		Name:      name,
+		Prefix:    secret[:len(keyPrefix)+8],
+		Hash:      HashKey(secret),
+		Scopes:    slices.Compact(scopes),
+		ExpiresAt: request.ExpiresAt,
+	})
+	if err != nil {
+		return apikey.CreateResponse{}, err
+	}
+
+	return apikey.CreateResponse{KeyID: id, Key: secret}, nil
+}
+
+func (s *APIKeyServiceImpl) Keys(token string) ([]APIKey, error) {
+	return s.repository.ByToken(token)
+}
+
+func (s *APIKeyServiceImpl) Delete(keyID int64, token string) error {
+	return s.repository.Delete(keyID, token)
+}
+
+// Checks a key grants a scope and returns a valid session token of its
+// owner, so the rest of the API keeps working with the Token header.
+// The session is refreshed if it expired, the same way Login does
+func (s *APIKeyServiceImpl) Authenticate(secret string, scope string) (string, error) {
+	if !strings.HasPrefix(secret, keyPrefix) {
+		return "", erro.ErrInvalidAPIKey
+	}
+
+	key, err := s.repository.ByHash(HashKey(secret))
+	if err != nil {
+		return "", err
+	}
+
+	if key.ExpiresAt != nil && time.Now().After(*key.ExpiresAt) {
+		return "", erro.ErrInvalidAPIKey
+	}
+
+	if !key.HasScope(scope) {
+		return "", erro.ErrMissingScope
+	}
+
+	if err := s.repository.Touch(key.ID); err != nil {
+		log.Println(err)
+	}
+
+	token, expires, err := s.repository.Session(key.AccID)
+	if err != nil {
+		return "", err
+	}
+
+	if time.Now().After(expires) {
+		b := make([]byte, 16)
+		if _, err := rand.Read(b); err != nil {
+			return "", err
+		}
+		token = hex.EncodeToString(b)
+		if err := s.repository.RefreshSession(key.AccID, token, time.Now().AddDate(0, 0, 7)); err != nil {
+			return "", err
+		}
+	}
+
+	return token, nil
+}
+
+// Keys are only stored hashed
+func HashKey(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,30 @@
+package audit
+
+import "time"
+
+// Keys used to pass audit information through the gin context
+const (
+	actionKey  = "audit_action"
+	targetKey  = "audit_target"
+	detailsKey = "audit_details"
+)
+
+// Action done by a staff member
+type Entry struct {
+	ID        int64          `json:"audit_id"`
+	ActorID   *int64         `json:"actor_id"` // Nil if the account was removed
+	Actor     string         `json:"actor,omitempty"`
+	Action    string         `json:"action"`
+	Target    string         `json:"target"` // e.g. account:alice or deck:12
+	Details   map[string]any `json:"details,omitempty"`
+	IP        string         `json:"ip"`
+	CreatedAt time.Time      `json:"created_at"`
+}
+
+type Filter struct {
+	Actor  string
+	Action string
+	Target string
+	Page   int
+	Size   int
+}
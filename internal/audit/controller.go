@@ -0,0 +1,128 @@
+package audit
+
+import (
+	"errors"
+	"learn-swiping-api/erro"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AuditController interface {
+	Entries(*gin.Context) // GET
+	Record(*gin.Context)
+}
+
+type AuditControllerImpl struct {
+	service AuditService
+}
+
+func NewAuditController(service AuditService) AuditController {
+	return &AuditControllerImpl{service: service}
+}
+
+// Lists the audit log, filtered by actor, action or target
+// Method: GET
+func (c *AuditControllerImpl) Entries(ctx *gin.Context) {
+	filter := Filter{
+		Actor:  ctx.Query("actor"),
+		Action: ctx.Query("action"),
+		Target: ctx.Query("target"),
+	}
+
+	var err error
+	if page := ctx.Query("page"); page != "" {
+		if filter.Page, err = strconv.Atoi(page); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrBadField.Error()})
+			return
+		}
+	}
+	if size := ctx.Query("size"); size != "" {
+		if filter.Size, err = strconv.Atoi(size); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrBadField.Error()})
+			return
+		}
+	}
+
+	entries, err := c.service.Entries(filter)
+	if err != nil {
+		if errors.Is(err, erro.ErrBadField) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, entries)
+}
+
+// Middleware that writes an entry for every successful request that
+// changes something and every action named by a handler. It must run
+// before the role middleware so the acc_id it sets is available once the
+// handler returns. Handlers can describe what they did with Action and
+// Detail, otherwise the method, route and parameters are recorded
+func (c *AuditControllerImpl) Record(ctx *gin.Context) {
+	ctx.Next()
+
+	// Handlers name the action once it's done, so those are recorded even
+	// if the response ends up being an error
+	_, named := ctx.Get(actionKey)
+	if !named && (ctx.Request.Method == http.MethodGet || ctx.Writer.Status() >= http.StatusBadRequest) {
+		return
+	}
+
+	entry := Entry{
+		Action: ctx.GetString(actionKey),
+		Target: ctx.GetString(targetKey),
+		IP:     ctx.ClientIP(),
+	}
+	if actor, ok := ctx.Get("acc_id"); ok {
+		if accID, ok := actor.(int64); ok {
+			entry.ActorID = &accID
+		}
+	}
+	if entry.Action == "" {
+		entry.Action = ctx.Request.Method + " " + ctx.FullPath()
+	}
+	if entry.Target == "" {
+		entry.Target = paramsTarget(ctx.Params)
+	}
+	if details, ok := ctx.Get(detailsKey); ok {
+		entry.Details, _ = details.(map[string]any)
+	}
+
+	if err := c.service.Record(entry); err != nil {
+		log.Println("audit:", err)
+	}
+}
+
+// Names what a handler did, e.g. Action(ctx, "account.ban", "account:alice")
+func Action(ctx *gin.Context, action string, target string) {
+	ctx.Set(actionKey, action)
+	ctx.Set(targetKey, target)
+}
+
+// Adds a value to the details of the entry being recorded
+func Detail(ctx *gin.Context, key string, value any) {
+	details, _ := ctx.Get(detailsKey)
+	m, ok := details.(map[string]any)
+	if !ok {
+		m = make(map[string]any)
+		ctx.Set(detailsKey, m)
+	}
+	m[key] = value
+}
+
+func paramsTarget(params gin.Params) string {
+	parts := make([]string, 0, len(params))
+	for _, param := range params {
+		parts = append(parts, param.Key+":"+param.Value)
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, " ")
+}
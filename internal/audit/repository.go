@@ -0,0 +1,111 @@
+package audit
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"strings"
+)
+
+type AuditRepository interface {
+	Create(Entry) error
+	Entries(Filter) ([]Entry, error)
+}
+
+type AuditRepositoryImpl struct {
+	db         *sql.DB
+	CreateStmt *sql.Stmt
+}
+
+func NewAuditRepository(db *sql.DB) *AuditRepositoryImpl {
+	repo := &AuditRepositoryImpl{db: db}
+	err := repo.InitStatements()
+	if err != nil {
+		log.Fatalln(err)
+	}
+	return repo
+}
+
+func (r *AuditRepositoryImpl) InitStatements() error {
+	var err error
+	r.CreateStmt, err = r.db.Prepare("INSERT INTO AUDIT_LOG (actor_id, action, target, details, ip) VALUES (?, ?, ?, ?, ?)")
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *AuditRepositoryImpl) Create(entry Entry) error {
+	var details []byte
+	if len(entry.Details) > 0 {
+		var err error
+		details, err = json.Marshal(entry.Details)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err := r.CreateStmt.Exec(entry.ActorID, entry.Action, entry.Target, details, entry.IP)
+	return err
+}
+
+// Newest first
+func (r *AuditRepositoryImpl) Entries(filter Filter) ([]Entry, error) {
+	var query strings.Builder
+	var args []any
+	query.WriteString(`SELECT l.audit_id, l.actor_id, COALESCE(a.username, ''), l.action, l.target, l.details, l.ip, l.created_at
+						FROM AUDIT_LOG l
+						LEFT JOIN ACCOUNT a ON l.actor_id = a.acc_id
+						WHERE 1 = 1`)
+
+	if filter.Actor != "" {
+		query.WriteString(" AND a.username = ?")
+		args = append(args, filter.Actor)
+	}
+	if filter.Action != "" {
+		query.WriteString(" AND l.action = ?")
+		args = append(args, filter.Action)
+	}
+	if filter.Target != "" {
+		query.WriteString(" AND l.target = ?")
+		args = append(args, filter.Target)
+	}
+
+	query.WriteString(" ORDER BY l.audit_id DESC LIMIT ? OFFSET ?")
+	args = append(args, filter.Size, (filter.Page-1)*filter.Size)
+
+	rows, err := r.db.Query(query.String(), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []Entry{}
+	for rows.Next() {
+		var entry Entry
+		var details []byte
+		err := rows.Scan(
+			&entry.ID,
+			&entry.ActorID,
+			&entry.Actor,
+			&entry.Action,
+			&entry.Target,
+			&details,
+			&entry.IP,
+			&entry.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(details) > 0 {
+			if err := json.Unmarshal(details, &entry.Details); err != nil {
+				return nil, err
+			}
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
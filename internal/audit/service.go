@@ -0,0 +1,42 @@
+package audit
+
+import "learn-swiping-api/erro"
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 200
+)
+
+type AuditService interface {
+	Record(Entry) error
+	Entries(Filter) ([]Entry, error)
+}
+
+type AuditServiceImpl struct {
+	repository AuditRepository
+}
+
+func NewAuditService(repository AuditRepository) AuditService {
+	return &AuditServiceImpl{repository: repository}
+}
+
+func (s *AuditServiceImpl) Record(entry Entry) error {
+	if entry.Action == "" {
+		return erro.ErrBadField
+	}
+	return s.repository.Create(entry)
+}
+
+func (s *AuditServiceImpl) Entries(filter Filter) ([]Entry, error) {
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	if filter.Size < 1 {
+		filter.Size = defaultPageSize
+	}
+	if filter.Size > maxPageSize {
+		return nil, erro.ErrBadField
+	}
+
+	return s.repository.Entries(filter)
+}
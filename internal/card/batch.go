@@ -0,0 +1,66 @@
+package card
+
+import "errors"
+
+// Operations of a batch
+const (
+	OpCreate = "create"
+	OpUpdate = "update"
+	OpDelete = "delete"
+	OpMove   = "move"
+)
+
+var batchOps = []string{OpCreate, OpUpdate, OpDelete, OpMove}
+
+const maxBatch = 500 // Operations of a batch
+
+// What happened to each operation of a batch. Batches are applied all or
+// nothing, so when one of them can't be the rest are skipped
+const (
+	StatusApplied = "applied"
+	StatusInvalid = "invalid"
+	StatusSkipped = "skipped"
+)
+
+// Result of an operation of a batch, in the order they were sent
+type BatchResult struct {
+	Index  int          `json:"index"`
+	Op     string       `json:"op"`
+	CardID int64        `json:"card_id,omitempty"` // Id of created cards once applied
+	Status string       `json:"status"`
+	Errors []FieldError `json:"errors,omitempty"`
+}
+
+// Operation of a batch as the repository applies it. Card is the card as
+// it will be, Columns and Options what an update changes like in Update,
+// and Target the deck a card moves to
+type BatchChange struct {
+	Op      string
+	Card    Card
+	Columns []string
+	Options []Option
+	Target  int64
+	// Accounts studying a moved card get subscribed to its new deck, as
+	// long as everyone can see it
+	Subscribe bool
+}
+
+// Access to a deck cards are moved to, asked once per deck
+type target struct {
+	visible bool
+	err     error
+}
+
+// Validation error with its fields nested under the member of the
+// operation they came from. Other errors are left as they are
+func nested(member string, err error) error {
+	var validation *ValidationError
+	if !errors.As(err, &validation) {
+		return err
+	}
+	errs := &ValidationError{}
+	for _, f := range validation.Fields {
+		errs.add(member+"."+f.Field, "%s", f.Error)
+	}
+	return errs
+}
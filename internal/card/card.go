@@ -1,18 +1,114 @@
 package card
 
+import (
+	"errors"
+	"fmt"
+	"learn-swiping-api/internal/comment"
+	"strings"
+)
+
+// How a card is answered
+const (
+	AnswerSingle    = "single"     // Pick the correct option
+	AnswerTrueFalse = "true_false" // Two options, one of them correct
+	AnswerSelectAll = "select_all" // Pick every correct option
+)
+
+// Number of options and of correct ones each answer type takes. A
+// maxCorrect of 0 allows all of them
+type answerRules struct {
+	minOptions, maxOptions int
+	minCorrect, maxCorrect int
+}
+
+var answerTypes = map[string]answerRules{
+	AnswerSingle:    {minOptions: 2, maxOptions: 6, minCorrect: 1, maxCorrect: 1},
+	AnswerTrueFalse: {minOptions: 2, maxOptions: 2, minCorrect: 1, maxCorrect: 1},
+	AnswerSelectAll: {minOptions: 2, maxOptions: 6, minCorrect: 1, maxCorrect: 0},
+}
+
 type Card struct {
-	CardID   int64         `json:"card_id"`
-	DeckID   int64         `json:"deck_id"`
-	Title    string        `json:"title"`
-	Front    string        `json:"front"`
-	Back     string        `json:"back"`
-	Question string        `json:"question"`
-	Answer   string        `json:"answer"`
-	Wrong    []WrongAnswer `json:"wrong,omitempty"`
+	CardID     int64             `json:"card_id"`
+	DeckID     int64             `json:"deck_id"`
+	SectionID  *int64            `json:"section_id,omitempty"`
+	Position   int               `json:"position"` // In the deck, siblings share the one of their source
+	CardType   string            `json:"card_type"`
+	Title      string            `json:"title"`
+	Front      string            `json:"front"`
+	Back       string            `json:"back"`
+	Question   string            `json:"question"`
+	AnswerType string            `json:"answer_type,omitempty"` // Only basic cards
+	Options    []Option          `json:"options,omitempty"`     // In the order they're shown
+	Cloze      *Cloze            `json:"cloze,omitempty"`       // Only the payload of the card type is set
+	Typed      *Typed            `json:"typed,omitempty"`
+	Occlusion  *Occlusion        `json:"occlusion,omitempty"`
+	ParentID   *int64            `json:"parent_id,omitempty"` // Card a sibling was generated from
+	Sibling    int               `json:"sibling,omitempty"`   // Deletion or mask the card asks about
+	Render     *Render           `json:"render,omitempty"`    // How to show it, for generated types
+	Comments   []comment.Comment `json:"comments,omitempty"`
+}
+
+type Option struct {
+	OptionID int64  `json:"option_id,omitempty"`
+	CardID   int64  `json:"card_id,omitempty"`
+	Answer   string `json:"answer"`
+	Correct  bool   `json:"correct"`
 }
 
-type WrongAnswer struct {
-	WrongID int64  `json:"wrong_id,omitempty"`
-	CardID  int64  `json:"card_id,omitempty"`
-	Answer  string `json:"answer"`
+// Answers of the correct options and of the wrong ones
+func (c Card) Answers() ([]string, []string) {
+	var correct, wrong []string
+	for _, o := range c.Options {
+		if o.Correct {
+			correct = append(correct, o.Answer)
+		} else {
+			wrong = append(wrong, o.Answer)
+		}
+	}
+	return correct, wrong
+}
+
+func ValidAnswerType(answerType string) bool {
+	_, ok := answerTypes[answerType]
+	return ok
+}
+
+// Checks the options fit the answer type. The error tells what's wrong
+// with them
+func ValidateOptions(answerType string, options []Option) error {
+	rules, ok := answerTypes[answerType]
+	if !ok {
+		return errors.New("unknown answer type")
+	}
+
+	if len(options) < rules.minOptions || len(options) > rules.maxOptions {
+		if rules.minOptions == rules.maxOptions {
+			return fmt.Errorf("needs %d options, has %d", rules.minOptions, len(options))
+		}
+		return fmt.Errorf("needs %d to %d options, has %d", rules.minOptions, rules.maxOptions, len(options))
+	}
+
+	correct := 0
+	seen := make(map[string]bool, len(options))
+	for _, o := range options {
+		answer := strings.ToLower(strings.TrimSpace(o.Answer))
+		if answer == "" {
+			return errors.New("options can't be empty")
+		}
+		if seen[answer] {
+			return fmt.Errorf("%q is repeated", o.Answer)
+		}
+		seen[answer] = true
+		if o.Correct {
+			correct++
+		}
+	}
+
+	if correct < rules.minCorrect || (rules.maxCorrect > 0 && correct > rules.maxCorrect) {
+		if rules.maxCorrect == 1 {
+			return fmt.Errorf("needs exactly one correct option, has %d", correct)
+		}
+		return fmt.Errorf("needs at least %d correct option, has %d", rules.minCorrect, correct)
+	}
+	return nil
 }
@@ -1,6 +1,7 @@
 package card
 
 import (
+	"encoding/json"
 	"errors"
 	"learn-swiping-api/erro"
 	card "learn-swiping-api/internal/card/dto"
@@ -11,12 +12,20 @@ import (
 )
 
 type CardController interface {
-	Create(*gin.Context)  // POST
-	Card(*gin.Context)    // GET
-	Cards(*gin.Context)   // GET
-	Pending(*gin.Context) // GET - ByProgress
-	Update(*gin.Context)  // PUT
-	Delete(*gin.Context)  // DELETE
+	Create(*gin.Context)        // POST
+	Card(*gin.Context)          // GET
+	Cards(*gin.Context)         // GET
+	Pending(*gin.Context)       // GET - ByProgress
+	Update(*gin.Context)        // PUT, PATCH
+	Delete(*gin.Context)        // DELETE
+	Batch(*gin.Context)         // POST
+	Outline(*gin.Context)       // GET
+	Reorder(*gin.Context)       // PUT
+	CreateSection(*gin.Context) // POST
+	RenameSection(*gin.Context) // PUT
+	DeleteSection(*gin.Context) // DELETE
+	Grade(*gin.Context)         // POST
+	Picture(*gin.Context)       // POST
 }
 
 type CardControllerImpl struct {
@@ -30,6 +39,12 @@ func NewCardController(service CardService) CardController {
 // Creates a card
 // Method: POST
 func (c *CardControllerImpl) Create(ctx *gin.Context) {
+	token := ctx.GetHeader("Token")
+	if token == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrInvalidToken.Error()})
+		return
+	}
+
 	var request card.CreateRequest
 	if err := ctx.ShouldBindJSON(&request); err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrBadField.Error()})
@@ -42,11 +57,25 @@ func (c *CardControllerImpl) Create(ctx *gin.Context) {
 		return
 	}
 
+	request.Token = token
 	request.DeckID = int64(deckID)
 
 	if _, err := c.service.Create(request); err != nil {
+		var validation *ValidationError
+		if errors.As(err, &validation) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "fields": validation.Fields})
+			return
+		}
 		if errors.Is(err, erro.ErrBadField) {
-			ctx.JSON(http.StatusBadRequest, err.Error())
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, erro.ErrInvalidToken) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, erro.ErrForbidden) {
+			ctx.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
 			return
 		}
 		if errors.Is(err, erro.ErrDeckNotFound) || errors.Is(err, erro.ErrCardNotFound) {
@@ -63,6 +92,12 @@ func (c *CardControllerImpl) Create(ctx *gin.Context) {
 // Retrieves a card by it's id inside a deck
 // Method: GET
 func (c *CardControllerImpl) Card(ctx *gin.Context) {
+	token := ctx.GetHeader("Token")
+	if token == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrInvalidToken.Error()})
+		return
+	}
+
 	cardID, err := strconv.Atoi(ctx.Param("cardID"))
 	deckID, derr := strconv.Atoi(ctx.Param("deckID"))
 	if err != nil || derr != nil {
@@ -70,9 +105,17 @@ func (c *CardControllerImpl) Card(ctx *gin.Context) {
 		return
 	}
 
-	card, err := c.service.Card(int64(cardID), int64(deckID))
+	card, err := c.service.Card(int64(cardID), int64(deckID), token)
 	if err != nil {
-		if errors.Is(err, erro.ErrCardNotFound) || errors.Is(err, erro.ErrWrongNotFound) {
+		if errors.Is(err, erro.ErrInvalidToken) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, erro.ErrForbidden) {
+			ctx.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, erro.ErrCardNotFound) || errors.Is(err, erro.ErrOptionNotFound) || errors.Is(err, erro.ErrDeckNotFound) {
 			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 			return
 		}
@@ -92,15 +135,29 @@ func (c *CardControllerImpl) Cards(ctx *gin.Context) {
 		return
 	}
 
+	token = ctx.GetHeader("Token")
+	if token == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrInvalidToken.Error()})
+		return
+	}
+
 	deckID, err := strconv.Atoi(ctx.Param("deckID"))
 	if err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrBadField.Error()})
 		return
 	}
 
-	cards, err := c.service.Cards(int64(deckID))
+	cards, err := c.service.Cards(int64(deckID), token)
 	if err != nil {
-		if errors.Is(err, erro.ErrCardNotFound) {
+		if errors.Is(err, erro.ErrInvalidToken) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, erro.ErrForbidden) {
+			ctx.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, erro.ErrCardNotFound) || errors.Is(err, erro.ErrDeckNotFound) {
 			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 			return
 		}
@@ -111,10 +168,10 @@ func (c *CardControllerImpl) Cards(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, cards)
 }
 
-// Retrieves a list of cards based on it's deckID and it's progress
+// Retrieves the study queue of a deck: cards due first, then new ones
 // Method: GET
 func (c *CardControllerImpl) Pending(ctx *gin.Context) {
-	token := ctx.Param("Token")
+	token := ctx.GetHeader("Token")
 	if token == "" {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrInvalidToken.Error()})
 		return
@@ -128,7 +185,15 @@ func (c *CardControllerImpl) Pending(ctx *gin.Context) {
 
 	cards, err := c.service.ByProgress(token, int64(deckID))
 	if err != nil {
-		if errors.Is(err, erro.ErrCardNotFound) {
+		if errors.Is(err, erro.ErrInvalidToken) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, erro.ErrForbidden) {
+			ctx.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, erro.ErrCardNotFound) || errors.Is(err, erro.ErrDeckNotFound) {
 			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 			return
 		}
@@ -139,12 +204,18 @@ func (c *CardControllerImpl) Pending(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, cards)
 }
 
-// Updates a card or it's wrong answers
-// Method: PUT
+// Updates a card and it's answer options with a merge patch
+// Method: PUT, PATCH
 func (c *CardControllerImpl) Update(ctx *gin.Context) {
+	token := ctx.GetHeader("Token")
+	if token == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrInvalidToken.Error()})
+		return
+	}
+
 	var request card.UpdateRequest
 	if err := ctx.ShouldBindJSON(&request); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrBadField.Error()})
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrBadField.Error(), "fields": bodyErrors(err)})
 		return
 	}
 
@@ -155,14 +226,32 @@ func (c *CardControllerImpl) Update(ctx *gin.Context) {
 		return
 	}
 
+	request.Token = token
 	request.CardID = int64(cardID)
 	request.DeckID = int64(deckID)
 
 	if err := c.service.Update(request); err != nil {
-		if errors.Is(err, erro.ErrCardNotFound) {
+		var validation *ValidationError
+		if errors.As(err, &validation) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "fields": validation.Fields})
+			return
+		}
+		if errors.Is(err, erro.ErrBadField) || errors.Is(err, erro.ErrInvalidToken) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, erro.ErrForbidden) {
+			ctx.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, erro.ErrCardNotFound) || errors.Is(err, erro.ErrDeckNotFound) || errors.Is(err, erro.ErrOptionNotFound) {
 			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 			return
 		}
+		if errors.Is(err, erro.ErrCardGenerated) {
+			ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -170,7 +259,7 @@ func (c *CardControllerImpl) Update(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, gin.H{})
 }
 
-// Deletes a card and it's wrong answers
+// Deletes a card and it's answer options
 // Method: DELETE
 func (c *CardControllerImpl) Delete(ctx *gin.Context) {
 	// var request card.DeleteRequest
@@ -179,6 +268,12 @@ func (c *CardControllerImpl) Delete(ctx *gin.Context) {
 	// 	return
 	// }
 
+	token := ctx.GetHeader("Token")
+	if token == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrInvalidToken.Error()})
+		return
+	}
+
 	cardID, err := strconv.Atoi(ctx.Param("cardID"))
 	deckID, derr := strconv.Atoi(ctx.Param("deckID"))
 	if err != nil || derr != nil {
@@ -186,14 +281,354 @@ func (c *CardControllerImpl) Delete(ctx *gin.Context) {
 		return
 	}
 
-	if err := c.service.Delete(int64(cardID), int64(deckID)); err != nil {
-		if errors.Is(err, erro.ErrCardNotFound) {
+	if err := c.service.Delete(int64(cardID), int64(deckID), token); err != nil {
+		if errors.Is(err, erro.ErrInvalidToken) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, erro.ErrForbidden) {
+			ctx.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, erro.ErrCardNotFound) || errors.Is(err, erro.ErrDeckNotFound) {
 			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 			return
 		}
+		if errors.Is(err, erro.ErrCardGenerated) {
+			ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
 	ctx.JSON(http.StatusOK, gin.H{})
 }
+
+// Creates, updates, deletes and moves many cards of a deck at once
+// Method: POST
+func (c *CardControllerImpl) Batch(ctx *gin.Context) {
+	// Routed as :deckID/cards:batch, which also takes anything else
+	// after cards
+	if ctx.Param("batch") != ":batch" {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+
+	token := ctx.GetHeader("Token")
+	if token == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrInvalidToken.Error()})
+		return
+	}
+
+	var request card.BatchRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrBadField.Error()})
+		return
+	}
+
+	deckID, err := strconv.Atoi(ctx.Param("deckID"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrBadField.Error()})
+		return
+	}
+
+	request.Token = token
+	request.DeckID = int64(deckID)
+
+	results, err := c.service.Batch(request)
+	if err != nil {
+		// The results tell which operations are the problem
+		if errors.Is(err, erro.ErrBatchInvalid) {
+			ctx.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error(), "results": results})
+			return
+		}
+		var validation *ValidationError
+		if errors.As(err, &validation) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "fields": validation.Fields})
+			return
+		}
+		if errors.Is(err, erro.ErrInvalidToken) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, erro.ErrForbidden) {
+			ctx.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, erro.ErrCardNotFound) || errors.Is(err, erro.ErrDeckNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"results": results})
+}
+
+// Retrieves the order of the cards of a deck with its sections
+// Method: GET
+func (c *CardControllerImpl) Outline(ctx *gin.Context) {
+	token := ctx.GetHeader("Token")
+	if token == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrInvalidToken.Error()})
+		return
+	}
+
+	deckID, err := strconv.Atoi(ctx.Param("deckID"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrBadField.Error()})
+		return
+	}
+
+	outline, err := c.service.Outline(int64(deckID), token)
+	if err != nil {
+		sectionError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, outline)
+}
+
+// Places the sections and cards of a deck in a new order
+// Method: PUT
+func (c *CardControllerImpl) Reorder(ctx *gin.Context) {
+	token := ctx.GetHeader("Token")
+	if token == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrInvalidToken.Error()})
+		return
+	}
+
+	var request card.OrderRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrBadField.Error()})
+		return
+	}
+
+	deckID, err := strconv.Atoi(ctx.Param("deckID"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrBadField.Error()})
+		return
+	}
+
+	request.Token = token
+	request.DeckID = int64(deckID)
+
+	if err := c.service.Reorder(request); err != nil {
+		sectionError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{})
+}
+
+// Adds a section to a deck
+// Method: POST
+func (c *CardControllerImpl) CreateSection(ctx *gin.Context) {
+	token := ctx.GetHeader("Token")
+	if token == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrInvalidToken.Error()})
+		return
+	}
+
+	var request card.SectionRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrBadField.Error()})
+		return
+	}
+
+	deckID, err := strconv.Atoi(ctx.Param("deckID"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrBadField.Error()})
+		return
+	}
+
+	request.Token = token
+	request.DeckID = int64(deckID)
+
+	sectionID, err := c.service.CreateSection(request)
+	if err != nil {
+		sectionError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{"section_id": sectionID})
+}
+
+// Renames a section of a deck
+// Method: PUT
+func (c *CardControllerImpl) RenameSection(ctx *gin.Context) {
+	token := ctx.GetHeader("Token")
+	if token == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrInvalidToken.Error()})
+		return
+	}
+
+	var request card.SectionRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrBadField.Error()})
+		return
+	}
+
+	sectionID, err := strconv.Atoi(ctx.Param("sectionID"))
+	deckID, derr := strconv.Atoi(ctx.Param("deckID"))
+	if err != nil || derr != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrBadField.Error()})
+		return
+	}
+
+	request.Token = token
+	request.DeckID = int64(deckID)
+	request.SectionID = int64(sectionID)
+
+	if err := c.service.RenameSection(request); err != nil {
+		sectionError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{})
+}
+
+// Deletes a section of a deck, leaving its cards outside of any
+// Method: DELETE
+func (c *CardControllerImpl) DeleteSection(ctx *gin.Context) {
+	token := ctx.GetHeader("Token")
+	if token == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrInvalidToken.Error()})
+		return
+	}
+
+	sectionID, err := strconv.Atoi(ctx.Param("sectionID"))
+	deckID, derr := strconv.Atoi(ctx.Param("deckID"))
+	if err != nil || derr != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrBadField.Error()})
+		return
+	}
+
+	if err := c.service.DeleteSection(int64(sectionID), int64(deckID), token); err != nil {
+		sectionError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{})
+}
+
+func sectionError(ctx *gin.Context, err error) {
+	var validation *ValidationError
+	if errors.As(err, &validation) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "fields": validation.Fields})
+		return
+	}
+	if errors.Is(err, erro.ErrBadField) || errors.Is(err, erro.ErrInvalidToken) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, erro.ErrForbidden) {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, erro.ErrDeckNotFound) || errors.Is(err, erro.ErrSectionNotFound) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
+
+// Grades an answer to a card
+// Method: POST
+func (c *CardControllerImpl) Grade(ctx *gin.Context) {
+	token := ctx.GetHeader("Token")
+	if token == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrInvalidToken.Error()})
+		return
+	}
+
+	var request card.GradeRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrBadField.Error()})
+		return
+	}
+
+	cardID, err := strconv.Atoi(ctx.Param("cardID"))
+	deckID, derr := strconv.Atoi(ctx.Param("deckID"))
+	if err != nil || derr != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrBadField.Error()})
+		return
+	}
+
+	request.Token = token
+	request.CardID = int64(cardID)
+	request.DeckID = int64(deckID)
+
+	grade, err := c.service.Grade(request)
+	if err != nil {
+		if errors.Is(err, erro.ErrInvalidToken) || errors.Is(err, erro.ErrBadField) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, erro.ErrForbidden) {
+			ctx.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, erro.ErrCardNotFound) || errors.Is(err, erro.ErrDeckNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, grade)
+}
+
+// Uploads a picture for the cards of a deck
+// Method: POST
+func (c *CardControllerImpl) Picture(ctx *gin.Context) {
+	token := ctx.GetHeader("Token")
+	if token == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrInvalidToken.Error()})
+		return
+	}
+
+	deckID, err := strconv.Atoi(ctx.Param("deckID"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrBadField.Error()})
+		return
+	}
+
+	file, err := ctx.FormFile("picture")
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrBadField.Error()})
+		return
+	}
+
+	picID, err := c.service.Picture(int64(deckID), token, file)
+	if err != nil {
+		if errors.Is(err, erro.ErrBadField) || errors.Is(err, erro.ErrInvalidToken) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, erro.ErrForbidden) {
+			ctx.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, erro.ErrDeckNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{"pic_id": picID})
+}
+
+// Field errors of a body that couldn't be read
+func bodyErrors(err error) []FieldError {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		return []FieldError{{Field: typeErr.Field, Error: "can't be a " + typeErr.Value}}
+	}
+	return []FieldError{{Field: "body", Error: "not a valid JSON merge patch of a card"}}
+}
@@ -0,0 +1,19 @@
+package card
+
+// Operations applied to the cards of a deck all at once. Each card can
+// only be in one of them
+type BatchRequest struct {
+	Token      string
+	DeckID     int64            `json:"-"` // Provided in GET params
+	Operations []BatchOperation `json:"operations" binding:"required"`
+}
+
+// Op is create, update, delete or move. Creates take a card, updates a
+// merge patch of one and moves the deck it goes to
+type BatchOperation struct {
+	Op     string         `json:"op"`
+	CardID int64          `json:"card_id"` // Every op but create
+	Card   *CreateRequest `json:"card"`
+	Patch  *UpdateRequest `json:"patch"`
+	DeckID int64          `json:"deck_id"` // Target of a move
+}
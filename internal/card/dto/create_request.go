@@ -1,15 +1,56 @@
 package card
 
+// Options are the answers in the order they're shown. Requests with an
+// answer and wrong answers instead, like the ones of older clients, make
+// a single choice card with the answer first. Other card types take their
+// payload instead of options, and only basic ones need every field
 type CreateRequest struct {
-	DeckID   int64                // Providen in GET params
-	Title    string               `json:"title" binding:required`
-	Front    string               `json:"front" binding:"required"`
-	Back     string               `json:"back" binding:"required"`
-	Question string               `json:"question" binding:"required"`
-	Answer   string               `json:"answer" binding:"required"`
-	Wrong    []CreateWrongRequest `json:"wrong" binding:"required"`
+	Token      string
+	DeckID     int64                 // Providen in GET params
+	CardType   string                `json:"card_type"` // basic when empty
+	Title      string                `json:"title" binding:"required"`
+	Front      string                `json:"front"`
+	Back       string                `json:"back"`
+	Question   string                `json:"question"`
+	AnswerType string                `json:"answer_type"` // single when empty
+	Options    []CreateOptionRequest `json:"options"`
+	Answer     string                `json:"answer"`
+	Wrong      []CreateWrongRequest  `json:"wrong"`
+	Cloze      *ClozeRequest         `json:"cloze"`
+	Typed      *TypedRequest         `json:"typed"`
+	Occlusion  *OcclusionRequest     `json:"occlusion"`
+}
+
+type CreateOptionRequest struct {
+	Answer  string `json:"answer" binding:"required"`
+	Correct bool   `json:"correct"`
 }
 
 type CreateWrongRequest struct {
 	Answer string `json:"answer" binding:"required"`
 }
+
+// Text with deletions like {{c1::answer}} or {{c1::answer::hint}}
+type ClozeRequest struct {
+	Text string `json:"text" binding:"required"`
+}
+
+type TypedRequest struct {
+	Answers       []string `json:"answers" binding:"required"`
+	CaseSensitive bool     `json:"case_sensitive"`
+	Threshold     float64  `json:"threshold"`
+}
+
+// Picture uploaded to the deck with the areas to guess
+type OcclusionRequest struct {
+	PicID string        `json:"pic_id" binding:"required"`
+	Masks []MaskRequest `json:"masks" binding:"required"`
+}
+
+type MaskRequest struct {
+	Label  string  `json:"label" binding:"required"`
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+	Width  float64 `json:"width"`
+	Height float64 `json:"height"`
+}
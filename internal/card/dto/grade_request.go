@@ -0,0 +1,10 @@
+package card
+
+// Basic cards are answered picking options, the rest typing the answer
+type GradeRequest struct {
+	Token   string
+	DeckID  int64   // Provided in GET params
+	CardID  int64   // Provided in GET params
+	Answer  string  `json:"answer"`
+	Options []int64 `json:"options"`
+}
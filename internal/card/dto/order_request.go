@@ -0,0 +1,16 @@
+package card
+
+// New order of the cards of a deck. Every section and every card has to
+// be placed, cards outside of any section go after them
+type OrderRequest struct {
+	Token    string
+	DeckID   int64          `json:"-"`         // Provided in GET params
+	NewCards string         `json:"new_cards"` // Unchanged when empty
+	Sections []OrderSection `json:"sections"`
+	Cards    []int64        `json:"cards"`
+}
+
+type OrderSection struct {
+	SectionID int64   `json:"section_id"`
+	Cards     []int64 `json:"cards"`
+}
@@ -0,0 +1,8 @@
+package card
+
+type SectionRequest struct {
+	Token     string
+	DeckID    int64  `json:"-"` // Provided in GET params
+	SectionID int64  `json:"-"` // Provided in GET params, when renaming
+	Name      string `json:"name" binding:"required"`
+}
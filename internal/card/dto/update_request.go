@@ -1,17 +1,50 @@
 package card
 
+import "encoding/json"
+
+// Changes to a card as a JSON merge patch. Fields left out aren't changed
+// and null ones are emptied. The payload of the card type is merged into
+// the current one. Options replace the current list: options with an id
+// are merged into that option, options without one are added, options
+// left out are removed and the order of the list is the new order
 type UpdateRequest struct {
-	DeckID   int64                // Provided in GET params
-	CardID   int64                `json:"card_id"`
-	Title    string               `json:"title"`
-	Front    string               `json:"front"`
-	Back     string               `json:"back"`
-	Question string               `json:"question"`
-	Answer   string               `json:"answer"`
-	Wrong    []updateWrongRequest `json:"wrong"`
+	Token      string
+	DeckID     int64                `json:"-"` // Provided in GET params
+	CardID     int64                `json:"-"` // Provided in GET params
+	Title      Field[string]        `json:"title"`
+	Front      Field[string]        `json:"front"`
+	Back       Field[string]        `json:"back"`
+	Question   Field[string]        `json:"question"`
+	AnswerType Field[string]        `json:"answer_type"`
+	Options    Field[[]OptionPatch] `json:"options"`
+	Cloze      json.RawMessage      `json:"cloze"`
+	Typed      json.RawMessage      `json:"typed"`
+	Occlusion  json.RawMessage      `json:"occlusion"`
+}
+
+type OptionPatch struct {
+	OptionID int64         `json:"option_id"` // Left out for new options
+	Answer   Field[string] `json:"answer"`
+	Correct  Field[bool]   `json:"correct"`
+}
+
+// Member of a merge patch, telling apart left out and null members
+type Field[T any] struct {
+	Set   bool // In the patch, even if null
+	Null  bool
+	Value T
+}
+
+// Field set to a value
+func Value[T any](value T) Field[T] {
+	return Field[T]{Set: true, Value: value}
 }
 
-type updateWrongRequest struct {
-	WrongID int64  `json:"wrong_id" binding:"required"`
-	Answer  string `json:"answer" binding:"required"` // Nothing to update if id is provided but not the new answer
+func (f *Field[T]) UnmarshalJSON(data []byte) error {
+	f.Set = true
+	if string(data) == "null" {
+		f.Null = true
+		return nil
+	}
+	return json.Unmarshal(data, &f.Value)
 }
@@ -0,0 +1,62 @@
+package card
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestFieldUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		title   Field[string]
+		correct Field[bool]
+		wantErr bool
+	}{
+		{"absent", `{}`, Field[string]{}, Field[bool]{}, false},
+		{"null", `{"title": null, "correct": null}`, Field[string]{Set: true, Null: true}, Field[bool]{Set: true, Null: true}, false},
+		{"value", `{"title": "Capitals", "correct": true}`, Value("Capitals"), Value(true), false},
+		{"zero value", `{"title": "", "correct": false}`, Value(""), Value(false), false},
+		{"wrong type", `{"title": 3}`, Field[string]{}, Field[bool]{}, true},
+	}
+
+	for _, tt := range tests {
+		var got struct {
+			Title   Field[string] `json:"title"`
+			Correct Field[bool]   `json:"correct"`
+		}
+		err := json.Unmarshal([]byte(tt.body), &got)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: error %v, want error %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if tt.wantErr {
+			continue
+		}
+		if got.Title != tt.title || got.Correct != tt.correct {
+			t.Errorf("%s: got %+v and %+v, want %+v and %+v", tt.name, got.Title, got.Correct, tt.title, tt.correct)
+		}
+	}
+}
+
+func TestUpdateRequestOptions(t *testing.T) {
+	var request UpdateRequest
+	body := `{"options": [{"option_id": 4, "correct": false}, {"answer": "New"}]}`
+	if err := json.Unmarshal([]byte(body), &request); err != nil {
+		t.Fatal(err)
+	}
+
+	if !request.Options.Set || len(request.Options.Value) != 2 {
+		t.Fatalf("options %+v", request.Options)
+	}
+	kept, added := request.Options.Value[0], request.Options.Value[1]
+	if kept.OptionID != 4 || kept.Answer.Set || kept.Correct != Value(false) {
+		t.Errorf("kept option %+v", kept)
+	}
+	if added.OptionID != 0 || added.Answer != Value("New") || added.Correct.Set {
+		t.Errorf("added option %+v", added)
+	}
+	if request.Title.Set || request.Cloze != nil {
+		t.Errorf("fields left out were set")
+	}
+}
@@ -0,0 +1,192 @@
+package card
+
+import (
+	"html"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// Typed answers can be answerFactor times as long as the longest expected
+// answer plus answerSlack letters. Longer ones can't be close to it, so
+// they're rejected before comparing them
+const (
+	answerFactor = 4
+	answerSlack  = 32
+)
+
+var (
+	tagRegexp   = regexp.MustCompile(`<[^>]*>`)
+	spaceRegexp = regexp.MustCompile(`\s+`)
+)
+
+// Result of answering a card. Score goes from 0 to 1, for typed answers
+// it's how close they were to the nearest accepted one
+type Grade struct {
+	CardID   int64    `json:"card_id"`
+	Correct  bool     `json:"correct"`
+	Score    float64  `json:"score"`
+	Expected []string `json:"expected"`
+}
+
+// Grades an answer to the card. Basic cards are answered with the ids of
+// the picked options, the rest with typed text
+func (c Card) Grade(answer string, picked []int64) Grade {
+	grade := Grade{CardID: c.CardID}
+	switch c.CardType {
+	case TypeBasic:
+		grade.Expected, _ = c.Answers()
+		hits, misses, correct := 0, 0, 0
+		for _, o := range c.Options {
+			if o.Correct {
+				correct++
+			}
+			if slices.Contains(picked, o.OptionID) {
+				if o.Correct {
+					hits++
+				} else {
+					misses++
+				}
+			}
+		}
+		grade.Correct = hits == correct && misses == 0
+		if c.AnswerType == AnswerSelectAll && correct > 0 {
+			grade.Score = max(0, float64(hits-misses)/float64(correct))
+		} else if grade.Correct {
+			grade.Score = 1
+		}
+	case TypeTyped:
+		if c.Typed == nil {
+			break
+		}
+		grade.Expected = c.expected()
+		for _, expected := range grade.Expected {
+			grade.Score = max(grade.Score, similarity(answer, expected, c.Typed.CaseSensitive))
+		}
+		threshold := c.Typed.Threshold
+		if threshold == 0 {
+			threshold = typedThreshold
+		}
+		grade.Correct = grade.Score >= threshold
+	case TypeCloze:
+		if c.Cloze == nil {
+			break
+		}
+		// Deletions asked together are answered separated by commas
+		grade.Expected = c.expected()
+		parts := strings.Split(answer, ",")
+		if len(grade.Expected) == 1 {
+			parts = []string{answer}
+		}
+		total := 0.0
+		grade.Correct = len(grade.Expected) > 0
+		for i, expected := range grade.Expected {
+			score := 0.0
+			if i < len(parts) {
+				score = similarity(parts[i], expected, false)
+			}
+			total += score
+			grade.Correct = grade.Correct && score >= typedThreshold
+		}
+		if len(grade.Expected) > 0 {
+			grade.Score = total / float64(len(grade.Expected))
+		}
+	case TypeOcclusion:
+		if c.Occlusion == nil || c.Sibling < 1 || c.Sibling > len(c.Occlusion.Masks) {
+			break
+		}
+		grade.Expected = c.expected()
+		grade.Score = similarity(answer, grade.Expected[0], false)
+		grade.Correct = grade.Score >= typedThreshold
+	}
+
+	if grade.Expected == nil {
+		grade.Expected = []string{}
+	}
+	return grade
+}
+
+// Answers a typed answer is compared with. None for basic cards
+func (c Card) expected() []string {
+	switch c.CardType {
+	case TypeTyped:
+		if c.Typed != nil {
+			return c.Typed.Answers
+		}
+	case TypeCloze:
+		if c.Cloze != nil {
+			var expected []string
+			for _, answer := range c.Cloze.answers(c.Sibling) {
+				expected = append(expected, plain(answer))
+			}
+			return expected
+		}
+	case TypeOcclusion:
+		if c.Occlusion != nil && c.Sibling >= 1 && c.Sibling <= len(c.Occlusion.Masks) {
+			return []string{c.Occlusion.Masks[c.Sibling-1].Label}
+		}
+	}
+	return nil
+}
+
+// Whether a typed answer is longer than any answer close to the expected
+// ones could be. Cloze answers have a part for each deletion
+func (c Card) tooLong(answer string) bool {
+	expected := c.expected()
+	longest := 0
+	for _, e := range expected {
+		longest = max(longest, utf8.RuneCountInString(e))
+	}
+	parts := 1
+	if c.CardType == TypeCloze {
+		parts = max(len(expected), 1)
+	}
+	return utf8.RuneCountInString(answer) > answerFactor*longest*parts+answerSlack
+}
+
+// How alike two answers are from 0 to 1, by their edit distance. Spacing
+// is ignored and so is case unless asked not to
+func similarity(answer string, expected string, caseSensitive bool) float64 {
+	normalize := func(s string) []rune {
+		s = strings.TrimSpace(spaceRegexp.ReplaceAllString(s, " "))
+		if !caseSensitive {
+			s = strings.ToLower(s)
+		}
+		return []rune(s)
+	}
+	a, b := normalize(answer), normalize(expected)
+	if len(a) == 0 && len(b) == 0 {
+		return 1
+	}
+	return 1 - float64(distance(a, b))/float64(max(len(a), len(b)))
+}
+
+// Edit distance where swapping two adjacent letters counts as one typo.
+// A swap looks two rows back, so only the last three rows are kept
+func distance(a []rune, b []rune) int {
+	before, previous, current := make([]int, len(b)+1), make([]int, len(b)+1), make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				current[j] = min(current[j], before[j-2]+1)
+			}
+		}
+		before, previous, current = previous, current, before
+	}
+	return previous[len(b)]
+}
+
+// Text of a deletion without its markup
+func plain(text string) string {
+	return strings.TrimSpace(html.UnescapeString(tagRegexp.ReplaceAllString(text, "")))
+}
@@ -0,0 +1,124 @@
+package card
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"", "abc", 3},
+		{"abc", "", 3},
+		{"kitten", "sitting", 3},
+		{"paris", "paris", 0},
+		{"paris", "pairs", 1}, // Swap
+		{"ab", "ba", 1},
+		{"abcd", "badc", 2},
+		{"madrid", "madird", 1},
+		{"señor", "senor", 1},
+	}
+
+	for _, tt := range tests {
+		if got := distance([]rune(tt.a), []rune(tt.b)); got != tt.want {
+			t.Errorf("distance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestGradeTyped(t *testing.T) {
+	paris := Card{CardType: TypeTyped, Typed: &Typed{Answers: []string{"Paris", "Paree"}}}
+	exact := Card{CardType: TypeTyped, Typed: &Typed{Answers: []string{"Paris"}, CaseSensitive: true, Threshold: 1}}
+
+	tests := []struct {
+		name    string
+		card    Card
+		answer  string
+		correct bool
+		score   float64
+	}{
+		{"exact", paris, "Paris", true, 1},
+		{"case and spacing", paris, "  paris ", true, 1},
+		{"typo", paris, "Pariz", true, 0.8},
+		{"swap", paris, "Pairs", true, 0.8},
+		{"second answer", paris, "paree", true, 1},
+		{"wrong", paris, "London", false, 0},
+		{"empty", paris, "", false, 0},
+		{"case sensitive", exact, "paris", false, 0.8},
+		{"threshold of 1", exact, "Pariz", false, 0.8},
+		{"exact only", exact, "Paris", true, 1},
+		{"no payload", Card{CardType: TypeTyped}, "Paris", false, 0},
+	}
+
+	for _, tt := range tests {
+		grade := tt.card.Grade(tt.answer, nil)
+		if grade.Correct != tt.correct || math.Abs(grade.Score-tt.score) > 1e-9 {
+			t.Errorf("%s: correct %v with %.2f, want %v with %.2f", tt.name, grade.Correct, grade.Score, tt.correct, tt.score)
+		}
+	}
+}
+
+func TestGradeCloze(t *testing.T) {
+	capital := Cloze{Text: "{{c1::Paris}} is the capital of {{c2::<b>France</b>::country}}"}
+	together := Cloze{Text: "{{c1::Red}}, {{c1::green}} and {{c1::blue}}"}
+
+	tests := []struct {
+		name     string
+		cloze    Cloze
+		sibling  int
+		answer   string
+		correct  bool
+		score    float64
+		expected []string
+	}{
+		{"first", capital, 1, "paris", true, 1, []string{"Paris"}},
+		{"markup isn't expected", capital, 2, "France", true, 1, []string{"France"}},
+		{"comma kept in a single deletion", capital, 2, "France, Europe", false, 6.0 / 14, []string{"France"}},
+		{"wrong", capital, 1, "Lyon", false, 0, []string{"Paris"}},
+		{"together", together, 1, "red, green, blue", true, 1, []string{"Red", "green", "blue"}},
+		{"one of them wrong", together, 1, "red, green, pink", false, 2.0 / 3, []string{"Red", "green", "blue"}},
+		{"missing parts", together, 1, "red", false, 1.0 / 3, []string{"Red", "green", "blue"}},
+		{"no such deletion", capital, 3, "Paris", false, 0, []string{}},
+	}
+
+	for _, tt := range tests {
+		c := Card{CardType: TypeCloze, Cloze: &tt.cloze, Sibling: tt.sibling}
+		grade := c.Grade(tt.answer, nil)
+		if grade.Correct != tt.correct || math.Abs(grade.Score-tt.score) > 1e-9 {
+			t.Errorf("%s: correct %v with %.2f, want %v with %.2f", tt.name, grade.Correct, grade.Score, tt.correct, tt.score)
+		}
+		if strings.Join(grade.Expected, "|") != strings.Join(tt.expected, "|") {
+			t.Errorf("%s: expected %q, want %q", tt.name, grade.Expected, tt.expected)
+		}
+	}
+}
+
+func TestTooLong(t *testing.T) {
+	typed := Card{CardType: TypeTyped, Typed: &Typed{Answers: []string{"cat", "Paris"}}}
+	cloze := Card{CardType: TypeCloze, Cloze: &Cloze{Text: "{{c1::Red}} and {{c1::blue}}"}, Sibling: 1}
+
+	tests := []struct {
+		name   string
+		card   Card
+		answer string
+		want   bool
+	}{
+		{"short", typed, "Paris", false},
+		{"at the limit", typed, strings.Repeat("a", answerFactor*5+answerSlack), false},
+		{"over the limit", typed, strings.Repeat("a", answerFactor*5+answerSlack+1), true},
+		{"letters, not bytes", typed, strings.Repeat("ñ", answerFactor*5+answerSlack), false},
+		{"a part per deletion", cloze, strings.Repeat("a", answerFactor*4*2+answerSlack), false},
+		{"over the deletions", cloze, strings.Repeat("a", answerFactor*4*2+answerSlack+1), true},
+		{"huge", typed, strings.Repeat("a", 1<<20), true},
+	}
+
+	for _, tt := range tests {
+		if got := tt.card.tooLong(tt.answer); got != tt.want {
+			t.Errorf("%s: tooLong = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,216 @@
+package card
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"learn-swiping-api/erro"
+	card "learn-swiping-api/internal/card/dto"
+	"learn-swiping-api/internal/picture"
+	"slices"
+	"strings"
+)
+
+// Field of a request that can't be applied and why
+type FieldError struct {
+	Field string `json:"field"`
+	Error string `json:"error"`
+}
+
+// Every field of a request that can't be applied. Is an erro.ErrBadField
+type ValidationError struct {
+	Fields []FieldError `json:"fields"`
+}
+
+func (e *ValidationError) Error() string {
+	return erro.ErrBadField.Error()
+}
+
+func (e *ValidationError) Unwrap() error {
+	return erro.ErrBadField
+}
+
+func (e *ValidationError) add(field string, format string, args ...any) {
+	e.Fields = append(e.Fields, FieldError{Field: field, Error: fmt.Sprintf(format, args...)})
+}
+
+// Field of the request holding the payload of each card type
+var payloadFields = map[string]string{
+	TypeBasic:     "options",
+	TypeCloze:     "cloze",
+	TypeTyped:     "typed",
+	TypeOcclusion: "occlusion",
+}
+
+// Applies an update to a card. Returns the card as it would be, the
+// columns that changed and whether the options did
+func applyPatch(current Card, request card.UpdateRequest) (Card, []string, bool, error) {
+	patched := current
+	patched.Options = slices.Clone(current.Options)
+	errs := &ValidationError{}
+	var columns []string
+
+	texts := []struct {
+		name   string
+		field  card.Field[string]
+		target *string
+	}{
+		{"title", request.Title, &patched.Title},
+		{"front", request.Front, &patched.Front},
+		{"back", request.Back, &patched.Back},
+		{"question", request.Question, &patched.Question},
+	}
+	for _, t := range texts {
+		if t.field.Set && t.field.Value != *t.target {
+			*t.target = t.field.Value
+			columns = append(columns, t.name)
+		}
+	}
+
+	if request.AnswerType.Set {
+		switch {
+		case current.CardType != TypeBasic:
+			errs.add("answer_type", "only basic cards have an answer type")
+		case !ValidAnswerType(request.AnswerType.Value):
+			errs.add("answer_type", "unknown answer type %q", request.AnswerType.Value)
+		case request.AnswerType.Value != current.AnswerType:
+			patched.AnswerType = request.AnswerType.Value
+			columns = append(columns, "answer_type")
+		}
+	}
+
+	payloads := []struct {
+		cardType string
+		raw      json.RawMessage
+	}{
+		{TypeCloze, request.Cloze},
+		{TypeTyped, request.Typed},
+		{TypeOcclusion, request.Occlusion},
+	}
+	for _, p := range payloads {
+		if p.raw == nil {
+			continue
+		}
+		field := payloadFields[p.cardType]
+		if p.cardType != current.CardType {
+			errs.add(field, "not a %s card", p.cardType)
+			continue
+		}
+
+		stored, err := current.Payload()
+		if err != nil {
+			return Card{}, nil, false, err
+		}
+		merged, err := mergePatch(stored, p.raw)
+		if err != nil || string(merged) == "null" {
+			errs.add(field, "has to be an object")
+			continue
+		}
+		patched.Cloze, patched.Typed, patched.Occlusion = nil, nil, nil
+		if err := patched.SetPayload(merged); err != nil {
+			errs.add(field, "has the wrong shape")
+			continue
+		}
+		if changed, _ := patched.Payload(); !bytes.Equal(changed, stored) {
+			columns = append(columns, "payload")
+		}
+	}
+
+	optionsChanged := request.Options.Set
+	if optionsChanged {
+		if current.CardType != TypeBasic {
+			errs.add("options", "only basic cards have options")
+		}
+		patched.Options = make([]Option, 0, len(request.Options.Value))
+		for i, o := range request.Options.Value {
+			field := fmt.Sprintf("options[%d]", i)
+			option := Option{CardID: current.CardID}
+			if o.OptionID != 0 {
+				at := slices.IndexFunc(current.Options, func(co Option) bool { return co.OptionID == o.OptionID })
+				if at < 0 {
+					errs.add(field+".option_id", "not an option of the card")
+					continue
+				}
+				if slices.ContainsFunc(patched.Options, func(po Option) bool { return po.OptionID == o.OptionID }) {
+					errs.add(field+".option_id", "repeated")
+					continue
+				}
+				option = current.Options[at]
+			} else if !o.Answer.Set {
+				errs.add(field+".answer", "required")
+				continue
+			}
+			if o.Answer.Set {
+				option.Answer = o.Answer.Value
+			}
+			if o.Correct.Set {
+				option.Correct = o.Correct.Value
+			}
+			patched.Options = append(patched.Options, option)
+		}
+	}
+
+	required := []struct {
+		name  string
+		value string
+		when  bool
+	}{
+		{"title", patched.Title, true},
+		{"front", patched.Front, current.CardType == TypeBasic},
+		{"back", patched.Back, current.CardType == TypeBasic},
+		{"question", patched.Question, current.CardType == TypeBasic || current.CardType == TypeTyped},
+	}
+	for _, r := range required {
+		if r.when && strings.TrimSpace(r.value) == "" {
+			errs.add(r.name, "required")
+		}
+	}
+
+	// Cards stored before the rules existed are left alone until their
+	// answers change
+	if len(errs.Fields) == 0 && (optionsChanged || slices.Contains(columns, "answer_type") || slices.Contains(columns, "payload")) {
+		if err := ValidatePayload(patched); err != nil {
+			errs.add(payloadFields[current.CardType], "%s", err.Error())
+		} else if patched.Occlusion != nil && !picture.Exists(patched.Occlusion.PicID) {
+			errs.add("occlusion", "picture not found")
+		}
+	}
+
+	if len(errs.Fields) > 0 {
+		return Card{}, nil, false, errs
+	}
+	return patched, columns, optionsChanged, nil
+}
+
+// Applies a JSON merge patch (RFC 7396) to a document
+func mergePatch(document []byte, patch []byte) ([]byte, error) {
+	var target, changes any
+	if len(document) > 0 {
+		if err := json.Unmarshal(document, &target); err != nil {
+			return nil, err
+		}
+	}
+	if err := json.Unmarshal(patch, &changes); err != nil {
+		return nil, err
+	}
+	return json.Marshal(merge(target, changes))
+}
+
+func merge(target any, patch any) any {
+	changes, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+	document, ok := target.(map[string]any)
+	if !ok {
+		document = map[string]any{}
+	}
+	for key, value := range changes {
+		if value == nil {
+			delete(document, key)
+		} else {
+			document[key] = merge(document[key], value)
+		}
+	}
+	return document
+}
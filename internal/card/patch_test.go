@@ -0,0 +1,151 @@
+package card
+
+import (
+	"encoding/json"
+	"errors"
+	card "learn-swiping-api/internal/card/dto"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+// Examples of RFC 7396, appendix A
+func TestMergePatch(t *testing.T) {
+	tests := []struct {
+		document, patch, want string
+	}{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`["a","b"]`, `["c","d"]`, `["c","d"]`},
+		{`{"a":"b"}`, `["c"]`, `["c"]`},
+		{`{"a":"foo"}`, `null`, `null`},
+		{`{"a":"foo"}`, `"bar"`, `"bar"`},
+		{`{"e":null}`, `{"a":1}`, `{"a":1,"e":null}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+		{``, `{"a":"b"}`, `{"a":"b"}`}, // Cards without a payload
+	}
+
+	for _, tt := range tests {
+		got, err := mergePatch([]byte(tt.document), []byte(tt.patch))
+		if err != nil {
+			t.Errorf("mergePatch(%s, %s): %v", tt.document, tt.patch, err)
+			continue
+		}
+		if !jsonEqual(t, got, []byte(tt.want)) {
+			t.Errorf("mergePatch(%s, %s) = %s, want %s", tt.document, tt.patch, got, tt.want)
+		}
+	}
+
+	if _, err := mergePatch([]byte(`{}`), []byte(`{`)); err == nil {
+		t.Error("broken patch was applied")
+	}
+}
+
+func jsonEqual(t *testing.T, a []byte, b []byte) bool {
+	var x, y any
+	if err := json.Unmarshal(a, &x); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(b, &y); err != nil {
+		t.Fatal(err)
+	}
+	return reflect.DeepEqual(x, y)
+}
+
+func TestApplyPatch(t *testing.T) {
+	basic := Card{
+		CardID: 1, CardType: TypeBasic, Title: "Capitals", Front: "France", Back: "Paris is the capital",
+		Question: "Capital of France?", AnswerType: AnswerSingle,
+		Options: []Option{{OptionID: 10, CardID: 1, Answer: "Paris", Correct: true}, {OptionID: 11, CardID: 1, Answer: "Lyon"}},
+	}
+	typed := Card{CardID: 2, CardType: TypeTyped, Title: "Typed", Question: "Capital of France?", Typed: &Typed{Answers: []string{"Paris"}}}
+	cloze := Card{CardID: 3, CardType: TypeCloze, Title: "Cloze", Cloze: &Cloze{Text: "{{c1::Paris}} is in France"}}
+
+	tests := []struct {
+		name    string
+		current Card
+		body    string
+		columns []string
+		options bool
+		errors  []string // Fields with errors
+		check   func(Card) bool
+	}{
+		{"nothing", basic, `{}`, nil, false, nil, nil},
+		{"same value", basic, `{"title": "Capitals"}`, nil, false, nil, nil},
+		{"title", basic, `{"title": "Cities"}`, []string{"title"}, false, nil,
+			func(c Card) bool { return c.Title == "Cities" }},
+		{"null title", basic, `{"title": null}`, nil, false, []string{"title"}, nil},
+		{"empty back of a basic card", basic, `{"back": ""}`, nil, false, []string{"back"}, nil},
+		{"empty back of a typed card", typed, `{"back": ""}`, nil, false, nil, nil},
+		{"answer type", basic, `{"answer_type": "select_all"}`, []string{"answer_type"}, false, nil,
+			func(c Card) bool { return c.AnswerType == AnswerSelectAll }},
+		{"unknown answer type", basic, `{"answer_type": "essay"}`, nil, false, []string{"answer_type"}, nil},
+		{"answer type of a typed card", typed, `{"answer_type": "single"}`, nil, false, []string{"answer_type"}, nil},
+		{"options kept and added", basic, `{"options": [{"option_id": 11, "correct": true}, {"option_id": 10, "correct": false}, {"answer": "Nice"}]}`, nil, true, nil,
+			func(c Card) bool {
+				return len(c.Options) == 3 && c.Options[0].OptionID == 11 && c.Options[0].Answer == "Lyon" && c.Options[0].Correct &&
+					!c.Options[1].Correct && c.Options[2].OptionID == 0 && c.Options[2].Answer == "Nice"
+			}},
+		{"option of another card", basic, `{"options": [{"option_id": 99}, {"answer": "Nice"}]}`, nil, false, []string{"options[0].option_id"}, nil},
+		{"repeated option", basic, `{"options": [{"option_id": 10}, {"option_id": 10}]}`, nil, false, []string{"options[1].option_id"}, nil},
+		{"new option without answer", basic, `{"options": [{"option_id": 10}, {"correct": true}]}`, nil, false, []string{"options[1].answer"}, nil},
+		{"no correct option left", basic, `{"options": [{"option_id": 10, "correct": false}, {"option_id": 11}]}`, nil, false, []string{"options"}, nil},
+		{"every error at once", basic, `{"title": "", "answer_type": "essay", "options": [{"option_id": 99}], "typed": {}}`, nil, false,
+			[]string{"answer_type", "typed", "options[0].option_id", "title"}, nil},
+		{"options of a typed card", typed, `{"options": [{"answer": "Paris", "correct": true}]}`, nil, false, []string{"options"}, nil},
+		{"typed payload", typed, `{"typed": {"answers": ["Paris", "Paree"], "threshold": 0.9}}`, []string{"payload"}, false, nil,
+			func(c Card) bool { return len(c.Typed.Answers) == 2 && c.Typed.Threshold == 0.9 }},
+		{"payload member removed", typed, `{"typed": {"case_sensitive": true, "threshold": null}}`, []string{"payload"}, false, nil,
+			func(c Card) bool { return c.Typed.CaseSensitive && c.Typed.Answers[0] == "Paris" }},
+		{"same payload", typed, `{"typed": {"answers": ["Paris"]}}`, nil, false, nil, nil},
+		{"invalid payload", typed, `{"typed": {"answers": []}}`, nil, false, []string{"typed"}, nil},
+		{"null payload", typed, `{"typed": null}`, nil, false, []string{"typed"}, nil},
+		{"payload of another type", typed, `{"cloze": {"text": "{{c1::a}}"}}`, nil, false, []string{"cloze"}, nil},
+		{"wrong shape", cloze, `{"cloze": {"text": 3}}`, nil, false, []string{"cloze"}, nil},
+		{"cloze without deletions", cloze, `{"cloze": {"text": "Paris is in France"}}`, nil, false, []string{"cloze"}, nil},
+	}
+
+	for _, tt := range tests {
+		var request card.UpdateRequest
+		if err := json.Unmarshal([]byte(tt.body), &request); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		before := tt.current
+		before.Options = slices.Clone(tt.current.Options)
+
+		patched, columns, options, err := applyPatch(tt.current, request)
+
+		var got []string
+		var validation *ValidationError
+		if errors.As(err, &validation) {
+			for _, f := range validation.Fields {
+				got = append(got, f.Field)
+			}
+		} else if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.errors) {
+			t.Errorf("%s: errors in %v, want %v", tt.name, got, tt.errors)
+		}
+		if !reflect.DeepEqual(tt.current, before) {
+			t.Errorf("%s: the current card changed", tt.name)
+		}
+		if err != nil {
+			continue
+		}
+		if !reflect.DeepEqual(columns, tt.columns) || options != tt.options {
+			t.Errorf("%s: columns %v and options %v, want %v and %v", tt.name, columns, options, tt.columns, tt.options)
+		}
+		if tt.check != nil && !tt.check(patched) {
+			t.Errorf("%s: patched card %+v", tt.name, patched)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package card
+
+import (
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/microcosm-cc/bluemonday"
+)
+
+// Cards can hold HTML, like the ones imported from Anki, so the sides are
+// sanitized instead of escaped. The render only adds the highlight of the
+// deletions to what user content may have
+var renderPolicy = func() *bluemonday.Policy {
+	policy := bluemonday.UGCPolicy()
+	policy.AllowAttrs("class").Matching(regexp.MustCompile(`^cloze$`)).OnElements("span")
+	return policy
+}()
+
+// Sides of a generated card as they're shown. The stored front and back
+// are extra text added to them
+type Render struct {
+	Front   string         `json:"front"`
+	Back    string         `json:"back"`
+	Picture string         `json:"picture,omitempty"` // Path of the occluded picture
+	Masks   []RenderedMask `json:"masks,omitempty"`   // Every mask is hidden on the front
+}
+
+type RenderedMask struct {
+	Mask
+	Asked bool `json:"asked"` // Revealed on the back
+}
+
+// Fills the render of cloze and occlusion cards. Other types are shown as
+// they're stored
+func (c *Card) render() {
+	switch {
+	case c.CardType == TypeCloze && c.Cloze != nil:
+		c.Render = &Render{
+			Front: renderPolicy.Sanitize(withExtra(clozeSide(c.Cloze.Text, c.Sibling, false), c.Front)),
+			Back:  renderPolicy.Sanitize(withExtra(clozeSide(c.Cloze.Text, c.Sibling, true), c.Back)),
+		}
+	case c.CardType == TypeOcclusion && c.Occlusion != nil:
+		c.Render = &Render{Front: renderPolicy.Sanitize(c.Front), Picture: "/pics/" + c.Occlusion.PicID}
+		for i, m := range c.Occlusion.Masks {
+			asked := i+1 == c.Sibling
+			if asked {
+				// Labels are plain text, they're what typed answers are graded against
+				c.Render.Back = renderPolicy.Sanitize(withExtra("<b>"+html.EscapeString(m.Label)+"</b>", c.Back))
+			}
+			c.Render.Masks = append(c.Render.Masks, RenderedMask{Mask: m, Asked: asked})
+		}
+	}
+}
+
+// Text with the asked deletions blanked, showing their hints, or
+// highlighted once revealed. The rest are shown as plain text
+func clozeSide(text string, number int, revealed bool) string {
+	return clozeRegexp.ReplaceAllStringFunc(text, func(deletion string) string {
+		d := clozeRegexp.FindStringSubmatch(deletion)
+		switch {
+		case d[1] != strconv.Itoa(number):
+			return d[2]
+		case revealed:
+			return `<span class="cloze">` + d[2] + `</span>`
+		case d[3] != "":
+			return `<span class="cloze">[` + d[3] + `]</span>`
+		}
+		return `<span class="cloze">[...]</span>`
+	})
+}
+
+// Text with every deletion replaced by what show returns for it, for
+// writers that show the whole card at once
+func (cl *Cloze) Fill(show func(answer string, hint string) string) string {
+	return clozeRegexp.ReplaceAllStringFunc(cl.Text, func(deletion string) string {
+		d := clozeRegexp.FindStringSubmatch(deletion)
+		return show(d[2], d[3])
+	})
+}
+
+func withExtra(side string, extra string) string {
+	if strings.TrimSpace(extra) == "" {
+		return side
+	}
+	return side + "<br><br>" + extra
+}
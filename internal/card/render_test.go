@@ -0,0 +1,79 @@
+package card
+
+import (
+	"regexp"
+	"testing"
+)
+
+// Scripts, event handlers and javascript: links
+var unsafeRegexp = regexp.MustCompile(`(?i)<script|<[^>]*(\son\w+\s*=|javascript:)`)
+
+func TestRender(t *testing.T) {
+	cloze := func(text string, sibling int, front string, back string) Card {
+		return Card{CardType: TypeCloze, Cloze: &Cloze{Text: text}, Sibling: sibling, Front: front, Back: back}
+	}
+	occlusion := func(label string, front string, back string) Card {
+		return Card{CardType: TypeOcclusion, Occlusion: &Occlusion{PicID: "pic.png", Masks: []Mask{{Label: "Heart"}, {Label: label}}},
+			Sibling: 2, Front: front, Back: back}
+	}
+
+	tests := []struct {
+		name        string
+		card        Card
+		front, back string
+	}{
+		{"cloze", cloze("{{c1::Paris}} is in {{c2::France::country}}", 2, "", "Extra"),
+			`Paris is in <span class="cloze">[country]</span>`,
+			`Paris is in <span class="cloze">France</span><br><br>Extra`},
+		{"cloze without hint", cloze("{{c1::Paris}} is in France", 1, "", ""),
+			`<span class="cloze">[...]</span> is in France`,
+			`<span class="cloze">Paris</span> is in France`},
+		{"formatting is kept", cloze("<b>{{c1::Paris}}</b>", 1, "", ""),
+			`<b><span class="cloze">[...]</span></b>`,
+			`<b><span class="cloze">Paris</span></b>`},
+		{"script in the answer", cloze("{{c1::<script>alert(1)</script>Paris}}", 1, "", ""),
+			`<span class="cloze">[...]</span>`,
+			`<span class="cloze">Paris</span>`},
+		{"script in the hint", cloze(`{{c1::Paris::<img src=x onerror="alert(1)">}}`, 1, "", ""),
+			`<span class="cloze">[<img src="x">]</span>`,
+			`<span class="cloze">Paris</span>`},
+		{"script around the deletions", cloze(`<a href="javascript:alert(1)">{{c1::Paris}}</a>`, 1, "<script>alert(1)</script>", `<p onclick="alert(1)">Extra</p>`),
+			`<span class="cloze">[...]</span><br><br>`,
+			`<span class="cloze">Paris</span><br><br><p>Extra</p>`},
+		{"class of other elements", cloze(`<b class="cloze">{{c1::Paris}}</b>`, 1, "", ""),
+			`<b><span class="cloze">[...]</span></b>`,
+			`<b><span class="cloze">Paris</span></b>`},
+		{"occlusion", occlusion("Lung", "Name it", "Extra"),
+			`Name it`,
+			`<b>Lung</b><br><br>Extra`},
+		{"script in a mask label", occlusion(`<img src=x onerror="alert(1)">`, `<svg onload="alert(1)">`, ""),
+			``,
+			`<b>&lt;img src=x onerror=&#34;alert(1)&#34;&gt;</b>`},
+		{"label closing the markup", occlusion(`</b><script>alert(1)</script>`, "", ""),
+			``,
+			`<b>&lt;/b&gt;&lt;script&gt;alert(1)&lt;/script&gt;</b>`},
+		{"label as text", occlusion("a < b & c", "", ""),
+			``,
+			`<b>a &lt; b &amp; c</b>`},
+	}
+
+	for _, tt := range tests {
+		c := tt.card
+		c.render()
+		if c.Render == nil {
+			t.Errorf("%s: not rendered", tt.name)
+			continue
+		}
+		if c.Render.Front != tt.front {
+			t.Errorf("%s: front %q, want %q", tt.name, c.Render.Front, tt.front)
+		}
+		if c.Render.Back != tt.back {
+			t.Errorf("%s: back %q, want %q", tt.name, c.Render.Back, tt.back)
+		}
+		for _, side := range []string{c.Render.Front, c.Render.Back} {
+			if unsafeRegexp.MatchString(side) {
+				t.Errorf("%s: unsafe side %q", tt.name, side)
+			}
+		}
+	}
+}
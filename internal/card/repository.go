@@ -15,22 +15,41 @@ type CardRepository interface {
 	ById(cardID int64, deckID int64) (Card, error)
 	ByDeckId(id int64) ([]Card, error)
 	ByProgress(token string, deckID int64) ([]Card, error)
-	Update(card Card) error
+	Update(card Card, columns []string, options []Option) error
 	Delete(cardID int64, deckID int64) error
-	// CreateWrong(wrong WrongAnswer) (int64, error)
-	WrongByCardId(cardID int64) ([]WrongAnswer, error)
-	UpdateWrong(id int64, wrong WrongAnswer) error
-	// DeleteWrong(id int64) error
+	Batch(deckID int64, changes []BatchChange) ([]int64, error)
+	OptionsByCardId(cardID int64) ([]Option, error)
+	Outline(deckID int64) (Outline, error)
+	Section(sectionID int64, deckID int64) (Section, error)
+	CreateSection(deckID int64, name string) (int64, error)
+	RenameSection(sectionID int64, deckID int64, name string) error
+	DeleteSection(sectionID int64, deckID int64) error
+	Reorder(deckID int64, outline Outline) error
 }
 
+// Columns read by ScanCard, also used by the packages that read cards on
+// their own. Siblings take the payload of their source. Decks are ordered
+// by section, cards outside of any go last
+const (
+	CardColumns = "c.card_id, c.deck_id, c.section_id, c.position, c.card_type, c.title, c.front, c.back, c.question, c.answer_type, COALESCE(src.payload, c.payload), c.parent_id, c.sibling"
+	CardFrom    = "FROM CARD c LEFT JOIN CARD src ON c.parent_id = src.card_id LEFT JOIN SECTION sec ON c.section_id = sec.section_id"
+	CardOrder   = "sec.position IS NULL, sec.position, c.position, c.sibling, c.card_id"
+)
+
 type CardRepositoryImpl struct {
-	db              *sql.DB
-	ByIdStmt        *sql.Stmt
-	ByDeckIdStmt    *sql.Stmt
-	ByProgressStmt  *sql.Stmt
-	DeleteStmt      *sql.Stmt
-	CreateWrongStmt *sql.Stmt
-	WrongByIdStmt   *sql.Stmt
+	db                  *sql.DB
+	ByIdStmt            *sql.Stmt
+	ByDeckIdStmt        *sql.Stmt
+	ByProgressStmt      *sql.Stmt
+	DeleteStmt          *sql.Stmt
+	OptionsByCardIdStmt *sql.Stmt
+	NewCardsStmt        *sql.Stmt
+	SectionsStmt        *sql.Stmt
+	OutlineStmt         *sql.Stmt
+	SectionStmt         *sql.Stmt
+	CreateSectionStmt   *sql.Stmt
+	RenameSectionStmt   *sql.Stmt
+	DeleteSectionStmt   *sql.Stmt
 }
 
 func NewCardRepository(db *sql.DB) *CardRepositoryImpl {
@@ -45,24 +64,26 @@ func NewCardRepository(db *sql.DB) *CardRepositoryImpl {
 func (repo *CardRepositoryImpl) InitStatements() error {
 	var err error
 	// TODO: Implement token check
-	repo.ByIdStmt, err = repo.db.Prepare("SELECT * FROM CARD WHERE card_id = ? AND deck_id = ?")
+	repo.ByIdStmt, err = repo.db.Prepare("SELECT " + CardColumns + " " + CardFrom + " WHERE c.card_id = ? AND c.deck_id = ?")
 	if err != nil {
 		return err
 	}
 
-	repo.ByDeckIdStmt, err = repo.db.Prepare("SELECT * FROM CARD WHERE deck_id = ?")
+	repo.ByDeckIdStmt, err = repo.db.Prepare("SELECT " + CardColumns + " " + CardFrom + " WHERE c.deck_id = ? ORDER BY " + CardOrder)
 	if err != nil {
 		return err
 	}
 
-	repo.ByProgressStmt, err = repo.db.Prepare(`SELECT c.*
-												FROM CARD c
+	// Cards due come before new ones, which are shuffled unless the deck
+	// introduces them in its order
+	repo.ByProgressStmt, err = repo.db.Prepare(`SELECT ` + CardColumns + ` ` + CardFrom + `
+												JOIN DECK d ON c.deck_id = d.deck_id
 												LEFT JOIN PROGRESS p ON c.card_id = p.card_id
-												LEFT JOIN ACCOUNT a ON p.acc_id = a.acc_id
+													AND p.acc_id = (SELECT acc_id FROM ACCOUNT WHERE token = ?)
 												WHERE ((p.days_hidden <= 0 AND p.is_buried = false)
    												OR p.card_id IS NULL) 
-												AND c.deck_id = ? 
-												AND a.token = ?`)
+												AND c.deck_id = ?
+												ORDER BY p.card_id IS NULL, IF(d.new_cards = '` + NewCardsSequential + `', 0, RAND()), ` + CardOrder)
 	if err != nil {
 		return err
 	}
@@ -73,12 +94,45 @@ func (repo *CardRepositoryImpl) InitStatements() error {
 		return err
 	}
 
-	repo.CreateWrongStmt, err = repo.db.Prepare("INSERT INTO WRONG_ANSWER (card_id, answer) VALUES (?, ?)")
+	repo.OptionsByCardIdStmt, err = repo.db.Prepare("SELECT option_id, card_id, answer, correct FROM CARD_OPTION WHERE card_id = ? ORDER BY position")
 	if err != nil {
 		return err
 	}
 
-	repo.WrongByIdStmt, err = repo.db.Prepare("SELECT wrong_id, answer FROM WRONG_ANSWER WHERE card_id = ?")
+	repo.NewCardsStmt, err = repo.db.Prepare("SELECT new_cards FROM DECK WHERE deck_id = ?")
+	if err != nil {
+		return err
+	}
+
+	repo.SectionsStmt, err = repo.db.Prepare("SELECT section_id, deck_id, name, position FROM SECTION WHERE deck_id = ? ORDER BY position")
+	if err != nil {
+		return err
+	}
+
+	repo.OutlineStmt, err = repo.db.Prepare("SELECT c.card_id, c.section_id FROM CARD c LEFT JOIN SECTION sec ON c.section_id = sec.section_id WHERE c.deck_id = ? AND c.parent_id IS NULL ORDER BY " + CardOrder)
+	if err != nil {
+		return err
+	}
+
+	repo.SectionStmt, err = repo.db.Prepare("SELECT section_id, deck_id, name, position FROM SECTION WHERE section_id = ? AND deck_id = ?")
+	if err != nil {
+		return err
+	}
+
+	// New sections go last
+	repo.CreateSectionStmt, err = repo.db.Prepare(`INSERT INTO SECTION (deck_id, name, position)
+													SELECT ?, ?, COALESCE(MAX(position), -1) + 1 FROM SECTION WHERE deck_id = ?`)
+	if err != nil {
+		return err
+	}
+
+	repo.RenameSectionStmt, err = repo.db.Prepare("UPDATE SECTION SET name = ? WHERE section_id = ? AND deck_id = ?")
+	if err != nil {
+		return err
+	}
+
+	// Cards of the section are left outside of any
+	repo.DeleteSectionStmt, err = repo.db.Prepare("DELETE FROM SECTION WHERE section_id = ? AND deck_id = ?")
 	if err != nil {
 		return err
 	}
@@ -92,25 +146,47 @@ func (r *CardRepositoryImpl) Create(card Card) (int64, error) {
 		return 0, err
 	}
 
-	// Cannot use globally prepared statements here because of the transaction
-	cardStmt, err := tx.Prepare("INSERT INTO CARD (deck_id, title, front, back, question, answer) VALUES (?, ?, ?, ?, ?, ?)")
+	id, err := InsertCard(tx, card)
 	if err != nil {
+		tx.Rollback()
 		return 0, err
 	}
 
-	wrongStmt, err := tx.Prepare("INSERT INTO WRONG_ANSWER (card_id, answer) VALUES (?,?)")
+	err = tx.Commit()
 	if err != nil {
+		tx.Rollback()
 		return 0, err
 	}
 
-	defer cardStmt.Close()
-	defer wrongStmt.Close()
+	return id, nil
+}
 
-	// Insert Card
-	result, err := cardStmt.Exec(card.DeckID, card.Title, card.Front, card.Back, card.Question, card.Answer)
+// Inserts a card with its options and siblings in the transaction of the
+// caller
+func InsertCard(tx *sql.Tx, card Card) (int64, error) {
+	payload, err := payloadArg(card)
 	if err != nil {
-		tx.Rollback()
-		if err.(*mysql.MySQLError).Number == 1452 {
+		return 0, err
+	}
+
+	// New cards go last
+	position, err := nextPosition(tx, card.DeckID)
+	if err != nil {
+		return 0, err
+	}
+
+	// Insert Card. It asks about the first deletion or mask
+	insert := `INSERT INTO CARD (deck_id, section_id, position, card_type, title, front, back, question, answer_type, payload, parent_id, sibling)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	siblings := card.Siblings()
+	first := 0
+	if len(siblings) > 0 {
+		first = siblings[0]
+	}
+	result, err := tx.Exec(insert, card.DeckID, card.SectionID, position, card.CardType, card.Title, card.Front, card.Back, card.Question,
+		card.AnswerType, payload, nil, first)
+	if err != nil {
+		if mysqlErr, ok := err.(*mysql.MySQLError); ok && mysqlErr.Number == 1452 {
 			return 0, erro.ErrDeckNotFound
 		}
 		return 0, err
@@ -118,44 +194,37 @@ func (r *CardRepositoryImpl) Create(card Card) (int64, error) {
 
 	id, err := result.LastInsertId()
 	if err != nil {
-		tx.Rollback()
 		return 0, err
 	}
 
-	// Insert wrong answers
-	for i := 0; i < len(card.Wrong); i++ {
-		_, err := wrongStmt.Exec(id, card.Wrong[i].Answer)
-		if err != nil {
-			tx.Rollback()
-			if err.(*mysql.MySQLError).Number == 1452 {
-				return 0, erro.ErrCardNotFound
-			}
+	// Insert options in order
+	for i, option := range card.Options {
+		if err := createOption(tx, id, i, option); err != nil {
 			return 0, err
 		}
 	}
 
-	err = tx.Commit()
-	if err != nil {
-		tx.Rollback()
-		return 0, err
+	// And its siblings about the rest
+	for _, sibling := range siblings[min(1, len(siblings)):] {
+		_, err := tx.Exec(insert, card.DeckID, card.SectionID, position, card.CardType, card.Title, card.Front, card.Back, card.Question,
+			card.AnswerType, nil, id, sibling)
+		if err != nil {
+			return 0, err
+		}
 	}
 
 	return id, nil
 }
 
-func (r *CardRepositoryImpl) ById(cardID int64, deckID int64) (Card, error) {
-	var card Card
-	row := r.ByIdStmt.QueryRow(cardID, deckID)
+// Position after the last card of a deck
+func nextPosition(tx *sql.Tx, deckID int64) (int, error) {
+	var position int
+	err := tx.QueryRow("SELECT COALESCE(MAX(position), -1) + 1 FROM CARD WHERE deck_id = ?", deckID).Scan(&position)
+	return position, err
+}
 
-	err := row.Scan(
-		&card.CardID,
-		&card.DeckID,
-		&card.Title,
-		&card.Front,
-		&card.Back,
-		&card.Question,
-		&card.Answer,
-	)
+func (r *CardRepositoryImpl) ById(cardID int64, deckID int64) (Card, error) {
+	card, err := ScanCard(r.ByIdStmt.QueryRow(cardID, deckID))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return Card{}, erro.ErrCardNotFound
@@ -174,17 +243,8 @@ func (r *CardRepositoryImpl) ByDeckId(id int64) ([]Card, error) {
 	}
 	defer rows.Close()
 
-	var card Card
 	for rows.Next() {
-		err := rows.Scan(
-			&card.CardID,
-			&card.DeckID,
-			&card.Title,
-			&card.Front,
-			&card.Back,
-			&card.Question,
-			&card.Answer,
-		)
+		card, err := ScanCard(rows)
 		if err != nil {
 			return cards, err
 		}
@@ -200,23 +260,14 @@ func (r *CardRepositoryImpl) ByDeckId(id int64) ([]Card, error) {
 
 func (r *CardRepositoryImpl) ByProgress(token string, deckID int64) ([]Card, error) {
 	var cards []Card
-	rows, err := r.ByProgressStmt.Query(deckID, token)
+	rows, err := r.ByProgressStmt.Query(token, deckID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var card Card
 	for rows.Next() {
-		err := rows.Scan(
-			&card.CardID,
-			&card.DeckID,
-			&card.Title,
-			&card.Front,
-			&card.Back,
-			&card.Question,
-			&card.Answer,
-		)
+		card, err := ScanCard(rows)
 		if err != nil {
 			return cards, err
 		}
@@ -230,26 +281,177 @@ func (r *CardRepositoryImpl) ByProgress(token string, deckID int64) ([]Card, err
 	return cards, nil
 }
 
-func (r *CardRepositoryImpl) Update(card Card) error {
-	var query strings.Builder
-	var args []any
-	query.WriteString("UPDATE CARD SET")
+// Writes the given columns of a card and, unless nil, replaces its
+// options with the given ones in their order, all or nothing. Options
+// without an id are created and the ones left out are deleted. Siblings
+// of the card are regenerated from it
+func (r *CardRepositoryImpl) Update(card Card, columns []string, options []Option) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
 
-	updateCardField(&query, &args, "title", card.Title)
-	updateCardField(&query, &args, "front", card.Front)
-	updateCardField(&query, &args, "back", card.Back)
-	updateCardField(&query, &args, "question", card.Question)
-	updateCardField(&query, &args, "answer", card.Answer)
+	if err := updateCard(tx, card, columns, options); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
 
-	args = append(args, card.CardID, card.DeckID)
-	query.WriteString(" WHERE card_id = ? AND deck_id = ?")
+func updateCard(tx *sql.Tx, card Card, columns []string, options []Option) error {
+	if len(columns) > 0 {
+		var query strings.Builder
+		var args []any
+		query.WriteString("UPDATE CARD SET")
+		for i, column := range columns {
+			value, err := cardValue(card, column)
+			if err != nil {
+				return err
+			}
+			if i > 0 {
+				query.WriteString(",")
+			}
+			query.WriteString(fmt.Sprintf(" %s = ?", column))
+			args = append(args, value)
+		}
+		query.WriteString(" WHERE card_id = ? AND deck_id = ?")
+		args = append(args, card.CardID, card.DeckID)
 
-	stmt, err := r.db.Prepare(query.String())
+		if _, err := tx.Exec(query.String(), args...); err != nil {
+			return err
+		}
+
+		if err := syncSiblings(tx, card); err != nil {
+			return err
+		}
+	}
+
+	if options != nil {
+		if err := replaceOptions(tx, card.CardID, options); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Value of a column that can be updated
+func cardValue(card Card, column string) (any, error) {
+	switch column {
+	case "title":
+		return card.Title, nil
+	case "front":
+		return card.Front, nil
+	case "back":
+		return card.Back, nil
+	case "question":
+		return card.Question, nil
+	case "answer_type":
+		return card.AnswerType, nil
+	case "payload":
+		return payloadArg(card)
+	}
+	return nil, fmt.Errorf("column %q can't be updated", column)
+}
+
+func replaceOptions(tx *sql.Tx, cardID int64, options []Option) error {
+	query := "DELETE FROM CARD_OPTION WHERE card_id = ?"
+	args := []any{cardID}
+	var kept []any
+	for _, option := range options {
+		if option.OptionID != 0 {
+			kept = append(kept, option.OptionID)
+		}
+	}
+	if len(kept) > 0 {
+		query += " AND option_id NOT IN (?" + strings.Repeat(", ?", len(kept)-1) + ")"
+		args = append(args, kept...)
+	}
+	if _, err := tx.Exec(query, args...); err != nil {
+		return err
+	}
+
+	// Frees the positions so options can swap them
+	if _, err := tx.Exec("UPDATE CARD_OPTION SET position = -1 - position WHERE card_id = ?", cardID); err != nil {
+		return err
+	}
+
+	for position, option := range options {
+		if option.OptionID == 0 {
+			if err := createOption(tx, cardID, position, option); err != nil {
+				return err
+			}
+			continue
+		}
+		_, err := tx.Exec("UPDATE CARD_OPTION SET position = ?, answer = ?, correct = ? WHERE option_id = ? AND card_id = ?",
+			position, option.Answer, option.Correct, option.OptionID, cardID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func createOption(tx *sql.Tx, cardID int64, position int, option Option) error {
+	_, err := tx.Exec("INSERT INTO CARD_OPTION (card_id, position, answer, correct) VALUES (?, ?, ?, ?)",
+		cardID, position, option.Answer, option.Correct)
+	if err != nil {
+		if mysqlErr, ok := err.(*mysql.MySQLError); ok && mysqlErr.Number == 1452 {
+			return erro.ErrCardNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// Regenerates the siblings of a cloze or occlusion card after it changed.
+// The card asks about its first deletion or mask, siblings that ask about
+// one that's gone are deleted with their progress, new ones are created
+// and the rest take the fields of the card
+func syncSiblings(tx *sql.Tx, source Card) error {
+	siblings := source.Siblings()
+	if len(siblings) == 0 {
+		return nil
+	}
+
+	if _, err := tx.Exec("UPDATE CARD SET sibling = ? WHERE card_id = ?", siblings[0], source.CardID); err != nil {
+		return err
+	}
+
+	query := "DELETE FROM CARD WHERE parent_id = ?"
+	args := []any{source.CardID}
+	if len(siblings) > 1 {
+		query += " AND sibling NOT IN (?" + strings.Repeat(", ?", len(siblings)-2) + ")"
+		for _, sibling := range siblings[1:] {
+			args = append(args, sibling)
+		}
+	}
+	if _, err := tx.Exec(query, args...); err != nil {
+		return err
+	}
+
+	upsertStmt, err := tx.Prepare(`INSERT INTO CARD (deck_id, section_id, position, card_type, title, front, back, question, answer_type, parent_id, sibling)
+									VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+									ON DUPLICATE KEY UPDATE title = VALUES(title), front = VALUES(front),
+										back = VALUES(back), question = VALUES(question)`)
 	if err != nil {
 		return err
 	}
+	defer upsertStmt.Close()
+
+	for _, sibling := range siblings[1:] {
+		_, err := upsertStmt.Exec(source.DeckID, source.SectionID, source.Position, source.CardType, source.Title, source.Front,
+			source.Back, source.Question, source.AnswerType, source.CardID, sibling)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
-	result, err := stmt.Exec(args...)
+func (r *CardRepositoryImpl) Delete(cardID int64, deckID int64) error {
+	// No need to delete wrong answers too because ON DELETE CASCADE will delete them
+	result, err := r.DeleteStmt.Exec(cardID, deckID)
 	if err != nil {
 		return err
 	}
@@ -266,9 +468,46 @@ func (r *CardRepositoryImpl) Update(card Card) error {
 	return nil
 }
 
-func (r *CardRepositoryImpl) Delete(cardID int64, deckID int64) error {
-	// No need to delete wrong answers too because ON DELETE CASCADE will delete them
-	result, err := r.DeleteStmt.Exec(cardID, deckID)
+// Applies the changes of a batch in their order, all or nothing. Returns
+// the id of the card of each change, new ones for created cards
+func (r *CardRepositoryImpl) Batch(deckID int64, changes []BatchChange) ([]int64, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]int64, len(changes))
+	for i, change := range changes {
+		ids[i] = change.Card.CardID
+		switch change.Op {
+		case OpCreate:
+			ids[i], err = InsertCard(tx, change.Card)
+		case OpUpdate:
+			err = updateCard(tx, change.Card, change.Columns, change.Options)
+		case OpDelete:
+			err = deleteCard(tx, change.Card.CardID, deckID)
+		case OpMove:
+			err = moveCard(tx, change.Card.CardID, deckID, change.Target, change.Subscribe)
+		default:
+			err = fmt.Errorf("unknown batch operation %q", change.Op)
+		}
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+func deleteCard(tx *sql.Tx, cardID int64, deckID int64) error {
+	result, err := tx.Exec("DELETE FROM CARD WHERE card_id = ? AND deck_id = ?", cardID, deckID)
 	if err != nil {
 		return err
 	}
@@ -277,57 +516,165 @@ func (r *CardRepositoryImpl) Delete(cardID int64, deckID int64) error {
 	if err != nil {
 		return err
 	}
+	if affected == 0 {
+		return erro.ErrCardNotFound
+	}
+	return nil
+}
+
+// Moves a card and its siblings to the end of another deck, outside of
+// any section. Progress is kept by card so it goes along, and so do the
+// comments, suggestions and reports of the cards
+func moveCard(tx *sql.Tx, cardID int64, from int64, to int64, subscribe bool) error {
+	position, err := nextPosition(tx, to)
+	if err != nil {
+		return err
+	}
+
+	result, err := tx.Exec("UPDATE CARD SET deck_id = ?, section_id = NULL, position = ? WHERE (card_id = ? OR parent_id = ?) AND deck_id = ?",
+		to, position, cardID, cardID, from)
+	if err != nil {
+		if mysqlErr, ok := err.(*mysql.MySQLError); ok && mysqlErr.Number == 1452 {
+			return erro.ErrDeckNotFound
+		}
+		return err
+	}
 
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
 	if affected == 0 {
 		return erro.ErrCardNotFound
 	}
 
+	moved := "SELECT card_id FROM CARD WHERE card_id = ? OR parent_id = ?"
+	queries := []string{
+		"UPDATE COMMENT SET deck_id = ? WHERE card_id IN (" + moved + ")",
+		"UPDATE SUGGESTION SET deck_id = ? WHERE card_id IN (" + moved + ")",
+		"UPDATE REPORT SET deck_id = ? WHERE target_type = 'card' AND target_id IN (" + moved + ")",
+	}
+	// Otherwise the cards would leave the study lists of whoever was on them
+	if subscribe {
+		queries = append(queries, "INSERT IGNORE INTO ACC_DECK (acc_id, deck_id) SELECT DISTINCT p.acc_id, ? FROM PROGRESS p WHERE p.card_id IN ("+moved+")")
+	}
+	for _, query := range queries {
+		if _, err := tx.Exec(query, to, cardID, cardID); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
-func (r *CardRepositoryImpl) WrongByCardId(cardID int64) ([]WrongAnswer, error) {
-	var wrong []WrongAnswer
-	rows, err := r.WrongByIdStmt.Query(cardID)
+func (r *CardRepositoryImpl) OptionsByCardId(cardID int64) ([]Option, error) {
+	rows, err := r.OptionsByCardIdStmt.Query(cardID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var wrongAnswer WrongAnswer
+	options := []Option{}
 	for rows.Next() {
+		var option Option
 		err := rows.Scan(
-			&wrongAnswer.WrongID,
-			&wrongAnswer.Answer,
+			&option.OptionID,
+			&option.CardID,
+			&option.Answer,
+			&option.Correct,
 		)
 		if err != nil {
-			return wrong, err
+			return nil, err
 		}
-		wrong = append(wrong, wrongAnswer)
+		options = append(options, option)
 	}
 
-	if len(wrong) == 0 {
-		return nil, erro.ErrWrongNotFound
+	return options, rows.Err()
+}
+
+// Order of the cards of a deck with its sections
+func (r *CardRepositoryImpl) Outline(deckID int64) (Outline, error) {
+	outline := Outline{Sections: []Section{}, Cards: []int64{}}
+	if err := r.NewCardsStmt.QueryRow(deckID).Scan(&outline.NewCards); err != nil {
+		if err == sql.ErrNoRows {
+			return Outline{}, erro.ErrDeckNotFound
+		}
+		return Outline{}, err
 	}
 
-	return wrong, nil
-}
+	sections, err := r.SectionsStmt.Query(deckID)
+	if err != nil {
+		return Outline{}, err
+	}
+	defer sections.Close()
+
+	index := map[int64]int{}
+	for sections.Next() {
+		section := Section{Cards: []int64{}}
+		if err := sections.Scan(&section.SectionID, &section.DeckID, &section.Name, &section.Position); err != nil {
+			return Outline{}, err
+		}
+		index[section.SectionID] = len(outline.Sections)
+		outline.Sections = append(outline.Sections, section)
+	}
+	if err := sections.Err(); err != nil {
+		return Outline{}, err
+	}
+
+	rows, err := r.OutlineStmt.Query(deckID)
+	if err != nil {
+		return Outline{}, err
+	}
+	defer rows.Close()
 
-func (r *CardRepositoryImpl) UpdateWrong(id int64, wrong WrongAnswer) error {
-	var query strings.Builder
-	var args []any
-	query.WriteString("UPDATE WRONG_ANSWER SET")
+	for rows.Next() {
+		var cardID int64
+		var sectionID *int64
+		if err := rows.Scan(&cardID, &sectionID); err != nil {
+			return Outline{}, err
+		}
+		if sectionID != nil {
+			if i, ok := index[*sectionID]; ok {
+				outline.Sections[i].Cards = append(outline.Sections[i].Cards, cardID)
+				continue
+			}
+		}
+		outline.Cards = append(outline.Cards, cardID)
+	}
 
-	updateCardField(&query, &args, "answer", wrong.Answer)
+	return outline, rows.Err()
+}
 
-	args = append(args, id)
-	query.WriteString(" WHERE wrong_id = ?")
+func (r *CardRepositoryImpl) Section(sectionID int64, deckID int64) (Section, error) {
+	var section Section
+	err := r.SectionStmt.QueryRow(sectionID, deckID).Scan(&section.SectionID, &section.DeckID, &section.Name, &section.Position)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return Section{}, erro.ErrSectionNotFound
+		}
+		return Section{}, err
+	}
+	return section, nil
+}
 
-	stmt, err := r.db.Prepare(query.String())
+func (r *CardRepositoryImpl) CreateSection(deckID int64, name string) (int64, error) {
+	result, err := r.CreateSectionStmt.Exec(deckID, name, deckID)
 	if err != nil {
-		return err
+		if mysqlErr, ok := err.(*mysql.MySQLError); ok && mysqlErr.Number == 1452 {
+			return 0, erro.ErrDeckNotFound
+		}
+		return 0, err
 	}
+	return result.LastInsertId()
+}
 
-	result, err := stmt.Exec(args...)
+// The section has to exist, renaming it to the same name changes no rows
+func (r *CardRepositoryImpl) RenameSection(sectionID int64, deckID int64, name string) error {
+	_, err := r.RenameSectionStmt.Exec(name, sectionID, deckID)
+	return err
+}
+
+func (r *CardRepositoryImpl) DeleteSection(sectionID int64, deckID int64) error {
+	result, err := r.DeleteSectionStmt.Exec(sectionID, deckID)
 	if err != nil {
 		return err
 	}
@@ -338,21 +685,99 @@ func (r *CardRepositoryImpl) UpdateWrong(id int64, wrong WrongAnswer) error {
 	}
 
 	if affected == 0 {
-		return erro.ErrCardNotFound
+		return erro.ErrSectionNotFound
 	}
 
 	return nil
 }
 
-func updateCardField(query *strings.Builder, args *[]any, field string, value any) {
-	if value == "" {
-		return
+// Places the sections and cards of a deck in the order of the outline,
+// all or nothing. Siblings take the place of their source card
+func (r *CardRepositoryImpl) Reorder(deckID int64, outline Outline) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	if outline.NewCards != "" {
+		if _, err := tx.Exec("UPDATE DECK SET new_cards = ? WHERE deck_id = ?", outline.NewCards, deckID); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	// Frees the positions so sections can swap them
+	if _, err := tx.Exec("UPDATE SECTION SET position = -1 - position WHERE deck_id = ?", deckID); err != nil {
+		tx.Rollback()
+		return err
 	}
 
-	if query.String() != "UPDATE CARD SET" && query.String() != "UPDATE WRONG_ANSWER SET" {
-		query.WriteString(",")
+	position := 0
+	place := func(sectionID *int64, cards []int64) error {
+		for _, cardID := range cards {
+			_, err := tx.Exec("UPDATE CARD SET section_id = ?, position = ? WHERE (card_id = ? OR parent_id = ?) AND deck_id = ?",
+				sectionID, position, cardID, cardID, deckID)
+			if err != nil {
+				return err
+			}
+			position++
+		}
+		return nil
 	}
 
-	query.WriteString(fmt.Sprintf(" %s = ?", field))
-	*args = append(*args, value)
+	for i, section := range outline.Sections {
+		if _, err := tx.Exec("UPDATE SECTION SET position = ? WHERE section_id = ? AND deck_id = ?", i, section.SectionID, deckID); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err := place(&section.SectionID, section.Cards); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	if err := place(nil, outline.Cards); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Scans from either *sql.Row or *sql.Rows
+func ScanCard(row interface{ Scan(...any) error }) (Card, error) {
+	var card Card
+	var payload []byte
+	err := row.Scan(
+		&card.CardID,
+		&card.DeckID,
+		&card.SectionID,
+		&card.Position,
+		&card.CardType,
+		&card.Title,
+		&card.Front,
+		&card.Back,
+		&card.Question,
+		&card.AnswerType,
+		&payload,
+		&card.ParentID,
+		&card.Sibling,
+	)
+	if err != nil {
+		return Card{}, err
+	}
+
+	if err := card.SetPayload(payload); err != nil {
+		return Card{}, err
+	}
+	return card, nil
+}
+
+// Payload of the card as a query argument, NULL for basic cards
+func payloadArg(card Card) (any, error) {
+	payload, err := card.Payload()
+	if err != nil || payload == nil {
+		return nil, err
+	}
+	return string(payload), nil
 }
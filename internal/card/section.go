@@ -0,0 +1,40 @@
+package card
+
+// How the study queue introduces the new cards of a deck
+const (
+	NewCardsRandom     = "random"     // Shuffled
+	NewCardsSequential = "sequential" // In the order of the deck
+)
+
+const maxSectionName = 100
+
+// Named part of a deck, like a chapter of a lesson
+type Section struct {
+	SectionID int64   `json:"section_id"`
+	DeckID    int64   `json:"deck_id"`
+	Name      string  `json:"name"`
+	Position  int     `json:"position"`
+	Cards     []int64 `json:"cards"` // In their order
+}
+
+// Order of the cards of a deck. Sections go in their order and cards
+// outside of any go after them. Siblings follow the card they were
+// generated from, so they aren't listed
+type Outline struct {
+	NewCards string    `json:"new_cards"`
+	Sections []Section `json:"sections"`
+	Cards    []int64   `json:"cards"`
+}
+
+func ValidNewCards(newCards string) bool {
+	return newCards == NewCardsRandom || newCards == NewCardsSequential
+}
+
+// Every card of the outline in its order
+func (o Outline) ordered() []int64 {
+	var cards []int64
+	for _, section := range o.Sections {
+		cards = append(cards, section.Cards...)
+	}
+	return append(cards, o.Cards...)
+}
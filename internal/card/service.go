@@ -1,76 +1,118 @@
 package card
 
 import (
+	"bytes"
 	"errors"
+	"fmt"
+	"io"
 	"learn-swiping-api/erro"
 	card "learn-swiping-api/internal/card/dto"
-	"strconv"
+	"learn-swiping-api/internal/collaborator"
+	"learn-swiping-api/internal/comment"
+	"learn-swiping-api/internal/picture"
+	"mime/multipart"
+	"path/filepath"
+	"slices"
+	"strings"
 )
 
 type CardService interface {
 	Create(card.CreateRequest) (int64, error)
-	Card(cardID int64, deckID int64) (Card, error)
-	Cards(deckID int64) ([]Card, error)
+	Card(cardID int64, deckID int64, token string) (Card, error)
+	Cards(deckID int64, token string) ([]Card, error)
+	ByDeck(deckID int64) ([]Card, error)
 	ByProgress(token string, deckID int64) ([]Card, error)
 	Update(card.UpdateRequest) error
-	Delete(cardID int64, deckID int64) error
+	Delete(cardID int64, deckID int64, token string) error
+	Batch(card.BatchRequest) ([]BatchResult, error)
+	Outline(deckID int64, token string) (Outline, error)
+	CreateSection(card.SectionRequest) (int64, error)
+	RenameSection(card.SectionRequest) error
+	DeleteSection(sectionID int64, deckID int64, token string) error
+	Reorder(card.OrderRequest) error
+	Grade(card.GradeRequest) (Grade, error)
+	Picture(deckID int64, token string, file *multipart.FileHeader) (string, error)
 }
 
 type CardServiceImpl struct {
-	repository CardRepository
+	repository    CardRepository
+	comments      comment.CommentService
+	collaborators collaborator.CollaboratorService
 }
 
-func NewCardService(repository CardRepository) CardService {
-	return &CardServiceImpl{repository: repository}
+func NewCardService(repository CardRepository, comments comment.CommentService, collaborators collaborator.CollaboratorService) CardService {
+	return &CardServiceImpl{repository: repository, comments: comments, collaborators: collaborators}
 }
 
 func (s *CardServiceImpl) Create(request card.CreateRequest) (int64, error) {
-	if request.Title == "" || len(request.Wrong) != 3 { // All cards should have four answers, one OK three bad
-		return 0, erro.ErrBadField
-	}
-
-	// Due to poor design choices this is necessary hahah
-	wrongAnswers := make([]WrongAnswer, 0, len(request.Wrong))
-	for _, value := range request.Wrong {
-		wrongAnswers = append(wrongAnswers, WrongAnswer{Answer: value.Answer})
+	card, err := newCard(request)
+	if err != nil {
+		return 0, err
 	}
 
-	card := Card{
-		DeckID:   request.DeckID,
-		Title:    request.Title,
-		Front:    request.Front,
-		Back:     request.Back,
-		Question: request.Question,
-		Answer:   request.Answer,
-		Wrong:    wrongAnswers,
+	if _, err := s.collaborators.Authorize(request.DeckID, request.Token, collaborator.WriteCards); err != nil {
+		return 0, err
 	}
 
 	return s.repository.Create(card)
 }
 
-func (s *CardServiceImpl) Card(cardID int64, deckID int64) (Card, error) {
+// Card with its options and comments. Hidden decks look like they don't
+// exist to anyone who can't read them
+func (s *CardServiceImpl) Card(cardID int64, deckID int64, token string) (Card, error) {
+	if _, err := s.collaborators.Authorize(deckID, token, collaborator.ReadDeck); err != nil {
+		return Card{}, err
+	}
+
 	card, err := s.repository.ById(cardID, deckID)
 	if err != nil {
 		return Card{}, err
 	}
 
-	card.Wrong, err = s.repository.WrongByCardId(cardID)
+	card.Options, err = s.repository.OptionsByCardId(cardID)
+	if err != nil {
+		return Card{}, err
+	}
+
+	card.Comments, err = s.comments.Thread(deckID, &cardID)
 	if err != nil {
 		return Card{}, err
 	}
 
+	card.render()
 	return card, nil
 }
 
-func (s *CardServiceImpl) Cards(deckID int64) ([]Card, error) {
-	// Wrong answers should only be needed when viewing one
+func (s *CardServiceImpl) Cards(deckID int64, token string) ([]Card, error) {
+	if _, err := s.collaborators.Authorize(deckID, token, collaborator.ReadDeck); err != nil {
+		return nil, err
+	}
+
+	return s.ByDeck(deckID)
+}
+
+// Cards of a deck for callers that already granted access, like share
+// links, so nobody is asked
+func (s *CardServiceImpl) ByDeck(deckID int64) ([]Card, error) {
+	// Options should only be needed when viewing one
 	// card at most
-	return s.repository.ByDeckId(deckID)
+	cards, err := s.repository.ByDeckId(deckID)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range cards {
+		cards[i].render()
+	}
+	return cards, nil
 }
 
+// Study queue of the caller on a deck
 func (s *CardServiceImpl) ByProgress(token string, deckID int64) ([]Card, error) {
-	// Wrong answers should only be needed when viewing one
-	// card at most
+	if _, err := s.collaborators.Authorize(deckID, token, collaborator.ReadDeck); err != nil {
+		return []Card{}, err
+	}
+
 	cards, err := s.repository.ByProgress(token, deckID)
 	if err != nil {
 		return []Card{}, err
@@ -78,53 +120,480 @@ func (s *CardServiceImpl) ByProgress(token string, deckID int64) ([]Card, error)
 
 	// TODO: Do this in the repository query
 	for i := 0; i < len(cards); i++ {
-		cards[i].Wrong, err = s.repository.WrongByCardId(cards[i].CardID)
+		cards[i].Options, err = s.repository.OptionsByCardId(cards[i].CardID)
 		if err != nil {
 			return []Card{}, err
 		}
+		cards[i].render()
 	}
 
 	return cards, nil
 }
 
+// Applies a merge patch to a card in one go. Fields that can't be applied
+// come back in a ValidationError
 func (s *CardServiceImpl) Update(request card.UpdateRequest) error {
-	if request.Title != "" || request.Front != "" || request.Back != "" || request.Question != "" || request.Answer != "" {
-		card := Card{
-			CardID:   request.CardID,
-			DeckID:   request.DeckID,
-			Title:    request.Title,
-			Front:    request.Front,
-			Back:     request.Back,
-			Question: request.Question,
-			Answer:   request.Answer,
-		}
-
-		err := s.repository.Update(card)
+	if _, err := s.collaborators.Authorize(request.DeckID, request.Token, collaborator.WriteCards); err != nil {
+		return err
+	}
+
+	// Siblings change with the card they were generated from
+	current, err := s.repository.ById(request.CardID, request.DeckID)
+	if err != nil {
+		return err
+	}
+	if current.ParentID != nil {
+		return erro.ErrCardGenerated
+	}
+
+	current.Options, err = s.repository.OptionsByCardId(request.CardID)
+	if err != nil {
+		return err
+	}
+
+	patched, columns, optionsChanged, err := applyPatch(current, request)
+	if err != nil {
+		return err
+	}
+
+	var options []Option
+	if optionsChanged {
+		options = patched.Options
+	}
+	if len(columns) == 0 && options == nil {
+		return nil
+	}
+	return s.repository.Update(patched, columns, options)
+}
+
+func (s *CardServiceImpl) Delete(cardID int64, deckID int64, token string) error {
+	if _, err := s.collaborators.Authorize(deckID, token, collaborator.WriteCards); err != nil {
+		return err
+	}
+
+	// Siblings go away with the card they were generated from
+	current, err := s.repository.ById(cardID, deckID)
+	if err != nil {
+		return err
+	}
+	if current.ParentID != nil {
+		return erro.ErrCardGenerated
+	}
+
+	return s.repository.Delete(cardID, deckID)
+}
+
+// Applies the operations of a batch in one go, in their order. When any
+// of them can't be applied nothing is, and the results tell why
+func (s *CardServiceImpl) Batch(request card.BatchRequest) ([]BatchResult, error) {
+	if len(request.Operations) == 0 || len(request.Operations) > maxBatch {
+		errs := &ValidationError{}
+		errs.add("operations", "needs 1 to %d operations, has %d", maxBatch, len(request.Operations))
+		return nil, errs
+	}
+
+	if _, err := s.collaborators.Authorize(request.DeckID, request.Token, collaborator.WriteCards); err != nil {
+		return nil, err
+	}
+
+	cards, err := s.repository.ByDeckId(request.DeckID)
+	if err != nil && !errors.Is(err, erro.ErrCardNotFound) {
+		return nil, err
+	}
+	current := make(map[int64]Card, len(cards))
+	for _, c := range cards {
+		current[c.CardID] = c
+	}
+
+	results := make([]BatchResult, len(request.Operations))
+	changes := make([]BatchChange, len(request.Operations))
+	targets := map[int64]target{}
+	seen := map[int64]int{}
+	invalid := false
+	for i, operation := range request.Operations {
+		results[i] = BatchResult{Index: i, Op: operation.Op, CardID: operation.CardID, Status: StatusSkipped}
+
+		// Each card can only change once
+		if first, ok := seen[operation.CardID]; ok && operation.Op != OpCreate {
+			results[i].Status = StatusInvalid
+			results[i].Errors = []FieldError{{Field: "card_id", Error: fmt.Sprintf("repeated, already in operation %d", first)}}
+			invalid = true
+			continue
+		}
+		if operation.CardID != 0 && operation.Op != OpCreate {
+			seen[operation.CardID] = i
+		}
+
+		changes[i], err = s.batchChange(request, operation, current, targets)
+		var validation *ValidationError
+		if errors.As(err, &validation) {
+			results[i].Status = StatusInvalid
+			results[i].Errors = validation.Fields
+			invalid = true
+		} else if err != nil {
+			return nil, err
+		}
+	}
+
+	if invalid {
+		return results, erro.ErrBatchInvalid
+	}
+
+	ids, err := s.repository.Batch(request.DeckID, changes)
+	if err != nil {
+		return nil, err
+	}
+	for i := range results {
+		results[i].CardID = ids[i]
+		results[i].Status = StatusApplied
+	}
+	return results, nil
+}
+
+// Change an operation of a batch makes on the current cards of the deck.
+// Targets of moves are authorized once and kept in targets
+func (s *CardServiceImpl) batchChange(request card.BatchRequest, operation card.BatchOperation, current map[int64]Card, targets map[int64]target) (BatchChange, error) {
+	errs := &ValidationError{}
+	if !slices.Contains(batchOps, operation.Op) {
+		errs.add("op", "has to be one of %s", strings.Join(batchOps, ", "))
+		return BatchChange{}, errs
+	}
+
+	if operation.Op == OpCreate {
+		if operation.Card == nil {
+			errs.add("card", "required")
+			return BatchChange{}, errs
+		}
+		create := *operation.Card
+		create.DeckID = request.DeckID
+		c, err := newCard(create)
 		if err != nil {
-			return err
+			return BatchChange{}, nested("card", err)
 		}
+		return BatchChange{Op: OpCreate, Card: c}, nil
 	}
 
-	// TODO: redo
-	if len(request.Wrong) > 0 {
-		affected := 0
-		for _, answer := range request.Wrong {
-			if answer.WrongID != 0 && answer.Answer != "" {
-				err := s.repository.UpdateWrong(answer.WrongID, WrongAnswer{Answer: answer.Answer})
-				if err != nil {
-					return err
-				}
-				affected++
+	// Siblings change with the card they were generated from
+	c, ok := current[operation.CardID]
+	switch {
+	case operation.CardID == 0:
+		errs.add("card_id", "required")
+	case !ok:
+		errs.add("card_id", "not a card of the deck")
+	case c.ParentID != nil:
+		errs.add("card_id", "%s", erro.ErrCardGenerated.Error())
+	}
+	if len(errs.Fields) > 0 {
+		return BatchChange{}, errs
+	}
+
+	switch operation.Op {
+	case OpUpdate:
+		if operation.Patch == nil {
+			errs.add("patch", "required")
+			return BatchChange{}, errs
+		}
+		var err error
+		c.Options, err = s.repository.OptionsByCardId(c.CardID)
+		if err != nil {
+			return BatchChange{}, err
+		}
+		patched, columns, optionsChanged, err := applyPatch(c, *operation.Patch)
+		if err != nil {
+			return BatchChange{}, nested("patch", err)
+		}
+		change := BatchChange{Op: OpUpdate, Card: patched, Columns: columns}
+		if optionsChanged {
+			change.Options = patched.Options
+		}
+		return change, nil
+
+	case OpMove:
+		if operation.DeckID == 0 || operation.DeckID == request.DeckID {
+			errs.add("deck_id", "has to be another deck")
+			return BatchChange{}, errs
+		}
+		t, ok := targets[operation.DeckID]
+		if !ok {
+			access, err := s.collaborators.Authorize(operation.DeckID, request.Token, collaborator.WriteCards)
+			if err != nil && !errors.Is(err, erro.ErrForbidden) && !errors.Is(err, erro.ErrDeckNotFound) {
+				return BatchChange{}, err
 			}
+			t = target{visible: access.Visible, err: err}
+			targets[operation.DeckID] = t
 		}
-		if affected < len(request.Wrong) {
-			return errors.New("some answers were not updated. updated: " + strconv.Itoa(affected))
+		if t.err != nil {
+			errs.add("deck_id", "%s", t.err.Error())
+			return BatchChange{}, errs
 		}
+		return BatchChange{Op: OpMove, Card: c, Target: operation.DeckID, Subscribe: t.visible}, nil
 	}
 
-	return nil
+	return BatchChange{Op: OpDelete, Card: c}, nil
 }
 
-func (s *CardServiceImpl) Delete(cardID int64, deckID int64) error {
-	return s.repository.Delete(cardID, deckID)
+// Order of the cards of a deck the caller can read
+func (s *CardServiceImpl) Outline(deckID int64, token string) (Outline, error) {
+	if _, err := s.collaborators.Authorize(deckID, token, collaborator.ReadDeck); err != nil {
+		return Outline{}, err
+	}
+
+	return s.repository.Outline(deckID)
+}
+
+// Adds a section after the last one of the deck. Returns its id
+func (s *CardServiceImpl) CreateSection(request card.SectionRequest) (int64, error) {
+	request.Name = strings.TrimSpace(request.Name)
+	if request.Name == "" || len(request.Name) > maxSectionName {
+		return 0, erro.ErrBadField
+	}
+
+	if _, err := s.collaborators.Authorize(request.DeckID, request.Token, collaborator.WriteCards); err != nil {
+		return 0, err
+	}
+
+	return s.repository.CreateSection(request.DeckID, request.Name)
+}
+
+func (s *CardServiceImpl) RenameSection(request card.SectionRequest) error {
+	request.Name = strings.TrimSpace(request.Name)
+	if request.Name == "" || len(request.Name) > maxSectionName {
+		return erro.ErrBadField
+	}
+
+	if _, err := s.collaborators.Authorize(request.DeckID, request.Token, collaborator.WriteCards); err != nil {
+		return err
+	}
+
+	if _, err := s.repository.Section(request.SectionID, request.DeckID); err != nil {
+		return err
+	}
+
+	return s.repository.RenameSection(request.SectionID, request.DeckID, request.Name)
+}
+
+// Deletes a section of a deck. Its cards are left outside of any section,
+// after the rest
+func (s *CardServiceImpl) DeleteSection(sectionID int64, deckID int64, token string) error {
+	if _, err := s.collaborators.Authorize(deckID, token, collaborator.WriteCards); err != nil {
+		return err
+	}
+
+	return s.repository.DeleteSection(sectionID, deckID)
+}
+
+// Places the sections and cards of a deck in a new order. Every one of
+// them has to be placed once, what can't be comes back in a
+// ValidationError
+func (s *CardServiceImpl) Reorder(request card.OrderRequest) error {
+	if _, err := s.collaborators.Authorize(request.DeckID, request.Token, collaborator.WriteCards); err != nil {
+		return err
+	}
+
+	current, err := s.repository.Outline(request.DeckID)
+	if err != nil {
+		return err
+	}
+
+	errs := &ValidationError{}
+	if request.NewCards != "" && !ValidNewCards(request.NewCards) {
+		errs.add("new_cards", "has to be %s or %s", NewCardsRandom, NewCardsSequential)
+	}
+
+	// Whether each section and card was placed
+	sections := map[int64]bool{}
+	for _, section := range current.Sections {
+		sections[section.SectionID] = false
+	}
+	cards := map[int64]bool{}
+	for _, cardID := range current.ordered() {
+		cards[cardID] = false
+	}
+
+	place := func(field string, ids []int64) {
+		for i, cardID := range ids {
+			placed, ok := cards[cardID]
+			switch {
+			case !ok:
+				errs.add(fmt.Sprintf("%s[%d]", field, i), "not a card of the deck")
+			case placed:
+				errs.add(fmt.Sprintf("%s[%d]", field, i), "repeated")
+			default:
+				cards[cardID] = true
+			}
+		}
+	}
+
+	outline := Outline{NewCards: request.NewCards, Sections: make([]Section, 0, len(request.Sections)), Cards: request.Cards}
+	for i, section := range request.Sections {
+		field := fmt.Sprintf("sections[%d]", i)
+		placed, ok := sections[section.SectionID]
+		switch {
+		case !ok:
+			errs.add(field+".section_id", "not a section of the deck")
+		case placed:
+			errs.add(field+".section_id", "repeated")
+		default:
+			sections[section.SectionID] = true
+		}
+		place(field+".cards", section.Cards)
+		outline.Sections = append(outline.Sections, Section{SectionID: section.SectionID, Cards: section.Cards})
+	}
+	place("cards", request.Cards)
+
+	// Listed in the current order so errors always come out the same
+	for _, section := range current.Sections {
+		if !sections[section.SectionID] {
+			errs.add("sections", "section %d has to be placed", section.SectionID)
+		}
+	}
+	for _, cardID := range current.ordered() {
+		if !cards[cardID] {
+			errs.add("cards", "card %d has to be placed", cardID)
+		}
+	}
+
+	if len(errs.Fields) > 0 {
+		return errs
+	}
+	return s.repository.Reorder(request.DeckID, outline)
+}
+
+// Grades an answer to a card of a deck the caller can read
+func (s *CardServiceImpl) Grade(request card.GradeRequest) (Grade, error) {
+	if _, err := s.collaborators.Authorize(request.DeckID, request.Token, collaborator.ReadDeck); err != nil {
+		return Grade{}, err
+	}
+
+	c, err := s.repository.ById(request.CardID, request.DeckID)
+	if err != nil {
+		return Grade{}, err
+	}
+
+	if c.CardType == TypeBasic {
+		c.Options, err = s.repository.OptionsByCardId(c.CardID)
+		if err != nil {
+			return Grade{}, err
+		}
+	} else if c.tooLong(request.Answer) {
+		return Grade{}, erro.ErrBadField
+	}
+
+	return c.Grade(request.Answer, request.Options), nil
+}
+
+// Stores a picture for the cards of a deck, like the one of an occlusion
+// card. Returns its id
+func (s *CardServiceImpl) Picture(deckID int64, token string, file *multipart.FileHeader) (string, error) {
+	if _, err := s.collaborators.Authorize(deckID, token, collaborator.WriteCards); err != nil {
+		return "", err
+	}
+
+	img, err := file.Open()
+	if err != nil {
+		return "", erro.ErrBadField
+	}
+	defer img.Close()
+
+	buf := bytes.NewBuffer(nil)
+	if _, err := io.Copy(buf, img); err != nil {
+		return "", erro.ErrBadField
+	}
+
+	return picture.Store(filepath.Ext(file.Filename), buf.Bytes())
+}
+
+// Card a create request makes. Fields that can't be used come back in a
+// ValidationError
+func newCard(request card.CreateRequest) (Card, error) {
+	if request.CardType == "" {
+		request.CardType = TypeBasic
+	}
+	errs := &ValidationError{}
+	if !ValidCardType(request.CardType) {
+		errs.add("card_type", "unknown card type %q", request.CardType)
+		return Card{}, errs
+	}
+
+	// Older clients send the correct answer and the wrong ones in Answer
+	// and Wrong instead of Options, so those become the options when no
+	// options were sent
+	options := make([]Option, 0, len(request.Options)+len(request.Wrong)+1)
+	for _, value := range request.Options {
+		options = append(options, Option{Answer: value.Answer, Correct: value.Correct})
+	}
+	if len(request.Options) == 0 && request.Answer != "" {
+		options = append(options, Option{Answer: request.Answer, Correct: true})
+		for _, value := range request.Wrong {
+			options = append(options, Option{Answer: value.Answer})
+		}
+	}
+
+	c := Card{
+		DeckID:     request.DeckID,
+		CardType:   request.CardType,
+		Title:      request.Title,
+		Front:      request.Front,
+		Back:       request.Back,
+		Question:   request.Question,
+		AnswerType: request.AnswerType,
+		Options:    options,
+	}
+	c.Cloze, c.Typed, c.Occlusion = payload(request.Cloze, request.Typed, request.Occlusion)
+
+	// Basic cards need every field, typed ones what to ask. Generated
+	// ones are made from their payload
+	required := []struct {
+		name  string
+		value string
+		when  bool
+	}{
+		{"title", c.Title, true},
+		{"front", c.Front, c.CardType == TypeBasic},
+		{"back", c.Back, c.CardType == TypeBasic},
+		{"question", c.Question, c.CardType == TypeBasic || c.CardType == TypeTyped},
+	}
+	for _, r := range required {
+		if r.when && r.value == "" {
+			errs.add(r.name, "required")
+		}
+	}
+	if c.CardType == TypeBasic && c.AnswerType == "" {
+		c.AnswerType = AnswerSingle
+	} else if c.CardType != TypeBasic && c.AnswerType != "" {
+		errs.add("answer_type", "only basic cards have an answer type")
+	}
+
+	if len(errs.Fields) == 0 {
+		if err := ValidatePayload(c); err != nil {
+			errs.add(payloadFields[c.CardType], "%s", err.Error())
+		} else if c.Occlusion != nil && !picture.Exists(c.Occlusion.PicID) {
+			errs.add("occlusion", "picture not found")
+		}
+	}
+
+	if len(errs.Fields) > 0 {
+		return Card{}, errs
+	}
+	return c, nil
+}
+
+// Payload of a card from the one of a request
+func payload(cloze *card.ClozeRequest, typed *card.TypedRequest, occlusion *card.OcclusionRequest) (*Cloze, *Typed, *Occlusion) {
+	var c *Cloze
+	var t *Typed
+	var o *Occlusion
+	if cloze != nil {
+		c = &Cloze{Text: cloze.Text}
+	}
+	if typed != nil {
+		t = &Typed{Answers: typed.Answers, CaseSensitive: typed.CaseSensitive, Threshold: typed.Threshold}
+	}
+	if occlusion != nil {
+		o = &Occlusion{PicID: occlusion.PicID, Masks: make([]Mask, 0, len(occlusion.Masks))}
+		for _, m := range occlusion.Masks {
+			o.Masks = append(o.Masks, Mask{Label: m.Label, X: m.X, Y: m.Y, Width: m.Width, Height: m.Height})
+		}
+	}
+	return c, t, o
 }
@@ -0,0 +1,226 @@
+package card
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// What a card asks and how it's answered
+const (
+	TypeBasic     = "basic"     // Question with answer options
+	TypeCloze     = "cloze"     // Text with deletions, one card per deletion
+	TypeTyped     = "typed"     // Answer typed by hand, graded with fuzzy matching
+	TypeOcclusion = "occlusion" // Picture with masked areas, one card per mask
+)
+
+var cardTypes = []string{TypeBasic, TypeCloze, TypeTyped, TypeOcclusion}
+
+const (
+	maxSiblings    = 20 // Deletions or masks of a card
+	maxTyped       = 10 // Accepted answers of a typed card
+	typedThreshold = 0.8
+)
+
+var (
+	// Deletions look like {{c1::answer}} or {{c1::answer::hint}}
+	clozeRegexp   = regexp.MustCompile(`\{\{c(\d+)::(.*?)(?:::(.*?))?\}\}`)
+	pictureRegexp = regexp.MustCompile(`^[0-9a-f]{64}\.(?:png|jpeg|webp)$`)
+)
+
+// Text with deletions. Deletions with the same number are asked together
+type Cloze struct {
+	Text string `json:"text"`
+}
+
+// Answers accepted for a typed card. Threshold is the similarity needed
+// to count a misspelled answer as correct, 1 only takes exact ones
+type Typed struct {
+	Answers       []string `json:"answers"`
+	CaseSensitive bool     `json:"case_sensitive"`
+	Threshold     float64  `json:"threshold,omitempty"` // typedThreshold when 0
+}
+
+// Uploaded picture with the areas to guess
+type Occlusion struct {
+	PicID string `json:"pic_id"`
+	Masks []Mask `json:"masks"`
+}
+
+// Area of the picture, as fractions of its width and height
+type Mask struct {
+	Label  string  `json:"label"`
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+	Width  float64 `json:"width"`
+	Height float64 `json:"height"`
+}
+
+func ValidCardType(cardType string) bool {
+	return slices.Contains(cardTypes, cardType)
+}
+
+// Checks the card has the payload of its type and nothing else. The
+// error tells what's wrong with it
+func ValidatePayload(c Card) error {
+	payloads := 0
+	for _, set := range []bool{c.Cloze != nil, c.Typed != nil, c.Occlusion != nil} {
+		if set {
+			payloads++
+		}
+	}
+	if c.CardType != TypeBasic && len(c.Options) > 0 {
+		return errors.New("only basic cards have options")
+	}
+
+	switch c.CardType {
+	case TypeBasic:
+		if payloads > 0 {
+			return errors.New("basic cards have no payload")
+		}
+		return ValidateOptions(c.AnswerType, c.Options)
+	case TypeCloze:
+		if payloads != 1 || c.Cloze == nil {
+			return errors.New("cloze cards need a cloze payload")
+		}
+		return c.Cloze.validate()
+	case TypeTyped:
+		if payloads != 1 || c.Typed == nil {
+			return errors.New("typed cards need a typed payload")
+		}
+		return c.Typed.validate()
+	case TypeOcclusion:
+		if payloads != 1 || c.Occlusion == nil {
+			return errors.New("occlusion cards need an occlusion payload")
+		}
+		return c.Occlusion.validate()
+	}
+	return errors.New("unknown card type")
+}
+
+func (cl *Cloze) validate() error {
+	deletions := clozeRegexp.FindAllStringSubmatch(cl.Text, -1)
+	if len(deletions) == 0 {
+		return errors.New("needs at least one deletion like {{c1::answer}}")
+	}
+	for _, d := range deletions {
+		if n, err := strconv.Atoi(d[1]); err != nil || n < 1 {
+			return fmt.Errorf("deletion %q has to be numbered from 1", d[0])
+		}
+		if strings.TrimSpace(d[2]) == "" {
+			return fmt.Errorf("deletion %q is empty", d[0])
+		}
+	}
+	if len(cl.numbers()) > maxSiblings {
+		return fmt.Errorf("can have up to %d deletions", maxSiblings)
+	}
+	return nil
+}
+
+// Numbers of the deletions, in order
+func (cl *Cloze) numbers() []int {
+	var numbers []int
+	for _, d := range clozeRegexp.FindAllStringSubmatch(cl.Text, -1) {
+		if n, err := strconv.Atoi(d[1]); err == nil && !slices.Contains(numbers, n) {
+			numbers = append(numbers, n)
+		}
+	}
+	slices.Sort(numbers)
+	return numbers
+}
+
+// Answers of the deletions with a number
+func (cl *Cloze) answers(number int) []string {
+	var answers []string
+	for _, d := range clozeRegexp.FindAllStringSubmatch(cl.Text, -1) {
+		if d[1] == strconv.Itoa(number) {
+			answers = append(answers, d[2])
+		}
+	}
+	return answers
+}
+
+func (t *Typed) validate() error {
+	if len(t.Answers) == 0 || len(t.Answers) > maxTyped {
+		return fmt.Errorf("needs 1 to %d answers, has %d", maxTyped, len(t.Answers))
+	}
+	for _, answer := range t.Answers {
+		if strings.TrimSpace(answer) == "" {
+			return errors.New("answers can't be empty")
+		}
+	}
+	if t.Threshold < 0 || t.Threshold > 1 {
+		return errors.New("threshold has to be between 0 and 1")
+	}
+	return nil
+}
+
+func (o *Occlusion) validate() error {
+	if !pictureRegexp.MatchString(o.PicID) {
+		return errors.New("needs an uploaded picture")
+	}
+	if len(o.Masks) == 0 || len(o.Masks) > maxSiblings {
+		return fmt.Errorf("needs 1 to %d masks, has %d", maxSiblings, len(o.Masks))
+	}
+	for i, m := range o.Masks {
+		if strings.TrimSpace(m.Label) == "" {
+			return fmt.Errorf("mask %d needs a label", i+1)
+		}
+		if m.X < 0 || m.Y < 0 || m.Width <= 0 || m.Height <= 0 || m.X+m.Width > 1 || m.Y+m.Height > 1 {
+			return fmt.Errorf("mask %d is outside of the picture", i+1)
+		}
+	}
+	return nil
+}
+
+// Deletions or masks the card and its siblings ask about. The card itself
+// asks about the first one
+func (c Card) Siblings() []int {
+	switch {
+	case c.CardType == TypeCloze && c.Cloze != nil:
+		return c.Cloze.numbers()
+	case c.CardType == TypeOcclusion && c.Occlusion != nil:
+		siblings := make([]int, len(c.Occlusion.Masks))
+		for i := range siblings {
+			siblings[i] = i + 1
+		}
+		return siblings
+	}
+	return nil
+}
+
+// Payload of the card type as stored, nil for basic cards
+func (c Card) Payload() ([]byte, error) {
+	switch c.CardType {
+	case TypeCloze:
+		return json.Marshal(c.Cloze)
+	case TypeTyped:
+		return json.Marshal(c.Typed)
+	case TypeOcclusion:
+		return json.Marshal(c.Occlusion)
+	}
+	return nil, nil
+}
+
+// Reads a stored payload into the field of the card type
+func (c *Card) SetPayload(payload []byte) error {
+	if len(payload) == 0 {
+		return nil
+	}
+	switch c.CardType {
+	case TypeCloze:
+		c.Cloze = &Cloze{}
+		return json.Unmarshal(payload, c.Cloze)
+	case TypeTyped:
+		c.Typed = &Typed{}
+		return json.Unmarshal(payload, c.Typed)
+	case TypeOcclusion:
+		c.Occlusion = &Occlusion{}
+		return json.Unmarshal(payload, c.Occlusion)
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package collaborator
+
+import (
+	"slices"
+	"time"
+)
+
+const (
+	Viewer     = "viewer"
+	Editor     = "editor"
+	Maintainer = "maintainer"
+	// Taken from DECK.acc_id. Can't be assigned
+	Owner = "owner"
+)
+
+// Assignable roles
+var Roles = []string{Viewer, Editor, Maintainer}
+
+type Permission string
+
+const (
+	ReadDeck            Permission = "deck:read" // Granted to everyone on visible decks
+	WriteCards          Permission = "cards:write"
+	WriteDeck           Permission = "deck:write"
+	PinComments         Permission = "comments:pin"
+	ReplyReviews        Permission = "reviews:reply"
+	ManageCollaborators Permission = "collaborators:manage"
+	DeleteDeck          Permission = "deck:delete"
+	TransferDeck        Permission = "deck:transfer"
+	ShareDeck           Permission = "deck:share"
+)
+
+var permissions = map[string][]Permission{
+	Viewer:     {ReadDeck},
+	Editor:     {ReadDeck, WriteCards},
+	Maintainer: {ReadDeck, WriteCards, WriteDeck, PinComments, ReplyReviews, ManageCollaborators, ShareDeck},
+	Owner:      {ReadDeck, WriteCards, WriteDeck, PinComments, ReplyReviews, ManageCollaborators, ShareDeck, DeleteDeck, TransferDeck},
+}
+
+func Permissions(role string) []Permission {
+	return permissions[role]
+}
+
+func Can(role string, permission Permission) bool {
+	return slices.Contains(permissions[role], permission)
+}
+
+// Roles can only be granted or changed by someone above them
+func rank(role string) int {
+	switch role {
+	case Viewer:
+		return 1
+	case Editor:
+		return 2
+	case Maintainer:
+		return 3
+	case Owner:
+		return 4
+	}
+	return 0
+}
+
+// What an account can do on a deck. AccID is 0 without a valid token
+// and Role is empty for accounts not working on the deck. Subscribers
+// can read hidden decks, they got there through a share link
+type Access struct {
+	AccID      int64
+	Role       string
+	Visible    bool
+	Subscribed bool
+}
+
+func (a Access) Can(permission Permission) bool {
+	if permission == ReadDeck && (a.Visible || a.Subscribed) {
+		return true
+	}
+	return Can(a.Role, permission)
+}
+
+type Collaborator struct {
+	DeckID     int64      `json:"deck_id"`
+	DeckTitle  string     `json:"deck_title,omitempty"`
+	AccID      int64      `json:"acc_id"`
+	Username   string     `json:"username"`
+	Role       string     `json:"role"`
+	InvitedBy  *int64     `json:"invited_by"`
+	InvitedAt  time.Time  `json:"invited_at"`
+	AcceptedAt *time.Time `json:"accepted_at"`
+}
@@ -0,0 +1,196 @@
+package collaborator
+
+import (
+	"errors"
+	"learn-swiping-api/erro"
+	collaborator "learn-swiping-api/internal/collaborator/dto"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CollaboratorController interface {
+	Collaborators(*gin.Context) // GET
+	Invite(*gin.Context)        // POST
+	SetRole(*gin.Context)       // PUT
+	Remove(*gin.Context)        // DELETE
+	Invitations(*gin.Context)   // GET
+	Accept(*gin.Context)        // POST
+	Decline(*gin.Context)       // DELETE
+}
+
+type CollaboratorControllerImpl struct {
+	service CollaboratorService
+}
+
+func NewCollaboratorController(service CollaboratorService) CollaboratorController {
+	return &CollaboratorControllerImpl{service: service}
+}
+
+// Lists the collaborators of a deck and its pending invitations
+// Method: GET
+func (c *CollaboratorControllerImpl) Collaborators(ctx *gin.Context) {
+	token, deckID, ok := deckParams(ctx)
+	if !ok {
+		return
+	}
+
+	collaborators, err := c.service.Collaborators(deckID, token)
+	if err != nil {
+		collaboratorError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, collaborators)
+}
+
+// Invites an account to work on a deck
+// Method: POST
+func (c *CollaboratorControllerImpl) Invite(ctx *gin.Context) {
+	token, deckID, ok := deckParams(ctx)
+	if !ok {
+		return
+	}
+
+	var request collaborator.InviteRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrBadField.Error()})
+		return
+	}
+	request.Token = token
+	request.DeckID = deckID
+
+	if err := c.service.Invite(request); err != nil {
+		collaboratorError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{})
+}
+
+// Changes the role of a collaborator
+// Method: PUT
+func (c *CollaboratorControllerImpl) SetRole(ctx *gin.Context) {
+	token, deckID, ok := deckParams(ctx)
+	if !ok {
+		return
+	}
+
+	var request collaborator.RoleRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrBadField.Error()})
+		return
+	}
+
+	if err := c.service.SetRole(deckID, ctx.Param("username"), request.Role, token); err != nil {
+		collaboratorError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{})
+}
+
+// Removes a collaborator, withdraws an invitation or leaves the deck
+// Method: DELETE
+func (c *CollaboratorControllerImpl) Remove(ctx *gin.Context) {
+	token, deckID, ok := deckParams(ctx)
+	if !ok {
+		return
+	}
+
+	if err := c.service.Remove(deckID, ctx.Param("username"), token); err != nil {
+		collaboratorError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{})
+}
+
+// Lists the pending invitations of the caller
+// Method: GET
+func (c *CollaboratorControllerImpl) Invitations(ctx *gin.Context) {
+	token := ctx.GetHeader("Token")
+	if token == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrInvalidToken.Error()})
+		return
+	}
+
+	invitations, err := c.service.Invitations(token)
+	if err != nil {
+		collaboratorError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, invitations)
+}
+
+// Accepts the invitation to a deck
+// Method: POST
+func (c *CollaboratorControllerImpl) Accept(ctx *gin.Context) {
+	token, deckID, ok := deckParams(ctx)
+	if !ok {
+		return
+	}
+
+	if err := c.service.Accept(deckID, token); err != nil {
+		collaboratorError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{})
+}
+
+// Declines the invitation to a deck
+// Method: DELETE
+func (c *CollaboratorControllerImpl) Decline(ctx *gin.Context) {
+	token, deckID, ok := deckParams(ctx)
+	if !ok {
+		return
+	}
+
+	if err := c.service.Decline(deckID, token); err != nil {
+		collaboratorError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{})
+}
+
+// Reads the token and the deck id
+func deckParams(ctx *gin.Context) (string, int64, bool) {
+	token := ctx.GetHeader("Token")
+	if token == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrInvalidToken.Error()})
+		return "", 0, false
+	}
+
+	deckID, err := strconv.Atoi(ctx.Param("deckID"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrBadField.Error()})
+		return "", 0, false
+	}
+
+	return token, int64(deckID), true
+}
+
+func collaboratorError(ctx *gin.Context, err error) {
+	if errors.Is(err, erro.ErrBadField) || errors.Is(err, erro.ErrInvalidToken) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, erro.ErrForbidden) {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, erro.ErrDeckNotFound) || errors.Is(err, erro.ErrAccountNotFound) ||
+		errors.Is(err, erro.ErrCollaboratorNotFound) || errors.Is(err, erro.ErrInvitationNotFound) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, erro.ErrCollaboratorExists) {
+		ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
@@ -0,0 +1,8 @@
+package collaborator
+
+type InviteRequest struct {
+	Token    string
+	DeckID   int64
+	Username string `json:"username" binding:"required"`
+	Role     string `json:"role" binding:"required"`
+}
@@ -0,0 +1,5 @@
+package collaborator
+
+type RoleRequest struct {
+	Role string `json:"role" binding:"required"`
+}
@@ -0,0 +1,288 @@
+package collaborator
+
+import (
+	"database/sql"
+	"learn-swiping-api/erro"
+	"log"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+type CollaboratorRepository interface {
+	Access(deckID int64, token string) (Access, error)
+	ByToken(token string) (int64, error)
+	Account(username string) (int64, error)
+	Invite(deckID int64, accID int64, role string, invitedBy int64) error
+	Collaborators(deckID int64) ([]Collaborator, error)
+	ByAccount(deckID int64, accID int64) (Collaborator, error)
+	Invitations(accID int64) ([]Collaborator, error)
+	Accept(deckID int64, accID int64) error
+	SetRole(deckID int64, accID int64, role string) error
+	Remove(deckID int64, accID int64) error
+	Contact(accID int64) (string, string, error) // Email and name
+}
+
+type CollaboratorRepositoryImpl struct {
+	db                *sql.DB
+	AccessStmt        *sql.Stmt
+	ByTokenStmt       *sql.Stmt
+	AccountStmt       *sql.Stmt
+	InviteStmt        *sql.Stmt
+	CollaboratorsStmt *sql.Stmt
+	ByAccountStmt     *sql.Stmt
+	InvitationsStmt   *sql.Stmt
+	AcceptStmt        *sql.Stmt
+	SetRoleStmt       *sql.Stmt
+	RemoveStmt        *sql.Stmt
+	ContactStmt       *sql.Stmt
+}
+
+func NewCollaboratorRepository(db *sql.DB) *CollaboratorRepositoryImpl {
+	repo := &CollaboratorRepositoryImpl{db: db}
+	err := repo.InitStatements()
+	if err != nil {
+		log.Fatalln(err)
+	}
+	return repo
+}
+
+// Columns read by scanCollaborator
+const collaboratorColumns = `c.deck_id, d.title, c.acc_id, a.username, c.role, c.invited_by, c.invited_at, c.accepted_at
+							FROM DECK_COLLABORATOR c
+							JOIN DECK d ON c.deck_id = d.deck_id
+							JOIN ACCOUNT a ON c.acc_id = a.acc_id`
+
+func (r *CollaboratorRepositoryImpl) InitStatements() error {
+	var err error
+	// Pending invitations don't grant anything
+	r.AccessStmt, err = r.db.Prepare(`SELECT d.visible, a.acc_id,
+										CASE WHEN d.acc_id = a.acc_id THEN 'owner' ELSE COALESCE(c.role, '') END,
+										EXISTS(SELECT 1 FROM ACC_DECK s WHERE s.deck_id = d.deck_id AND s.acc_id = a.acc_id)
+										FROM DECK d
+										LEFT JOIN ACCOUNT a ON a.token = ? AND a.token_expire >= NOW()
+										LEFT JOIN DECK_COLLABORATOR c ON c.deck_id = d.deck_id AND c.acc_id = a.acc_id AND c.accepted_at IS NOT NULL
+										WHERE d.deck_id = ?`)
+	if err != nil {
+		return err
+	}
+
+	r.ByTokenStmt, err = r.db.Prepare("SELECT acc_id FROM ACCOUNT WHERE token = ? AND token_expire >= NOW()")
+	if err != nil {
+		return err
+	}
+
+	r.AccountStmt, err = r.db.Prepare("SELECT acc_id FROM ACCOUNT WHERE username = ?")
+	if err != nil {
+		return err
+	}
+
+	// The owner can't be invited to their own deck
+	r.InviteStmt, err = r.db.Prepare(`INSERT INTO DECK_COLLABORATOR (deck_id, acc_id, role, invited_by)
+										SELECT deck_id, ?, ?, ? FROM DECK WHERE deck_id = ? AND acc_id <> ?`)
+	if err != nil {
+		return err
+	}
+
+	r.CollaboratorsStmt, err = r.db.Prepare("SELECT " + collaboratorColumns + " WHERE c.deck_id = ? ORDER BY c.accepted_at IS NULL, a.username")
+	if err != nil {
+		return err
+	}
+
+	r.ByAccountStmt, err = r.db.Prepare("SELECT " + collaboratorColumns + " WHERE c.deck_id = ? AND c.acc_id = ?")
+	if err != nil {
+		return err
+	}
+
+	r.InvitationsStmt, err = r.db.Prepare("SELECT " + collaboratorColumns + " WHERE c.acc_id = ? AND c.accepted_at IS NULL ORDER BY c.invited_at DESC")
+	if err != nil {
+		return err
+	}
+
+	r.AcceptStmt, err = r.db.Prepare("UPDATE DECK_COLLABORATOR SET accepted_at = NOW() WHERE deck_id = ? AND acc_id = ? AND accepted_at IS NULL")
+	if err != nil {
+		return err
+	}
+
+	r.SetRoleStmt, err = r.db.Prepare("UPDATE DECK_COLLABORATOR SET role = ? WHERE deck_id = ? AND acc_id = ?")
+	if err != nil {
+		return err
+	}
+
+	r.RemoveStmt, err = r.db.Prepare("DELETE FROM DECK_COLLABORATOR WHERE deck_id = ? AND acc_id = ?")
+	if err != nil {
+		return err
+	}
+
+	r.ContactStmt, err = r.db.Prepare("SELECT email, name FROM ACCOUNT WHERE acc_id = ?")
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *CollaboratorRepositoryImpl) Access(deckID int64, token string) (Access, error) {
+	var access Access
+	var accID *int64
+	if err := r.AccessStmt.QueryRow(token, deckID).Scan(&access.Visible, &accID, &access.Role, &access.Subscribed); err != nil {
+		if err == sql.ErrNoRows {
+			return Access{}, erro.ErrDeckNotFound
+		}
+		return Access{}, err
+	}
+	if accID != nil {
+		access.AccID = *accID
+	}
+	return access, nil
+}
+
+func (r *CollaboratorRepositoryImpl) ByToken(token string) (int64, error) {
+	var accID int64
+	if err := r.ByTokenStmt.QueryRow(token).Scan(&accID); err != nil {
+		if err == sql.ErrNoRows {
+			return 0, erro.ErrInvalidToken
+		}
+		return 0, err
+	}
+	return accID, nil
+}
+
+func (r *CollaboratorRepositoryImpl) Account(username string) (int64, error) {
+	var accID int64
+	if err := r.AccountStmt.QueryRow(username).Scan(&accID); err != nil {
+		if err == sql.ErrNoRows {
+			return 0, erro.ErrAccountNotFound
+		}
+		return 0, err
+	}
+	return accID, nil
+}
+
+func (r *CollaboratorRepositoryImpl) Invite(deckID int64, accID int64, role string, invitedBy int64) error {
+	result, err := r.InviteStmt.Exec(accID, role, invitedBy, deckID, accID)
+	if err != nil {
+		if mysqlErr, ok := err.(*mysql.MySQLError); ok {
+			switch mysqlErr.Number {
+			case 1062:
+				return erro.ErrCollaboratorExists
+			case 1452:
+				return erro.ErrAccountNotFound
+			}
+		}
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return erro.ErrCollaboratorExists
+	}
+
+	return nil
+}
+
+// Collaborators of a deck, pending invitations last
+func (r *CollaboratorRepositoryImpl) Collaborators(deckID int64) ([]Collaborator, error) {
+	return r.query(r.CollaboratorsStmt, deckID)
+}
+
+func (r *CollaboratorRepositoryImpl) ByAccount(deckID int64, accID int64) (Collaborator, error) {
+	collaborator, err := scanCollaborator(r.ByAccountStmt.QueryRow(deckID, accID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return Collaborator{}, erro.ErrCollaboratorNotFound
+		}
+		return Collaborator{}, err
+	}
+	return collaborator, nil
+}
+
+// Pending invitations of an account, newest first
+func (r *CollaboratorRepositoryImpl) Invitations(accID int64) ([]Collaborator, error) {
+	return r.query(r.InvitationsStmt, accID)
+}
+
+func (r *CollaboratorRepositoryImpl) Accept(deckID int64, accID int64) error {
+	return exec(r.AcceptStmt, erro.ErrInvitationNotFound, deckID, accID)
+}
+
+func (r *CollaboratorRepositoryImpl) SetRole(deckID int64, accID int64, role string) error {
+	_, err := r.SetRoleStmt.Exec(role, deckID, accID)
+	return err
+}
+
+// Removes a collaborator or withdraws an invitation
+func (r *CollaboratorRepositoryImpl) Remove(deckID int64, accID int64) error {
+	return exec(r.RemoveStmt, erro.ErrCollaboratorNotFound, deckID, accID)
+}
+
+func (r *CollaboratorRepositoryImpl) Contact(accID int64) (string, string, error) {
+	var email, name string
+	if err := r.ContactStmt.QueryRow(accID).Scan(&email, &name); err != nil {
+		if err == sql.ErrNoRows {
+			return "", "", erro.ErrAccountNotFound
+		}
+		return "", "", err
+	}
+	return email, name, nil
+}
+
+func (r *CollaboratorRepositoryImpl) query(stmt *sql.Stmt, args ...any) ([]Collaborator, error) {
+	rows, err := stmt.Query(args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	collaborators := []Collaborator{}
+	for rows.Next() {
+		collaborator, err := scanCollaborator(rows)
+		if err != nil {
+			return nil, err
+		}
+		collaborators = append(collaborators, collaborator)
+	}
+
+	return collaborators, rows.Err()
+}
+
+// Runs a statement that must change a single row
+func exec(stmt *sql.Stmt, notFound error, args ...any) error {
+	result, err := stmt.Exec(args...)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return notFound
+	}
+
+	return nil
+}
+
+// Scans from either *sql.Row or *sql.Rows
+func scanCollaborator(row interface{ Scan(...any) error }) (Collaborator, error) {
+	var collaborator Collaborator
+	err := row.Scan(
+		&collaborator.DeckID,
+		&collaborator.DeckTitle,
+		&collaborator.AccID,
+		&collaborator.Username,
+		&collaborator.Role,
+		&collaborator.InvitedBy,
+		&collaborator.InvitedAt,
+		&collaborator.AcceptedAt,
+	)
+	if err != nil {
+		return Collaborator{}, err
+	}
+	return collaborator, nil
+}
@@ -0,0 +1,233 @@
+package collaborator
+
+import (
+	"fmt"
+	"learn-swiping-api/erro"
+	collaborator "learn-swiping-api/internal/collaborator/dto"
+	"learn-swiping-api/internal/mailer"
+	"log"
+	"slices"
+)
+
+type CollaboratorService interface {
+	Access(deckID int64, token string) (Access, error)
+	Authorize(deckID int64, token string, permission Permission) (Access, error)
+	Invite(collaborator.InviteRequest) error
+	Collaborators(deckID int64, token string) ([]Collaborator, error)
+	Invitations(token string) ([]Collaborator, error)
+	Accept(deckID int64, token string) error
+	Decline(deckID int64, token string) error
+	SetRole(deckID int64, username string, role string, token string) error
+	Remove(deckID int64, username string, token string) error
+	manage(deckID int64, username string, token string) (Access, Collaborator, error)
+	notify(deckID int64, accID int64, role string)
+}
+
+type CollaboratorServiceImpl struct {
+	repository CollaboratorRepository
+	mailer     mailer.Mailer
+}
+
+func NewCollaboratorService(repository CollaboratorRepository, mailer mailer.Mailer) CollaboratorService {
+	return &CollaboratorServiceImpl{repository: repository, mailer: mailer}
+}
+
+// What the token can do on the deck. An empty or expired token isn't an
+// error, it just has no account
+func (s *CollaboratorServiceImpl) Access(deckID int64, token string) (Access, error) {
+	return s.repository.Access(deckID, token)
+}
+
+// Checks the caller can do something on the deck. Hidden decks look
+// like they don't exist to accounts that can't read them
+func (s *CollaboratorServiceImpl) Authorize(deckID int64, token string, permission Permission) (Access, error) {
+	access, err := s.repository.Access(deckID, token)
+	if err != nil {
+		return Access{}, err
+	}
+
+	if access.AccID == 0 {
+		return Access{}, erro.ErrInvalidToken
+	}
+	if !access.Can(ReadDeck) {
+		return Access{}, erro.ErrDeckNotFound
+	}
+	if !access.Can(permission) {
+		return Access{}, erro.ErrForbidden
+	}
+
+	return access, nil
+}
+
+// Invites an account to the deck. Roles can only be granted by someone
+// above them, so maintainers can invite viewers and editors
+func (s *CollaboratorServiceImpl) Invite(request collaborator.InviteRequest) error {
+	if !slices.Contains(Roles, request.Role) {
+		return erro.ErrBadField
+	}
+
+	access, err := s.Authorize(request.DeckID, request.Token, ManageCollaborators)
+	if err != nil {
+		return err
+	}
+	if rank(access.Role) <= rank(request.Role) {
+		return erro.ErrForbidden
+	}
+
+	accID, err := s.repository.Account(request.Username)
+	if err != nil {
+		return err
+	}
+	if accID == access.AccID {
+		return erro.ErrBadField
+	}
+
+	if err := s.repository.Invite(request.DeckID, accID, request.Role, access.AccID); err != nil {
+		return err
+	}
+
+	s.notify(request.DeckID, accID, request.Role)
+	return nil
+}
+
+// Lists the collaborators and pending invitations. Only for people
+// working on the deck
+func (s *CollaboratorServiceImpl) Collaborators(deckID int64, token string) ([]Collaborator, error) {
+	access, err := s.Authorize(deckID, token, ReadDeck)
+	if err != nil {
+		return nil, err
+	}
+	if access.Role == "" {
+		return nil, erro.ErrForbidden
+	}
+
+	return s.repository.Collaborators(deckID)
+}
+
+func (s *CollaboratorServiceImpl) Invitations(token string) ([]Collaborator, error) {
+	accID, err := s.repository.ByToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.repository.Invitations(accID)
+}
+
+func (s *CollaboratorServiceImpl) Accept(deckID int64, token string) error {
+	access, err := s.repository.Access(deckID, token)
+	if err != nil {
+		return err
+	}
+	if access.AccID == 0 {
+		return erro.ErrInvalidToken
+	}
+
+	return s.repository.Accept(deckID, access.AccID)
+}
+
+func (s *CollaboratorServiceImpl) Decline(deckID int64, token string) error {
+	access, err := s.repository.Access(deckID, token)
+	if err != nil {
+		return err
+	}
+	if access.AccID == 0 {
+		return erro.ErrInvalidToken
+	}
+
+	invitation, err := s.repository.ByAccount(deckID, access.AccID)
+	if err != nil || invitation.AcceptedAt != nil {
+		return erro.ErrInvitationNotFound
+	}
+
+	return s.repository.Remove(deckID, access.AccID)
+}
+
+func (s *CollaboratorServiceImpl) SetRole(deckID int64, username string, role string, token string) error {
+	if !slices.Contains(Roles, role) {
+		return erro.ErrBadField
+	}
+
+	access, target, err := s.manage(deckID, username, token)
+	if err != nil {
+		return err
+	}
+	if rank(access.Role) <= rank(role) {
+		return erro.ErrForbidden
+	}
+
+	return s.repository.SetRole(deckID, target.AccID, role)
+}
+
+// Removes a collaborator or withdraws an invitation. Collaborators can
+// always remove themselves
+func (s *CollaboratorServiceImpl) Remove(deckID int64, username string, token string) error {
+	access, err := s.repository.Access(deckID, token)
+	if err != nil {
+		return err
+	}
+	if access.AccID == 0 {
+		return erro.ErrInvalidToken
+	}
+
+	accID, err := s.repository.Account(username)
+	if err != nil {
+		return err
+	}
+	if accID == access.AccID {
+		return s.repository.Remove(deckID, accID)
+	}
+
+	_, target, err := s.manage(deckID, username, token)
+	if err != nil {
+		return err
+	}
+
+	return s.repository.Remove(deckID, target.AccID)
+}
+
+// Loads a collaborator the caller is allowed to manage
+func (s *CollaboratorServiceImpl) manage(deckID int64, username string, token string) (Access, Collaborator, error) {
+	access, err := s.Authorize(deckID, token, ManageCollaborators)
+	if err != nil {
+		return Access{}, Collaborator{}, err
+	}
+
+	accID, err := s.repository.Account(username)
+	if err != nil {
+		return Access{}, Collaborator{}, err
+	}
+
+	target, err := s.repository.ByAccount(deckID, accID)
+	if err != nil {
+		return Access{}, Collaborator{}, err
+	}
+	if rank(access.Role) <= rank(target.Role) {
+		return Access{}, Collaborator{}, erro.ErrForbidden
+	}
+
+	return access, target, nil
+}
+
+// Mails the invitation. Failures are only logged
+func (s *CollaboratorServiceImpl) notify(deckID int64, accID int64, role string) {
+	invitation, err := s.repository.ByAccount(deckID, accID)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	email, name, err := s.repository.Contact(accID)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	err = s.mailer.Send(mailer.Message{
+		To:      email,
+		Subject: fmt.Sprintf("You were invited to collaborate on %s", invitation.DeckTitle),
+		Body:    fmt.Sprintf("Hi %s,\n\nYou were invited as %s of the deck %q. Accept the invitation from the app to start working on it.\n", name, role, invitation.DeckTitle),
+	})
+	if err != nil {
+		log.Println(err)
+	}
+}
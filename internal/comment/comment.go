@@ -0,0 +1,34 @@
+package comment
+
+import "time"
+
+const (
+	StatusVisible = "visible"
+	StatusDeleted = "deleted" // By its author
+	StatusRemoved = "removed" // By a moderator
+)
+
+type Comment struct {
+	ID        int64      `json:"comment_id"`
+	DeckID    int64      `json:"deck_id"`
+	CardID    *int64     `json:"card_id,omitempty"`
+	ParentID  *int64     `json:"parent_id,omitempty"`
+	AccID     *int64     `json:"acc_id"`
+	Username  string     `json:"username"`
+	Body      string     `json:"body"` // Markdown
+	HTML      string     `json:"html"`
+	Mentions  []string   `json:"mentions"`
+	Pinned    bool       `json:"pinned"`
+	Status    string     `json:"status"`
+	CreatedAt time.Time  `json:"created_at"`
+	EditedAt  *time.Time `json:"edited_at"`
+	Replies   []Comment  `json:"replies,omitempty"`
+}
+
+// Account mentioned in a comment
+type Mention struct {
+	AccID    int64
+	Username string
+	Email    string
+	Name     string
+}
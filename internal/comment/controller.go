@@ -0,0 +1,278 @@
+package comment
+
+import (
+	"errors"
+	"learn-swiping-api/erro"
+	"learn-swiping-api/internal/audit"
+	comment "learn-swiping-api/internal/comment/dto"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CommentController interface {
+	DeckComments(*gin.Context) // GET
+	CardComments(*gin.Context) // GET
+	CommentDeck(*gin.Context)  // POST
+	CommentCard(*gin.Context)  // POST
+	Update(*gin.Context)       // PUT
+	Delete(*gin.Context)       // DELETE
+	Pin(*gin.Context)          // PUT
+	Unpin(*gin.Context)        // DELETE
+	Remove(*gin.Context)       // DELETE
+}
+
+type CommentControllerImpl struct {
+	service CommentService
+}
+
+func NewCommentController(service CommentService) CommentController {
+	return &CommentControllerImpl{service: service}
+}
+
+// Retrieves the thread of a deck
+// Method: GET
+func (c *CommentControllerImpl) DeckComments(ctx *gin.Context) {
+	deckID, _, ok := threadParams(ctx, false)
+	if !ok {
+		return
+	}
+
+	comments, err := c.service.Comments(deckID, nil, ctx.GetHeader("Token"))
+	if err != nil {
+		commentError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, comments)
+}
+
+// Retrieves the thread of a card
+// Method: GET
+func (c *CommentControllerImpl) CardComments(ctx *gin.Context) {
+	deckID, cardID, ok := threadParams(ctx, true)
+	if !ok {
+		return
+	}
+
+	comments, err := c.service.Comments(deckID, cardID, ctx.GetHeader("Token"))
+	if err != nil {
+		commentError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, comments)
+}
+
+// Comments on a deck or replies to one of its comments
+// Method: POST
+func (c *CommentControllerImpl) CommentDeck(ctx *gin.Context) {
+	deckID, _, ok := threadParams(ctx, false)
+	if !ok {
+		return
+	}
+
+	c.create(ctx, deckID, nil)
+}
+
+// Comments on a card or replies to one of its comments
+// Method: POST
+func (c *CommentControllerImpl) CommentCard(ctx *gin.Context) {
+	deckID, cardID, ok := threadParams(ctx, true)
+	if !ok {
+		return
+	}
+
+	c.create(ctx, deckID, cardID)
+}
+
+func (c *CommentControllerImpl) create(ctx *gin.Context, deckID int64, cardID *int64) {
+	token := ctx.GetHeader("Token")
+	if token == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrInvalidToken.Error()})
+		return
+	}
+
+	var request comment.CreateRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrBadField.Error()})
+		return
+	}
+	request.Token = token
+	request.DeckID = deckID
+	request.CardID = cardID
+
+	created, err := c.service.Create(request)
+	if err != nil {
+		commentError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, created)
+}
+
+// Edits a comment of the caller
+// Method: PUT
+func (c *CommentControllerImpl) Update(ctx *gin.Context) {
+	token := ctx.GetHeader("Token")
+	if token == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrInvalidToken.Error()})
+		return
+	}
+
+	deckID, commentID, ok := commentParams(ctx)
+	if !ok {
+		return
+	}
+
+	var request comment.UpdateRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrBadField.Error()})
+		return
+	}
+	request.Token = token
+	request.DeckID = deckID
+	request.CommentID = commentID
+
+	updated, err := c.service.Update(request)
+	if err != nil {
+		commentError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, updated)
+}
+
+// Deletes a comment of the caller
+// Method: DELETE
+func (c *CommentControllerImpl) Delete(ctx *gin.Context) {
+	token := ctx.GetHeader("Token")
+	if token == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrInvalidToken.Error()})
+		return
+	}
+
+	deckID, commentID, ok := commentParams(ctx)
+	if !ok {
+		return
+	}
+
+	if err := c.service.Delete(deckID, commentID, token); err != nil {
+		commentError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{})
+}
+
+// Pins a top level comment. Only for the owner and maintainers
+// Method: PUT
+func (c *CommentControllerImpl) Pin(ctx *gin.Context) {
+	c.pin(ctx, true)
+}
+
+// Unpins a comment. Only for the owner and maintainers
+// Method: DELETE
+func (c *CommentControllerImpl) Unpin(ctx *gin.Context) {
+	c.pin(ctx, false)
+}
+
+func (c *CommentControllerImpl) pin(ctx *gin.Context, pinned bool) {
+	token := ctx.GetHeader("Token")
+	if token == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrInvalidToken.Error()})
+		return
+	}
+
+	deckID, commentID, ok := commentParams(ctx)
+	if !ok {
+		return
+	}
+
+	if err := c.service.Pin(deckID, commentID, pinned, token); err != nil {
+		commentError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{})
+}
+
+// Removes a comment breaking the rules
+// Method: DELETE
+func (c *CommentControllerImpl) Remove(ctx *gin.Context) {
+	commentID, err := strconv.Atoi(ctx.Param("commentID"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrBadField.Error()})
+		return
+	}
+
+	removed, err := c.service.Remove(int64(commentID))
+	if err != nil {
+		commentError(ctx, err)
+		return
+	}
+
+	audit.Action(ctx, "comment.remove", "comment:"+strconv.Itoa(commentID))
+	audit.Detail(ctx, "deck", removed.DeckID)
+	if removed.AccID != nil {
+		audit.Detail(ctx, "author", *removed.AccID)
+	}
+	audit.Detail(ctx, "body", removed.Body)
+	ctx.JSON(http.StatusOK, gin.H{})
+}
+
+// Reads the deck id and, for card threads, the card id
+func threadParams(ctx *gin.Context, card bool) (int64, *int64, bool) {
+	deckID, err := strconv.Atoi(ctx.Param("deckID"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrBadField.Error()})
+		return 0, nil, false
+	}
+
+	if !card {
+		return int64(deckID), nil, true
+	}
+
+	cardID, err := strconv.ParseInt(ctx.Param("cardID"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrBadField.Error()})
+		return 0, nil, false
+	}
+
+	return int64(deckID), &cardID, true
+}
+
+func commentParams(ctx *gin.Context) (int64, int64, bool) {
+	deckID, err := strconv.Atoi(ctx.Param("deckID"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrBadField.Error()})
+		return 0, 0, false
+	}
+
+	commentID, err := strconv.Atoi(ctx.Param("commentID"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrBadField.Error()})
+		return 0, 0, false
+	}
+
+	return int64(deckID), int64(commentID), true
+}
+
+func commentError(ctx *gin.Context, err error) {
+	if errors.Is(err, erro.ErrBadField) || errors.Is(err, erro.ErrInvalidToken) ||
+		errors.Is(err, erro.ErrCommentParent) || errors.Is(err, erro.ErrNotPinnable) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, erro.ErrForbidden) {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, erro.ErrCommentNotFound) || errors.Is(err, erro.ErrDeckNotFound) ||
+		errors.Is(err, erro.ErrCardNotFound) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
@@ -0,0 +1,9 @@
+package comment
+
+type CreateRequest struct {
+	Token    string
+	DeckID   int64
+	CardID   *int64 // Nil for the deck thread
+	ParentID *int64 `json:"parent_id"`
+	Body     string `json:"body" binding:"required"`
+}
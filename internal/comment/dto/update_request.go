@@ -0,0 +1,8 @@
+package comment
+
+type UpdateRequest struct {
+	Token     string
+	DeckID    int64
+	CommentID int64
+	Body      string `json:"body" binding:"required"`
+}
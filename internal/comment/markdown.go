@@ -0,0 +1,63 @@
+package comment
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+)
+
+// @username preceded by the start of the text or a non word character
+var mentionRegexp = regexp.MustCompile(`(^|[^\w@])@([A-Za-z0-9_.-]{1,32})`)
+
+var (
+	markdown = goldmark.New(goldmark.WithExtensions(extension.Strikethrough, extension.Linkify))
+	// Raw HTML is already escaped by goldmark, the policy is a second
+	// line of defence for links and attributes
+	policy = bluemonday.UGCPolicy().RequireNoFollowOnLinks(true)
+)
+
+// Usernames mentioned in a text, without duplicates
+func mentions(body string) []string {
+	seen := make(map[string]bool)
+	var usernames []string
+	for _, match := range mentionRegexp.FindAllStringSubmatch(body, -1) {
+		username := strings.TrimRight(match[2], ".-")
+		if username != "" && !seen[username] {
+			seen[username] = true
+			usernames = append(usernames, username)
+		}
+	}
+	return usernames
+}
+
+// Renders markdown to sanitised HTML. Mentions of existing accounts
+// become links to their profile
+func render(body string, mentioned []string) (string, error) {
+	// Usernames aren't case sensitive, links use the stored one
+	exists := make(map[string]string, len(mentioned))
+	for _, username := range mentioned {
+		exists[strings.ToLower(username)] = username
+	}
+
+	body = mentionRegexp.ReplaceAllStringFunc(body, func(match string) string {
+		groups := mentionRegexp.FindStringSubmatch(match)
+		username := strings.TrimRight(groups[2], ".-")
+		stored, ok := exists[strings.ToLower(username)]
+		if !ok {
+			return match
+		}
+		rest := groups[2][len(username):]
+		return groups[1] + "[@" + username + "](/users/" + stored + ")" + rest
+	})
+
+	var buf bytes.Buffer
+	if err := markdown.Convert([]byte(body), &buf); err != nil {
+		return "", err
+	}
+
+	return policy.Sanitize(buf.String()), nil
+}
@@ -0,0 +1,261 @@
+package comment
+
+import (
+	"database/sql"
+	"learn-swiping-api/erro"
+	"log"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+type CommentRepository interface {
+	CardExists(cardID int64, deckID int64) bool
+	Thread(deckID int64, cardID *int64) ([]Comment, error)
+	ById(commentID int64) (Comment, error)
+	Create(Comment) (int64, error)
+	Update(commentID int64, accID int64, body string, html string, mentions []string) error
+	Delete(commentID int64, accID int64) error
+	Remove(commentID int64) error
+	Pin(commentID int64, deckID int64, pinned bool) error
+	Mentionable(usernames []string) ([]Mention, error)
+}
+
+type CommentRepositoryImpl struct {
+	db             *sql.DB
+	CardExistsStmt *sql.Stmt
+	DeckThreadStmt *sql.Stmt
+	CardThreadStmt *sql.Stmt
+	ByIdStmt       *sql.Stmt
+	CreateStmt     *sql.Stmt
+	UpdateStmt     *sql.Stmt
+	DeleteStmt     *sql.Stmt
+	RemoveStmt     *sql.Stmt
+	PinStmt        *sql.Stmt
+}
+
+func NewCommentRepository(db *sql.DB) *CommentRepositoryImpl {
+	repo := &CommentRepositoryImpl{db: db}
+	err := repo.InitStatements()
+	if err != nil {
+		log.Fatalln(err)
+	}
+	return repo
+}
+
+// Columns read by scanComment
+const commentColumns = `c.comment_id, c.deck_id, c.card_id, c.parent_id, c.acc_id, COALESCE(a.username, ''),
+							c.body, c.html, c.mentions, c.pinned, c.status, c.created_at, c.edited_at
+						FROM COMMENT c
+						LEFT JOIN ACCOUNT a ON c.acc_id = a.acc_id`
+
+func (r *CommentRepositoryImpl) InitStatements() error {
+	var err error
+	r.CardExistsStmt, err = r.db.Prepare("SELECT 1 FROM CARD WHERE card_id = ? AND deck_id = ?")
+	if err != nil {
+		return err
+	}
+
+	r.DeckThreadStmt, err = r.db.Prepare("SELECT " + commentColumns + " WHERE c.deck_id = ? AND c.card_id IS NULL ORDER BY c.created_at, c.comment_id")
+	if err != nil {
+		return err
+	}
+
+	r.CardThreadStmt, err = r.db.Prepare("SELECT " + commentColumns + " WHERE c.deck_id = ? AND c.card_id = ? ORDER BY c.created_at, c.comment_id")
+	if err != nil {
+		return err
+	}
+
+	r.ByIdStmt, err = r.db.Prepare("SELECT " + commentColumns + " WHERE c.comment_id = ?")
+	if err != nil {
+		return err
+	}
+
+	r.CreateStmt, err = r.db.Prepare(`INSERT INTO COMMENT (deck_id, card_id, parent_id, acc_id, body, html, mentions)
+										VALUES (?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+
+	r.UpdateStmt, err = r.db.Prepare(`UPDATE COMMENT SET body = ?, html = ?, mentions = ?, edited_at = NOW()
+										WHERE comment_id = ? AND acc_id = ? AND status = 'visible'`)
+	if err != nil {
+		return err
+	}
+
+	// The row is kept so replies stay in place
+	r.DeleteStmt, err = r.db.Prepare(`UPDATE COMMENT SET status = 'deleted', body = '', html = '', mentions = '', pinned = FALSE
+										WHERE comment_id = ? AND acc_id = ? AND status = 'visible'`)
+	if err != nil {
+		return err
+	}
+
+	r.RemoveStmt, err = r.db.Prepare(`UPDATE COMMENT SET status = 'removed', body = '', html = '', mentions = '', pinned = FALSE
+										WHERE comment_id = ? AND status = 'visible'`)
+	if err != nil {
+		return err
+	}
+
+	r.PinStmt, err = r.db.Prepare(`UPDATE COMMENT SET pinned = ?
+										WHERE comment_id = ? AND deck_id = ? AND parent_id IS NULL AND status = 'visible'`)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *CommentRepositoryImpl) CardExists(cardID int64, deckID int64) bool {
+	var exists int
+	return r.CardExistsStmt.QueryRow(cardID, deckID).Scan(&exists) == nil
+}
+
+// Comments of the deck thread, or of a card thread when cardID isn't nil,
+// oldest first
+func (r *CommentRepositoryImpl) Thread(deckID int64, cardID *int64) ([]Comment, error) {
+	var rows *sql.Rows
+	var err error
+	if cardID == nil {
+		rows, err = r.DeckThreadStmt.Query(deckID)
+	} else {
+		rows, err = r.CardThreadStmt.Query(deckID, *cardID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	comments := []Comment{}
+	for rows.Next() {
+		comment, err := scanComment(rows)
+		if err != nil {
+			return nil, err
+		}
+		comments = append(comments, comment)
+	}
+
+	return comments, rows.Err()
+}
+
+func (r *CommentRepositoryImpl) ById(commentID int64) (Comment, error) {
+	comment, err := scanComment(r.ByIdStmt.QueryRow(commentID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return Comment{}, erro.ErrCommentNotFound
+		}
+		return Comment{}, err
+	}
+	return comment, nil
+}
+
+func (r *CommentRepositoryImpl) Create(comment Comment) (int64, error) {
+	result, err := r.CreateStmt.Exec(comment.DeckID, comment.CardID, comment.ParentID, comment.AccID,
+		comment.Body, comment.HTML, strings.Join(comment.Mentions, " "))
+	if err != nil {
+		if mysqlErr, ok := err.(*mysql.MySQLError); ok && mysqlErr.Number == 1452 {
+			return 0, erro.ErrDeckNotFound
+		}
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+// Only the author can edit a comment
+func (r *CommentRepositoryImpl) Update(commentID int64, accID int64, body string, html string, mentions []string) error {
+	return exec(r.UpdateStmt, body, html, strings.Join(mentions, " "), commentID, accID)
+}
+
+// Only the author can delete a comment
+func (r *CommentRepositoryImpl) Delete(commentID int64, accID int64) error {
+	return exec(r.DeleteStmt, commentID, accID)
+}
+
+func (r *CommentRepositoryImpl) Remove(commentID int64) error {
+	return exec(r.RemoveStmt, commentID)
+}
+
+func (r *CommentRepositoryImpl) Pin(commentID int64, deckID int64, pinned bool) error {
+	return exec(r.PinStmt, pinned, commentID, deckID)
+}
+
+// Accounts matching the mentioned usernames. Unknown ones are left out
+func (r *CommentRepositoryImpl) Mentionable(usernames []string) ([]Mention, error) {
+	if len(usernames) == 0 {
+		return []Mention{}, nil
+	}
+
+	var query strings.Builder
+	args := make([]any, 0, len(usernames))
+	query.WriteString("SELECT acc_id, username, email, name FROM ACCOUNT WHERE username IN (")
+	for i, username := range usernames {
+		if i > 0 {
+			query.WriteString(", ")
+		}
+		query.WriteString("?")
+		args = append(args, username)
+	}
+	query.WriteString(")")
+
+	rows, err := r.db.Query(query.String(), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	mentions := []Mention{}
+	for rows.Next() {
+		var mention Mention
+		if err := rows.Scan(&mention.AccID, &mention.Username, &mention.Email, &mention.Name); err != nil {
+			return nil, err
+		}
+		mentions = append(mentions, mention)
+	}
+
+	return mentions, rows.Err()
+}
+
+// Runs an update on a single visible comment
+func exec(stmt *sql.Stmt, args ...any) error {
+	result, err := stmt.Exec(args...)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return erro.ErrCommentNotFound
+	}
+
+	return nil
+}
+
+// Scans from either *sql.Row or *sql.Rows
+func scanComment(row interface{ Scan(...any) error }) (Comment, error) {
+	var comment Comment
+	var mentions string
+	err := row.Scan(
+		&comment.ID,
+		&comment.DeckID,
+		&comment.CardID,
+		&comment.ParentID,
+		&comment.AccID,
+		&comment.Username,
+		&comment.Body,
+		&comment.HTML,
+		&mentions,
+		&comment.Pinned,
+		&comment.Status,
+		&comment.CreatedAt,
+		&comment.EditedAt,
+	)
+	if err != nil {
+		return Comment{}, err
+	}
+
+	comment.Mentions = strings.Fields(mentions)
+	return comment, nil
+}
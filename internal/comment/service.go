@@ -0,0 +1,313 @@
+package comment
+
+import (
+	"fmt"
+	"learn-swiping-api/erro"
+	"learn-swiping-api/internal/collaborator"
+	comment "learn-swiping-api/internal/comment/dto"
+	"learn-swiping-api/internal/mailer"
+	"log"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+)
+
+const (
+	maxBody     = 5000
+	maxMentions = 10
+)
+
+type CommentService interface {
+	Thread(deckID int64, cardID *int64) ([]Comment, error)
+	Comments(deckID int64, cardID *int64, token string) ([]Comment, error)
+	Create(comment.CreateRequest) (Comment, error)
+	Update(comment.UpdateRequest) (Comment, error)
+	Delete(deckID int64, commentID int64, token string) error
+	Pin(deckID int64, commentID int64, pinned bool, token string) error
+	Remove(commentID int64) (Comment, error)
+	prepare(body string) (string, string, []Mention, error)
+	notify(author Comment, mentioned []Mention)
+}
+
+type CommentServiceImpl struct {
+	repository    CommentRepository
+	collaborators collaborator.CollaboratorService
+	mailer        mailer.Mailer
+}
+
+func NewCommentService(repository CommentRepository, collaborators collaborator.CollaboratorService, mailer mailer.Mailer) CommentService {
+	return &CommentServiceImpl{repository: repository, collaborators: collaborators, mailer: mailer}
+}
+
+// Builds the thread tree. Pinned comments come first, then the oldest.
+// Deleted and removed comments are only kept while they have replies
+func (s *CommentServiceImpl) Thread(deckID int64, cardID *int64) ([]Comment, error) {
+	comments, err := s.repository.Thread(deckID, cardID)
+	if err != nil {
+		return nil, err
+	}
+
+	children := make(map[int64][]Comment)
+	roots := []Comment{}
+	for _, c := range comments {
+		if c.ParentID == nil {
+			roots = append(roots, c)
+		} else {
+			children[*c.ParentID] = append(children[*c.ParentID], c)
+		}
+	}
+
+	var build func([]Comment) []Comment
+	build = func(level []Comment) []Comment {
+		result := []Comment{}
+		for _, c := range level {
+			c.Replies = build(children[c.ID])
+			if c.Status != StatusVisible && len(c.Replies) == 0 {
+				continue
+			}
+			result = append(result, c)
+		}
+		return result
+	}
+
+	thread := build(roots)
+	sort.SliceStable(thread, func(i, j int) bool {
+		return thread[i].Pinned && !thread[j].Pinned
+	})
+
+	return thread, nil
+}
+
+// Threads of hidden decks can only be read by the people working on them
+func (s *CommentServiceImpl) Comments(deckID int64, cardID *int64, token string) ([]Comment, error) {
+	access, err := s.collaborators.Access(deckID, token)
+	if err != nil {
+		return nil, err
+	}
+	if !access.Can(collaborator.ReadDeck) {
+		return nil, erro.ErrDeckNotFound
+	}
+	if cardID != nil && !s.repository.CardExists(*cardID, deckID) {
+		return nil, erro.ErrCardNotFound
+	}
+
+	return s.Thread(deckID, cardID)
+}
+
+func (s *CommentServiceImpl) Create(request comment.CreateRequest) (Comment, error) {
+	body, html, mentioned, err := s.prepare(request.Body)
+	if err != nil {
+		return Comment{}, err
+	}
+
+	access, err := s.collaborators.Authorize(request.DeckID, request.Token, collaborator.ReadDeck)
+	if err != nil {
+		return Comment{}, err
+	}
+
+	if request.CardID != nil && !s.repository.CardExists(*request.CardID, request.DeckID) {
+		return Comment{}, erro.ErrCardNotFound
+	}
+
+	// Replies go in the same thread as their parent
+	if request.ParentID != nil {
+		parent, err := s.repository.ById(*request.ParentID)
+		if err != nil {
+			return Comment{}, err
+		}
+		if parent.DeckID != request.DeckID || !sameCard(parent.CardID, request.CardID) {
+			return Comment{}, erro.ErrCommentParent
+		}
+		if parent.Status != StatusVisible {
+			return Comment{}, erro.ErrCommentNotFound
+		}
+	}
+
+	c := Comment{
+		DeckID:   request.DeckID,
+		CardID:   request.CardID,
+		ParentID: request.ParentID,
+		AccID:    &access.AccID,
+		Body:     body,
+		HTML:     html,
+		Mentions: usernames(mentioned),
+	}
+
+	c.ID, err = s.repository.Create(c)
+	if err != nil {
+		return Comment{}, err
+	}
+
+	created, err := s.repository.ById(c.ID)
+	if err != nil {
+		return Comment{}, err
+	}
+
+	s.notify(created, mentioned)
+	return created, nil
+}
+
+// Edits a comment of the caller. Only newly mentioned accounts are notified
+func (s *CommentServiceImpl) Update(request comment.UpdateRequest) (Comment, error) {
+	body, html, mentioned, err := s.prepare(request.Body)
+	if err != nil {
+		return Comment{}, err
+	}
+
+	access, err := s.collaborators.Authorize(request.DeckID, request.Token, collaborator.ReadDeck)
+	if err != nil {
+		return Comment{}, err
+	}
+
+	previous, err := s.repository.ById(request.CommentID)
+	if err != nil {
+		return Comment{}, err
+	}
+	if previous.DeckID != request.DeckID {
+		return Comment{}, erro.ErrCommentNotFound
+	}
+
+	if err := s.repository.Update(request.CommentID, access.AccID, body, html, usernames(mentioned)); err != nil {
+		return Comment{}, err
+	}
+
+	updated, err := s.repository.ById(request.CommentID)
+	if err != nil {
+		return Comment{}, err
+	}
+
+	var added []Mention
+	for _, m := range mentioned {
+		if !slices.Contains(previous.Mentions, m.Username) {
+			added = append(added, m)
+		}
+	}
+	s.notify(updated, added)
+
+	return updated, nil
+}
+
+// Deletes a comment of the caller. Replies to it are kept
+func (s *CommentServiceImpl) Delete(deckID int64, commentID int64, token string) error {
+	access, err := s.collaborators.Authorize(deckID, token, collaborator.ReadDeck)
+	if err != nil {
+		return err
+	}
+
+	c, err := s.repository.ById(commentID)
+	if err != nil {
+		return err
+	}
+	if c.DeckID != deckID {
+		return erro.ErrCommentNotFound
+	}
+
+	return s.repository.Delete(commentID, access.AccID)
+}
+
+// Only the owner and maintainers can pin comments of the deck threads
+func (s *CommentServiceImpl) Pin(deckID int64, commentID int64, pinned bool, token string) error {
+	if _, err := s.collaborators.Authorize(deckID, token, collaborator.PinComments); err != nil {
+		return err
+	}
+
+	c, err := s.repository.ById(commentID)
+	if err != nil {
+		return err
+	}
+	if c.DeckID != deckID || c.Status != StatusVisible {
+		return erro.ErrCommentNotFound
+	}
+	if c.ParentID != nil {
+		return erro.ErrNotPinnable
+	}
+	if c.Pinned == pinned {
+		return nil
+	}
+
+	return s.repository.Pin(commentID, deckID, pinned)
+}
+
+// Removes a comment breaking the rules. Returns it as it was so the
+// moderation can be audited
+func (s *CommentServiceImpl) Remove(commentID int64) (Comment, error) {
+	c, err := s.repository.ById(commentID)
+	if err != nil {
+		return Comment{}, err
+	}
+
+	if err := s.repository.Remove(commentID); err != nil {
+		return Comment{}, err
+	}
+
+	return c, nil
+}
+
+// Validates the body and renders it. Returns the trimmed body, its HTML
+// and the existing accounts it mentions
+func (s *CommentServiceImpl) prepare(body string) (string, string, []Mention, error) {
+	body = strings.TrimSpace(body)
+	if body == "" || len(body) > maxBody {
+		return "", "", nil, erro.ErrBadField
+	}
+
+	names := mentions(body)
+	if len(names) > maxMentions {
+		return "", "", nil, erro.ErrBadField
+	}
+
+	mentioned, err := s.repository.Mentionable(names)
+	if err != nil {
+		return "", "", nil, err
+	}
+
+	html, err := render(body, usernames(mentioned))
+	if err != nil {
+		return "", "", nil, err
+	}
+
+	return body, html, mentioned, nil
+}
+
+// Mails the mentioned accounts. Failures are only logged
+func (s *CommentServiceImpl) notify(c Comment, mentioned []Mention) {
+	base := os.Getenv("APP_URL")
+	if base == "" {
+		base = "http://localhost:9999"
+	}
+
+	link := fmt.Sprintf("%s/decks/%d/comments#%d", base, c.DeckID, c.ID)
+	if c.CardID != nil {
+		link = fmt.Sprintf("%s/decks/%d/%d/comments#%d", base, c.DeckID, *c.CardID, c.ID)
+	}
+
+	for _, m := range mentioned {
+		if c.AccID != nil && m.AccID == *c.AccID {
+			continue
+		}
+		err := s.mailer.Send(mailer.Message{
+			To:      m.Email,
+			Subject: fmt.Sprintf("%s mentioned you in a comment", c.Username),
+			Body:    fmt.Sprintf("Hi %s,\n\n%s mentioned you:\n\n%s\n\nReply at %s\n", m.Name, c.Username, c.Body, link),
+		})
+		if err != nil {
+			log.Println(err)
+		}
+	}
+}
+
+func sameCard(a *int64, b *int64) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
+
+func usernames(mentioned []Mention) []string {
+	names := make([]string, 0, len(mentioned))
+	for _, m := range mentioned {
+		names = append(names, m.Username)
+	}
+	return names
+}
@@ -26,6 +26,13 @@ type DeckController interface {
 	SaveRating(ctx *gin.Context)
 	Rating(ctx *gin.Context)
 	DeleteRating(ctx *gin.Context)
+
+	SaveReview(*gin.Context)    // PUT
+	Reviews(*gin.Context)       // GET
+	VoteHelpful(*gin.Context)   // POST
+	RemoveHelpful(*gin.Context) // DELETE
+	Reply(*gin.Context)         // PUT
+	DeleteReply(*gin.Context)   // DELETE
 }
 
 type DeckControllerImpl struct {
@@ -200,6 +207,10 @@ func (c *DeckControllerImpl) Update(ctx *gin.Context) {
 			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
+		if errors.Is(err, erro.ErrDeckHidden) || errors.Is(err, erro.ErrForbidden) {
+			ctx.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
 		if errors.Is(err, erro.ErrDeckNotFound) {
 			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 			return
@@ -231,6 +242,10 @@ func (c *DeckControllerImpl) Delete(ctx *gin.Context) {
 			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
+		if errors.Is(err, erro.ErrForbidden) {
+			ctx.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
 
 		if errors.Is(err, erro.ErrDeckNotFound) {
 			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
@@ -264,7 +279,7 @@ func (c *DeckControllerImpl) AddDeckSubscription(ctx *gin.Context) {
 	}
 
 	if err := c.service.AddDeckSubscription(request); err != nil {
-		if errors.Is(err, erro.ErrAlreadySuscribed) {
+		if errors.Is(err, erro.ErrAlreadySuscribed) || errors.Is(err, erro.ErrDeckNotFound) {
 			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 			return
 		}
@@ -391,7 +406,8 @@ func (c *DeckControllerImpl) SaveRating(ctx *gin.Context) {
 		return
 	}
 
-	rating, err := strconv.Atoi(ctx.Param("rating"))
+	// Parsing as 8 bits so big numbers fail instead of wrapping around
+	rating, err := strconv.ParseInt(ctx.Param("rating"), 10, 8)
 	if err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrBadField.Error()})
 		return
@@ -399,10 +415,18 @@ func (c *DeckControllerImpl) SaveRating(ctx *gin.Context) {
 
 	err = c.service.SaveRating(int64(deckID), int8(rating), token)
 	if err != nil {
-		if errors.Is(err, erro.ErrInvalidToken) {
+		if errors.Is(err, erro.ErrInvalidToken) || errors.Is(err, erro.ErrBadField) {
 			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
+		if errors.Is(err, erro.ErrOwnDeckRating) || errors.Is(err, erro.ErrNotEligible) {
+			ctx.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, erro.ErrDeckNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -476,3 +500,185 @@ func (c *DeckControllerImpl) DeleteRating(ctx *gin.Context) {
 	}
 	ctx.JSON(http.StatusOK, gin.H{})
 }
+
+// Rates a deck with an optional written review, or edits the previous one
+// Method: PUT
+func (c *DeckControllerImpl) SaveReview(ctx *gin.Context) {
+	token := ctx.GetHeader("Token")
+	if token == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrInvalidToken.Error()})
+		return
+	}
+
+	deckID, err := strconv.Atoi(ctx.Param("deckID"))
+	if err != nil || deckID == 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrBadField.Error()})
+		return
+	}
+
+	var request deck.ReviewRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrBadField.Error()})
+		return
+	}
+	request.Token = token
+	request.DeckID = int64(deckID)
+
+	if err := c.service.SaveReview(request); err != nil {
+		reviewError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{})
+}
+
+// Lists the reviews of a deck sorted by helpfulness or recency
+// Method: GET
+func (c *DeckControllerImpl) Reviews(ctx *gin.Context) {
+	token := ctx.GetHeader("Token")
+	if token == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrInvalidToken.Error()})
+		return
+	}
+
+	deckID, err := strconv.Atoi(ctx.Param("deckID"))
+	if err != nil || deckID == 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrBadField.Error()})
+		return
+	}
+
+	request := deck.ReviewsRequest{Token: token, DeckID: int64(deckID), Sort: ctx.Query("sort")}
+	if page := ctx.Query("page"); page != "" {
+		if request.Page, err = strconv.Atoi(page); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrBadField.Error()})
+			return
+		}
+	}
+	if size := ctx.Query("size"); size != "" {
+		if request.Size, err = strconv.Atoi(size); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrBadField.Error()})
+			return
+		}
+	}
+
+	reviews, err := c.service.Reviews(request)
+	if err != nil {
+		reviewError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, reviews)
+}
+
+// Marks a review of another account as helpful
+// Method: POST
+func (c *DeckControllerImpl) VoteHelpful(ctx *gin.Context) {
+	token, deckID, ratingID, ok := reviewParams(ctx)
+	if !ok {
+		return
+	}
+
+	if err := c.service.VoteHelpful(deckID, ratingID, token); err != nil {
+		reviewError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{})
+}
+
+// Removes a helpful vote
+// Method: DELETE
+func (c *DeckControllerImpl) RemoveHelpful(ctx *gin.Context) {
+	token, deckID, ratingID, ok := reviewParams(ctx)
+	if !ok {
+		return
+	}
+
+	if err := c.service.RemoveHelpful(deckID, ratingID, token); err != nil {
+		reviewError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{})
+}
+
+// Replies to a review as the deck owner
+// Method: PUT
+func (c *DeckControllerImpl) Reply(ctx *gin.Context) {
+	token, deckID, ratingID, ok := reviewParams(ctx)
+	if !ok {
+		return
+	}
+
+	var request deck.ReplyRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrBadField.Error()})
+		return
+	}
+
+	if err := c.service.Reply(deckID, ratingID, &request.Reply, token); err != nil {
+		reviewError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{})
+}
+
+// Removes the owner reply of a review
+// Method: DELETE
+func (c *DeckControllerImpl) DeleteReply(ctx *gin.Context) {
+	token, deckID, ratingID, ok := reviewParams(ctx)
+	if !ok {
+		return
+	}
+
+	if err := c.service.Reply(deckID, ratingID, nil, token); err != nil {
+		reviewError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{})
+}
+
+// Reads the token, deckID and ratingID every review endpoint needs
+func reviewParams(ctx *gin.Context) (string, int64, int64, bool) {
+	token := ctx.GetHeader("Token")
+	if token == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrInvalidToken.Error()})
+		return "", 0, 0, false
+	}
+
+	deckID, err := strconv.Atoi(ctx.Param("deckID"))
+	if err != nil || deckID == 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrBadField.Error()})
+		return "", 0, 0, false
+	}
+
+	ratingID, err := strconv.Atoi(ctx.Param("ratingID"))
+	if err != nil || ratingID == 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrBadField.Error()})
+		return "", 0, 0, false
+	}
+
+	return token, int64(deckID), int64(ratingID), true
+}
+
+func reviewError(ctx *gin.Context, err error) {
+	if errors.Is(err, erro.ErrBadField) || errors.Is(err, erro.ErrInvalidToken) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, erro.ErrDeckNotFound) || errors.Is(err, erro.ErrRatingNotFound) || errors.Is(err, erro.ErrVoteNotFound) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, erro.ErrAlreadyVoted) {
+		ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, erro.ErrOwnDeckRating) || errors.Is(err, erro.ErrNotEligible) || errors.Is(err, erro.ErrForbidden) {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
@@ -5,6 +5,6 @@ type CreateRequest struct {
 	Owner       int64  // Obtained with token
 	Title       string `json:"title" binding:"required"`
 	Description string `json:"description"`
-	PicID       string `json:"pic_id`
+	PicID       string `json:"pic_id"`
 	Visible     bool   `json:"visible"` // Default hidden
 }
@@ -17,4 +17,7 @@ type Details struct {
 	TotalProgress  float32   `json:"total_progress,omitempty"`
 	CardsRevised   int64     `json:"cards_revised,omitempty"`
 	CardsRemaining int64     `json:"cards_remaining,omitempty"`
+	// Number of ratings for each star and ranking score, only in the shop view
+	RatingHistogram map[int8]int64 `json:"rating_histogram,omitempty"`
+	Score           float32        `json:"score,omitempty"`
 }
@@ -4,4 +4,12 @@ type Rating struct {
 	Rating      int8    `json:"rating,omitempty"`
 	RatingCount int64   `json:"rating_count,omitempty"`
 	AvgRating   float32 `json:"avg_rating,omitempty"`
+	Score       float32 `json:"score,omitempty"` // Bayesian average used to rank decks
+}
+
+// Whether an account can rate a deck
+type Eligibility struct {
+	Subscribed bool
+	Studied    int64 // Cards of the deck with progress
+	Cards      int64 // Cards in the deck
 }
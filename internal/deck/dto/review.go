@@ -0,0 +1,26 @@
+package deck
+
+import "time"
+
+// Rating with its written review. Title and body are empty for ratings
+// given without a review
+type Review struct {
+	RatingID  int64      `json:"rating_id"`
+	DeckID    int64      `json:"deck_id"`
+	AccID     int64      `json:"acc_id"`
+	Username  string     `json:"username"`
+	Rating    int8       `json:"rating"`
+	Title     string     `json:"title"`
+	Body      string     `json:"body"`
+	Helpful   int64      `json:"helpful"`
+	CreatedAt time.Time  `json:"created_at"`
+	EditedAt  *time.Time `json:"edited_at"`
+	Reply     *string    `json:"reply"`
+	RepliedAt *time.Time `json:"replied_at"`
+}
+
+// Orders reviews can be listed in
+const (
+	SortHelpful = "helpful"
+	SortRecent  = "recent"
+)
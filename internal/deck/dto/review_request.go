@@ -0,0 +1,21 @@
+package deck
+
+type ReviewRequest struct {
+	Token  string
+	DeckID int64
+	Rating int8   `json:"rating" binding:"required"`
+	Title  string `json:"title"`
+	Body   string `json:"body"`
+}
+
+type ReviewsRequest struct {
+	Token  string
+	DeckID int64
+	Sort   string // SortHelpful (default) or SortRecent
+	Page   int
+	Size   int
+}
+
+type ReplyRequest struct {
+	Reply string `json:"reply" binding:"required"`
+}
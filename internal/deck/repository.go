@@ -22,7 +22,7 @@ type DeckRepository interface {
 	// TODO: Only insert sub if token matches with the account's token of the id
 	AddDeckSubscription(token string, deckId int64) error
 	RemoveDeckSubscription(token string, deckId int64) error
-	CheckOwnership(deckID int64, token string) bool
+	IsHidden(deckID int64) bool
 
 	DeckDetailsSubscription(deckID int64, token string) (deck.Details, error)
 	DeckDetailsOwner(deckID int64, token string) (deck.Details, error)
@@ -32,6 +32,14 @@ type DeckRepository interface {
 	Rating(deckID int64, token string) (deck.Rating, error)
 	DeckRating(deckID int64) ([]deck.Rating, error)
 	DeleteRating(deckID int64, token string) error
+
+	SaveReview(deck.ReviewRequest) error
+	Reviews(deck.ReviewsRequest) ([]deck.Review, error)
+	Histogram(deckID int64) (map[int8]int64, error)
+	VoteHelpful(deckID int64, ratingID int64, token string) error
+	RemoveHelpful(deckID int64, ratingID int64, token string) error
+	Reply(deckID int64, ratingID int64, reply *string) error
+	Eligibility(deckID int64, token string) (deck.Eligibility, error)
 }
 
 type DeckRepositoryImpl struct {
@@ -43,7 +51,7 @@ type DeckRepositoryImpl struct {
 	DeleteStmt                 *sql.Stmt
 	AddDeckSubscriptionStmt    *sql.Stmt
 	RemoveDeckSubscriptionStmt *sql.Stmt
-	CheckOwnerStmt             *sql.Stmt
+	IsHiddenStmt               *sql.Stmt
 
 	DeckDetailsOwnerStmt *sql.Stmt
 	DeckDetailsShopStmt  *sql.Stmt
@@ -53,8 +61,32 @@ type DeckRepositoryImpl struct {
 	RatingStmt       *sql.Stmt
 	DeckRatingStmt   *sql.Stmt
 	DeleteRatingStmt *sql.Stmt
+
+	SaveReviewStmt     *sql.Stmt
+	HelpfulReviewsStmt *sql.Stmt
+	RecentReviewsStmt  *sql.Stmt
+	HistogramStmt      *sql.Stmt
+	VoteHelpfulStmt    *sql.Stmt
+	RemoveHelpfulStmt  *sql.Stmt
+	ReplyStmt          *sql.Stmt
+	EligibilityStmt    *sql.Stmt
 }
 
+// Weight and fallback mean of the Bayesian rating score
+const (
+	ratingPriorWeight = 10
+	ratingPriorMean   = 3
+)
+
+// Reviews of a deck with their helpful votes, ordered by the caller
+const reviewsQuery = `SELECT r.rating_id, r.deck_id, r.acc_id, a.username, r.rating, COALESCE(r.title, ''), COALESCE(r.body, ''),
+									COUNT(v.acc_id) AS helpful, r.created_at, r.edited_at, r.reply, r.replied_at
+								FROM RATING r
+								LEFT JOIN ACCOUNT a ON r.acc_id = a.acc_id
+								LEFT JOIN REVIEW_VOTE v ON r.rating_id = v.rating_id
+								WHERE r.deck_id = ?
+								GROUP BY r.rating_id`
+
 func NewDeckRepository(db *sql.DB) *DeckRepositoryImpl {
 	repo := &DeckRepositoryImpl{db: db}
 	err := repo.InitStatements()
@@ -72,10 +104,17 @@ func (repo *DeckRepositoryImpl) InitStatements() error {
 		return err
 	}
 
+	// Hidden decks can be read by their owner, collaborators and subscribers
 	repo.ByIdStmt, err = repo.db.Prepare(`SELECT d.* FROM DECK d 
 											LEFT JOIN ACCOUNT a ON d.acc_id = a.acc_id
 											WHERE d.deck_id = ? 
-												AND (d.visible = 1 OR a.token = ?)`)
+												AND (d.visible = 1 OR a.token = ? OR EXISTS(
+													SELECT 1 FROM DECK_COLLABORATOR c
+													JOIN ACCOUNT ca ON c.acc_id = ca.acc_id
+													WHERE c.deck_id = d.deck_id AND c.accepted_at IS NOT NULL AND ca.token = ?) OR EXISTS(
+													SELECT 1 FROM ACC_DECK s
+													JOIN ACCOUNT sa ON s.acc_id = sa.acc_id
+													WHERE s.deck_id = d.deck_id AND sa.token = ?))`)
 	if err != nil {
 		return err
 	}
@@ -95,7 +134,7 @@ func (repo *DeckRepositoryImpl) InitStatements() error {
 														LEFT JOIN ACCOUNT a ON d.acc_id = a.acc_id
 														LEFT JOIN ACCOUNT acc ON ad.acc_id = acc.acc_id
 														WHERE acc.username = ?
-														AND (d.visible = 1 OR (a.acc_id = ad.acc_id AND a.token = ?))`)
+														AND (d.visible = 1 OR acc.token = ?)`)
 	if err != nil {
 		return err
 	}
@@ -105,10 +144,7 @@ func (repo *DeckRepositoryImpl) InitStatements() error {
 		return err
 	}
 
-	repo.CheckOwnerStmt, err = repo.db.Prepare(`SELECT d.*
-													FROM DECK d
-													LEFT JOIN ACCOUNT a ON d.acc_id = a.acc_id
-													WHERE d.deck_id = ? AND a.token = ?`)
+	repo.IsHiddenStmt, err = repo.db.Prepare("SELECT COUNT(*) FROM DECK_HIDDEN WHERE deck_id = ?")
 	if err != nil {
 		return err
 	}
@@ -228,7 +264,14 @@ func (repo *DeckRepositoryImpl) InitStatements() error {
 		return err
 	}
 
-	repo.DeckRatingStmt, err = repo.db.Prepare(`SELECT COUNT(rating_id) AS rating_count, COALESCE(AVG(rating), 0) AS avg_rating FROM RATING WHERE deck_id = ?`)
+	// Bayesian average: every deck starts with ratingPriorWeight ratings
+	// equal to the mean of all ratings, so a few votes don't outrank
+	// decks with many good ones
+	repo.DeckRatingStmt, err = repo.db.Prepare(`SELECT
+													COUNT(rating_id) AS rating_count,
+													COALESCE(AVG(rating), 0) AS avg_rating,
+													(? * COALESCE((SELECT AVG(rating) FROM RATING), ?) + COALESCE(SUM(rating), 0)) / (? + COUNT(rating_id)) AS score
+												FROM RATING WHERE deck_id = ?`)
 	if err != nil {
 		return err
 	}
@@ -237,7 +280,68 @@ func (repo *DeckRepositoryImpl) InitStatements() error {
 													LEFT JOIN ACCOUNT ON RATING.acc_id = ACCOUNT.acc_id
 													WHERE deck_id = ? AND ACCOUNT.token = ?`)
 	if err != nil {
-		return nil
+		return err
+	}
+
+	repo.SaveReviewStmt, err = repo.db.Prepare(`INSERT INTO RATING (deck_id, acc_id, rating, title, body)
+													SELECT ?, acc_id, ?, ?, ? FROM ACCOUNT WHERE token = ?
+												ON DUPLICATE KEY UPDATE
+													edited_at = NOW(),
+													rating = VALUES(rating),
+													title = VALUES(title),
+													body = VALUES(body)`)
+	if err != nil {
+		return err
+	}
+
+	repo.HelpfulReviewsStmt, err = repo.db.Prepare(reviewsQuery + " ORDER BY helpful DESC, r.created_at DESC LIMIT ? OFFSET ?")
+	if err != nil {
+		return err
+	}
+
+	repo.RecentReviewsStmt, err = repo.db.Prepare(reviewsQuery + " ORDER BY r.created_at DESC LIMIT ? OFFSET ?")
+	if err != nil {
+		return err
+	}
+
+	repo.HistogramStmt, err = repo.db.Prepare("SELECT rating, COUNT(*) FROM RATING WHERE deck_id = ? GROUP BY rating")
+	if err != nil {
+		return err
+	}
+
+	// Own reviews can't be voted, the select returns no rows for them
+	repo.VoteHelpfulStmt, err = repo.db.Prepare(`INSERT INTO REVIEW_VOTE (rating_id, acc_id)
+													SELECT r.rating_id, a.acc_id
+													FROM RATING r, ACCOUNT a
+													WHERE r.rating_id = ? AND r.deck_id = ? AND a.token = ? AND r.acc_id <> a.acc_id`)
+	if err != nil {
+		return err
+	}
+
+	repo.RemoveHelpfulStmt, err = repo.db.Prepare(`DELETE v FROM REVIEW_VOTE v
+													LEFT JOIN RATING r ON v.rating_id = r.rating_id
+													LEFT JOIN ACCOUNT a ON v.acc_id = a.acc_id
+													WHERE v.rating_id = ? AND r.deck_id = ? AND a.token = ?`)
+	if err != nil {
+		return err
+	}
+
+	repo.ReplyStmt, err = repo.db.Prepare(`UPDATE RATING SET reply = ?, replied_at = IF(? IS NULL, NULL, NOW())
+											WHERE rating_id = ? AND deck_id = ?`)
+	if err != nil {
+		return err
+	}
+
+	repo.EligibilityStmt, err = repo.db.Prepare(`SELECT
+													(SELECT COUNT(*) FROM ACC_DECK ad WHERE ad.deck_id = ? AND ad.acc_id = a.acc_id),
+													(SELECT COUNT(*) FROM PROGRESS p
+														LEFT JOIN CARD c ON p.card_id = c.card_id
+														WHERE c.deck_id = ? AND p.acc_id = a.acc_id),
+													(SELECT COUNT(*) FROM CARD WHERE deck_id = ?)
+												FROM ACCOUNT a
+												WHERE a.token = ? AND a.token_expire >= NOW()`)
+	if err != nil {
+		return err
 	}
 
 	return nil
@@ -261,7 +365,7 @@ func (r *DeckRepositoryImpl) Create(deck deck.CreateRequest) (int64, error) {
 }
 
 func (r *DeckRepositoryImpl) ById(deckID int64, token string) (Deck, error) {
-	row := r.ByIdStmt.QueryRow(deckID, token)
+	row := r.ByIdStmt.QueryRow(deckID, token, token, token)
 	return scanDeck(row)
 }
 
@@ -372,11 +476,13 @@ func (r *DeckRepositoryImpl) RemoveDeckSubscription(token string, deckId int64)
 	return nil
 }
 
-// This function should be in service
-func (r *DeckRepositoryImpl) CheckOwnership(deckID int64, token string) bool {
-	row := r.CheckOwnerStmt.QueryRow(deckID, token)
-	_, err := scanDeck(row)
-	return err == nil
+// Hidden by an administrator
+func (r *DeckRepositoryImpl) IsHidden(deckID int64) bool {
+	var count int
+	if err := r.IsHiddenStmt.QueryRow(deckID).Scan(&count); err != nil {
+		return false
+	}
+	return count > 0
 }
 
 func (r *DeckRepositoryImpl) DeckDetailsSubscription(deckID int64, token string) (deck.Details, error) {
@@ -485,7 +591,7 @@ func (r *DeckRepositoryImpl) Rating(deckID int64, token string) (deck.Rating, er
 }
 
 func (r *DeckRepositoryImpl) DeckRating(deckID int64) ([]deck.Rating, error) {
-	rows, err := r.DeckRatingStmt.Query(deckID)
+	rows, err := r.DeckRatingStmt.Query(ratingPriorWeight, ratingPriorMean, ratingPriorWeight, deckID)
 	if err != nil {
 		return []deck.Rating{}, err
 	}
@@ -497,6 +603,7 @@ func (r *DeckRepositoryImpl) DeckRating(deckID int64) ([]deck.Rating, error) {
 		err := rows.Scan(
 			&rating.RatingCount,
 			&rating.AvgRating,
+			&rating.Score,
 		)
 		if err != nil {
 			return []deck.Rating{}, err
@@ -529,6 +636,165 @@ func (r *DeckRepositoryImpl) DeleteRating(deckID int64, token string) error {
 	return nil
 }
 
+func (r *DeckRepositoryImpl) SaveReview(review deck.ReviewRequest) error {
+	_, err := r.SaveReviewStmt.Exec(review.DeckID, review.Rating, nullString(review.Title), nullString(review.Body), review.Token)
+	if err != nil {
+		if mysqlErr, ok := err.(*mysql.MySQLError); ok {
+			if mysqlErr.Number == 1048 {
+				return erro.ErrInvalidToken
+			}
+			if mysqlErr.Number == 1452 {
+				return erro.ErrDeckNotFound
+			}
+		}
+		return err
+	}
+	return nil
+}
+
+func (r *DeckRepositoryImpl) Reviews(request deck.ReviewsRequest) ([]deck.Review, error) {
+	stmt := r.HelpfulReviewsStmt
+	if request.Sort == deck.SortRecent {
+		stmt = r.RecentReviewsStmt
+	}
+
+	rows, err := stmt.Query(request.DeckID, request.Size, (request.Page-1)*request.Size)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reviews := []deck.Review{}
+	for rows.Next() {
+		var review deck.Review
+		err := rows.Scan(
+			&review.RatingID,
+			&review.DeckID,
+			&review.AccID,
+			&review.Username,
+			&review.Rating,
+			&review.Title,
+			&review.Body,
+			&review.Helpful,
+			&review.CreatedAt,
+			&review.EditedAt,
+			&review.Reply,
+			&review.RepliedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		reviews = append(reviews, review)
+	}
+
+	return reviews, nil
+}
+
+// Number of ratings per star
+func (r *DeckRepositoryImpl) Histogram(deckID int64) (map[int8]int64, error) {
+	rows, err := r.HistogramStmt.Query(deckID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	histogram := make(map[int8]int64)
+	for rows.Next() {
+		var rating int8
+		var count int64
+		if err := rows.Scan(&rating, &count); err != nil {
+			return nil, err
+		}
+		histogram[rating] = count
+	}
+
+	return histogram, nil
+}
+
+func (r *DeckRepositoryImpl) VoteHelpful(deckID int64, ratingID int64, token string) error {
+	result, err := r.VoteHelpfulStmt.Exec(ratingID, deckID, token)
+	if err != nil {
+		if mysqlErr, ok := err.(*mysql.MySQLError); ok && mysqlErr.Number == 1062 {
+			return erro.ErrAlreadyVoted
+		}
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return erro.ErrRatingNotFound
+	}
+
+	return nil
+}
+
+func (r *DeckRepositoryImpl) RemoveHelpful(deckID int64, ratingID int64, token string) error {
+	result, err := r.RemoveHelpfulStmt.Exec(ratingID, deckID, token)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return erro.ErrVoteNotFound
+	}
+
+	return nil
+}
+
+// Sets the owner reply of a review, nil removes it
+func (r *DeckRepositoryImpl) Reply(deckID int64, ratingID int64, reply *string) error {
+	result, err := r.ReplyStmt.Exec(reply, reply, ratingID, deckID)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return erro.ErrRatingNotFound
+	}
+
+	return nil
+}
+
+func (r *DeckRepositoryImpl) Eligibility(deckID int64, token string) (deck.Eligibility, error) {
+	var eligibility deck.Eligibility
+	var subscribed int
+	err := r.EligibilityStmt.QueryRow(deckID, deckID, deckID, token).Scan(
+		&subscribed,
+		&eligibility.Studied,
+		&eligibility.Cards,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return deck.Eligibility{}, erro.ErrInvalidToken
+		}
+		return deck.Eligibility{}, err
+	}
+
+	eligibility.Subscribed = subscribed > 0
+	return eligibility, nil
+}
+
+func nullString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
 func updateDeckField(query *strings.Builder, args *[]any, field string, value any) {
 	// Just checking if it's a date and it isn't empty
 	if _, ok := value.(time.Time); ok && value.(time.Time).IsZero() {
@@ -5,9 +5,11 @@ import (
 	"fmt"
 	"io"
 	"learn-swiping-api/erro"
+	"learn-swiping-api/internal/collaborator"
 	deck "learn-swiping-api/internal/deck/dto"
 	"learn-swiping-api/internal/picture"
 	"path/filepath"
+	"strings"
 	"time"
 )
 
@@ -26,14 +28,36 @@ type DeckService interface {
 	Rating(deckID int64, token string) (deck.Rating, error)
 	DeckRating(deckID int64) ([]deck.Rating, error)
 	DeleteRating(deckID int64, token string) error
+
+	SaveReview(deck.ReviewRequest) error
+	Reviews(deck.ReviewsRequest) ([]deck.Review, error)
+	VoteHelpful(deckID int64, ratingID int64, token string) error
+	RemoveHelpful(deckID int64, ratingID int64, token string) error
+	Reply(deckID int64, ratingID int64, reply *string, token string) error
+	checkRating(deckID int64, rating int8, token string) error
 }
 
+const (
+	maxReviewTitle  = 120
+	maxReviewBody   = 5000
+	maxReviewReply  = 2000
+	defaultPageSize = 20
+	maxPageSize     = 100
+
+	minRating = 1
+	maxRating = 5
+	// Cards of a deck an account must have studied to rate it. Smaller
+	// decks need all of them
+	minStudiedToRate = 5
+)
+
 type DeckServiceImpl struct {
-	repository DeckRepository
+	repository    DeckRepository
+	collaborators collaborator.CollaboratorService
 }
 
-func NewDeckService(repository DeckRepository) DeckService {
-	return &DeckServiceImpl{repository: repository}
+func NewDeckService(repository DeckRepository, collaborators collaborator.CollaboratorService) DeckService {
+	return &DeckServiceImpl{repository: repository, collaborators: collaborators}
 }
 
 func (s *DeckServiceImpl) Create(request deck.CreateRequest) (int64, error) {
@@ -81,6 +105,15 @@ func (s *DeckServiceImpl) Update(request deck.UpdateRequest, token string) error
 		return erro.ErrBadField
 	}
 
+	if _, err := s.collaborators.Authorize(request.DeckID, token, collaborator.WriteDeck); err != nil {
+		return err
+	}
+
+	// Only an administrator can publish a deck they hid
+	if request.Visible != nil && *request.Visible && s.repository.IsHidden(request.DeckID) {
+		return erro.ErrDeckHidden
+	}
+
 	deck := Deck{
 		Title:       request.Title,
 		Description: request.Description,
@@ -117,23 +150,23 @@ func (s *DeckServiceImpl) Update(request deck.UpdateRequest, token string) error
 		deck.PicID = picID
 	}
 
-	// TODO: Only update if requested deck owner matched with the token provided directly into the update query if possible
-	if s.repository.CheckOwnership(request.DeckID, token) {
-		return s.repository.Update(request.DeckID, deck)
-	}
-
-	return erro.ErrInvalidToken
+	return s.repository.Update(request.DeckID, deck)
 }
 
+// Only the owner can delete a deck
 func (s *DeckServiceImpl) Delete(deckID int64, token string) error {
-	// Doesn't work as intended. revisar
-	if s.repository.CheckOwnership(deckID, token) {
-		return s.repository.Delete(deckID)
+	if _, err := s.collaborators.Authorize(deckID, token, collaborator.DeleteDeck); err != nil {
+		return err
 	}
-	return erro.ErrInvalidToken
+	return s.repository.Delete(deckID)
 }
 
+// Hidden decks can only be subscribed to by the people working on them,
+// everyone else needs a share link
 func (s *DeckServiceImpl) AddDeckSubscription(request deck.DeckSuscriptionRequest) error {
+	if _, err := s.collaborators.Authorize(request.DeckID, request.Token, collaborator.ReadDeck); err != nil {
+		return err
+	}
 	return s.repository.AddDeckSubscription(request.Token, request.DeckID)
 }
 
@@ -157,11 +190,26 @@ func (s *DeckServiceImpl) DeckDetails(mode int8, deckID int64, token string) (de
 		details, err = s.repository.DeckDetailsOwner(deckID, token)
 	default:
 		details, err = s.repository.DeckDetailsShop(deckID)
+		if err != nil {
+			return deck.Details{}, err
+		}
+		details.RatingHistogram, err = s.repository.Histogram(deckID)
+		if err != nil {
+			return deck.Details{}, err
+		}
+		var ratings []deck.Rating
+		ratings, err = s.repository.DeckRating(deckID)
+		if err == nil {
+			details.Score = ratings[0].Score
+		}
 	}
 	return details, err
 }
 
 func (s *DeckServiceImpl) SaveRating(deckID int64, rating int8, token string) error {
+	if err := s.checkRating(deckID, rating, token); err != nil {
+		return err
+	}
 	return s.repository.SaveRating(deckID, rating, token)
 }
 
@@ -176,3 +224,96 @@ func (s *DeckServiceImpl) DeckRating(deckID int64) ([]deck.Rating, error) {
 func (s *DeckServiceImpl) DeleteRating(deckID int64, token string) error {
 	return s.repository.DeleteRating(deckID, token)
 }
+
+// Saves the rating of the caller with an optional written review.
+// Saving it again edits the previous one
+func (s *DeckServiceImpl) SaveReview(request deck.ReviewRequest) error {
+	request.Title = strings.TrimSpace(request.Title)
+	request.Body = strings.TrimSpace(request.Body)
+	if len(request.Title) > maxReviewTitle || len(request.Body) > maxReviewBody {
+		return erro.ErrBadField
+	}
+
+	if err := s.checkRating(request.DeckID, request.Rating, request.Token); err != nil {
+		return err
+	}
+
+	return s.repository.SaveReview(request)
+}
+
+// Reviews are as visible as the deck they're about
+func (s *DeckServiceImpl) Reviews(request deck.ReviewsRequest) ([]deck.Review, error) {
+	if _, err := s.collaborators.Authorize(request.DeckID, request.Token, collaborator.ReadDeck); err != nil {
+		return nil, err
+	}
+	if request.Sort == "" {
+		request.Sort = deck.SortHelpful
+	}
+	if request.Sort != deck.SortHelpful && request.Sort != deck.SortRecent {
+		return nil, erro.ErrBadField
+	}
+	if request.Page < 1 {
+		request.Page = 1
+	}
+	if request.Size < 1 {
+		request.Size = defaultPageSize
+	}
+	if request.Size > maxPageSize {
+		return nil, erro.ErrBadField
+	}
+
+	return s.repository.Reviews(request)
+}
+
+func (s *DeckServiceImpl) VoteHelpful(deckID int64, ratingID int64, token string) error {
+	return s.repository.VoteHelpful(deckID, ratingID, token)
+}
+
+func (s *DeckServiceImpl) RemoveHelpful(deckID int64, ratingID int64, token string) error {
+	return s.repository.RemoveHelpful(deckID, ratingID, token)
+}
+
+// Only the owner and maintainers can reply to reviews. A nil reply removes it
+func (s *DeckServiceImpl) Reply(deckID int64, ratingID int64, reply *string, token string) error {
+	if reply != nil {
+		trimmed := strings.TrimSpace(*reply)
+		if trimmed == "" || len(trimmed) > maxReviewReply {
+			return erro.ErrBadField
+		}
+		reply = &trimmed
+	}
+
+	if _, err := s.collaborators.Authorize(deckID, token, collaborator.ReplyReviews); err != nil {
+		return err
+	}
+
+	return s.repository.Reply(deckID, ratingID, reply)
+}
+
+// Ratings go from 1 to 5 and can only be given by subscribers who don't
+// write the deck and have studied enough cards of it
+func (s *DeckServiceImpl) checkRating(deckID int64, rating int8, token string) error {
+	if rating < minRating || rating > maxRating {
+		return erro.ErrBadField
+	}
+
+	access, err := s.collaborators.Access(deckID, token)
+	if err != nil {
+		return err
+	}
+	if access.Can(collaborator.WriteCards) {
+		return erro.ErrOwnDeckRating
+	}
+
+	eligibility, err := s.repository.Eligibility(deckID, token)
+	if err != nil {
+		return err
+	}
+
+	required := min(eligibility.Cards, minStudiedToRate)
+	if !eligibility.Subscribed || eligibility.Cards == 0 || eligibility.Studied < required {
+		return erro.ErrNotEligible
+	}
+
+	return nil
+}
@@ -0,0 +1,60 @@
+package export
+
+import (
+	"errors"
+	"learn-swiping-api/erro"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ExportController interface {
+	Export(*gin.Context) // GET
+}
+
+type ExportControllerImpl struct {
+	service ExportService
+}
+
+func NewExportController(service ExportService) ExportController {
+	return &ExportControllerImpl{service: service}
+}
+
+// Downloads a deck as JSON, CSV, Markdown or printable PDF flashcards,
+// chosen with ?format=
+// Method: GET
+func (c *ExportControllerImpl) Export(ctx *gin.Context) {
+	token := ctx.GetHeader("Token")
+	if token == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrInvalidToken.Error()})
+		return
+	}
+
+	deckID, err := strconv.Atoi(ctx.Param("deckID"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrBadField.Error()})
+		return
+	}
+
+	file, err := c.service.Export(int64(deckID), token, ctx.DefaultQuery("format", FormatJSON))
+	if err != nil {
+		if errors.Is(err, erro.ErrBadField) || errors.Is(err, erro.ErrInvalidToken) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, erro.ErrForbidden) {
+			ctx.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, erro.ErrDeckNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.Header("Content-Disposition", `attachment; filename="`+file.Name+`"`)
+	ctx.Data(http.StatusOK, file.ContentType, file.Data)
+}
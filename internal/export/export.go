@@ -0,0 +1,39 @@
+package export
+
+import (
+	"learn-swiping-api/internal/card"
+	"learn-swiping-api/internal/deck"
+	"time"
+)
+
+const (
+	FormatJSON     = "json"
+	FormatCSV      = "csv"
+	FormatMarkdown = "md"
+	FormatPDF      = "pdf"
+)
+
+// Version of the JSON backup layout
+const backupVersion = 1
+
+// JSON export of a deck. Card import reads the cards back
+type Backup struct {
+	Version    int         `json:"version"`
+	ExportedAt time.Time   `json:"exported_at"`
+	Deck       deck.Deck   `json:"deck"`
+	Cards      []card.Card `json:"cards"`
+	Pictures   []Picture   `json:"pictures"` // Used by the deck or its cards
+}
+
+// Pictures aren't embedded, they can be downloaded from the url
+type Picture struct {
+	PicID string `json:"pic_id"`
+	URL   string `json:"url"`
+}
+
+// Exported file
+type File struct {
+	Name        string
+	ContentType string
+	Data        []byte
+}
@@ -0,0 +1,299 @@
+package export
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"learn-swiping-api/internal/card"
+	"strings"
+)
+
+// A4 in points, cut into a grid of cards
+const (
+	pageWidth   = 595.0
+	pageHeight  = 842.0
+	margin      = 36.0
+	columns     = 2
+	rows        = 4
+	cellPadding = 14.0
+	titleSize   = 12.0
+	textSize    = 11.0
+	footerSize  = 8.0
+	lineHeight  = 1.3
+)
+
+// Widths of the printable ASCII characters in Helvetica, per 1000 points
+var helveticaWidths = [95]int{
+	278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+	1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+	333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+	556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+}
+
+// Windows-1252 bytes of the characters it has outside of Latin-1
+var winAnsi = map[rune]byte{
+	'€': 0x80, '‚': 0x82, 'ƒ': 0x83, '„': 0x84, '…': 0x85, '†': 0x86, '‡': 0x87, 'ˆ': 0x88,
+	'‰': 0x89, 'Š': 0x8a, '‹': 0x8b, 'Œ': 0x8c, 'Ž': 0x8e, '‘': 0x91, '’': 0x92, '“': 0x93,
+	'”': 0x94, '•': 0x95, '–': 0x96, '—': 0x97, '˜': 0x98, '™': 0x99, 'š': 0x9a, '›': 0x9b,
+	'œ': 0x9c, 'ž': 0x9e, 'Ÿ': 0x9f,
+}
+
+// One side of a printed card
+type side struct {
+	title string
+	lines []string
+}
+
+// Printable flashcards. Each sheet of fronts is followed by the sheet of
+// their backs, mirrored so they line up when printed on both sides
+// flipping on the long edge
+func toPDF(title string, cards []card.Card) ([]byte, error) {
+	perPage := columns * rows
+	var pages [][]byte
+	for start := 0; start < len(cards) || start == 0; start += perPage {
+		end := min(start+perPage, len(cards))
+		sheet := cards[start:end]
+
+		var fronts, backs bytes.Buffer
+		for i, c := range sheet {
+			column, row := i%columns, i/columns
+			drawCell(&fronts, column, row, front(c))
+			drawCell(&backs, columns-1-column, row, back(c))
+		}
+
+		page := start/perPage + 1
+		drawFooter(&fronts, fmt.Sprintf("%s - sheet %d, fronts", title, page))
+		pages = append(pages, fronts.Bytes())
+		if len(sheet) > 0 {
+			drawFooter(&backs, fmt.Sprintf("%s - sheet %d, backs", title, page))
+			pages = append(pages, backs.Bytes())
+		}
+	}
+	return writePDF(pages)
+}
+
+// Cloze cards show their deletions blanked with their hints, typed ones
+// what's asked and occlusion ones how many areas there are to name
+func front(c card.Card) side {
+	s := side{title: toText(c.Title), lines: []string{toText(c.Front)}}
+	switch {
+	case c.CardType == card.TypeCloze && c.Cloze != nil:
+		text := c.Cloze.Fill(func(_ string, hint string) string {
+			if hint == "" {
+				hint = "..."
+			}
+			return "[" + hint + "]"
+		})
+		s.lines = append([]string{toText(text)}, s.lines...)
+	case c.CardType == card.TypeTyped:
+		s.lines = append(s.lines, "", toText(c.Question))
+	case c.CardType == card.TypeOcclusion && c.Occlusion != nil:
+		s.lines = append(s.lines, "", fmt.Sprintf("Name the %d areas of the picture", len(c.Occlusion.Masks)))
+	}
+	return s
+}
+
+func back(c card.Card) side {
+	s := side{lines: []string{toText(c.Back)}}
+	switch {
+	case c.CardType == card.TypeCloze && c.Cloze != nil:
+		text := c.Cloze.Fill(func(answer string, _ string) string { return "[" + answer + "]" })
+		s.lines = append([]string{toText(text)}, s.lines...)
+		return s
+	case c.CardType == card.TypeTyped && c.Typed != nil:
+		answers := make([]string, 0, len(c.Typed.Answers))
+		for _, answer := range c.Typed.Answers {
+			answers = append(answers, toText(answer))
+		}
+		s.lines = append(s.lines, "", "Answer: "+strings.Join(answers, " / "))
+		return s
+	case c.CardType == card.TypeOcclusion && c.Occlusion != nil:
+		for i, m := range c.Occlusion.Masks {
+			s.lines = append(s.lines, fmt.Sprintf("%d. %s", i+1, toText(m.Label)))
+		}
+		return s
+	}
+
+	if correct, _ := c.Answers(); c.Question != "" && len(correct) > 0 {
+		answers := make([]string, 0, len(correct))
+		for _, answer := range correct {
+			answers = append(answers, toText(answer))
+		}
+		s.lines = append(s.lines, "", toText(c.Question), "Answer: "+strings.Join(answers, ", "))
+	}
+	return s
+}
+
+// Dashed cutting guide with the text of the side centered inside
+func drawCell(b *bytes.Buffer, column int, row int, s side) {
+	width := (pageWidth - 2*margin) / columns
+	height := (pageHeight - 2*margin) / rows
+	x := margin + float64(column)*width
+	y := pageHeight - margin - float64(row+1)*height
+
+	fmt.Fprintf(b, "q 0.6 G 0.5 w [4 4] 0 d %.2f %.2f %.2f %.2f re S Q\n", x, y, width, height)
+
+	type line struct {
+		text string
+		bold bool
+	}
+	var lines []line
+	textWidth := width - 2*cellPadding
+	if s.title != "" {
+		for _, l := range wrap(s.title, titleSize, textWidth) {
+			lines = append(lines, line{l, true})
+		}
+		lines = append(lines, line{"", false})
+	}
+	for _, paragraph := range s.lines {
+		for _, part := range strings.Split(paragraph, "\n") {
+			for _, l := range wrap(part, textSize, textWidth) {
+				lines = append(lines, line{l, false})
+			}
+		}
+	}
+
+	// Whatever doesn't fit is cut
+	fit := int((height - 2*cellPadding) / (textSize * lineHeight))
+	if len(lines) > fit {
+		lines = lines[:fit]
+		lines[fit-1].text = strings.TrimSpace(lines[fit-1].text) + "..."
+	}
+
+	top := y + height/2 + float64(len(lines))*textSize*lineHeight/2 - textSize
+	for i, l := range lines {
+		size, font := textSize, "F1"
+		if l.bold {
+			size, font = titleSize, "F2"
+		}
+		lineX := x + (width-textWidthOf(l.text, size))/2
+		lineY := top - float64(i)*textSize*lineHeight
+		fmt.Fprintf(b, "BT /%s %.1f Tf %.2f %.2f Td (%s) Tj ET\n", font, size, lineX, lineY, pdfString(l.text))
+	}
+}
+
+func drawFooter(b *bytes.Buffer, text string) {
+	fmt.Fprintf(b, "BT /F1 %.1f Tf %.2f %.2f Td (%s) Tj ET\n", footerSize, margin, margin/2, pdfString(text))
+}
+
+// Splits a paragraph into lines that fit a width
+func wrap(text string, size float64, width float64) []string {
+	words := strings.Fields(text)
+	if len(words) == 0 {
+		return []string{""}
+	}
+
+	var lines []string
+	current := ""
+	for _, word := range words {
+		// Words longer than a line are cut
+		for textWidthOf(word, size) > width {
+			cut := len([]rune(word))
+			for cut > 1 && textWidthOf(string([]rune(word)[:cut]), size) > width {
+				cut--
+			}
+			if current != "" {
+				lines = append(lines, current)
+				current = ""
+			}
+			lines = append(lines, string([]rune(word)[:cut]))
+			word = string([]rune(word)[cut:])
+		}
+
+		if current == "" {
+			current = word
+		} else if textWidthOf(current+" "+word, size) <= width {
+			current += " " + word
+		} else {
+			lines = append(lines, current)
+			current = word
+		}
+	}
+	if current != "" {
+		lines = append(lines, current)
+	}
+	return lines
+}
+
+func textWidthOf(text string, size float64) float64 {
+	total := 0
+	for _, r := range text {
+		if r >= ' ' && r <= '~' {
+			total += helveticaWidths[r-' ']
+		} else {
+			total += 556
+		}
+	}
+	return float64(total) * size / 1000
+}
+
+// Literal string in the fonts' encoding. Characters it doesn't have are
+// printed as ?
+func pdfString(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		c, ok := winAnsi[r]
+		switch {
+		case ok:
+		case r >= 0xa0 && r <= 0xff, r >= ' ' && r <= '~':
+			c = byte(r)
+		default:
+			c = '?'
+		}
+		if c == '(' || c == ')' || c == '\\' {
+			b.WriteByte('\\')
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+// Lays out the document objects. Pages share the two fonts
+func writePDF(contents [][]byte) ([]byte, error) {
+	var objects [][]byte
+	add := func(object string) int {
+		objects = append(objects, []byte(object))
+		return len(objects)
+	}
+
+	add("<< /Type /Catalog /Pages 2 0 R >>")
+	add("") // Pages, once the kids are known
+	add("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	add("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+
+	kids := make([]string, 0, len(contents))
+	for _, content := range contents {
+		var compressed bytes.Buffer
+		zw := zlib.NewWriter(&compressed)
+		if _, err := zw.Write(content); err != nil {
+			return nil, err
+		}
+		if err := zw.Close(); err != nil {
+			return nil, err
+		}
+
+		stream := add(fmt.Sprintf("<< /Length %d /Filter /FlateDecode >>\nstream\n%s\nendstream", compressed.Len(), compressed.String()))
+		page := add(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.0f %.0f] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>",
+			pageWidth, pageHeight, stream))
+		kids = append(kids, fmt.Sprintf("%d 0 R", page))
+	}
+	objects[1] = []byte(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(kids)))
+
+	var b bytes.Buffer
+	b.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	offsets := make([]int, len(objects))
+	for i, object := range objects {
+		offsets[i] = b.Len()
+		fmt.Fprintf(&b, "%d 0 obj\n%s\nendobj\n", i+1, object)
+	}
+
+	xref := b.Len()
+	fmt.Fprintf(&b, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&b, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&b, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return b.Bytes(), nil
+}
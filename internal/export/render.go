@@ -0,0 +1,217 @@
+package export
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"html"
+	"learn-swiping-api/internal/card"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/microcosm-cc/bluemonday"
+)
+
+var (
+	pictureRegexp = regexp.MustCompile(`/pics/([A-Za-z0-9_-]+\.(?:png|jpeg|webp))`)
+	breakRegexp   = regexp.MustCompile(`(?i)<br\s*/?>|</(?:div|p|li)>`)
+	spaceRegexp   = regexp.MustCompile(`[ \t\f\v\x{00A0}]+`)
+
+	textPolicy = bluemonday.StrictPolicy()
+)
+
+func toJSON(backup Backup) ([]byte, error) {
+	return json.MarshalIndent(backup, "", "  ")
+}
+
+// Same columns card import maps by default. Options are split into the
+// correct and the wrong ones, payloads go as JSON in the column of their
+// card type, only there when a card has it
+func toCSV(cards []card.Card) ([]byte, error) {
+	answers, wrongs := 0, 0
+	var payloads []string
+	for _, c := range cards {
+		correct, wrong := c.Answers()
+		answers, wrongs = max(answers, len(correct)), max(wrongs, len(wrong))
+		if c.CardType != card.TypeBasic && !slices.Contains(payloads, c.CardType) {
+			payloads = append(payloads, c.CardType)
+		}
+	}
+	slices.Sort(payloads)
+
+	header := []string{"title", "front", "back", "question", "answer_type", "card_type"}
+	header = append(header, payloads...)
+	first := len(header) // Of the answers
+	for i := 1; i <= answers; i++ {
+		header = append(header, "answer "+strconv.Itoa(i))
+	}
+	for i := 1; i <= wrongs; i++ {
+		header = append(header, "wrong "+strconv.Itoa(i))
+	}
+
+	buf := &bytes.Buffer{}
+	w := csv.NewWriter(buf)
+	if err := w.Write(header); err != nil {
+		return nil, err
+	}
+	for _, c := range cards {
+		record := make([]string, len(header))
+		copy(record, []string{c.Title, c.Front, c.Back, c.Question, c.AnswerType, c.CardType})
+		if i := slices.Index(payloads, c.CardType); i >= 0 {
+			payload, err := c.Payload()
+			if err != nil {
+				return nil, err
+			}
+			record[6+i] = string(payload)
+		}
+		correct, wrong := c.Answers()
+		copy(record[first:], correct)
+		copy(record[first+answers:], wrong)
+		if err := w.Write(record); err != nil {
+			return nil, err
+		}
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+// Study sheet with every card and its answers
+func toMarkdown(backup Backup) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n", backup.Deck.Title)
+	if backup.Deck.Description != "" {
+		fmt.Fprintf(&b, "%s\n\n", markdownText(backup.Deck.Description))
+	}
+
+	// Fields that can be left empty
+	optional := func(field string) {
+		if strings.TrimSpace(field) != "" {
+			fmt.Fprintf(&b, "%s\n\n", markdownText(field))
+		}
+	}
+
+	for i, c := range backup.Cards {
+		fmt.Fprintf(&b, "## %d. %s\n\n", i+1, c.Title)
+		switch {
+		case c.CardType == card.TypeCloze && c.Cloze != nil:
+			// Deletions are shown in bold
+			text := c.Cloze.Fill(func(answer string, _ string) string { return "**" + answer + "**" })
+			fmt.Fprintf(&b, "%s\n\n", markdownText(text))
+			optional(c.Front)
+			optional(c.Back)
+			continue
+		case c.CardType == card.TypeOcclusion && c.Occlusion != nil:
+			optional(c.Front)
+			fmt.Fprintf(&b, "![](%s/pics/%s)\n\n", appURL(), c.Occlusion.PicID)
+			for n, m := range c.Occlusion.Masks {
+				fmt.Fprintf(&b, "%d. %s\n", n+1, markdownText(m.Label))
+			}
+			b.WriteString("\n")
+			optional(c.Back)
+			continue
+		}
+
+		fmt.Fprintf(&b, "%s\n\n---\n\n%s\n\n", markdownText(c.Front), markdownText(c.Back))
+		if c.CardType == card.TypeTyped && c.Typed != nil {
+			fmt.Fprintf(&b, "**%s**\n\n", markdownText(c.Question))
+			for _, answer := range c.Typed.Answers {
+				fmt.Fprintf(&b, "- %s\n", markdownText(answer))
+			}
+			b.WriteString("\n")
+		} else if c.Question != "" {
+			fmt.Fprintf(&b, "**%s**\n\n", markdownText(c.Question))
+			for _, o := range c.Options {
+				mark := " "
+				if o.Correct {
+					mark = "x"
+				}
+				fmt.Fprintf(&b, "- [%s] %s\n", mark, markdownText(o.Answer))
+			}
+			b.WriteString("\n")
+		}
+	}
+	return []byte(b.String())
+}
+
+// Keeps the line breaks of the field and makes pictures work outside
+// the app
+func markdownText(field string) string {
+	field = pictureRegexp.ReplaceAllStringFunc(field, func(path string) string {
+		return appURL() + path
+	})
+	return strings.ReplaceAll(strings.TrimSpace(field), "\n", "  \n")
+}
+
+// Plain text of a field, keeping its line breaks
+func toText(field string) string {
+	field = breakRegexp.ReplaceAllString(field, "\n")
+	field = html.UnescapeString(textPolicy.Sanitize(field))
+
+	lines := strings.Split(field, "\n")
+	kept := lines[:0]
+	for _, line := range lines {
+		line = strings.TrimSpace(spaceRegexp.ReplaceAllString(line, " "))
+		if line != "" {
+			kept = append(kept, line)
+		}
+	}
+	return strings.Join(kept, "\n")
+}
+
+// Pictures of the deck and its cards, in order of appearance
+func pictures(backup Backup) []Picture {
+	found := []Picture{}
+	seen := map[string]bool{}
+	add := func(picID string) {
+		if picID != "" && !seen[picID] {
+			seen[picID] = true
+			found = append(found, Picture{PicID: picID, URL: appURL() + "/pics/" + picID})
+		}
+	}
+
+	add(backup.Deck.PicID)
+	for _, c := range backup.Cards {
+		fields := []string{c.Front, c.Back, c.Question}
+		for _, o := range c.Options {
+			fields = append(fields, o.Answer)
+		}
+		if c.Cloze != nil {
+			fields = append(fields, c.Cloze.Text)
+		}
+		if c.Occlusion != nil {
+			add(c.Occlusion.PicID)
+		}
+		for _, field := range fields {
+			for _, match := range pictureRegexp.FindAllStringSubmatch(field, -1) {
+				add(match[1])
+			}
+		}
+	}
+	return found
+}
+
+func appURL() string {
+	base := os.Getenv("APP_URL")
+	if base == "" {
+		base = "http://localhost:9999"
+	}
+	return base
+}
+
+// Title safe to use in a Content-Disposition header
+func fileName(title string) string {
+	name := strings.Map(func(r rune) rune {
+		if r < ' ' || r == '"' || r == '\\' || r == '/' || r > '~' {
+			return '_'
+		}
+		return r
+	}, strings.TrimSpace(title))
+	if name == "" {
+		return "deck"
+	}
+	return name
+}
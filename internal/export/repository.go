@@ -0,0 +1,113 @@
+package export
+
+import (
+	"database/sql"
+	"learn-swiping-api/erro"
+	"learn-swiping-api/internal/card"
+	"learn-swiping-api/internal/deck"
+	"log"
+)
+
+type ExportRepository interface {
+	Deck(deckID int64) (deck.Deck, error)
+	Cards(deckID int64) ([]card.Card, error)
+}
+
+type ExportRepositoryImpl struct {
+	db          *sql.DB
+	DeckStmt    *sql.Stmt
+	CardsStmt   *sql.Stmt
+	OptionsStmt *sql.Stmt
+}
+
+func NewExportRepository(db *sql.DB) *ExportRepositoryImpl {
+	repo := &ExportRepositoryImpl{db: db}
+	err := repo.InitStatements()
+	if err != nil {
+		log.Fatalln(err)
+	}
+	return repo
+}
+
+func (r *ExportRepositoryImpl) InitStatements() error {
+	var err error
+	r.DeckStmt, err = r.db.Prepare(`SELECT deck_id, acc_id, title, description, pic_id, visible, updated_at, created_at
+										FROM DECK WHERE deck_id = ?`)
+	if err != nil {
+		return err
+	}
+
+	// Siblings are made from the payload of their source, so they aren't
+	// exported on their own
+	r.CardsStmt, err = r.db.Prepare("SELECT " + card.CardColumns + " " + card.CardFrom +
+		" WHERE c.deck_id = ? AND c.parent_id IS NULL ORDER BY " + card.CardOrder)
+	if err != nil {
+		return err
+	}
+
+	r.OptionsStmt, err = r.db.Prepare(`SELECT o.option_id, o.card_id, o.answer, o.correct FROM CARD_OPTION o
+										JOIN CARD c ON o.card_id = c.card_id
+										WHERE c.deck_id = ?
+										ORDER BY o.card_id, o.position`)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *ExportRepositoryImpl) Deck(deckID int64) (deck.Deck, error) {
+	var d deck.Deck
+	err := r.DeckStmt.QueryRow(deckID).Scan(&d.ID, &d.Owner, &d.Title, &d.Description, &d.PicID, &d.Visible, &d.UpdatedAt, &d.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return deck.Deck{}, erro.ErrDeckNotFound
+		}
+		return deck.Deck{}, err
+	}
+	return d, nil
+}
+
+// Cards of a deck with their options, in the order of the deck
+func (r *ExportRepositoryImpl) Cards(deckID int64) ([]card.Card, error) {
+	rows, err := r.CardsStmt.Query(deckID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	cards := []card.Card{}
+	index := map[int64]int{}
+	for rows.Next() {
+		c, err := card.ScanCard(rows)
+		if err != nil {
+			return nil, err
+		}
+		if c.CardType == card.TypeBasic {
+			c.Options = []card.Option{}
+		}
+		index[c.CardID] = len(cards)
+		cards = append(cards, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	options, err := r.OptionsStmt.Query(deckID)
+	if err != nil {
+		return nil, err
+	}
+	defer options.Close()
+
+	for options.Next() {
+		var o card.Option
+		if err := options.Scan(&o.OptionID, &o.CardID, &o.Answer, &o.Correct); err != nil {
+			return nil, err
+		}
+		if i, ok := index[o.CardID]; ok {
+			cards[i].Options = append(cards[i].Options, o)
+		}
+	}
+
+	return cards, options.Err()
+}
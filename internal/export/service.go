@@ -0,0 +1,65 @@
+package export
+
+import (
+	"learn-swiping-api/erro"
+	"learn-swiping-api/internal/collaborator"
+	"time"
+)
+
+type ExportService interface {
+	Export(deckID int64, token string, format string) (File, error)
+}
+
+type ExportServiceImpl struct {
+	repository    ExportRepository
+	collaborators collaborator.CollaboratorService
+}
+
+func NewExportService(repository ExportRepository, collaborators collaborator.CollaboratorService) ExportService {
+	return &ExportServiceImpl{repository: repository, collaborators: collaborators}
+}
+
+// Writes a deck in one of the export formats. Only accounts that can
+// read the deck can export it
+func (s *ExportServiceImpl) Export(deckID int64, token string, format string) (File, error) {
+	if format != FormatJSON && format != FormatCSV && format != FormatMarkdown && format != FormatPDF {
+		return File{}, erro.ErrBadField
+	}
+
+	if _, err := s.collaborators.Authorize(deckID, token, collaborator.ReadDeck); err != nil {
+		return File{}, err
+	}
+
+	backup := Backup{Version: backupVersion, ExportedAt: time.Now().UTC()}
+	var err error
+	backup.Deck, err = s.repository.Deck(deckID)
+	if err != nil {
+		return File{}, err
+	}
+
+	backup.Cards, err = s.repository.Cards(deckID)
+	if err != nil {
+		return File{}, err
+	}
+	backup.Pictures = pictures(backup)
+
+	file := File{Name: fileName(backup.Deck.Title) + "." + format}
+	switch format {
+	case FormatJSON:
+		file.ContentType = "application/json"
+		file.Data, err = toJSON(backup)
+	case FormatCSV:
+		file.ContentType = "text/csv; charset=utf-8"
+		file.Data, err = toCSV(backup.Cards)
+	case FormatMarkdown:
+		file.ContentType = "text/markdown; charset=utf-8"
+		file.Data = toMarkdown(backup)
+	case FormatPDF:
+		file.ContentType = "application/pdf"
+		file.Data, err = toPDF(backup.Deck.Title, backup.Cards)
+	}
+	if err != nil {
+		return File{}, err
+	}
+	return file, nil
+}
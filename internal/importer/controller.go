@@ -0,0 +1,140 @@
+package importer
+
+import (
+	"errors"
+	"learn-swiping-api/erro"
+	importer "learn-swiping-api/internal/importer/dto"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ImporterController interface {
+	Preview(*gin.Context) // POST
+	Import(*gin.Context)  // POST
+}
+
+type ImporterControllerImpl struct {
+	service ImporterService
+}
+
+func NewImporterController(service ImporterService) ImporterController {
+	return &ImporterControllerImpl{service: service}
+}
+
+// Reads a CSV, TSV or JSON file of cards and tells what importing it
+// would do
+// Method: POST
+func (c *ImporterControllerImpl) Preview(ctx *gin.Context) {
+	token := ctx.GetHeader("Token")
+	if token == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrInvalidToken.Error()})
+		return
+	}
+
+	deckID, err := strconv.Atoi(ctx.Param("deckID"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrBadField.Error()})
+		return
+	}
+
+	ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, maxFile+maxForm)
+
+	var request importer.PreviewRequest
+	if err := ctx.ShouldBind(&request); err != nil {
+		if tooLarge(ctx, err) {
+			return
+		}
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrBadField.Error()})
+		return
+	}
+	request.Token = token
+	request.DeckID = int64(deckID)
+
+	preview, err := c.service.Preview(request)
+	if err != nil {
+		importerError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, preview)
+}
+
+// Creates the cards of a previewed file
+// Method: POST
+func (c *ImporterControllerImpl) Import(ctx *gin.Context) {
+	token := ctx.GetHeader("Token")
+	if token == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrInvalidToken.Error()})
+		return
+	}
+
+	deckID, err := strconv.Atoi(ctx.Param("deckID"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrBadField.Error()})
+		return
+	}
+
+	ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, maxFile+maxForm)
+
+	var request importer.ImportRequest
+	if err := ctx.ShouldBind(&request); err != nil {
+		if tooLarge(ctx, err) {
+			return
+		}
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrBadField.Error()})
+		return
+	}
+	request.Token = token
+	request.DeckID = int64(deckID)
+
+	preview, err := c.service.Import(request)
+	if err != nil {
+		// The preview tells which rows are the problem
+		if errors.Is(err, erro.ErrImportInvalid) || errors.Is(err, erro.ErrImportEmpty) {
+			ctx.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error(), "preview": preview})
+			return
+		}
+		importerError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, preview)
+}
+
+func importerError(ctx *gin.Context, err error) {
+	if errors.Is(err, erro.ErrBadField) || errors.Is(err, erro.ErrInvalidToken) || errors.Is(err, erro.ErrImportFormat) ||
+		errors.Is(err, erro.ErrImportFile) || errors.Is(err, erro.ErrImportMapping) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, erro.ErrFileTooLarge) {
+		ctx.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, erro.ErrForbidden) {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, erro.ErrDeckNotFound) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, erro.ErrImportOutdated) {
+		ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
+
+// Writes the response when the upload was cut at the size limit
+func tooLarge(ctx *gin.Context, err error) bool {
+	var limit *http.MaxBytesError
+	if !errors.As(err, &limit) {
+		return false
+	}
+
+	ctx.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": erro.ErrFileTooLarge.Error()})
+	return true
+}
@@ -0,0 +1,8 @@
+package importer
+
+type ImportRequest struct {
+	PreviewRequest
+	PreviewID   string `form:"preview_id" binding:"required"` // Returned by the preview of the same file and mapping
+	Duplicates  string `form:"duplicates"`                    // skip (default) or import
+	SkipInvalid bool   `form:"skip_invalid"`                  // Imports the valid rows when others have errors
+}
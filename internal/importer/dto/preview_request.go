@@ -0,0 +1,11 @@
+package importer
+
+import "mime/multipart"
+
+type PreviewRequest struct {
+	Token   string
+	DeckID  int64                 // Provided in GET params
+	File    *multipart.FileHeader `form:"file" binding:"required"`
+	Format  string                `form:"format"`  // csv, tsv or json. Taken from the file name if empty
+	Mapping string                `form:"mapping"` // JSON object of card field to column name, a list of them for wrong
+}
@@ -0,0 +1,68 @@
+package importer
+
+import "learn-swiping-api/internal/card"
+
+const (
+	FormatCSV  = "csv"
+	FormatTSV  = "tsv"
+	FormatJSON = "json"
+)
+
+// What to do with rows that repeat a card
+const (
+	DuplicatesSkip   = "skip"
+	DuplicatesImport = "import"
+)
+
+// Card fields a column can be mapped to. Answers and wrong answers can
+// take more than one, options take a JSON list of answers with their
+// correctness. Cloze, typed and occlusion take the payload of their card
+// type as JSON, like deck exports write it
+var fields = []string{"title", "front", "back", "question", "answer_type", "answer", "wrong", "options",
+	"card_type", card.TypeCloze, card.TypeTyped, card.TypeOcclusion}
+
+// Fields that take more than one column
+var listFields = []string{"answer", "wrong"}
+
+// Columns of the file used for each card field
+type Mapping map[string][]string
+
+// Result of reading a file. Nothing is stored until the preview is
+// confirmed with the same file and mapping
+type Preview struct {
+	PreviewID  string   `json:"preview_id"` // Needed to confirm the import
+	Format     string   `json:"format"`
+	Columns    []string `json:"columns"`
+	Mapping    Mapping  `json:"mapping"`
+	Rows       []Row    `json:"rows"`
+	Valid      int      `json:"valid"`
+	Invalid    int      `json:"invalid"`
+	Duplicates int      `json:"duplicates"`
+	Imported   int      `json:"imported"` // Only after confirming
+}
+
+type Row struct {
+	Line      int          `json:"line"` // Line of the file, or position in the JSON list
+	Card      card.Card    `json:"card"`
+	Errors    []FieldError `json:"errors,omitempty"`
+	Duplicate *Duplicate   `json:"duplicate,omitempty"`
+}
+
+type FieldError struct {
+	Field string `json:"field"`
+	Error string `json:"error"`
+}
+
+// Card a row repeats, either already in the deck or earlier in the file
+type Duplicate struct {
+	CardID int64 `json:"card_id,omitempty"`
+	Line   int   `json:"line,omitempty"`
+}
+
+// Values of a row by column name. Repeated columns keep every value
+type record struct {
+	line    int
+	values  map[string][]string
+	options map[string][]card.Option // JSON lists of objects, by column name
+	err     string                   // Set when the row couldn't be read
+}
@@ -0,0 +1,251 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"learn-swiping-api/erro"
+	"learn-swiping-api/internal/card"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Reads the header and rows of a file
+func parse(format string, data []byte) ([]string, []record, error) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")) // Spreadsheets like to add a BOM
+	switch format {
+	case FormatCSV:
+		return parseDelimited(data, ',')
+	case FormatTSV:
+		return parseDelimited(data, '\t')
+	case FormatJSON:
+		return parseJSON(data)
+	}
+	return nil, nil, erro.ErrImportFormat
+}
+
+// The first row names the columns
+func parseDelimited(data []byte, comma rune) ([]string, []record, error) {
+	r := csv.NewReader(bytes.NewReader(data))
+	r.Comma = comma
+	r.FieldsPerRecord = -1 // Checked per row to report it
+	r.LazyQuotes = comma == '\t'
+
+	header, err := r.Read()
+	if err != nil {
+		return nil, nil, erro.ErrImportFile
+	}
+	columns := make([]string, len(header))
+	for i, name := range header {
+		columns[i] = strings.TrimSpace(name)
+	}
+
+	var records []record
+	for {
+		values, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, erro.ErrImportFile
+		}
+		if len(records) >= maxRows {
+			return nil, nil, erro.ErrBadField
+		}
+		if blank(values) {
+			continue
+		}
+
+		line, _ := r.FieldPos(0)
+		rec := record{line: line, values: map[string][]string{}}
+		if len(values) != len(columns) {
+			rec.err = "row has " + strconv.Itoa(len(values)) + " columns, the header has " + strconv.Itoa(len(columns))
+		}
+		for i, value := range values {
+			if i < len(columns) {
+				rec.values[columns[i]] = append(rec.values[columns[i]], value)
+			}
+		}
+		records = append(records, rec)
+	}
+	return columns, records, nil
+}
+
+// A list of objects, or an object with the list in cards like the ones
+// deck exports produce
+func parseJSON(data []byte) ([]string, []record, error) {
+	var items []map[string]any
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+	if err := d.Decode(&items); err != nil {
+		var wrapped struct {
+			Cards []map[string]any `json:"cards"`
+		}
+		d := json.NewDecoder(bytes.NewReader(data))
+		d.UseNumber()
+		if err := d.Decode(&wrapped); err != nil || wrapped.Cards == nil {
+			return nil, nil, erro.ErrImportFile
+		}
+		items = wrapped.Cards
+	}
+	if len(items) > maxRows {
+		return nil, nil, erro.ErrBadField
+	}
+
+	var columns []string
+	seen := map[string]bool{}
+	records := make([]record, 0, len(items))
+	for i, item := range items {
+		keys := make([]string, 0, len(item))
+		for key := range item {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		rec := record{line: i + 1, values: map[string][]string{}, options: map[string][]card.Option{}}
+		for _, key := range keys {
+			if !seen[key] {
+				seen[key] = true
+				columns = append(columns, key)
+			}
+			values, options, err := jsonValues(item[key])
+			if err != nil {
+				rec.err = key + " must be text, an object or a list of texts"
+				continue
+			}
+			rec.values[key] = values
+			rec.options[key] = options
+		}
+		records = append(records, rec)
+	}
+	return columns, records, nil
+}
+
+// Texts of a value. Lists of objects, like the options of the card
+// format, also give their answers with their correctness. Objects, like
+// payloads, are kept as their JSON
+func jsonValues(value any) ([]string, []card.Option, error) {
+	if object, ok := value.(map[string]any); ok {
+		encoded, err := json.Marshal(object)
+		if err != nil {
+			return nil, nil, erro.ErrBadField
+		}
+		return []string{string(encoded)}, nil, nil
+	}
+
+	if list, ok := value.([]any); ok {
+		values := make([]string, 0, len(list))
+		var options []card.Option
+		for _, item := range list {
+			if object, ok := item.(map[string]any); ok {
+				correct, _ := object["correct"].(bool)
+				item = object["answer"]
+				v, _ := jsonScalar(item)
+				options = append(options, card.Option{Answer: v, Correct: correct})
+			}
+			v, ok := jsonScalar(item)
+			if !ok {
+				return nil, nil, erro.ErrBadField
+			}
+			values = append(values, v)
+		}
+		return values, options, nil
+	}
+
+	v, ok := jsonScalar(value)
+	if !ok {
+		return nil, nil, erro.ErrBadField
+	}
+	return []string{v}, nil, nil
+}
+
+func jsonScalar(value any) (string, bool) {
+	switch v := value.(type) {
+	case string:
+		return v, true
+	case json.Number:
+		return v.String(), true
+	case bool:
+		return strconv.FormatBool(v), true
+	case nil:
+		return "", true
+	}
+	return "", false
+}
+
+func blank(values []string) bool {
+	for _, v := range values {
+		if strings.TrimSpace(v) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// Mapping used when the request has none. Columns named like the card
+// fields, and answer 1 or wrong_2 or wrong answer 3 for the answers
+func defaultMapping(columns []string) Mapping {
+	mapping := Mapping{}
+	for _, column := range columns {
+		name := strings.ToLower(column)
+		for _, field := range fields {
+			if name == field {
+				mapping[field] = appendUnique(mapping[field], column)
+			}
+		}
+		if match := numberedColumn.FindStringSubmatch(name); match != nil {
+			mapping[match[1]] = appendUnique(mapping[match[1]], column)
+		}
+	}
+	return mapping
+}
+
+// Parses the mapping of a request. Each field takes a column name, or a
+// list of them for the answers and wrong answers
+func parseMapping(raw string, columns []string) (Mapping, error) {
+	var values map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(raw), &values); err != nil {
+		return nil, erro.ErrImportMapping
+	}
+
+	known := map[string]bool{}
+	for _, column := range columns {
+		known[column] = true
+	}
+
+	mapping := Mapping{}
+	for field, value := range values {
+		if !slices.Contains(fields, field) {
+			return nil, erro.ErrImportMapping
+		}
+
+		var names []string
+		var name string
+		if err := json.Unmarshal(value, &name); err == nil {
+			names = []string{name}
+		} else if err := json.Unmarshal(value, &names); err != nil {
+			return nil, erro.ErrImportMapping
+		}
+		if len(names) == 0 || (len(names) > 1 && !slices.Contains(listFields, field)) {
+			return nil, erro.ErrImportMapping
+		}
+
+		for _, name := range names {
+			if !known[name] {
+				return nil, erro.ErrImportMapping
+			}
+			mapping[field] = appendUnique(mapping[field], name)
+		}
+	}
+	return mapping, nil
+}
+
+func appendUnique(list []string, value string) []string {
+	if slices.Contains(list, value) {
+		return list
+	}
+	return append(list, value)
+}
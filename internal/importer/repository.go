@@ -0,0 +1,77 @@
+package importer
+
+import (
+	"database/sql"
+	"learn-swiping-api/internal/card"
+	"log"
+)
+
+type ImporterRepository interface {
+	Fronts(deckID int64) (map[string]int64, error) // Card id by normalized front
+	Import(deckID int64, cards []card.Card) error
+}
+
+type ImporterRepositoryImpl struct {
+	db         *sql.DB
+	FrontsStmt *sql.Stmt
+}
+
+func NewImporterRepository(db *sql.DB) *ImporterRepositoryImpl {
+	repo := &ImporterRepositoryImpl{db: db}
+	err := repo.InitStatements()
+	if err != nil {
+		log.Fatalln(err)
+	}
+	return repo
+}
+
+func (r *ImporterRepositoryImpl) InitStatements() error {
+	var err error
+	r.FrontsStmt, err = r.db.Prepare("SELECT card_id, front FROM CARD WHERE deck_id = ?")
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *ImporterRepositoryImpl) Fronts(deckID int64) (map[string]int64, error) {
+	rows, err := r.FrontsStmt.Query(deckID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	fronts := map[string]int64{}
+	for rows.Next() {
+		var cardID int64
+		var front string
+		if err := rows.Scan(&cardID, &front); err != nil {
+			return nil, err
+		}
+		if _, ok := fronts[normalize(front)]; !ok {
+			fronts[normalize(front)] = cardID
+		}
+	}
+
+	return fronts, rows.Err()
+}
+
+// Creates every card with its options and siblings, or none. Imported
+// cards go after the ones in the deck
+func (r *ImporterRepositoryImpl) Import(deckID int64, cards []card.Card) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	for _, c := range cards {
+		c.DeckID = deckID
+		if _, err := card.InsertCard(tx, c); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
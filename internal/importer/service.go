@@ -0,0 +1,309 @@
+package importer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"learn-swiping-api/erro"
+	"learn-swiping-api/internal/card"
+	"learn-swiping-api/internal/collaborator"
+	importer "learn-swiping-api/internal/importer/dto"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	maxFile  = 5 << 20
+	maxForm  = 1 << 20 // Fields of the upload besides the file
+	maxRows  = 5000
+	maxTitle = 255
+)
+
+var (
+	numberedColumn = regexp.MustCompile(`^(answer|wrong)(?:[ _-]*answer)?[ _-]*\d+$`)
+	spaceRegexp    = regexp.MustCompile(`\s+`)
+)
+
+type ImporterService interface {
+	Preview(importer.PreviewRequest) (Preview, error)
+	Import(importer.ImportRequest) (Preview, error)
+	read(importer.PreviewRequest) (Preview, error)
+	toRow(rec record, mapping Mapping) Row
+	typedRow(row Row, payload string) Row
+}
+
+type ImporterServiceImpl struct {
+	repository    ImporterRepository
+	collaborators collaborator.CollaboratorService
+}
+
+func NewImporterService(repository ImporterRepository, collaborators collaborator.CollaboratorService) ImporterService {
+	return &ImporterServiceImpl{repository: repository, collaborators: collaborators}
+}
+
+// Reads a file without storing anything
+func (s *ImporterServiceImpl) Preview(request importer.PreviewRequest) (Preview, error) {
+	if _, err := s.collaborators.Authorize(request.DeckID, request.Token, collaborator.WriteCards); err != nil {
+		return Preview{}, err
+	}
+	return s.read(request)
+}
+
+// Creates the cards of a previewed file in one go. Rows with errors stop
+// the import unless asked to skip them, duplicates are skipped unless
+// asked to import them
+func (s *ImporterServiceImpl) Import(request importer.ImportRequest) (Preview, error) {
+	if request.Duplicates == "" {
+		request.Duplicates = DuplicatesSkip
+	}
+	if request.Duplicates != DuplicatesSkip && request.Duplicates != DuplicatesImport {
+		return Preview{}, erro.ErrBadField
+	}
+
+	if _, err := s.collaborators.Authorize(request.DeckID, request.Token, collaborator.WriteCards); err != nil {
+		return Preview{}, err
+	}
+
+	preview, err := s.read(request.PreviewRequest)
+	if err != nil {
+		return Preview{}, err
+	}
+	if preview.PreviewID != request.PreviewID {
+		return Preview{}, erro.ErrImportOutdated
+	}
+	if preview.Invalid > 0 && !request.SkipInvalid {
+		return preview, erro.ErrImportInvalid
+	}
+
+	cards := make([]card.Card, 0, preview.Valid)
+	for _, row := range preview.Rows {
+		if len(row.Errors) > 0 || (row.Duplicate != nil && request.Duplicates == DuplicatesSkip) {
+			continue
+		}
+		cards = append(cards, row.Card)
+	}
+	if len(cards) == 0 {
+		return preview, erro.ErrImportEmpty
+	}
+
+	if err := s.repository.Import(request.DeckID, cards); err != nil {
+		return Preview{}, err
+	}
+	preview.Imported = len(cards)
+	return preview, nil
+}
+
+// Parses, maps and validates a file, and looks for cards the deck
+// already has
+func (s *ImporterServiceImpl) read(request importer.PreviewRequest) (Preview, error) {
+	if request.File.Size > maxFile {
+		return Preview{}, erro.ErrFileTooLarge
+	}
+
+	format := strings.ToLower(request.Format)
+	if format == "" {
+		format = strings.TrimPrefix(strings.ToLower(filepath.Ext(request.File.Filename)), ".")
+	}
+
+	file, err := request.File.Open()
+	if err != nil {
+		return Preview{}, erro.ErrBadField
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, maxFile+1))
+	if err != nil || len(data) > maxFile {
+		return Preview{}, erro.ErrBadField
+	}
+
+	columns, records, err := parse(format, data)
+	if err != nil {
+		return Preview{}, err
+	}
+
+	mapping := defaultMapping(columns)
+	if strings.TrimSpace(request.Mapping) != "" {
+		mapping, err = parseMapping(request.Mapping, columns)
+		if err != nil {
+			return Preview{}, err
+		}
+	}
+
+	existing, err := s.repository.Fronts(request.DeckID)
+	if err != nil {
+		return Preview{}, err
+	}
+
+	preview := Preview{
+		PreviewID: previewID(request.DeckID, format, mapping, data),
+		Format:    format,
+		Columns:   columns,
+		Mapping:   mapping,
+		Rows:      make([]Row, 0, len(records)),
+	}
+	if preview.Columns == nil {
+		preview.Columns = []string{}
+	}
+
+	inFile := map[string]int{} // Line of the first row with each front
+	for _, rec := range records {
+		row := s.toRow(rec, mapping)
+
+		key := normalize(row.Card.Front)
+		if cardID, ok := existing[key]; ok && key != "" {
+			row.Duplicate = &Duplicate{CardID: cardID}
+		} else if line, ok := inFile[key]; ok && key != "" {
+			row.Duplicate = &Duplicate{Line: line}
+		} else if len(row.Errors) == 0 {
+			inFile[key] = rec.line
+		}
+
+		if len(row.Errors) > 0 {
+			preview.Invalid++
+		} else {
+			preview.Valid++
+		}
+		if row.Duplicate != nil {
+			preview.Duplicates++
+		}
+		preview.Rows = append(preview.Rows, row)
+	}
+	return preview, nil
+}
+
+// Card of a row with the reasons it can't be created. Options come from
+// a JSON list when mapped, or else from the answers followed by the wrong
+// answers. Other card types take their payload instead
+func (s *ImporterServiceImpl) toRow(rec record, mapping Mapping) Row {
+	row := Row{Line: rec.line, Card: card.Card{Options: []card.Option{}}}
+	if rec.err != "" {
+		row.Errors = append(row.Errors, FieldError{Field: "row", Error: rec.err})
+	}
+
+	values := func(field string) []string {
+		var found []string
+		for _, column := range mapping[field] {
+			for _, v := range rec.values[column] {
+				if v = strings.TrimSpace(v); v != "" {
+					found = append(found, v)
+				}
+			}
+		}
+		return found
+	}
+	value := func(field string) string {
+		if found := values(field); len(found) > 0 {
+			return found[0]
+		}
+		return ""
+	}
+
+	row.Card.Title = value("title")
+	row.Card.Front = value("front")
+	row.Card.Back = value("back")
+	row.Card.Question = value("question")
+
+	row.Card.CardType = strings.ToLower(value("card_type"))
+	if row.Card.CardType == "" {
+		row.Card.CardType = card.TypeBasic
+	}
+	if !card.ValidCardType(row.Card.CardType) {
+		row.Errors = append(row.Errors, FieldError{Field: "card_type", Error: "unknown card type " + strconv.Quote(row.Card.CardType)})
+		return row
+	}
+	if row.Card.CardType != card.TypeBasic {
+		return s.typedRow(row, value(row.Card.CardType))
+	}
+
+	for _, column := range mapping["options"] {
+		for _, o := range rec.options[column] {
+			row.Card.Options = append(row.Card.Options, card.Option{Answer: strings.TrimSpace(o.Answer), Correct: o.Correct})
+		}
+	}
+	if len(row.Card.Options) == 0 {
+		for _, answer := range values("answer") {
+			row.Card.Options = append(row.Card.Options, card.Option{Answer: answer, Correct: true})
+		}
+		for _, answer := range values("wrong") {
+			row.Card.Options = append(row.Card.Options, card.Option{Answer: answer})
+		}
+	}
+
+	// Several correct answers make a select all that apply card
+	row.Card.AnswerType = strings.ToLower(value("answer_type"))
+	if correct, _ := row.Card.Answers(); row.Card.AnswerType == "" && len(correct) > 1 {
+		row.Card.AnswerType = card.AnswerSelectAll
+	} else if row.Card.AnswerType == "" {
+		row.Card.AnswerType = card.AnswerSingle
+	}
+
+	required := []struct{ field, value string }{
+		{"title", row.Card.Title},
+		{"front", row.Card.Front},
+		{"back", row.Card.Back},
+		{"question", row.Card.Question},
+	}
+	for _, r := range required {
+		if r.value == "" {
+			row.Errors = append(row.Errors, FieldError{Field: r.field, Error: "required"})
+		}
+	}
+	if len([]rune(row.Card.Title)) > maxTitle {
+		row.Errors = append(row.Errors, FieldError{Field: "title", Error: "longer than " + strconv.Itoa(maxTitle) + " characters"})
+	}
+
+	if !card.ValidAnswerType(row.Card.AnswerType) {
+		row.Errors = append(row.Errors, FieldError{Field: "answer_type", Error: "unknown answer type " + strconv.Quote(row.Card.AnswerType)})
+	} else if err := card.ValidateOptions(row.Card.AnswerType, row.Card.Options); err != nil {
+		row.Errors = append(row.Errors, FieldError{Field: "options", Error: err.Error()})
+	}
+	return row
+}
+
+// Checks a row of a card type with a payload. Like when creating them,
+// cloze and occlusion cards only need a title besides it and typed ones
+// what to ask
+func (s *ImporterServiceImpl) typedRow(row Row, payload string) Row {
+	if row.Card.Title == "" {
+		row.Errors = append(row.Errors, FieldError{Field: "title", Error: "required"})
+	}
+	if row.Card.Question == "" && row.Card.CardType == card.TypeTyped {
+		row.Errors = append(row.Errors, FieldError{Field: "question", Error: "required"})
+	}
+	if len([]rune(row.Card.Title)) > maxTitle {
+		row.Errors = append(row.Errors, FieldError{Field: "title", Error: "longer than " + strconv.Itoa(maxTitle) + " characters"})
+	}
+
+	if payload == "" {
+		row.Errors = append(row.Errors, FieldError{Field: row.Card.CardType, Error: "required"})
+		return row
+	}
+	if err := row.Card.SetPayload([]byte(payload)); err != nil {
+		row.Errors = append(row.Errors, FieldError{Field: row.Card.CardType, Error: "must be a JSON object"})
+		return row
+	}
+	if err := card.ValidatePayload(row.Card); err != nil {
+		row.Errors = append(row.Errors, FieldError{Field: row.Card.CardType, Error: err.Error()})
+	}
+	return row
+}
+
+// Identifies a file read with a mapping, so a confirmation can't import
+// something else than what was previewed
+func previewID(deckID int64, format string, mapping Mapping, data []byte) string {
+	encoded, _ := json.Marshal(mapping) // Keys come out sorted
+	h := sha256.New()
+	h.Write([]byte(strconv.FormatInt(deckID, 10) + "\x00" + format + "\x00"))
+	h.Write(encoded)
+	h.Write([]byte{0})
+	h.Write(data)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Fronts are compared ignoring case and spacing
+func normalize(text string) string {
+	return strings.ToLower(strings.TrimSpace(spaceRegexp.ReplaceAllString(text, " ")))
+}
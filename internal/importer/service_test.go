@@ -0,0 +1,94 @@
+package importer
+
+import (
+	"encoding/json"
+	"learn-swiping-api/internal/card"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Cards of a JSON deck backup read back as they were exported
+func TestToRowBackup(t *testing.T) {
+	picID := strings.Repeat("ab", 32) + ".png"
+	cards := []card.Card{
+		{CardID: 1, CardType: card.TypeBasic, Title: "Basic", Front: "Front", Back: "Back", Question: "Question", AnswerType: card.AnswerSingle,
+			Options: []card.Option{{OptionID: 1, Answer: "Right", Correct: true}, {OptionID: 2, Answer: "Wrong"}}},
+		{CardID: 2, CardType: card.TypeCloze, Title: "Cloze", Back: "Extra", Sibling: 1,
+			Cloze: &card.Cloze{Text: "{{c1::Paris}} is in {{c2::France::country}}"}},
+		{CardID: 3, CardType: card.TypeTyped, Title: "Typed", Front: "Front", Question: "Capital of France?",
+			Typed: &card.Typed{Answers: []string{"Paris", "Paree"}, Threshold: 0.9}},
+		{CardID: 4, CardType: card.TypeOcclusion, Title: "Occlusion", Sibling: 1,
+			Occlusion: &card.Occlusion{PicID: picID, Masks: []card.Mask{{Label: "Heart", X: 0.1, Y: 0.2, Width: 0.3, Height: 0.4}}}},
+	}
+	backup, err := json.Marshal(map[string]any{"version": 1, "cards": cards})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	columns, records, err := parse(FormatJSON, backup)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mapping := defaultMapping(columns)
+	s := &ImporterServiceImpl{}
+
+	for i, rec := range records {
+		row := s.toRow(rec, mapping)
+		if len(row.Errors) > 0 {
+			t.Errorf("card %d: %v", i+1, row.Errors)
+			continue
+		}
+
+		want := cards[i]
+		want.CardID, want.Sibling = 0, 0
+		for j := range want.Options {
+			want.Options[j].OptionID = 0
+		}
+		if want.Options == nil {
+			want.Options = []card.Option{}
+		}
+		if !reflect.DeepEqual(row.Card, want) {
+			t.Errorf("card %d:\n got %+v\nwant %+v", i+1, row.Card, want)
+		}
+	}
+}
+
+func TestToRowTypes(t *testing.T) {
+	mapping := Mapping{}
+	for _, field := range fields {
+		mapping[field] = []string{field}
+	}
+
+	tests := []struct {
+		name   string
+		values map[string]string
+		errors []string // Fields with errors
+	}{
+		{"basic by default", map[string]string{"title": "t", "front": "f", "back": "b", "question": "q", "answer": "a", "wrong": "w"}, nil},
+		{"unknown type", map[string]string{"title": "t", "card_type": "essay"}, []string{"card_type"}},
+		{"cloze", map[string]string{"title": "t", "card_type": "Cloze", "cloze": `{"text": "{{c1::a}}"}`}, nil},
+		{"cloze without deletions", map[string]string{"title": "t", "card_type": "cloze", "cloze": `{"text": "a"}`}, []string{"cloze"}},
+		{"missing payload", map[string]string{"title": "t", "card_type": "typed", "question": "q"}, []string{"typed"}},
+		{"payload isn't JSON", map[string]string{"title": "t", "card_type": "typed", "question": "q", "typed": "Paris"}, []string{"typed"}},
+		{"typed without question", map[string]string{"title": "t", "card_type": "typed", "typed": `{"answers": ["a"]}`}, []string{"question"}},
+		{"options of a typed card", map[string]string{"title": "t", "card_type": "typed", "question": "q", "typed": `{"answers": ["a"]}`, "answer": "a"}, nil},
+	}
+
+	s := &ImporterServiceImpl{}
+	for _, tt := range tests {
+		rec := record{line: 1, values: map[string][]string{}}
+		for column, value := range tt.values {
+			rec.values[column] = []string{value}
+		}
+
+		row := s.toRow(rec, mapping)
+		var got []string
+		for _, e := range row.Errors {
+			got = append(got, e.Field)
+		}
+		if !reflect.DeepEqual(got, tt.errors) {
+			t.Errorf("%s: errors in %v, want %v (%v)", tt.name, got, tt.errors, row.Errors)
+		}
+	}
+}
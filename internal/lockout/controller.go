@@ -0,0 +1,62 @@
+package lockout
+
+import (
+	"errors"
+	"learn-swiping-api/erro"
+	"learn-swiping-api/internal/audit"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type LockoutController interface {
+	Lockouts(*gin.Context) // GET
+	Clear(*gin.Context)    // DELETE
+}
+
+type LockoutControllerImpl struct {
+	service LockoutService
+}
+
+func NewLockoutController(service LockoutService) LockoutController {
+	return &LockoutControllerImpl{service: service}
+}
+
+// Lists the accounts and IPs that currently can't log in
+// Method: GET
+func (c *LockoutControllerImpl) Lockouts(ctx *gin.Context) {
+	lockouts, err := c.service.Lockouts()
+	if err != nil {
+		lockoutError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, lockouts)
+}
+
+// Removes the failed attempts of an account or IP
+// Method: DELETE
+func (c *LockoutControllerImpl) Clear(ctx *gin.Context) {
+	scope := ctx.Param("scope")
+	if scope != ScopeAccount && scope != ScopeIP {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrBadField.Error()})
+		return
+	}
+
+	if err := c.service.Clear(scope, ctx.Param("subject")); err != nil {
+		lockoutError(ctx, err)
+		return
+	}
+
+	audit.Action(ctx, "lockout.clear", scope+":"+ctx.Param("subject"))
+
+	ctx.JSON(http.StatusOK, gin.H{})
+}
+
+func lockoutError(ctx *gin.Context, err error) {
+	if errors.Is(err, erro.ErrLockoutNotFound) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
@@ -0,0 +1,54 @@
+package lockout
+
+import "time"
+
+// Scopes failures are counted by
+const (
+	ScopeAccount = "account"
+	ScopeIP      = "ip"
+)
+
+type Lockout struct {
+	Scope       string     `json:"scope"`
+	Subject     string     `json:"subject"` // Username or IP address
+	Failures    int        `json:"failures"`
+	LastFailure time.Time  `json:"last_failure"`
+	LockedUntil *time.Time `json:"locked_until,omitempty"`
+}
+
+// How failures turn into waiting time. After BackoffAfter failures each
+// new one doubles the wait, starting at one second and up to LockFor.
+// After LockAfter failures the subject is locked for LockFor
+type Policy struct {
+	BackoffAfter int
+	LockAfter    int
+	LockFor      time.Duration
+	Window       time.Duration // Failures older than this are forgotten
+}
+
+var policies = map[string]Policy{
+	ScopeAccount: {BackoffAfter: 3, LockAfter: 10, LockFor: 15 * time.Minute, Window: time.Hour},
+	// An IP can be shared by many users, so it's more permissive
+	ScopeIP: {BackoffAfter: 10, LockAfter: 50, LockFor: time.Hour, Window: time.Hour},
+}
+
+// Returns until when a subject with that many failures has to wait
+func (p Policy) lockedUntil(failures int, now time.Time) *time.Time {
+	var wait time.Duration
+	switch {
+	case failures >= p.LockAfter:
+		wait = p.LockFor
+	case failures >= p.BackoffAfter:
+		// Never longer than a lock. Shifting a second by 34 or more
+		// overflows a Duration
+		wait = p.LockFor
+		if shift := failures - p.BackoffAfter; shift < 34 {
+			wait = min(time.Second<<shift, p.LockFor)
+		}
+	default:
+		return nil
+	}
+
+	until := now.Add(wait)
+	return &until
+}
@@ -0,0 +1,69 @@
+package lockout
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLockedUntil(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		scope    string
+		failures int
+		wait     time.Duration // -1 when not locked
+	}{
+		{ScopeAccount, 0, -1},
+		{ScopeAccount, 2, -1},
+		{ScopeAccount, 3, time.Second},
+		{ScopeAccount, 4, 2 * time.Second},
+		{ScopeAccount, 9, 64 * time.Second},
+		{ScopeAccount, 10, 15 * time.Minute},
+		{ScopeAccount, 100, 15 * time.Minute},
+		{ScopeIP, 9, -1},
+		{ScopeIP, 10, time.Second},
+		{ScopeIP, 21, 2048 * time.Second},
+		{ScopeIP, 22, time.Hour}, // 4096s would be longer than the lock
+		{ScopeIP, 42, time.Hour},
+		{ScopeIP, 43, time.Hour},
+		{ScopeIP, 44, time.Hour}, // Shift of 34 overflows
+		{ScopeIP, 49, time.Hour},
+		{ScopeIP, 50, time.Hour},
+		{ScopeIP, 1000, time.Hour},
+	}
+
+	for _, tt := range tests {
+		until := policies[tt.scope].lockedUntil(tt.failures, now)
+		if tt.wait < 0 {
+			if until != nil {
+				t.Errorf("%s with %d failures: locked until %v, want not locked", tt.scope, tt.failures, until)
+			}
+			continue
+		}
+		if until == nil {
+			t.Errorf("%s with %d failures: not locked, want %v", tt.scope, tt.failures, tt.wait)
+			continue
+		}
+		if wait := until.Sub(now); wait != tt.wait {
+			t.Errorf("%s with %d failures: wait %v, want %v", tt.scope, tt.failures, wait, tt.wait)
+		}
+	}
+}
+
+// Waits never shrink as failures grow
+func TestLockedUntilGrows(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for scope, policy := range policies {
+		var last time.Duration
+		for failures := 0; failures <= policy.LockAfter+100; failures++ {
+			until := policy.lockedUntil(failures, now)
+			if until == nil {
+				continue
+			}
+			wait := until.Sub(now)
+			if wait < last || wait <= 0 || wait > policy.LockFor {
+				t.Fatalf("%s with %d failures: wait %v after %v", scope, failures, wait, last)
+			}
+			last = wait
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package lockout
+
+import (
+	"database/sql"
+	"learn-swiping-api/erro"
+	"log"
+	"time"
+)
+
+type LockoutRepository interface {
+	Get(scope string, subject string) (Lockout, error)
+	Fail(scope string, subject string, policy Policy, now time.Time) (Lockout, error)
+	Delete(scope string, subject string) error
+	Active() ([]Lockout, error)
+}
+
+type LockoutRepositoryImpl struct {
+	db         *sql.DB
+	GetStmt    *sql.Stmt
+	DeleteStmt *sql.Stmt
+	ActiveStmt *sql.Stmt
+}
+
+func NewLockoutRepository(db *sql.DB) *LockoutRepositoryImpl {
+	repo := &LockoutRepositoryImpl{db: db}
+	err := repo.InitStatements()
+	if err != nil {
+		log.Fatalln(err)
+	}
+	return repo
+}
+
+func (r *LockoutRepositoryImpl) InitStatements() error {
+	var err error
+	r.GetStmt, err = r.db.Prepare("SELECT scope, subject, failures, last_failure, locked_until FROM LOGIN_FAILURE WHERE scope = ? AND subject = ?")
+	if err != nil {
+		return err
+	}
+
+	r.DeleteStmt, err = r.db.Prepare("DELETE FROM LOGIN_FAILURE WHERE scope = ? AND subject = ?")
+	if err != nil {
+		return err
+	}
+
+	r.ActiveStmt, err = r.db.Prepare(`SELECT scope, subject, failures, last_failure, locked_until
+										FROM LOGIN_FAILURE
+										WHERE locked_until > NOW()
+										ORDER BY locked_until DESC`)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *LockoutRepositoryImpl) Get(scope string, subject string) (Lockout, error) {
+	lockout, err := scanLockout(r.GetStmt.QueryRow(scope, subject))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return Lockout{}, erro.ErrLockoutNotFound
+		}
+		return Lockout{}, err
+	}
+	return lockout, nil
+}
+
+// Counts a failure and stores the wait it leads to. The count goes up in
+// the upsert, which locks the row until the wait is stored, so concurrent
+// failures can't overwrite each other. Failures older than the window of
+// the policy are forgotten
+func (r *LockoutRepositoryImpl) Fail(scope string, subject string, policy Policy, now time.Time) (Lockout, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return Lockout{}, err
+	}
+
+	// Cannot use globally prepared statements here because of the transaction
+	_, err = tx.Exec(`INSERT INTO LOGIN_FAILURE (scope, subject, failures, last_failure) VALUES (?, ?, 1, ?)
+						ON DUPLICATE KEY UPDATE
+							failures = IF(last_failure < ?, 1, failures + 1),
+							last_failure = VALUES(last_failure)`,
+		scope, subject, now, now.Add(-policy.Window))
+	if err != nil {
+		tx.Rollback()
+		return Lockout{}, err
+	}
+
+	lockout, err := scanLockout(tx.QueryRow(`SELECT scope, subject, failures, last_failure, locked_until
+												FROM LOGIN_FAILURE WHERE scope = ? AND subject = ? FOR UPDATE`, scope, subject))
+	if err != nil {
+		tx.Rollback()
+		return Lockout{}, err
+	}
+
+	lockout.LockedUntil = policy.lockedUntil(lockout.Failures, now)
+	if _, err := tx.Exec("UPDATE LOGIN_FAILURE SET locked_until = ? WHERE scope = ? AND subject = ?", lockout.LockedUntil, scope, subject); err != nil {
+		tx.Rollback()
+		return Lockout{}, err
+	}
+
+	return lockout, tx.Commit()
+}
+
+func (r *LockoutRepositoryImpl) Delete(scope string, subject string) error {
+	result, err := r.DeleteStmt.Exec(scope, subject)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return erro.ErrLockoutNotFound
+	}
+
+	return nil
+}
+
+func (r *LockoutRepositoryImpl) Active() ([]Lockout, error) {
+	rows, err := r.ActiveStmt.Query()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	lockouts := []Lockout{}
+	for rows.Next() {
+		lockout, err := scanLockout(rows)
+		if err != nil {
+			return nil, err
+		}
+		lockouts = append(lockouts, lockout)
+	}
+
+	return lockouts, nil
+}
+
+// Scans from either *sql.Row or *sql.Rows
+func scanLockout(row interface{ Scan(...any) error }) (Lockout, error) {
+	var lockout Lockout
+	err := row.Scan(
+		&lockout.Scope,
+		&lockout.Subject,
+		&lockout.Failures,
+		&lockout.LastFailure,
+		&lockout.LockedUntil,
+	)
+	return lockout, err
+}
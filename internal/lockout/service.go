@@ -0,0 +1,93 @@
+package lockout
+
+import (
+	"errors"
+	"learn-swiping-api/erro"
+	"strings"
+	"time"
+)
+
+type LockoutService interface {
+	Check(username string, ip string) error
+	Fail(username string, ip string) (bool, error) // Returns true when the account just got locked
+	Succeed(username string) error
+	Lockouts() ([]Lockout, error)
+	Clear(scope string, subject string) error
+	fail(scope string, subject string, now time.Time) (Lockout, error)
+}
+
+type LockoutServiceImpl struct {
+	repository LockoutRepository
+}
+
+func NewLockoutService(repository LockoutRepository) LockoutService {
+	return &LockoutServiceImpl{repository: repository}
+}
+
+// Returns a *erro.LockedError if either the account or the IP has to wait
+func (s *LockoutServiceImpl) Check(username string, ip string) error {
+	now := time.Now()
+	var until time.Time
+
+	for scope, subject := range map[string]string{ScopeAccount: strings.ToLower(username), ScopeIP: ip} {
+		lockout, err := s.repository.Get(scope, subject)
+		if err != nil {
+			if errors.Is(err, erro.ErrLockoutNotFound) {
+				continue
+			}
+			return err
+		}
+		if lockout.LockedUntil != nil && lockout.LockedUntil.After(now) && lockout.LockedUntil.After(until) {
+			until = *lockout.LockedUntil
+		}
+	}
+
+	if !until.IsZero() {
+		return &erro.LockedError{Until: until}
+	}
+	return nil
+}
+
+// Records a failed login for the username and the IP. Usernames without
+// an account are counted too, so responses don't reveal which exist
+func (s *LockoutServiceImpl) Fail(username string, ip string) (bool, error) {
+	now := time.Now()
+
+	account, err := s.fail(ScopeAccount, strings.ToLower(username), now)
+	if err != nil {
+		return false, err
+	}
+
+	if _, err := s.fail(ScopeIP, ip, now); err != nil {
+		return false, err
+	}
+
+	return account.Failures == policies[ScopeAccount].LockAfter, nil
+}
+
+// The IP counter isn't reset, otherwise an attacker could clear it by
+// logging into their own account between attempts
+func (s *LockoutServiceImpl) Succeed(username string) error {
+	err := s.repository.Delete(ScopeAccount, strings.ToLower(username))
+	if errors.Is(err, erro.ErrLockoutNotFound) {
+		return nil
+	}
+	return err
+}
+
+func (s *LockoutServiceImpl) Lockouts() ([]Lockout, error) {
+	return s.repository.Active()
+}
+
+func (s *LockoutServiceImpl) Clear(scope string, subject string) error {
+	if scope == ScopeAccount {
+		subject = strings.ToLower(subject)
+	}
+	return s.repository.Delete(scope, subject)
+}
+
+// The count is kept by the repository, so failures at the same time all
+// add up
+func (s *LockoutServiceImpl) fail(scope string, subject string, now time.Time) (Lockout, error) {
+	return s.repository.Fail(scope, subject, policies[scope], now)
+}
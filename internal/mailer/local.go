@@ -0,0 +1,48 @@
+package mailer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Writes every message to the standard logger instead of sending it.
+// Meant for local development.
+type LogMailer struct {
+	from string
+}
+
+func NewLogMailer(from string) *LogMailer {
+	return &LogMailer{from: from}
+}
+
+func (m *LogMailer) Send(msg Message) error {
+	log.Printf("[mailer] from=%s to=%s subject=%q\n%s\n", m.from, msg.To, msg.Subject, msg.Body)
+	return nil
+}
+
+// Stores every message as an .eml file inside a directory so they
+// can be opened with any mail client. Meant for local development.
+type FileMailer struct {
+	dir  string
+	from string
+}
+
+func NewFileMailer(dir, from string) *FileMailer {
+	return &FileMailer{dir: dir, from: from}
+}
+
+func (m *FileMailer) Send(msg Message) error {
+	if err := os.MkdirAll(m.dir, 0777); err != nil {
+		return err
+	}
+
+	// The address isn't trusted as part of a path
+	to := sha256.Sum256([]byte(msg.To))
+	name := fmt.Sprintf("%d_%s.eml", time.Now().UnixNano(), hex.EncodeToString(to[:8]))
+	return os.WriteFile(filepath.Join(m.dir, name), compose(m.from, msg), 0644)
+}
@@ -0,0 +1,45 @@
+package mailer
+
+import (
+	"os"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer sends outgoing email. Implementations must be safe to use
+// from multiple goroutines.
+type Mailer interface {
+	Send(Message) error
+}
+
+// Creates a mailer based on the MAIL_DRIVER environment variable.
+// Supported drivers are "smtp", "file" and "log" (default).
+func NewMailer() Mailer {
+	from := os.Getenv("MAIL_FROM")
+	if from == "" {
+		from = "no-reply@learnswiping.local"
+	}
+
+	switch os.Getenv("MAIL_DRIVER") {
+	case "smtp":
+		return NewSMTPMailer(
+			os.Getenv("SMTP_HOST"),
+			os.Getenv("SMTP_PORT"),
+			os.Getenv("SMTP_USER"),
+			os.Getenv("SMTP_PASS"),
+			from,
+		)
+	case "file":
+		dir := os.Getenv("MAIL_DIR")
+		if dir == "" {
+			dir = "./data/mail/"
+		}
+		return NewFileMailer(dir, from)
+	default:
+		return NewLogMailer(from)
+	}
+}
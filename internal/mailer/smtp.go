@@ -0,0 +1,58 @@
+package mailer
+
+import (
+	"fmt"
+	"mime"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+type SMTPMailer struct {
+	addr string
+	host string
+	auth smtp.Auth
+	from string
+}
+
+func NewSMTPMailer(host, port, user, pass, from string) *SMTPMailer {
+	if port == "" {
+		port = "587"
+	}
+
+	var auth smtp.Auth
+	if user != "" {
+		auth = smtp.PlainAuth("", user, pass, host)
+	}
+
+	return &SMTPMailer{
+		addr: host + ":" + port,
+		host: host,
+		auth: auth,
+		from: from,
+	}
+}
+
+func (m *SMTPMailer) Send(msg Message) error {
+	return smtp.SendMail(m.addr, m.auth, m.from, []string{msg.To}, compose(m.from, msg))
+}
+
+// Builds a plain text RFC 5322 message
+func compose(from string, msg Message) []byte {
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("From: %s\r\n", header(from)))
+	b.WriteString(fmt.Sprintf("To: %s\r\n", header(msg.To)))
+	b.WriteString(fmt.Sprintf("Subject: %s\r\n", mime.QEncoding.Encode("utf-8", header(msg.Subject))))
+	b.WriteString(fmt.Sprintf("Date: %s\r\n", time.Now().Format(time.RFC1123Z)))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=\"utf-8\"\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	return []byte(b.String())
+}
+
+// Header value on a single line. Subjects carry user content like deck
+// titles, which could otherwise add headers of their own
+func header(value string) string {
+	return strings.NewReplacer("\r", "", "\n", " ").Replace(value)
+}
@@ -0,0 +1,171 @@
+package oidc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"learn-swiping-api/erro"
+	"log"
+	"os"
+	"strings"
+	"sync"
+
+	gooidc "github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+)
+
+var (
+	ErrMissingIDToken = errors.New("token response has no id_token")
+	ErrNonceMismatch  = errors.New("id_token nonce mismatch")
+)
+
+// Settings of an OpenID Connect issuer. Any compliant issuer works,
+// including a local mock server for development
+type Config struct {
+	Name         string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+}
+
+// Claims of the ID token the API cares about
+type Claims struct {
+	Subject           string `json:"sub"`
+	Email             string `json:"email"`
+	EmailVerified     bool   `json:"email_verified"`
+	Name              string `json:"name"`
+	PreferredUsername string `json:"preferred_username"`
+}
+
+type Provider struct {
+	Name     string
+	verifier *gooidc.IDTokenVerifier
+	config   oauth2.Config
+}
+
+// Holds every configured provider. Discovery is done on first use so
+// the API can start even if an issuer is temporarily down
+type Providers struct {
+	mu        sync.Mutex
+	configs   map[string]Config
+	providers map[string]*Provider
+}
+
+// Reads the providers from the environment. OIDC_PROVIDERS is a comma
+// separated list of names and each name is configured with
+// OIDC_<NAME>_ISSUER, OIDC_<NAME>_CLIENT_ID, OIDC_<NAME>_CLIENT_SECRET,
+// OIDC_<NAME>_REDIRECT_URL and optionally OIDC_<NAME>_SCOPES
+func NewProvidersFromEnv() *Providers {
+	configs := make(map[string]Config)
+	for _, name := range strings.Split(os.Getenv("OIDC_PROVIDERS"), ",") {
+		name = strings.TrimSpace(strings.ToLower(name))
+		if name == "" {
+			continue
+		}
+
+		prefix := "OIDC_" + strings.ToUpper(name) + "_"
+		config := Config{
+			Name:         name,
+			Issuer:       os.Getenv(prefix + "ISSUER"),
+			ClientID:     os.Getenv(prefix + "CLIENT_ID"),
+			ClientSecret: os.Getenv(prefix + "CLIENT_SECRET"),
+			RedirectURL:  os.Getenv(prefix + "REDIRECT_URL"),
+			Scopes:       strings.Fields(os.Getenv(prefix + "SCOPES")),
+		}
+		if config.Issuer == "" || config.ClientID == "" {
+			log.Printf("oidc: provider %s is missing issuer or client id, skipping\n", name)
+			continue
+		}
+		configs[name] = config
+	}
+
+	return NewProviders(configs)
+}
+
+func NewProviders(configs map[string]Config) *Providers {
+	return &Providers{
+		configs:   configs,
+		providers: make(map[string]*Provider),
+	}
+}
+
+// Returns a provider by name, running the discovery if needed
+func (p *Providers) Get(ctx context.Context, name string) (*Provider, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if provider, ok := p.providers[name]; ok {
+		return provider, nil
+	}
+
+	config, ok := p.configs[name]
+	if !ok {
+		return nil, erro.ErrProviderNotFound
+	}
+
+	discovered, err := gooidc.NewProvider(ctx, config.Issuer)
+	if err != nil {
+		return nil, fmt.Errorf("oidc discovery for %s: %w", name, err)
+	}
+
+	scopes := config.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{"profile", "email"}
+	}
+
+	provider := &Provider{
+		Name:     name,
+		verifier: discovered.Verifier(&gooidc.Config{ClientID: config.ClientID}),
+		config: oauth2.Config{
+			ClientID:     config.ClientID,
+			ClientSecret: config.ClientSecret,
+			RedirectURL:  config.RedirectURL,
+			Endpoint:     discovered.Endpoint(),
+			Scopes:       append([]string{gooidc.ScopeOpenID}, scopes...),
+		},
+	}
+	p.providers[name] = provider
+
+	return provider, nil
+}
+
+// Builds the authorization URL using PKCE with the S256 method
+func (p *Provider) AuthURL(state string, nonce string, verifier string) string {
+	return p.config.AuthCodeURL(state, gooidc.Nonce(nonce), oauth2.S256ChallengeOption(verifier))
+}
+
+// Exchanges an authorization code and verifies the returned ID token
+func (p *Provider) Exchange(ctx context.Context, code string, verifier string, nonce string) (Claims, error) {
+	token, err := p.config.Exchange(ctx, code, oauth2.VerifierOption(verifier))
+	if err != nil {
+		return Claims{}, err
+	}
+
+	raw, ok := token.Extra("id_token").(string)
+	if !ok {
+		return Claims{}, ErrMissingIDToken
+	}
+
+	idToken, err := p.verifier.Verify(ctx, raw)
+	if err != nil {
+		return Claims{}, err
+	}
+
+	if idToken.Nonce != nonce {
+		return Claims{}, ErrNonceMismatch
+	}
+
+	var claims Claims
+	if err := idToken.Claims(&claims); err != nil {
+		return Claims{}, err
+	}
+
+	return claims, nil
+}
+
+// Generates a PKCE code verifier
+func GenerateVerifier() string {
+	return oauth2.GenerateVerifier()
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/hex"
 	"learn-swiping-api/erro"
 	"os"
+	"path/filepath"
 )
 
 var (
@@ -48,6 +49,15 @@ func Picture(imageID string) ([]byte, error) {
 	return image, nil
 }
 
+// Whether a stored picture exists. Only takes ids made by Store
+func Exists(imageID string) bool {
+	if imageID == "" || imageID != filepath.Base(imageID) {
+		return false
+	}
+	_, err := os.Stat(storageDir + imageID)
+	return err == nil
+}
+
 func Modify(imageID string, image []byte) error {
 	return os.WriteFile(imageID, image, 0060)
 }
@@ -0,0 +1,58 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMemoryStoreRefill(t *testing.T) {
+	store := &MemoryStore{buckets: make(map[string]*bucket)}
+	policy := Policy{Name: "test", Limit: 2, Period: 2 * time.Second} // A token per second
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	steps := []struct {
+		after     time.Duration
+		allowed   bool
+		remaining int
+	}{
+		{0, true, 1},
+		{0, true, 0},
+		{0, false, 0},
+		{500 * time.Millisecond, false, 0}, // Half a token
+		{time.Second, true, 0},
+		{10 * time.Second, true, 1}, // Never more than the limit
+		{10 * time.Second, true, 1},
+		{10 * time.Second, true, 1},
+	}
+
+	now := start
+	for i, step := range steps {
+		now = now.Add(step.after)
+		result, err := store.Take("client", policy, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Allowed != step.allowed || result.Remaining != step.remaining {
+			t.Errorf("step %d: allowed %v with %d remaining, want %v with %d", i, result.Allowed, result.Remaining, step.allowed, step.remaining)
+		}
+		if !result.Allowed && result.RetryAfter <= 0 {
+			t.Errorf("step %d: limited without a Retry-After", i)
+		}
+	}
+}
+
+func TestMemoryStoreSeparatesKeys(t *testing.T) {
+	store := &MemoryStore{buckets: make(map[string]*bucket)}
+	policy := Policy{Name: "test", Limit: 1, Period: time.Minute}
+	now := time.Now()
+
+	if result, _ := store.Take("a", policy, now); !result.Allowed {
+		t.Fatal("first request of a was limited")
+	}
+	if result, _ := store.Take("a", policy, now); result.Allowed {
+		t.Fatal("second request of a was allowed")
+	}
+	if result, _ := store.Take("b", policy, now); !result.Allowed {
+		t.Fatal("b was limited by the bucket of a")
+	}
+}
@@ -0,0 +1,92 @@
+package ratelimit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"learn-swiping-api/erro"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Identifies who a request is counted against
+type KeyFunc func(*gin.Context, Resolver) string
+
+// Counts requests by client IP. Only trusted proxies can set it
+func ByIP(ctx *gin.Context, _ Resolver) string {
+	return "ip:" + ctx.ClientIP()
+}
+
+// Counts requests by the account of the session token, falling back to
+// the IP when there's none or it doesn't resolve
+func ByAccount(ctx *gin.Context, resolver Resolver) string {
+	if token := ctx.GetHeader("Token"); token != "" {
+		if accID, err := resolver.Token(token); err == nil {
+			return fmt.Sprintf("acc:%d", accID)
+		}
+	}
+	return ByIP(ctx, resolver)
+}
+
+// Counts requests by the account of the API key, then like ByAccount.
+// Keys of an account share its bucket
+func ByAPIKey(ctx *gin.Context, resolver Resolver) string {
+	if key := ctx.GetHeader("Api-Key"); key != "" {
+		if accID, err := resolver.APIKey(key); err == nil {
+			return fmt.Sprintf("acc:%d", accID)
+		}
+		return ByIP(ctx, resolver)
+	}
+	return ByAccount(ctx, resolver)
+}
+
+type Limiter struct {
+	store    Store
+	resolver Resolver
+}
+
+func NewLimiter(store Store, resolver Resolver) *Limiter {
+	return &Limiter{store: store, resolver: newCachedResolver(resolver)}
+}
+
+// Middleware enforcing a policy. Answers 429 with Retry-After when the
+// bucket is empty and always sets the RateLimit-* headers. If the store
+// fails the request is let through
+func (l *Limiter) Limit(policy Policy, key KeyFunc) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		result, err := l.store.Take(policy.Name+":"+key(ctx, l.resolver), policy, time.Now())
+		if err != nil {
+			log.Println("ratelimit:", err)
+			ctx.Next()
+			return
+		}
+
+		ctx.Header("RateLimit-Policy", fmt.Sprintf("%d;w=%d", policy.Limit, int(policy.Period.Seconds())))
+		ctx.Header("RateLimit-Limit", strconv.Itoa(result.Limit))
+		ctx.Header("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		ctx.Header("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
+
+		if !result.Allowed {
+			ctx.Header("Retry-After", strconv.Itoa(max(ceilSeconds(result.RetryAfter), 1)))
+			ctx.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": erro.ErrRateLimited.Error()})
+			return
+		}
+
+		ctx.Next()
+	}
+}
+
+// Keys may be secrets, so only a digest is kept
+func digest(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:16])
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
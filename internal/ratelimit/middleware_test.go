@@ -0,0 +1,131 @@
+package ratelimit
+
+import (
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type fakeResolver struct {
+	tokens map[string]int64
+	keys   map[string]int64
+	calls  int
+}
+
+func (r *fakeResolver) Token(token string) (int64, error) {
+	r.calls++
+	if accID, ok := r.tokens[token]; ok {
+		return accID, nil
+	}
+	return 0, sql.ErrNoRows
+}
+
+func (r *fakeResolver) APIKey(key string) (int64, error) {
+	r.calls++
+	if accID, ok := r.keys[key]; ok {
+		return accID, nil
+	}
+	return 0, sql.ErrNoRows
+}
+
+func TestKeyFuncs(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	resolver := &fakeResolver{tokens: map[string]int64{"valid-token": 7}, keys: map[string]int64{"lsk_valid": 9}}
+
+	tests := []struct {
+		name    string
+		headers map[string]string
+		key     KeyFunc
+		want    string
+	}{
+		{"ip", nil, ByIP, "ip:192.0.2.1"},
+		{"ip ignores the token", map[string]string{"Token": "valid-token"}, ByIP, "ip:192.0.2.1"},
+		{"forwarded for isn't trusted", map[string]string{"X-Forwarded-For": "203.0.113.5"}, ByIP, "ip:192.0.2.1"},
+		{"account", map[string]string{"Token": "valid-token"}, ByAccount, "acc:7"},
+		{"unknown token", map[string]string{"Token": "made-up"}, ByAccount, "ip:192.0.2.1"},
+		{"no token", nil, ByAccount, "ip:192.0.2.1"},
+		{"api key", map[string]string{"Api-Key": "lsk_valid"}, ByAPIKey, "acc:9"},
+		{"api key over token", map[string]string{"Api-Key": "lsk_valid", "Token": "valid-token"}, ByAPIKey, "acc:9"},
+		{"unknown api key", map[string]string{"Api-Key": "lsk_made_up", "Token": "valid-token"}, ByAPIKey, "ip:192.0.2.1"},
+		{"token without api key", map[string]string{"Token": "valid-token"}, ByAPIKey, "acc:7"},
+		{"nothing", nil, ByAPIKey, "ip:192.0.2.1"},
+	}
+
+	for _, tt := range tests {
+		engine := gin.New()
+		if err := engine.SetTrustedProxies(nil); err != nil {
+			t.Fatal(err)
+		}
+
+		var got string
+		engine.GET("/", func(ctx *gin.Context) { got = tt.key(ctx, resolver) })
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = "192.0.2.1:1234"
+		for name, value := range tt.headers {
+			req.Header.Set(name, value)
+		}
+		engine.ServeHTTP(httptest.NewRecorder(), req)
+
+		if got != tt.want {
+			t.Errorf("%s: key %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+// Made up keys all fall in the bucket of the IP
+func TestLimitMadeUpKeys(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	resolver := &fakeResolver{}
+	limiter := &Limiter{store: &MemoryStore{buckets: make(map[string]*bucket)}, resolver: newCachedResolver(resolver)}
+
+	engine := gin.New()
+	engine.GET("/", limiter.Limit(Policy{Name: "test", Limit: 3, Period: time.Hour}, ByAPIKey), func(ctx *gin.Context) {
+		ctx.Status(http.StatusOK)
+	})
+
+	codes := []int{}
+	for i := 0; i < 5; i++ {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = "192.0.2.1:1234"
+		req.Header.Set("Api-Key", "lsk_"+string(rune('a'+i)))
+		rec := httptest.NewRecorder()
+		engine.ServeHTTP(rec, req)
+		codes = append(codes, rec.Code)
+	}
+
+	want := []int{200, 200, 200, 429, 429}
+	for i := range want {
+		if codes[i] != want[i] {
+			t.Fatalf("codes %v, want %v", codes, want)
+		}
+	}
+}
+
+func TestCachedResolver(t *testing.T) {
+	resolver := &fakeResolver{tokens: map[string]int64{"valid-token": 7}}
+	cached := newCachedResolver(resolver)
+
+	for i := 0; i < 3; i++ {
+		if accID, err := cached.Token("valid-token"); err != nil || accID != 7 {
+			t.Fatalf("got %d, %v", accID, err)
+		}
+	}
+	if resolver.calls != 1 {
+		t.Errorf("resolved %d times, want once", resolver.calls)
+	}
+
+	// Values that don't resolve aren't kept
+	for i := 0; i < 2; i++ {
+		if _, err := cached.Token("made-up"); err == nil {
+			t.Fatal("made up token resolved")
+		}
+	}
+	if resolver.calls != 3 {
+		t.Errorf("resolved %d times, want 3", resolver.calls)
+	}
+}
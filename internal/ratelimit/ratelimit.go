@@ -0,0 +1,69 @@
+package ratelimit
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Token bucket settings. A client can burst up to Limit requests and
+// the bucket refills completely every Period
+type Policy struct {
+	Name   string
+	Limit  int
+	Period time.Duration
+}
+
+// Outcome of taking a token from a bucket
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	Reset      time.Duration // Until the bucket is full again
+	RetryAfter time.Duration // Until the next request is allowed, zero if allowed
+}
+
+// Backend holding the buckets. The in-memory one works for a single
+// instance, a shared store is needed to limit across several instances
+type Store interface {
+	Take(key string, policy Policy, now time.Time) (Result, error)
+}
+
+// Returns the policy with the limit overridden by the RATE_LIMIT_<NAME>
+// environment variable if set, with the format "<limit>/<period>",
+// e.g. "10/1m"
+func PolicyFromEnv(policy Policy) Policy {
+	value := os.Getenv("RATE_LIMIT_" + strings.ToUpper(policy.Name))
+	if value == "" {
+		return policy
+	}
+
+	overridden, err := parsePolicy(policy.Name, value)
+	if err != nil {
+		log.Printf("ratelimit: ignoring RATE_LIMIT_%s: %v\n", strings.ToUpper(policy.Name), err)
+		return policy
+	}
+	return overridden
+}
+
+func parsePolicy(name string, value string) (Policy, error) {
+	limitStr, periodStr, ok := strings.Cut(value, "/")
+	if !ok {
+		return Policy{}, fmt.Errorf("expected <limit>/<period>, got %q", value)
+	}
+
+	limit, err := strconv.Atoi(strings.TrimSpace(limitStr))
+	if err != nil || limit <= 0 {
+		return Policy{}, fmt.Errorf("invalid limit %q", limitStr)
+	}
+
+	period, err := time.ParseDuration(strings.TrimSpace(periodStr))
+	if err != nil || period <= 0 {
+		return Policy{}, fmt.Errorf("invalid period %q", periodStr)
+	}
+
+	return Policy{Name: name, Limit: limit, Period: period}, nil
+}
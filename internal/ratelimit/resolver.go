@@ -0,0 +1,119 @@
+package ratelimit
+
+import (
+	"database/sql"
+	"learn-swiping-api/internal/apikey"
+	"log"
+	"sync"
+	"time"
+)
+
+// Finds the account a session token or API key belongs to. Requests are
+// only counted by them once they resolve, so made up headers can't get
+// fresh buckets
+type Resolver interface {
+	Token(token string) (int64, error)
+	APIKey(key string) (int64, error)
+}
+
+type SQLResolver struct {
+	db         *sql.DB
+	TokenStmt  *sql.Stmt
+	APIKeyStmt *sql.Stmt
+}
+
+func NewSQLResolver(db *sql.DB) *SQLResolver {
+	resolver := &SQLResolver{db: db}
+	err := resolver.InitStatements()
+	if err != nil {
+		log.Fatalln(err)
+	}
+	return resolver
+}
+
+func (r *SQLResolver) InitStatements() error {
+	var err error
+	r.TokenStmt, err = r.db.Prepare("SELECT acc_id FROM ACCOUNT WHERE token = ? AND token_expire >= NOW()")
+	if err != nil {
+		return err
+	}
+
+	r.APIKeyStmt, err = r.db.Prepare("SELECT acc_id FROM API_KEY WHERE key_hash = ? AND (expires_at IS NULL OR expires_at > NOW())")
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *SQLResolver) Token(token string) (int64, error) {
+	var accID int64
+	err := r.TokenStmt.QueryRow(token).Scan(&accID)
+	return accID, err
+}
+
+func (r *SQLResolver) APIKey(key string) (int64, error) {
+	var accID int64
+	err := r.APIKeyStmt.QueryRow(apikey.HashKey(key)).Scan(&accID)
+	return accID, err
+}
+
+const (
+	resolvedFor = time.Minute
+	maxResolved = 10000
+)
+
+type resolved struct {
+	accID   int64
+	expires time.Time
+}
+
+// Keeps what a Resolver found for a while so it isn't asked on every
+// request. Only values that resolved are kept
+type cachedResolver struct {
+	resolver Resolver
+	mu       sync.Mutex
+	cache    map[string]resolved
+}
+
+func newCachedResolver(resolver Resolver) *cachedResolver {
+	return &cachedResolver{resolver: resolver, cache: make(map[string]resolved)}
+}
+
+func (c *cachedResolver) Token(token string) (int64, error) {
+	return c.lookup("token:"+digest(token), func() (int64, error) { return c.resolver.Token(token) })
+}
+
+func (c *cachedResolver) APIKey(key string) (int64, error) {
+	return c.lookup("key:"+digest(key), func() (int64, error) { return c.resolver.APIKey(key) })
+}
+
+func (c *cachedResolver) lookup(key string, resolve func() (int64, error)) (int64, error) {
+	now := time.Now()
+	c.mu.Lock()
+	entry, ok := c.cache[key]
+	c.mu.Unlock()
+	if ok && now.Before(entry.expires) {
+		return entry.accID, nil
+	}
+
+	accID, err := resolve()
+	if err != nil {
+		return 0, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.cache) >= maxResolved {
+		for k, e := range c.cache {
+			if now.After(e.expires) {
+				delete(c.cache, k)
+			}
+		}
+		if len(c.cache) >= maxResolved {
+			clear(c.cache)
+		}
+	}
+	c.cache[key] = resolved{accID: accID, expires: now.Add(resolvedFor)}
+	return accID, nil
+}
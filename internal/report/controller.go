@@ -0,0 +1,265 @@
+package report
+
+import (
+	"errors"
+	"learn-swiping-api/erro"
+	"learn-swiping-api/internal/audit"
+	report "learn-swiping-api/internal/report/dto"
+	"learn-swiping-api/internal/role"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ReportController interface {
+	ReportDeck(*gin.Context)    // POST
+	ReportCard(*gin.Context)    // POST
+	ReportAccount(*gin.Context) // POST
+	Reports(*gin.Context)       // GET
+	Report(*gin.Context)        // GET
+	Triage(*gin.Context)        // POST
+	Escalate(*gin.Context)      // POST
+	Dismiss(*gin.Context)       // POST
+	Resolve(*gin.Context)       // POST
+}
+
+type ReportControllerImpl struct {
+	service ReportService
+}
+
+func NewReportController(service ReportService) ReportController {
+	return &ReportControllerImpl{service: service}
+}
+
+// Reports a public deck
+// Method: POST
+func (c *ReportControllerImpl) ReportDeck(ctx *gin.Context) {
+	deckID, err := strconv.Atoi(ctx.Param("deckID"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrBadField.Error()})
+		return
+	}
+
+	c.create(ctx, report.CreateRequest{TargetType: TargetDeck, TargetID: int64(deckID)})
+}
+
+// Reports a card of a public deck
+// Method: POST
+func (c *ReportControllerImpl) ReportCard(ctx *gin.Context) {
+	deckID, err := strconv.Atoi(ctx.Param("deckID"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrBadField.Error()})
+		return
+	}
+
+	cardID, err := strconv.Atoi(ctx.Param("cardID"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrBadField.Error()})
+		return
+	}
+
+	c.create(ctx, report.CreateRequest{TargetType: TargetCard, TargetID: int64(cardID), DeckID: int64(deckID)})
+}
+
+// Reports a profile
+// Method: POST
+func (c *ReportControllerImpl) ReportAccount(ctx *gin.Context) {
+	c.create(ctx, report.CreateRequest{TargetType: TargetAccount, Username: ctx.Param("username")})
+}
+
+func (c *ReportControllerImpl) create(ctx *gin.Context, target report.CreateRequest) {
+	token := ctx.GetHeader("Token")
+	if token == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrInvalidToken.Error()})
+		return
+	}
+
+	var request report.CreateRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrBadField.Error()})
+		return
+	}
+	request.Token = token
+	request.TargetType = target.TargetType
+	request.TargetID = target.TargetID
+	request.DeckID = target.DeckID
+	request.Username = target.Username
+
+	reportID, err := c.service.Create(request)
+	if err != nil {
+		reportError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{"report_id": reportID})
+}
+
+// Lists the moderation queue. Without a status only pending reports are shown
+// Method: GET
+func (c *ReportControllerImpl) Reports(ctx *gin.Context) {
+	filter := Filter{
+		Status:     ctx.Query("status"),
+		TargetType: ctx.Query("type"),
+	}
+
+	var err error
+	if page := ctx.Query("page"); page != "" {
+		if filter.Page, err = strconv.Atoi(page); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrBadField.Error()})
+			return
+		}
+	}
+	if size := ctx.Query("size"); size != "" {
+		if filter.Size, err = strconv.Atoi(size); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrBadField.Error()})
+			return
+		}
+	}
+
+	reports, err := c.service.Reports(filter)
+	if err != nil {
+		reportError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, reports)
+}
+
+// Retrieves a report
+// Method: GET
+func (c *ReportControllerImpl) Report(ctx *gin.Context) {
+	reportID, err := strconv.Atoi(ctx.Param("reportID"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrBadField.Error()})
+		return
+	}
+
+	r, err := c.service.Report(int64(reportID))
+	if err != nil {
+		reportError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, r)
+}
+
+// Assigns a report to the caller
+// Method: POST
+func (c *ReportControllerImpl) Triage(ctx *gin.Context) {
+	reportID, err := strconv.Atoi(ctx.Param("reportID"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrBadField.Error()})
+		return
+	}
+
+	if err := c.service.Triage(moderator(ctx), int64(reportID)); err != nil {
+		reportError(ctx, err)
+		return
+	}
+
+	audit.Action(ctx, "report.triage", "report:"+strconv.Itoa(reportID))
+	ctx.JSON(http.StatusOK, gin.H{})
+}
+
+// Hands a report over to administrators
+// Method: POST
+func (c *ReportControllerImpl) Escalate(ctx *gin.Context) {
+	reportID, request, ok := handleRequest(ctx)
+	if !ok {
+		return
+	}
+
+	if err := c.service.Escalate(moderator(ctx), reportID, request); err != nil {
+		reportError(ctx, err)
+		return
+	}
+
+	audit.Action(ctx, "report.escalate", "report:"+strconv.FormatInt(reportID, 10))
+	audit.Detail(ctx, "note", request.Note)
+	ctx.JSON(http.StatusOK, gin.H{})
+}
+
+// Closes the reports about some content without doing anything
+// Method: POST
+func (c *ReportControllerImpl) Dismiss(ctx *gin.Context) {
+	reportID, request, ok := handleRequest(ctx)
+	if !ok {
+		return
+	}
+
+	if err := c.service.Dismiss(moderator(ctx), reportID, request); err != nil {
+		reportError(ctx, err)
+		return
+	}
+
+	audit.Action(ctx, "report.dismiss", "report:"+strconv.FormatInt(reportID, 10))
+	audit.Detail(ctx, "note", request.Note)
+	ctx.JSON(http.StatusOK, gin.H{})
+}
+
+// Closes the reports about some content applying the given actions
+// Method: POST
+func (c *ReportControllerImpl) Resolve(ctx *gin.Context) {
+	reportID, request, ok := handleRequest(ctx)
+	if !ok {
+		return
+	}
+
+	r, err := c.service.Resolve(moderator(ctx), reportID, request)
+	if err != nil {
+		reportError(ctx, err)
+		return
+	}
+
+	audit.Action(ctx, "report.resolve", "report:"+strconv.FormatInt(reportID, 10))
+	audit.Detail(ctx, "target", r.TargetType+":"+strconv.FormatInt(r.TargetID, 10))
+	audit.Detail(ctx, "actions", r.Actions)
+	audit.Detail(ctx, "note", request.Note)
+	ctx.JSON(http.StatusOK, r)
+}
+
+// Reads the report id and the optional body of the moderation endpoints
+func handleRequest(ctx *gin.Context) (int64, report.HandleRequest, bool) {
+	reportID, err := strconv.Atoi(ctx.Param("reportID"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrBadField.Error()})
+		return 0, report.HandleRequest{}, false
+	}
+
+	var request report.HandleRequest
+	if ctx.Request.ContentLength != 0 {
+		if err := ctx.ShouldBindJSON(&request); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrBadField.Error()})
+			return 0, report.HandleRequest{}, false
+		}
+	}
+
+	return int64(reportID), request, true
+}
+
+// Caller as stored by the role middleware
+func moderator(ctx *gin.Context) role.AccountRole {
+	return role.AccountRole{AccID: ctx.GetInt64("acc_id"), Role: ctx.GetString("role")}
+}
+
+func reportError(ctx *gin.Context, err error) {
+	if errors.Is(err, erro.ErrBadField) || errors.Is(err, erro.ErrInvalidToken) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, erro.ErrForbidden) {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, erro.ErrReportNotFound) || errors.Is(err, erro.ErrDeckNotFound) ||
+		errors.Is(err, erro.ErrCardNotFound) || errors.Is(err, erro.ErrAccountNotFound) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, erro.ErrReportExists) || errors.Is(err, erro.ErrReportClosed) {
+		ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
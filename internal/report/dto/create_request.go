@@ -0,0 +1,11 @@
+package report
+
+type CreateRequest struct {
+	Token      string
+	TargetType string
+	TargetID   int64
+	DeckID     int64  // Needed for cards
+	Username   string // Needed for accounts
+	Reason     string `json:"reason" binding:"required"`
+	Comment    string `json:"comment"`
+}
@@ -0,0 +1,8 @@
+package report
+
+// Body of the triage, escalate, dismiss and resolve endpoints
+type HandleRequest struct {
+	Note    string   `json:"note"`
+	Actions []string `json:"actions"` // Only used when resolving
+	Message string   `json:"message"` // Sent to the owner with the warn_owner action
+}
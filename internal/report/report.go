@@ -0,0 +1,92 @@
+package report
+
+import (
+	"slices"
+	"time"
+)
+
+// What can be reported
+const (
+	TargetDeck    = "deck"
+	TargetCard    = "card"
+	TargetAccount = "account"
+)
+
+// Reason codes
+const (
+	ReasonSpam         = "spam"
+	ReasonPlagiarism   = "plagiarism"
+	ReasonAbuse        = "abuse"
+	ReasonSexual       = "sexual"
+	ReasonMisleading   = "misleading"
+	ReasonPersonalInfo = "personal_info"
+	ReasonOther        = "other"
+)
+
+var Reasons = []string{ReasonSpam, ReasonPlagiarism, ReasonAbuse, ReasonSexual, ReasonMisleading, ReasonPersonalInfo, ReasonOther}
+
+// Report lifecycle. Open and triaged reports are handled by moderators,
+// escalated ones need someone who can manage accounts
+const (
+	StatusOpen      = "open"
+	StatusTriaged   = "triaged"
+	StatusEscalated = "escalated"
+	StatusResolved  = "resolved"
+	StatusDismissed = "dismissed"
+)
+
+var Statuses = []string{StatusOpen, StatusTriaged, StatusEscalated, StatusResolved, StatusDismissed}
+
+// Applied automatically when a report is resolved
+const (
+	ActionHideDeck  = "hide_deck"
+	ActionWarnOwner = "warn_owner"
+)
+
+var Actions = []string{ActionHideDeck, ActionWarnOwner}
+
+type Report struct {
+	ID         int64      `json:"report_id"`
+	TargetType string     `json:"target_type"`
+	TargetID   int64      `json:"target_id"`
+	DeckID     *int64     `json:"deck_id,omitempty"`
+	OwnerID    *int64     `json:"owner_id"`
+	ReporterID *int64     `json:"reporter_id"`
+	Reason     string     `json:"reason"`
+	Comment    string     `json:"comment"`
+	Status     string     `json:"status"`
+	AssignedTo *int64     `json:"assigned_to"`
+	Actions    []string   `json:"actions"`
+	Note       string     `json:"note"`
+	HandledBy  *int64     `json:"handled_by"`
+	HandledAt  *time.Time `json:"handled_at"`
+	Reports    int        `json:"reports"` // Pending reports about the same target
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+func (r Report) Closed() bool {
+	return r.Status == StatusResolved || r.Status == StatusDismissed
+}
+
+// Deck the reported content belongs to, if any
+func (r Report) Deck() (int64, bool) {
+	switch {
+	case r.TargetType == TargetDeck:
+		return r.TargetID, true
+	case r.DeckID != nil:
+		return *r.DeckID, true
+	}
+	return 0, false
+}
+
+type Filter struct {
+	Status     string
+	TargetType string
+	Page       int
+	Size       int
+}
+
+func validReason(reason string) bool {
+	return slices.Contains(Reasons, reason)
+}
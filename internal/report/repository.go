@@ -0,0 +1,307 @@
+package report
+
+import (
+	"database/sql"
+	"learn-swiping-api/erro"
+	"log"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+type ReportRepository interface {
+	Reporter(token string) (int64, error)
+	Target(report Report, username string) (Report, error) // Fills the target id, deck and owner
+	Create(Report) (int64, error)
+	ById(reportID int64) (Report, error)
+	Reports(Filter) ([]Report, error)
+	Triage(reportID int64, accID int64) error
+	Escalate(reportID int64, accID int64, note string) error
+	Close(report Report, status string, actions []string, note string, accID int64) error
+	Warn(accID int64, reportID int64, message string, createdBy int64) error
+	Contact(accID int64) (string, string, error) // Email and name
+}
+
+type ReportRepositoryImpl struct {
+	db           *sql.DB
+	ReporterStmt *sql.Stmt
+	DeckStmt     *sql.Stmt
+	CardStmt     *sql.Stmt
+	AccountStmt  *sql.Stmt
+	CreateStmt   *sql.Stmt
+	ByIdStmt     *sql.Stmt
+	TriageStmt   *sql.Stmt
+	EscalateStmt *sql.Stmt
+	CloseStmt    *sql.Stmt
+	WarnStmt     *sql.Stmt
+	ContactStmt  *sql.Stmt
+}
+
+func NewReportRepository(db *sql.DB) *ReportRepositoryImpl {
+	repo := &ReportRepositoryImpl{db: db}
+	err := repo.InitStatements()
+	if err != nil {
+		log.Fatalln(err)
+	}
+	return repo
+}
+
+// Columns read by scanReport
+const reportColumns = `r.report_id, r.target_type, r.target_id, r.deck_id, r.owner_id, r.reporter_id, r.reason, r.comment,
+							r.status, r.assigned_to, r.actions, r.note, r.handled_by, r.handled_at,
+							(SELECT COUNT(*) FROM REPORT o WHERE o.target_type = r.target_type AND o.target_id = r.target_id AND o.pending = 1) AS reports,
+							r.created_at, r.updated_at
+						FROM REPORT r`
+
+func (r *ReportRepositoryImpl) InitStatements() error {
+	var err error
+	r.ReporterStmt, err = r.db.Prepare("SELECT acc_id FROM ACCOUNT WHERE token = ? AND token_expire >= NOW()")
+	if err != nil {
+		return err
+	}
+
+	// Only content people can see in the shop can be reported
+	r.DeckStmt, err = r.db.Prepare("SELECT acc_id FROM DECK WHERE deck_id = ? AND visible = 1")
+	if err != nil {
+		return err
+	}
+
+	r.CardStmt, err = r.db.Prepare(`SELECT d.acc_id FROM CARD c
+										LEFT JOIN DECK d ON c.deck_id = d.deck_id
+										WHERE c.card_id = ? AND c.deck_id = ? AND d.visible = 1`)
+	if err != nil {
+		return err
+	}
+
+	r.AccountStmt, err = r.db.Prepare("SELECT acc_id FROM ACCOUNT WHERE username = ?")
+	if err != nil {
+		return err
+	}
+
+	r.CreateStmt, err = r.db.Prepare(`INSERT INTO REPORT (target_type, target_id, deck_id, owner_id, reporter_id, reason, comment)
+										VALUES (?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+
+	r.ByIdStmt, err = r.db.Prepare("SELECT " + reportColumns + " WHERE r.report_id = ?")
+	if err != nil {
+		return err
+	}
+
+	r.TriageStmt, err = r.db.Prepare(`UPDATE REPORT SET status = 'triaged', assigned_to = ?
+										WHERE report_id = ? AND status IN ('open', 'triaged')`)
+	if err != nil {
+		return err
+	}
+
+	r.EscalateStmt, err = r.db.Prepare(`UPDATE REPORT SET status = 'escalated', assigned_to = NULL, note = ?, handled_by = ?
+										WHERE report_id = ? AND status IN ('open', 'triaged')`)
+	if err != nil {
+		return err
+	}
+
+	// A decision about some content closes every pending report about it
+	r.CloseStmt, err = r.db.Prepare(`UPDATE REPORT SET status = ?, pending = NULL, actions = ?, note = ?, handled_by = ?, handled_at = NOW()
+										WHERE target_type = ? AND target_id = ? AND pending = 1`)
+	if err != nil {
+		return err
+	}
+
+	r.WarnStmt, err = r.db.Prepare("INSERT INTO ACCOUNT_WARNING (acc_id, report_id, message, created_by) VALUES (?, ?, ?, ?)")
+	if err != nil {
+		return err
+	}
+
+	r.ContactStmt, err = r.db.Prepare("SELECT email, name FROM ACCOUNT WHERE acc_id = ?")
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *ReportRepositoryImpl) Reporter(token string) (int64, error) {
+	var accID int64
+	if err := r.ReporterStmt.QueryRow(token).Scan(&accID); err != nil {
+		if err == sql.ErrNoRows {
+			return 0, erro.ErrInvalidToken
+		}
+		return 0, err
+	}
+	return accID, nil
+}
+
+func (r *ReportRepositoryImpl) Target(report Report, username string) (Report, error) {
+	var ownerID int64
+	var err error
+	var notFound error
+	switch report.TargetType {
+	case TargetDeck:
+		err = r.DeckStmt.QueryRow(report.TargetID).Scan(&ownerID)
+		notFound = erro.ErrDeckNotFound
+	case TargetCard:
+		err = r.CardStmt.QueryRow(report.TargetID, report.DeckID).Scan(&ownerID)
+		notFound = erro.ErrCardNotFound
+	case TargetAccount:
+		err = r.AccountStmt.QueryRow(username).Scan(&ownerID)
+		report.TargetID = ownerID
+		notFound = erro.ErrAccountNotFound
+	default:
+		return Report{}, erro.ErrBadField
+	}
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return Report{}, notFound
+		}
+		return Report{}, err
+	}
+
+	report.OwnerID = &ownerID
+	return report, nil
+}
+
+func (r *ReportRepositoryImpl) Create(report Report) (int64, error) {
+	result, err := r.CreateStmt.Exec(report.TargetType, report.TargetID, report.DeckID, report.OwnerID, report.ReporterID, report.Reason, report.Comment)
+	if err != nil {
+		if mysqlErr, ok := err.(*mysql.MySQLError); ok && mysqlErr.Number == 1062 {
+			return 0, erro.ErrReportExists
+		}
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+func (r *ReportRepositoryImpl) ById(reportID int64) (Report, error) {
+	report, err := scanReport(r.ByIdStmt.QueryRow(reportID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return Report{}, erro.ErrReportNotFound
+		}
+		return Report{}, err
+	}
+	return report, nil
+}
+
+// Escalated reports and targets with more reports come first
+func (r *ReportRepositoryImpl) Reports(filter Filter) ([]Report, error) {
+	var query strings.Builder
+	var args []any
+	query.WriteString("SELECT " + reportColumns)
+
+	if filter.Status != "" {
+		query.WriteString(" WHERE r.status = ?")
+		args = append(args, filter.Status)
+	} else {
+		query.WriteString(" WHERE r.pending = 1")
+	}
+	if filter.TargetType != "" {
+		query.WriteString(" AND r.target_type = ?")
+		args = append(args, filter.TargetType)
+	}
+
+	query.WriteString(" ORDER BY r.status = 'escalated' DESC, reports DESC, r.created_at LIMIT ? OFFSET ?")
+	args = append(args, filter.Size, (filter.Page-1)*filter.Size)
+
+	rows, err := r.db.Query(query.String(), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reports := []Report{}
+	for rows.Next() {
+		report, err := scanReport(rows)
+		if err != nil {
+			return nil, err
+		}
+		reports = append(reports, report)
+	}
+
+	return reports, nil
+}
+
+// Assigns the report to a moderator
+func (r *ReportRepositoryImpl) Triage(reportID int64, accID int64) error {
+	return r.exec(r.TriageStmt, accID, reportID)
+}
+
+func (r *ReportRepositoryImpl) Escalate(reportID int64, accID int64, note string) error {
+	return r.exec(r.EscalateStmt, note, accID, reportID)
+}
+
+func (r *ReportRepositoryImpl) Close(report Report, status string, actions []string, note string, accID int64) error {
+	return r.exec(r.CloseStmt, status, strings.Join(actions, " "), note, accID, report.TargetType, report.TargetID)
+}
+
+func (r *ReportRepositoryImpl) Warn(accID int64, reportID int64, message string, createdBy int64) error {
+	_, err := r.WarnStmt.Exec(accID, reportID, message, createdBy)
+	if err != nil {
+		if mysqlErr, ok := err.(*mysql.MySQLError); ok && mysqlErr.Number == 1452 {
+			return erro.ErrAccountNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+func (r *ReportRepositoryImpl) Contact(accID int64) (string, string, error) {
+	var email, name string
+	if err := r.ContactStmt.QueryRow(accID).Scan(&email, &name); err != nil {
+		if err == sql.ErrNoRows {
+			return "", "", erro.ErrAccountNotFound
+		}
+		return "", "", err
+	}
+	return email, name, nil
+}
+
+// Runs an update that only changes pending reports
+func (r *ReportRepositoryImpl) exec(stmt *sql.Stmt, args ...any) error {
+	result, err := stmt.Exec(args...)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return erro.ErrReportClosed
+	}
+
+	return nil
+}
+
+// Scans from either *sql.Row or *sql.Rows
+func scanReport(row interface{ Scan(...any) error }) (Report, error) {
+	var report Report
+	var actions string
+	err := row.Scan(
+		&report.ID,
+		&report.TargetType,
+		&report.TargetID,
+		&report.DeckID,
+		&report.OwnerID,
+		&report.ReporterID,
+		&report.Reason,
+		&report.Comment,
+		&report.Status,
+		&report.AssignedTo,
+		&actions,
+		&report.Note,
+		&report.HandledBy,
+		&report.HandledAt,
+		&report.Reports,
+		&report.CreatedAt,
+		&report.UpdatedAt,
+	)
+	if err != nil {
+		return Report{}, err
+	}
+
+	report.Actions = strings.Fields(actions)
+	return report, nil
+}
@@ -0,0 +1,226 @@
+package report
+
+import (
+	"fmt"
+	"learn-swiping-api/erro"
+	"learn-swiping-api/internal/admin"
+	admindto "learn-swiping-api/internal/admin/dto"
+	"learn-swiping-api/internal/mailer"
+	report "learn-swiping-api/internal/report/dto"
+	"learn-swiping-api/internal/role"
+	"log"
+	"slices"
+	"strings"
+)
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 200
+)
+
+// Moderator is the account handling the queue and its role
+type ReportService interface {
+	Create(report.CreateRequest) (int64, error)
+	Reports(Filter) ([]Report, error)
+	Report(reportID int64) (Report, error)
+	Triage(moderator role.AccountRole, reportID int64) error
+	Escalate(moderator role.AccountRole, reportID int64, request report.HandleRequest) error
+	Dismiss(moderator role.AccountRole, reportID int64, request report.HandleRequest) error
+	Resolve(moderator role.AccountRole, reportID int64, request report.HandleRequest) (Report, error)
+	pending(moderator role.AccountRole, reportID int64) (Report, error)
+	warn(moderator role.AccountRole, r Report, message string) error
+}
+
+type ReportServiceImpl struct {
+	repository ReportRepository
+	admin      admin.AdminService
+	mailer     mailer.Mailer
+}
+
+func NewReportService(repository ReportRepository, admin admin.AdminService, mailer mailer.Mailer) ReportService {
+	return &ReportServiceImpl{repository: repository, admin: admin, mailer: mailer}
+}
+
+func (s *ReportServiceImpl) Create(request report.CreateRequest) (int64, error) {
+	if !validReason(request.Reason) || len(request.Comment) > 1000 {
+		return 0, erro.ErrBadField
+	}
+
+	reporter, err := s.repository.Reporter(request.Token)
+	if err != nil {
+		return 0, err
+	}
+
+	r := Report{
+		TargetType: request.TargetType,
+		TargetID:   request.TargetID,
+		ReporterID: &reporter,
+		Reason:     request.Reason,
+		Comment:    strings.TrimSpace(request.Comment),
+	}
+	if request.TargetType == TargetCard {
+		r.DeckID = &request.DeckID
+	}
+
+	r, err = s.repository.Target(r, request.Username)
+	if err != nil {
+		return 0, err
+	}
+
+	// Reporting yourself doesn't make sense
+	if *r.OwnerID == reporter {
+		return 0, erro.ErrForbidden
+	}
+
+	return s.repository.Create(r)
+}
+
+func (s *ReportServiceImpl) Reports(filter Filter) ([]Report, error) {
+	if filter.Status != "" && !slices.Contains(Statuses, filter.Status) {
+		return nil, erro.ErrBadField
+	}
+	if filter.TargetType != "" && filter.TargetType != TargetDeck && filter.TargetType != TargetCard && filter.TargetType != TargetAccount {
+		return nil, erro.ErrBadField
+	}
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	if filter.Size < 1 {
+		filter.Size = defaultPageSize
+	}
+	if filter.Size > maxPageSize {
+		return nil, erro.ErrBadField
+	}
+
+	return s.repository.Reports(filter)
+}
+
+func (s *ReportServiceImpl) Report(reportID int64) (Report, error) {
+	return s.repository.ById(reportID)
+}
+
+// Assigns the report to the moderator
+func (s *ReportServiceImpl) Triage(moderator role.AccountRole, reportID int64) error {
+	if _, err := s.pending(moderator, reportID); err != nil {
+		return err
+	}
+	return s.repository.Triage(reportID, moderator.AccID)
+}
+
+// Hands the report over to administrators
+func (s *ReportServiceImpl) Escalate(moderator role.AccountRole, reportID int64, request report.HandleRequest) error {
+	if _, err := s.pending(moderator, reportID); err != nil {
+		return err
+	}
+	return s.repository.Escalate(reportID, moderator.AccID, strings.TrimSpace(request.Note))
+}
+
+func (s *ReportServiceImpl) Dismiss(moderator role.AccountRole, reportID int64, request report.HandleRequest) error {
+	r, err := s.pending(moderator, reportID)
+	if err != nil {
+		return err
+	}
+	return s.repository.Close(r, StatusDismissed, nil, strings.TrimSpace(request.Note), moderator.AccID)
+}
+
+// Applies the actions and closes every pending report about the same
+// content. If an action fails the reports stay pending so it can be retried
+func (s *ReportServiceImpl) Resolve(moderator role.AccountRole, reportID int64, request report.HandleRequest) (Report, error) {
+	for _, action := range request.Actions {
+		if !slices.Contains(Actions, action) {
+			return Report{}, erro.ErrBadField
+		}
+	}
+
+	r, err := s.pending(moderator, reportID)
+	if err != nil {
+		return Report{}, err
+	}
+
+	note := strings.TrimSpace(request.Note)
+	actions := slices.Clone(request.Actions)
+	slices.Sort(actions)
+	actions = slices.Compact(actions)
+	for _, action := range actions {
+		switch action {
+		case ActionHideDeck:
+			deckID, ok := r.Deck()
+			if !ok {
+				return Report{}, erro.ErrBadField
+			}
+			hidden := false
+			reason := fmt.Sprintf("Report %d (%s)", r.ID, r.Reason)
+			if note != "" {
+				reason += ": " + note
+			}
+			err = s.admin.SetDeckVisibility(moderator.AccID, deckID, admindto.VisibilityRequest{Visible: &hidden, Reason: reason})
+		case ActionWarnOwner:
+			err = s.warn(moderator, r, request.Message)
+		}
+		if err != nil {
+			return Report{}, err
+		}
+	}
+
+	if err := s.repository.Close(r, StatusResolved, actions, note, moderator.AccID); err != nil {
+		return Report{}, err
+	}
+
+	r.Status = StatusResolved
+	r.Actions = actions
+	return r, nil
+}
+
+// Loads a report that can still be handled by the moderator. Escalated
+// reports need someone who can manage accounts
+func (s *ReportServiceImpl) pending(moderator role.AccountRole, reportID int64) (Report, error) {
+	r, err := s.repository.ById(reportID)
+	if err != nil {
+		return Report{}, err
+	}
+
+	if r.Closed() {
+		return Report{}, erro.ErrReportClosed
+	}
+
+	if r.Status == StatusEscalated && !role.Can(moderator.Role, role.ManageAccounts) {
+		return Report{}, erro.ErrForbidden
+	}
+
+	return r, nil
+}
+
+// Stores a warning for the owner of the content and mails it. The mail
+// failing doesn't undo the warning
+func (s *ReportServiceImpl) warn(moderator role.AccountRole, r Report, message string) error {
+	if r.OwnerID == nil {
+		return erro.ErrAccountNotFound
+	}
+
+	message = strings.TrimSpace(message)
+	if message == "" {
+		message = fmt.Sprintf("Some of your content was reported for %s and a moderator agreed it breaks the rules.", strings.ReplaceAll(r.Reason, "_", " "))
+	}
+	if len(message) > 1000 {
+		return erro.ErrBadField
+	}
+
+	if err := s.repository.Warn(*r.OwnerID, r.ID, message, moderator.AccID); err != nil {
+		return err
+	}
+
+	email, name, err := s.repository.Contact(*r.OwnerID)
+	if err != nil {
+		return err
+	}
+
+	err = s.mailer.Send(mailer.Message{
+		To:      email,
+		Subject: "A moderator reviewed your content",
+		Body:    fmt.Sprintf("Hi %s,\n\n%s\n\nRepeated warnings can lead to your account being suspended.\n", name, message),
+	})
+	if err != nil {
+		log.Println(err)
+	}
+	return nil
+}
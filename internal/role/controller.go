@@ -0,0 +1,122 @@
+package role
+
+import (
+	"errors"
+	"learn-swiping-api/erro"
+	"learn-swiping-api/internal/audit"
+	role "learn-swiping-api/internal/role/dto"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type RoleController interface {
+	Permissions(*gin.Context) // GET
+	Assign(*gin.Context)      // PUT
+	Privileged(*gin.Context)  // GET
+	Require(permission Permission) gin.HandlerFunc
+}
+
+type RoleControllerImpl struct {
+	service RoleService
+}
+
+func NewRoleController(service RoleService) RoleController {
+	return &RoleControllerImpl{service: service}
+}
+
+// Retrieves the role and permissions of the caller
+// Method: GET
+func (c *RoleControllerImpl) Permissions(ctx *gin.Context) {
+	token := ctx.GetHeader("Token")
+	if token == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrInvalidToken.Error()})
+		return
+	}
+
+	permissions, err := c.service.Permissions(token)
+	if err != nil {
+		roleError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, permissions)
+}
+
+// Changes the role of an account
+// Method: PUT
+func (c *RoleControllerImpl) Assign(ctx *gin.Context) {
+	var request role.AssignRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrBadField.Error()})
+		return
+	}
+	request.Username = ctx.Param("username")
+
+	if err := c.service.Assign(ctx.GetHeader("Token"), request); err != nil {
+		roleError(ctx, err)
+		return
+	}
+
+	audit.Action(ctx, "role.assign", "account:"+request.Username)
+	audit.Detail(ctx, "role", request.Role)
+	ctx.JSON(http.StatusOK, gin.H{})
+}
+
+// Lists moderators and admins
+// Method: GET
+func (c *RoleControllerImpl) Privileged(ctx *gin.Context) {
+	roles, err := c.service.Privileged()
+	if err != nil {
+		roleError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, roles)
+}
+
+// Middleware that only lets through accounts whose role has the
+// permission. The caller's acc_id and role are stored in the context
+func (c *RoleControllerImpl) Require(permission Permission) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		token := ctx.GetHeader("Token")
+		if token == "" {
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": erro.ErrInvalidToken.Error()})
+			return
+		}
+
+		caller, err := c.service.Authorize(token, permission)
+		if err != nil {
+			if errors.Is(err, erro.ErrInvalidToken) {
+				ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+				return
+			}
+			if errors.Is(err, erro.ErrForbidden) {
+				ctx.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": err.Error()})
+				return
+			}
+			ctx.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		ctx.Set("acc_id", caller.AccID)
+		ctx.Set("role", caller.Role)
+		ctx.Next()
+	}
+}
+
+func roleError(ctx *gin.Context, err error) {
+	if errors.Is(err, erro.ErrInvalidToken) || errors.Is(err, erro.ErrBadField) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, erro.ErrForbidden) {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, erro.ErrAccountNotFound) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
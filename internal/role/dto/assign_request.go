@@ -0,0 +1,6 @@
+package role
+
+type AssignRequest struct {
+	Username string // Provided in URL params
+	Role     string `json:"role" binding:"required"`
+}
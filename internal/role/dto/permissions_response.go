@@ -0,0 +1,6 @@
+package role
+
+type PermissionsResponse struct {
+	Role        string   `json:"role"`
+	Permissions []string `json:"permissions"`
+}
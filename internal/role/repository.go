@@ -0,0 +1,155 @@
+package role
+
+import (
+	"database/sql"
+	"learn-swiping-api/erro"
+	"log"
+)
+
+type RoleRepository interface {
+	ByToken(token string) (AccountRole, error)
+	ByUsername(username string) (AccountRole, error)
+	Assign(accID int64, role string, grantedBy *int64) error
+	Privileged() ([]AccountRole, error)
+	Count(role string) (int, error)
+}
+
+type RoleRepositoryImpl struct {
+	db             *sql.DB
+	ByTokenStmt    *sql.Stmt
+	ByUsernameStmt *sql.Stmt
+	AssignStmt     *sql.Stmt
+	RemoveStmt     *sql.Stmt
+	PrivilegedStmt *sql.Stmt
+	CountStmt      *sql.Stmt
+}
+
+func NewRoleRepository(db *sql.DB) *RoleRepositoryImpl {
+	repo := &RoleRepositoryImpl{db: db}
+	err := repo.InitStatements()
+	if err != nil {
+		log.Fatalln(err)
+	}
+	return repo
+}
+
+func (r *RoleRepositoryImpl) InitStatements() error {
+	var err error
+	// Accounts without a role row are regular users
+	r.ByTokenStmt, err = r.db.Prepare(`SELECT a.acc_id, a.username, COALESCE(r.role, 'user'), r.granted_by, r.granted_at
+										FROM ACCOUNT a
+										LEFT JOIN ACCOUNT_ROLE r ON a.acc_id = r.acc_id
+										WHERE a.token = ? AND a.token_expire >= NOW()`)
+	if err != nil {
+		return err
+	}
+
+	r.ByUsernameStmt, err = r.db.Prepare(`SELECT a.acc_id, a.username, COALESCE(r.role, 'user'), r.granted_by, r.granted_at
+											FROM ACCOUNT a
+											LEFT JOIN ACCOUNT_ROLE r ON a.acc_id = r.acc_id
+											WHERE a.username = ?`)
+	if err != nil {
+		return err
+	}
+
+	r.AssignStmt, err = r.db.Prepare(`INSERT INTO ACCOUNT_ROLE (acc_id, role, granted_by) VALUES (?, ?, ?)
+										ON DUPLICATE KEY UPDATE
+											role = VALUES(role),
+											granted_by = VALUES(granted_by),
+											granted_at = NOW()`)
+	if err != nil {
+		return err
+	}
+
+	r.RemoveStmt, err = r.db.Prepare("DELETE FROM ACCOUNT_ROLE WHERE acc_id = ?")
+	if err != nil {
+		return err
+	}
+
+	r.PrivilegedStmt, err = r.db.Prepare(`SELECT a.acc_id, a.username, r.role, r.granted_by, r.granted_at
+											FROM ACCOUNT_ROLE r
+											LEFT JOIN ACCOUNT a ON a.acc_id = r.acc_id
+											WHERE r.role IN ('moderator', 'admin')
+											ORDER BY r.role, a.username`)
+	if err != nil {
+		return err
+	}
+
+	r.CountStmt, err = r.db.Prepare("SELECT COUNT(*) FROM ACCOUNT_ROLE WHERE role = ?")
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *RoleRepositoryImpl) ByToken(token string) (AccountRole, error) {
+	role, err := scanAccountRole(r.ByTokenStmt.QueryRow(token))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return AccountRole{}, erro.ErrInvalidToken
+		}
+		return AccountRole{}, err
+	}
+	return role, nil
+}
+
+func (r *RoleRepositoryImpl) ByUsername(username string) (AccountRole, error) {
+	role, err := scanAccountRole(r.ByUsernameStmt.QueryRow(username))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return AccountRole{}, erro.ErrAccountNotFound
+		}
+		return AccountRole{}, err
+	}
+	return role, nil
+}
+
+// Sets the role of an account. Going back to user removes the row
+func (r *RoleRepositoryImpl) Assign(accID int64, role string, grantedBy *int64) error {
+	var err error
+	if role == User {
+		_, err = r.RemoveStmt.Exec(accID)
+	} else {
+		_, err = r.AssignStmt.Exec(accID, role, grantedBy)
+	}
+	return err
+}
+
+func (r *RoleRepositoryImpl) Privileged() ([]AccountRole, error) {
+	rows, err := r.PrivilegedStmt.Query()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	roles := []AccountRole{}
+	for rows.Next() {
+		role, err := scanAccountRole(rows)
+		if err != nil {
+			return nil, err
+		}
+		roles = append(roles, role)
+	}
+
+	return roles, nil
+}
+
+func (r *RoleRepositoryImpl) Count(role string) (int, error) {
+	var count int
+	err := r.CountStmt.QueryRow(role).Scan(&count)
+	return count, err
+}
+
+// Scans from either *sql.Row or *sql.Rows
+func scanAccountRole(row interface{ Scan(...any) error }) (AccountRole, error) {
+	var role AccountRole
+	err := row.Scan(
+		&role.AccID,
+		&role.Username,
+		&role.Role,
+		&role.GrantedBy,
+		&role.GrantedAt,
+	)
+	return role, err
+}
@@ -0,0 +1,51 @@
+package role
+
+import (
+	"slices"
+	"time"
+)
+
+const (
+	User      = "user"
+	Moderator = "moderator"
+	Admin     = "admin"
+	// Internal accounts like the "deleted user" one. Can't be assigned
+	System = "system"
+)
+
+// Assignable roles
+var Roles = []string{User, Moderator, Admin}
+
+type Permission string
+
+const (
+	ModerateContent Permission = "content:moderate"
+	ManageAccounts  Permission = "accounts:manage"
+	ManageRoles     Permission = "roles:manage"
+	ManageLockouts  Permission = "lockouts:manage"
+	ManageDecks     Permission = "decks:manage"
+	ReadAudit       Permission = "audit:read"
+)
+
+var permissions = map[string][]Permission{
+	User:      {},
+	Moderator: {ModerateContent},
+	Admin:     {ModerateContent, ManageAccounts, ManageRoles, ManageLockouts, ManageDecks, ReadAudit},
+	System:    {},
+}
+
+func Permissions(role string) []Permission {
+	return permissions[role]
+}
+
+func Can(role string, permission Permission) bool {
+	return slices.Contains(permissions[role], permission)
+}
+
+type AccountRole struct {
+	AccID     int64      `json:"acc_id"`
+	Username  string     `json:"username"`
+	Role      string     `json:"role"`
+	GrantedBy *int64     `json:"granted_by,omitempty"`
+	GrantedAt *time.Time `json:"granted_at,omitempty"`
+}
@@ -0,0 +1,105 @@
+package role
+
+import (
+	"errors"
+	"learn-swiping-api/erro"
+	role "learn-swiping-api/internal/role/dto"
+	"log"
+	"slices"
+)
+
+type RoleService interface {
+	Authorize(token string, permission Permission) (AccountRole, error)
+	Permissions(token string) (role.PermissionsResponse, error)
+	Assign(token string, request role.AssignRequest) error
+	Privileged() ([]AccountRole, error)
+	Bootstrap(username string) error
+}
+
+type RoleServiceImpl struct {
+	repository RoleRepository
+}
+
+func NewRoleService(repository RoleRepository) RoleService {
+	return &RoleServiceImpl{repository: repository}
+}
+
+// Returns the caller's role if it grants the permission
+func (s *RoleServiceImpl) Authorize(token string, permission Permission) (AccountRole, error) {
+	caller, err := s.repository.ByToken(token)
+	if err != nil {
+		return AccountRole{}, err
+	}
+
+	if !Can(caller.Role, permission) {
+		return AccountRole{}, erro.ErrForbidden
+	}
+
+	return caller, nil
+}
+
+func (s *RoleServiceImpl) Permissions(token string) (role.PermissionsResponse, error) {
+	caller, err := s.repository.ByToken(token)
+	if err != nil {
+		return role.PermissionsResponse{}, err
+	}
+
+	response := role.PermissionsResponse{Role: caller.Role, Permissions: []string{}}
+	for _, permission := range Permissions(caller.Role) {
+		response.Permissions = append(response.Permissions, string(permission))
+	}
+	return response, nil
+}
+
+func (s *RoleServiceImpl) Assign(token string, request role.AssignRequest) error {
+	if !slices.Contains(Roles, request.Role) {
+		return erro.ErrBadField
+	}
+
+	caller, err := s.Authorize(token, ManageRoles)
+	if err != nil {
+		return err
+	}
+
+	target, err := s.repository.ByUsername(request.Username)
+	if err != nil {
+		return err
+	}
+
+	// Admins can't demote themselves so there's always at least one left,
+	// and system accounts keep their role
+	if target.AccID == caller.AccID || target.Role == System {
+		return erro.ErrForbidden
+	}
+
+	return s.repository.Assign(target.AccID, request.Role, &caller.AccID)
+}
+
+// Accounts with a role other than user
+func (s *RoleServiceImpl) Privileged() ([]AccountRole, error) {
+	return s.repository.Privileged()
+}
+
+// Makes an account admin if there are none yet, so a fresh install
+// doesn't need manual database edits
+func (s *RoleServiceImpl) Bootstrap(username string) error {
+	if username == "" {
+		return nil
+	}
+
+	admins, err := s.repository.Count(Admin)
+	if err != nil || admins > 0 {
+		return err
+	}
+
+	target, err := s.repository.ByUsername(username)
+	if err != nil {
+		if errors.Is(err, erro.ErrAccountNotFound) {
+			log.Printf("role: bootstrap admin %s doesn't exist\n", username)
+			return nil
+		}
+		return err
+	}
+
+	return s.repository.Assign(target.AccID, Admin, nil)
+}
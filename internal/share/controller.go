@@ -0,0 +1,157 @@
+package share
+
+import (
+	"errors"
+	"learn-swiping-api/erro"
+	share "learn-swiping-api/internal/share/dto"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ShareController interface {
+	Links(*gin.Context)     // GET
+	Create(*gin.Context)    // POST
+	Revoke(*gin.Context)    // DELETE
+	Open(*gin.Context)      // GET
+	Subscribe(*gin.Context) // POST
+}
+
+type ShareControllerImpl struct {
+	service ShareService
+}
+
+func NewShareController(service ShareService) ShareController {
+	return &ShareControllerImpl{service: service}
+}
+
+// Lists the share links of a deck
+// Method: GET
+func (c *ShareControllerImpl) Links(ctx *gin.Context) {
+	token, deckID, ok := deckParams(ctx)
+	if !ok {
+		return
+	}
+
+	links, err := c.service.Links(deckID, token)
+	if err != nil {
+		shareError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, links)
+}
+
+// Creates a share link for a deck
+// Method: POST
+func (c *ShareControllerImpl) Create(ctx *gin.Context) {
+	token, deckID, ok := deckParams(ctx)
+	if !ok {
+		return
+	}
+
+	var request share.CreateRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrBadField.Error()})
+		return
+	}
+	request.Token = token
+	request.DeckID = deckID
+
+	link, err := c.service.Create(request)
+	if err != nil {
+		shareError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, link)
+}
+
+// Revokes a share link
+// Method: DELETE
+func (c *ShareControllerImpl) Revoke(ctx *gin.Context) {
+	token, deckID, ok := deckParams(ctx)
+	if !ok {
+		return
+	}
+
+	shareID, err := strconv.Atoi(ctx.Param("shareID"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrBadField.Error()})
+		return
+	}
+
+	if err := c.service.Revoke(deckID, int64(shareID), token); err != nil {
+		shareError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{})
+}
+
+// Retrieves the deck behind a share link
+// Method: GET
+func (c *ShareControllerImpl) Open(ctx *gin.Context) {
+	view, err := c.service.Open(ctx.Param("slug"), ctx.GetHeader("Token"))
+	if err != nil {
+		shareError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, view)
+}
+
+// Subscribes to the deck behind a share link
+// Method: POST
+func (c *ShareControllerImpl) Subscribe(ctx *gin.Context) {
+	token := ctx.GetHeader("Token")
+	if token == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrInvalidToken.Error()})
+		return
+	}
+
+	if err := c.service.Subscribe(ctx.Param("slug"), token); err != nil {
+		shareError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{})
+}
+
+func deckParams(ctx *gin.Context) (string, int64, bool) {
+	token := ctx.GetHeader("Token")
+	if token == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrInvalidToken.Error()})
+		return "", 0, false
+	}
+
+	deckID, err := strconv.Atoi(ctx.Param("deckID"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrBadField.Error()})
+		return "", 0, false
+	}
+
+	return token, int64(deckID), true
+}
+
+func shareError(ctx *gin.Context, err error) {
+	if errors.Is(err, erro.ErrBadField) || errors.Is(err, erro.ErrInvalidToken) ||
+		errors.Is(err, erro.ErrShareLinkExpired) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, erro.ErrForbidden) || errors.Is(err, erro.ErrShareViewOnly) {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, erro.ErrDeckNotFound) || errors.Is(err, erro.ErrShareLinkNotFound) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, erro.ErrAlreadySuscribed) {
+		ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
@@ -0,0 +1,11 @@
+package share
+
+import "time"
+
+type CreateRequest struct {
+	Token      string
+	DeckID     int64
+	Permission string     `json:"permission" binding:"required"` // view or subscribe
+	ExpiresAt  *time.Time `json:"expires_at"`                    // Never expires if empty
+	MaxUses    *int64     `json:"max_uses"`                      // Unlimited if empty
+}
@@ -0,0 +1,234 @@
+package share
+
+import (
+	"database/sql"
+	"learn-swiping-api/erro"
+	"log"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+type ShareRepository interface {
+	ByToken(token string) (int64, error)
+	Create(Link) (int64, error)
+	ById(shareID int64) (Link, error)
+	BySlug(slug string) (Link, error)
+	ByDeck(deckID int64) ([]Link, error)
+	Use(shareID int64) error
+	Subscribe(shareID int64, deckID int64, accID int64) error
+	Revoke(shareID int64, deckID int64) error
+}
+
+type ShareRepositoryImpl struct {
+	db          *sql.DB
+	ByTokenStmt *sql.Stmt
+	CreateStmt  *sql.Stmt
+	ByIdStmt    *sql.Stmt
+	BySlugStmt  *sql.Stmt
+	ByDeckStmt  *sql.Stmt
+	UseStmt     *sql.Stmt
+	RevokeStmt  *sql.Stmt
+}
+
+func NewShareRepository(db *sql.DB) *ShareRepositoryImpl {
+	repo := &ShareRepositoryImpl{db: db}
+	err := repo.InitStatements()
+	if err != nil {
+		log.Fatalln(err)
+	}
+	return repo
+}
+
+// Columns read by scanLink
+const linkColumns = `share_id, deck_id, slug, permission, max_uses, uses, expires_at, created_by, created_at, revoked_at
+						FROM SHARE_LINK`
+
+// Counts a use of a link that is still active
+const useQuery = `UPDATE SHARE_LINK SET uses = uses + 1
+					WHERE share_id = ? AND revoked_at IS NULL
+						AND (expires_at IS NULL OR expires_at > NOW())
+						AND (max_uses IS NULL OR uses < max_uses)`
+
+func (r *ShareRepositoryImpl) InitStatements() error {
+	var err error
+	r.ByTokenStmt, err = r.db.Prepare("SELECT acc_id FROM ACCOUNT WHERE token = ? AND token_expire >= NOW()")
+	if err != nil {
+		return err
+	}
+
+	r.CreateStmt, err = r.db.Prepare(`INSERT INTO SHARE_LINK (deck_id, slug, permission, max_uses, expires_at, created_by)
+										VALUES (?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+
+	r.ByIdStmt, err = r.db.Prepare("SELECT " + linkColumns + " WHERE share_id = ?")
+	if err != nil {
+		return err
+	}
+
+	r.BySlugStmt, err = r.db.Prepare("SELECT " + linkColumns + " WHERE slug = ?")
+	if err != nil {
+		return err
+	}
+
+	r.ByDeckStmt, err = r.db.Prepare("SELECT " + linkColumns + " WHERE deck_id = ? ORDER BY created_at DESC")
+	if err != nil {
+		return err
+	}
+
+	r.UseStmt, err = r.db.Prepare(useQuery)
+	if err != nil {
+		return err
+	}
+
+	r.RevokeStmt, err = r.db.Prepare("UPDATE SHARE_LINK SET revoked_at = NOW() WHERE share_id = ? AND deck_id = ? AND revoked_at IS NULL")
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *ShareRepositoryImpl) ByToken(token string) (int64, error) {
+	var accID int64
+	if err := r.ByTokenStmt.QueryRow(token).Scan(&accID); err != nil {
+		if err == sql.ErrNoRows {
+			return 0, erro.ErrInvalidToken
+		}
+		return 0, err
+	}
+	return accID, nil
+}
+
+func (r *ShareRepositoryImpl) Create(link Link) (int64, error) {
+	result, err := r.CreateStmt.Exec(link.DeckID, link.Slug, link.Permission, link.MaxUses, link.ExpiresAt, link.CreatedBy)
+	if err != nil {
+		if mysqlErr, ok := err.(*mysql.MySQLError); ok && mysqlErr.Number == 1452 {
+			return 0, erro.ErrDeckNotFound
+		}
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+func (r *ShareRepositoryImpl) ById(shareID int64) (Link, error) {
+	return r.one(r.ByIdStmt, shareID)
+}
+
+// The link even if it was revoked or can't be used anymore
+func (r *ShareRepositoryImpl) BySlug(slug string) (Link, error) {
+	return r.one(r.BySlugStmt, slug)
+}
+
+// Links of a deck, newest first
+func (r *ShareRepositoryImpl) ByDeck(deckID int64) ([]Link, error) {
+	rows, err := r.ByDeckStmt.Query(deckID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	links := []Link{}
+	for rows.Next() {
+		link, err := scanLink(rows)
+		if err != nil {
+			return nil, err
+		}
+		links = append(links, link)
+	}
+
+	return links, rows.Err()
+}
+
+func (r *ShareRepositoryImpl) Use(shareID int64) error {
+	result, err := r.UseStmt.Exec(shareID)
+	if err != nil {
+		return err
+	}
+	return affected(result, erro.ErrShareLinkExpired)
+}
+
+// Subscribes the account and counts the use in the same transaction, so
+// a link can't go over its max uses and already subscribed accounts
+// don't spend one
+func (r *ShareRepositoryImpl) Subscribe(shareID int64, deckID int64, accID int64) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec("INSERT INTO ACC_DECK (acc_id, deck_id) VALUES (?, ?)", accID, deckID); err != nil {
+		tx.Rollback()
+		if mysqlErr, ok := err.(*mysql.MySQLError); ok && mysqlErr.Number == 1062 {
+			return erro.ErrAlreadySuscribed
+		}
+		return err
+	}
+
+	result, err := tx.Exec(useQuery, shareID)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := affected(result, erro.ErrShareLinkExpired); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *ShareRepositoryImpl) Revoke(shareID int64, deckID int64) error {
+	result, err := r.RevokeStmt.Exec(shareID, deckID)
+	if err != nil {
+		return err
+	}
+	return affected(result, erro.ErrShareLinkNotFound)
+}
+
+func (r *ShareRepositoryImpl) one(stmt *sql.Stmt, arg any) (Link, error) {
+	link, err := scanLink(stmt.QueryRow(arg))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return Link{}, erro.ErrShareLinkNotFound
+		}
+		return Link{}, err
+	}
+	return link, nil
+}
+
+// Checks an update changed a link
+func affected(result sql.Result, notFound error) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return notFound
+	}
+
+	return nil
+}
+
+// Scans from either *sql.Row or *sql.Rows
+func scanLink(row interface{ Scan(...any) error }) (Link, error) {
+	var link Link
+	err := row.Scan(
+		&link.ID,
+		&link.DeckID,
+		&link.Slug,
+		&link.Permission,
+		&link.MaxUses,
+		&link.Uses,
+		&link.ExpiresAt,
+		&link.CreatedBy,
+		&link.CreatedAt,
+		&link.RevokedAt,
+	)
+	if err != nil {
+		return Link{}, err
+	}
+	return link, nil
+}
@@ -0,0 +1,193 @@
+package share
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"learn-swiping-api/erro"
+	"learn-swiping-api/internal/card"
+	"learn-swiping-api/internal/collaborator"
+	"learn-swiping-api/internal/deck"
+	share "learn-swiping-api/internal/share/dto"
+	"os"
+	"slices"
+	"time"
+)
+
+// Random bytes in a slug, hex encoded
+const slugBytes = 12
+
+type ShareService interface {
+	Create(share.CreateRequest) (Link, error)
+	Links(deckID int64, token string) ([]Link, error)
+	Revoke(deckID int64, shareID int64, token string) error
+	Open(slug string, token string) (View, error)
+	Subscribe(slug string, token string) error
+	active(slug string) (Link, error)
+}
+
+type ShareServiceImpl struct {
+	repository    ShareRepository
+	decks         deck.DeckService
+	cards         card.CardService
+	collaborators collaborator.CollaboratorService
+}
+
+func NewShareService(repository ShareRepository, decks deck.DeckService, cards card.CardService, collaborators collaborator.CollaboratorService) ShareService {
+	return &ShareServiceImpl{repository: repository, decks: decks, cards: cards, collaborators: collaborators}
+}
+
+// Creates a link to the deck. Only for the owner and maintainers
+func (s *ShareServiceImpl) Create(request share.CreateRequest) (Link, error) {
+	if !slices.Contains(Permissions, request.Permission) {
+		return Link{}, erro.ErrBadField
+	}
+	if request.ExpiresAt != nil && request.ExpiresAt.Before(time.Now()) {
+		return Link{}, erro.ErrBadField
+	}
+	if request.MaxUses != nil && *request.MaxUses < 1 {
+		return Link{}, erro.ErrBadField
+	}
+
+	access, err := s.collaborators.Authorize(request.DeckID, request.Token, collaborator.ShareDeck)
+	if err != nil {
+		return Link{}, err
+	}
+
+	b := make([]byte, slugBytes)
+	if _, err := rand.Read(b); err != nil {
+		return Link{}, err
+	}
+
+	shareID, err := s.repository.Create(Link{
+		DeckID:     request.DeckID,
+		Slug:       hex.EncodeToString(b),
+		Permission: request.Permission,
+		MaxUses:    request.MaxUses,
+		ExpiresAt:  request.ExpiresAt,
+		CreatedBy:  &access.AccID,
+	})
+	if err != nil {
+		return Link{}, err
+	}
+
+	link, err := s.repository.ById(shareID)
+	if err != nil {
+		return Link{}, err
+	}
+
+	link.URL = url(link.Slug)
+	return link, nil
+}
+
+// Lists every link of the deck, revoked ones included
+func (s *ShareServiceImpl) Links(deckID int64, token string) ([]Link, error) {
+	if _, err := s.collaborators.Authorize(deckID, token, collaborator.ShareDeck); err != nil {
+		return nil, err
+	}
+
+	links, err := s.repository.ByDeck(deckID)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range links {
+		links[i].URL = url(links[i].Slug)
+	}
+	return links, nil
+}
+
+// Stops the link from working. Subscriptions made through it are kept
+func (s *ShareServiceImpl) Revoke(deckID int64, shareID int64, token string) error {
+	if _, err := s.collaborators.Authorize(deckID, token, collaborator.ShareDeck); err != nil {
+		return err
+	}
+
+	return s.repository.Revoke(shareID, deckID)
+}
+
+// Shows the deck behind a link. The token is optional, it only tells
+// whether the visitor is already subscribed
+func (s *ShareServiceImpl) Open(slug string, token string) (View, error) {
+	link, err := s.active(slug)
+	if err != nil {
+		return View{}, err
+	}
+
+	if link.Permission == PermissionView {
+		if err := s.repository.Use(link.ID); err != nil {
+			return View{}, err
+		}
+	}
+
+	details, err := s.decks.DeckDetails(2, link.DeckID, "")
+	if err != nil {
+		return View{}, err
+	}
+
+	cards, err := s.cards.ByDeck(link.DeckID)
+	if err != nil {
+		return View{}, err
+	}
+
+	view := View{
+		DeckID:     link.DeckID,
+		Permission: link.Permission,
+		Details:    details,
+		Cards:      cards,
+	}
+
+	if token != "" {
+		access, err := s.collaborators.Access(link.DeckID, token)
+		if err != nil {
+			return View{}, err
+		}
+		view.IsSubscribed = access.Subscribed
+	}
+
+	return view, nil
+}
+
+// Subscribes the caller to the deck behind a link, even if it's hidden
+func (s *ShareServiceImpl) Subscribe(slug string, token string) error {
+	accID, err := s.repository.ByToken(token)
+	if err != nil {
+		return err
+	}
+
+	link, err := s.active(slug)
+	if err != nil {
+		return err
+	}
+	if link.Permission != PermissionSubscribe {
+		return erro.ErrShareViewOnly
+	}
+
+	return s.repository.Subscribe(link.ID, link.DeckID, accID)
+}
+
+// Loads a link that can still be used. Revoked links look like they
+// never existed
+func (s *ShareServiceImpl) active(slug string) (Link, error) {
+	link, err := s.repository.BySlug(slug)
+	if err != nil {
+		return Link{}, err
+	}
+	if link.RevokedAt != nil {
+		return Link{}, erro.ErrShareLinkNotFound
+	}
+	if !link.Active() {
+		return Link{}, erro.ErrShareLinkExpired
+	}
+
+	return link, nil
+}
+
+// Builds the link to the frontend
+func url(slug string) string {
+	base := os.Getenv("APP_URL")
+	if base == "" {
+		base = "http://localhost:9999"
+	}
+	return fmt.Sprintf("%s/share/%s", base, slug)
+}
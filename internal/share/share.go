@@ -0,0 +1,49 @@
+package share
+
+import (
+	"learn-swiping-api/internal/card"
+	deck "learn-swiping-api/internal/deck/dto"
+	"time"
+)
+
+// What a link lets its visitors do
+const (
+	PermissionView      = "view"
+	PermissionSubscribe = "subscribe"
+)
+
+var Permissions = []string{PermissionView, PermissionSubscribe}
+
+type Link struct {
+	ID         int64      `json:"share_id"`
+	DeckID     int64      `json:"deck_id"`
+	Slug       string     `json:"slug"`
+	URL        string     `json:"url"`
+	Permission string     `json:"permission"`
+	MaxUses    *int64     `json:"max_uses"`
+	Uses       int64      `json:"uses"` // Visits on view links, subscriptions on subscribe links
+	ExpiresAt  *time.Time `json:"expires_at"`
+	CreatedBy  *int64     `json:"created_by"`
+	CreatedAt  time.Time  `json:"created_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+}
+
+// Whether the link can still be used
+func (l Link) Active() bool {
+	if l.RevokedAt != nil {
+		return false
+	}
+	if l.ExpiresAt != nil && !l.ExpiresAt.After(time.Now()) {
+		return false
+	}
+	return l.MaxUses == nil || l.Uses < *l.MaxUses
+}
+
+// What visitors of a link get
+type View struct {
+	DeckID       int64        `json:"deck_id"`
+	Permission   string       `json:"permission"`
+	IsSubscribed bool         `json:"is_subscribed"`
+	Details      deck.Details `json:"details"`
+	Cards        []card.Card  `json:"cards"`
+}
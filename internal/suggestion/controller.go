@@ -0,0 +1,241 @@
+package suggestion
+
+import (
+	"errors"
+	"learn-swiping-api/erro"
+	suggestion "learn-swiping-api/internal/suggestion/dto"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SuggestionController interface {
+	SuggestCard(*gin.Context) // POST
+	SuggestEdit(*gin.Context) // POST
+	Suggestions(*gin.Context) // GET
+	Suggestion(*gin.Context)  // GET
+	Accept(*gin.Context)      // POST
+	Reject(*gin.Context)      // POST
+	Withdraw(*gin.Context)    // DELETE
+}
+
+type SuggestionControllerImpl struct {
+	service SuggestionService
+}
+
+func NewSuggestionController(service SuggestionService) SuggestionController {
+	return &SuggestionControllerImpl{service: service}
+}
+
+// Proposes a new card for the deck
+// Method: POST
+func (c *SuggestionControllerImpl) SuggestCard(ctx *gin.Context) {
+	deckID, err := strconv.Atoi(ctx.Param("deckID"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrBadField.Error()})
+		return
+	}
+
+	c.create(ctx, int64(deckID), nil)
+}
+
+// Proposes changes to a card
+// Method: POST
+func (c *SuggestionControllerImpl) SuggestEdit(ctx *gin.Context) {
+	deckID, err := strconv.Atoi(ctx.Param("deckID"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrBadField.Error()})
+		return
+	}
+
+	cardID, err := strconv.ParseInt(ctx.Param("cardID"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrBadField.Error()})
+		return
+	}
+
+	c.create(ctx, int64(deckID), &cardID)
+}
+
+func (c *SuggestionControllerImpl) create(ctx *gin.Context, deckID int64, cardID *int64) {
+	token := ctx.GetHeader("Token")
+	if token == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrInvalidToken.Error()})
+		return
+	}
+
+	var request suggestion.CreateRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrBadField.Error()})
+		return
+	}
+	request.Token = token
+	request.DeckID = deckID
+	request.CardID = cardID
+
+	suggestionID, err := c.service.Create(request)
+	if err != nil {
+		suggestionError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{"suggestion_id": suggestionID})
+}
+
+// Lists the suggestions of a deck, pending ones unless a status is given.
+// Only for the deck owner
+// Method: GET
+func (c *SuggestionControllerImpl) Suggestions(ctx *gin.Context) {
+	token := ctx.GetHeader("Token")
+	if token == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrInvalidToken.Error()})
+		return
+	}
+
+	deckID, err := strconv.Atoi(ctx.Param("deckID"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrBadField.Error()})
+		return
+	}
+
+	suggestions, err := c.service.Suggestions(int64(deckID), ctx.Query("status"), token)
+	if err != nil {
+		suggestionError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, suggestions)
+}
+
+// Retrieves a suggestion and its diff
+// Method: GET
+func (c *SuggestionControllerImpl) Suggestion(ctx *gin.Context) {
+	token, deckID, suggestionID, ok := suggestionParams(ctx)
+	if !ok {
+		return
+	}
+
+	sg, err := c.service.Suggestion(deckID, suggestionID, token)
+	if err != nil {
+		suggestionError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, sg)
+}
+
+// Applies a suggestion to the deck
+// Method: POST
+func (c *SuggestionControllerImpl) Accept(ctx *gin.Context) {
+	token, deckID, suggestionID, ok := suggestionParams(ctx)
+	if !ok {
+		return
+	}
+
+	request, ok := reviewRequest(ctx)
+	if !ok {
+		return
+	}
+
+	sg, err := c.service.Accept(deckID, suggestionID, request, token)
+	if err != nil {
+		suggestionError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, sg)
+}
+
+// Rejects a suggestion with a comment for its author
+// Method: POST
+func (c *SuggestionControllerImpl) Reject(ctx *gin.Context) {
+	token, deckID, suggestionID, ok := suggestionParams(ctx)
+	if !ok {
+		return
+	}
+
+	request, ok := reviewRequest(ctx)
+	if !ok {
+		return
+	}
+
+	if err := c.service.Reject(deckID, suggestionID, request, token); err != nil {
+		suggestionError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{})
+}
+
+// Withdraws a pending suggestion of the caller
+// Method: DELETE
+func (c *SuggestionControllerImpl) Withdraw(ctx *gin.Context) {
+	token, deckID, suggestionID, ok := suggestionParams(ctx)
+	if !ok {
+		return
+	}
+
+	if err := c.service.Withdraw(deckID, suggestionID, token); err != nil {
+		suggestionError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{})
+}
+
+// Reads the token, the deck id and the suggestion id
+func suggestionParams(ctx *gin.Context) (string, int64, int64, bool) {
+	token := ctx.GetHeader("Token")
+	if token == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrInvalidToken.Error()})
+		return "", 0, 0, false
+	}
+
+	deckID, err := strconv.Atoi(ctx.Param("deckID"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrBadField.Error()})
+		return "", 0, 0, false
+	}
+
+	suggestionID, err := strconv.Atoi(ctx.Param("suggestionID"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrBadField.Error()})
+		return "", 0, 0, false
+	}
+
+	return token, int64(deckID), int64(suggestionID), true
+}
+
+// The review comment is optional when accepting
+func reviewRequest(ctx *gin.Context) (suggestion.ReviewRequest, bool) {
+	var request suggestion.ReviewRequest
+	if ctx.Request.ContentLength != 0 {
+		if err := ctx.ShouldBindJSON(&request); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrBadField.Error()})
+			return suggestion.ReviewRequest{}, false
+		}
+	}
+	return request, true
+}
+
+func suggestionError(ctx *gin.Context, err error) {
+	if errors.Is(err, erro.ErrBadField) || errors.Is(err, erro.ErrInvalidToken) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, erro.ErrForbidden) || errors.Is(err, erro.ErrOwnDeckSuggestion) || errors.Is(err, erro.ErrNotSubscribed) {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, erro.ErrSuggestionNotFound) || errors.Is(err, erro.ErrDeckNotFound) ||
+		errors.Is(err, erro.ErrCardNotFound) || errors.Is(err, erro.ErrOptionNotFound) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, erro.ErrSuggestionClosed) {
+		ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
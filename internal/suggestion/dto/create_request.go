@@ -0,0 +1,22 @@
+package suggestion
+
+// Fields left out of an edit aren't changed. New cards need all of them
+// and options that fit their answer type
+type CreateRequest struct {
+	Token      string
+	DeckID     int64
+	CardID     *int64          // Nil for a new card
+	Title      *string         `json:"title"`
+	Front      *string         `json:"front"`
+	Back       *string         `json:"back"`
+	Question   *string         `json:"question"`
+	AnswerType *string         `json:"answer_type"`
+	Options    []OptionRequest `json:"options"`
+	Message    string          `json:"message"`
+}
+
+type OptionRequest struct {
+	OptionID int64  `json:"option_id"` // Required when editing
+	Answer   string `json:"answer" binding:"required"`
+	Correct  bool   `json:"correct"`
+}
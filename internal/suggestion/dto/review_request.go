@@ -0,0 +1,6 @@
+package suggestion
+
+// Body of the accept and reject endpoints
+type ReviewRequest struct {
+	Comment string `json:"comment"`
+}
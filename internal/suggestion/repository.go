@@ -0,0 +1,255 @@
+package suggestion
+
+import (
+	"database/sql"
+	"encoding/json"
+	"learn-swiping-api/erro"
+	"log"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+type SuggestionRepository interface {
+	Subscribed(deckID int64, accID int64) bool
+	Create(Suggestion) (int64, error)
+	ById(suggestionID int64) (Suggestion, error)
+	ByDeck(deckID int64, status string) ([]Suggestion, error)
+	Claim(suggestionID int64, deckID int64, comment string) error
+	Release(suggestionID int64) error
+	Result(suggestionID int64, cardID int64) error
+	Reject(suggestionID int64, deckID int64, comment string) error
+	Withdraw(suggestionID int64, accID int64) error
+	Contact(accID int64) (string, string, error) // Email and name
+}
+
+type SuggestionRepositoryImpl struct {
+	db             *sql.DB
+	SubscribedStmt *sql.Stmt
+	CreateStmt     *sql.Stmt
+	ByIdStmt       *sql.Stmt
+	ByDeckStmt     *sql.Stmt
+	ClaimStmt      *sql.Stmt
+	ReleaseStmt    *sql.Stmt
+	ResultStmt     *sql.Stmt
+	RejectStmt     *sql.Stmt
+	WithdrawStmt   *sql.Stmt
+	ContactStmt    *sql.Stmt
+}
+
+func NewSuggestionRepository(db *sql.DB) *SuggestionRepositoryImpl {
+	repo := &SuggestionRepositoryImpl{db: db}
+	err := repo.InitStatements()
+	if err != nil {
+		log.Fatalln(err)
+	}
+	return repo
+}
+
+// Columns read by scanSuggestion
+const suggestionColumns = `s.suggestion_id, s.deck_id, s.card_id, s.acc_id, COALESCE(a.username, ''),
+							s.title, s.front, s.back, s.question, s.answer_type, s.options, s.message,
+							s.status, s.comment, s.result_card, s.reviewed_at, s.created_at
+						FROM SUGGESTION s
+						LEFT JOIN ACCOUNT a ON s.acc_id = a.acc_id`
+
+func (r *SuggestionRepositoryImpl) InitStatements() error {
+	var err error
+	r.SubscribedStmt, err = r.db.Prepare("SELECT 1 FROM ACC_DECK WHERE deck_id = ? AND acc_id = ?")
+	if err != nil {
+		return err
+	}
+
+	r.CreateStmt, err = r.db.Prepare(`INSERT INTO SUGGESTION (deck_id, card_id, acc_id, title, front, back, question, answer_type, options, message)
+										VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+
+	r.ByIdStmt, err = r.db.Prepare("SELECT " + suggestionColumns + " WHERE s.suggestion_id = ?")
+	if err != nil {
+		return err
+	}
+
+	r.ByDeckStmt, err = r.db.Prepare("SELECT " + suggestionColumns + " WHERE s.deck_id = ? AND s.status = ? ORDER BY s.created_at")
+	if err != nil {
+		return err
+	}
+
+	// Marks the suggestion as accepted before applying it so it can't be
+	// applied twice
+	r.ClaimStmt, err = r.db.Prepare(`UPDATE SUGGESTION SET status = 'accepted', comment = ?, reviewed_at = NOW()
+										WHERE suggestion_id = ? AND deck_id = ? AND status = 'pending'`)
+	if err != nil {
+		return err
+	}
+
+	r.ReleaseStmt, err = r.db.Prepare(`UPDATE SUGGESTION SET status = 'pending', comment = '', reviewed_at = NULL
+										WHERE suggestion_id = ? AND status = 'accepted'`)
+	if err != nil {
+		return err
+	}
+
+	r.ResultStmt, err = r.db.Prepare("UPDATE SUGGESTION SET result_card = ? WHERE suggestion_id = ?")
+	if err != nil {
+		return err
+	}
+
+	r.RejectStmt, err = r.db.Prepare(`UPDATE SUGGESTION SET status = 'rejected', comment = ?, reviewed_at = NOW()
+										WHERE suggestion_id = ? AND deck_id = ? AND status = 'pending'`)
+	if err != nil {
+		return err
+	}
+
+	r.WithdrawStmt, err = r.db.Prepare(`UPDATE SUGGESTION SET status = 'withdrawn'
+										WHERE suggestion_id = ? AND acc_id = ? AND status = 'pending'`)
+	if err != nil {
+		return err
+	}
+
+	r.ContactStmt, err = r.db.Prepare("SELECT email, name FROM ACCOUNT WHERE acc_id = ?")
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *SuggestionRepositoryImpl) Subscribed(deckID int64, accID int64) bool {
+	var subscribed int
+	return r.SubscribedStmt.QueryRow(deckID, accID).Scan(&subscribed) == nil
+}
+
+func (r *SuggestionRepositoryImpl) Create(suggestion Suggestion) (int64, error) {
+	options, err := json.Marshal(suggestion.Options)
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := r.CreateStmt.Exec(suggestion.DeckID, suggestion.CardID, suggestion.AccID, suggestion.Title, suggestion.Front,
+		suggestion.Back, suggestion.Question, suggestion.AnswerType, string(options), suggestion.Message)
+	if err != nil {
+		if mysqlErr, ok := err.(*mysql.MySQLError); ok && mysqlErr.Number == 1452 {
+			return 0, erro.ErrCardNotFound
+		}
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+func (r *SuggestionRepositoryImpl) ById(suggestionID int64) (Suggestion, error) {
+	suggestion, err := scanSuggestion(r.ByIdStmt.QueryRow(suggestionID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return Suggestion{}, erro.ErrSuggestionNotFound
+		}
+		return Suggestion{}, err
+	}
+	return suggestion, nil
+}
+
+// Suggestions of a deck with the given status, oldest first
+func (r *SuggestionRepositoryImpl) ByDeck(deckID int64, status string) ([]Suggestion, error) {
+	rows, err := r.ByDeckStmt.Query(deckID, status)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	suggestions := []Suggestion{}
+	for rows.Next() {
+		suggestion, err := scanSuggestion(rows)
+		if err != nil {
+			return nil, err
+		}
+		suggestions = append(suggestions, suggestion)
+	}
+
+	return suggestions, rows.Err()
+}
+
+func (r *SuggestionRepositoryImpl) Claim(suggestionID int64, deckID int64, comment string) error {
+	return exec(r.ClaimStmt, comment, suggestionID, deckID)
+}
+
+// Puts back a claimed suggestion that couldn't be applied
+func (r *SuggestionRepositoryImpl) Release(suggestionID int64) error {
+	return exec(r.ReleaseStmt, suggestionID)
+}
+
+// Links an accepted new card suggestion to the card it created
+func (r *SuggestionRepositoryImpl) Result(suggestionID int64, cardID int64) error {
+	_, err := r.ResultStmt.Exec(cardID, suggestionID)
+	return err
+}
+
+func (r *SuggestionRepositoryImpl) Reject(suggestionID int64, deckID int64, comment string) error {
+	return exec(r.RejectStmt, comment, suggestionID, deckID)
+}
+
+// Only the author can withdraw a suggestion
+func (r *SuggestionRepositoryImpl) Withdraw(suggestionID int64, accID int64) error {
+	return exec(r.WithdrawStmt, suggestionID, accID)
+}
+
+func (r *SuggestionRepositoryImpl) Contact(accID int64) (string, string, error) {
+	var email, name string
+	if err := r.ContactStmt.QueryRow(accID).Scan(&email, &name); err != nil {
+		if err == sql.ErrNoRows {
+			return "", "", erro.ErrAccountNotFound
+		}
+		return "", "", err
+	}
+	return email, name, nil
+}
+
+// Runs an update that only changes pending suggestions
+func exec(stmt *sql.Stmt, args ...any) error {
+	result, err := stmt.Exec(args...)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return erro.ErrSuggestionClosed
+	}
+
+	return nil
+}
+
+// Scans from either *sql.Row or *sql.Rows
+func scanSuggestion(row interface{ Scan(...any) error }) (Suggestion, error) {
+	var suggestion Suggestion
+	var options string
+	err := row.Scan(
+		&suggestion.ID,
+		&suggestion.DeckID,
+		&suggestion.CardID,
+		&suggestion.AccID,
+		&suggestion.Username,
+		&suggestion.Title,
+		&suggestion.Front,
+		&suggestion.Back,
+		&suggestion.Question,
+		&suggestion.AnswerType,
+		&options,
+		&suggestion.Message,
+		&suggestion.Status,
+		&suggestion.Comment,
+		&suggestion.ResultCard,
+		&suggestion.ReviewedAt,
+		&suggestion.CreatedAt,
+	)
+	if err != nil {
+		return Suggestion{}, err
+	}
+
+	if err := json.Unmarshal([]byte(options), &suggestion.Options); err != nil {
+		return Suggestion{}, err
+	}
+	return suggestion, nil
+}
@@ -0,0 +1,460 @@
+package suggestion
+
+import (
+	"fmt"
+	"learn-swiping-api/erro"
+	"learn-swiping-api/internal/card"
+	carddto "learn-swiping-api/internal/card/dto"
+	"learn-swiping-api/internal/collaborator"
+	"learn-swiping-api/internal/mailer"
+	suggestion "learn-swiping-api/internal/suggestion/dto"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	maxMessage = 1000
+	maxComment = 1000
+)
+
+type SuggestionService interface {
+	Create(suggestion.CreateRequest) (int64, error)
+	Suggestions(deckID int64, status string, token string) ([]Suggestion, error)
+	Suggestion(deckID int64, suggestionID int64, token string) (Suggestion, error)
+	Accept(deckID int64, suggestionID int64, request suggestion.ReviewRequest, token string) (Suggestion, error)
+	Reject(deckID int64, suggestionID int64, request suggestion.ReviewRequest, token string) error
+	Withdraw(deckID int64, suggestionID int64, token string) error
+	reviewable(deckID int64, suggestionID int64, token string) (Suggestion, error)
+	apply(s Suggestion, token string) (int64, error)
+	diff(s Suggestion, token string) ([]Change, error)
+	notify(s Suggestion)
+}
+
+type SuggestionServiceImpl struct {
+	repository    SuggestionRepository
+	cards         card.CardService
+	collaborators collaborator.CollaboratorService
+	mailer        mailer.Mailer
+}
+
+func NewSuggestionService(repository SuggestionRepository, cards card.CardService, collaborators collaborator.CollaboratorService, mailer mailer.Mailer) SuggestionService {
+	return &SuggestionServiceImpl{repository: repository, cards: cards, collaborators: collaborators, mailer: mailer}
+}
+
+// Subscribers that can't edit the cards themselves can propose a new card
+// or changes to an existing one
+func (s *SuggestionServiceImpl) Create(request suggestion.CreateRequest) (int64, error) {
+	request.Message = strings.TrimSpace(request.Message)
+	if len(request.Message) > maxMessage {
+		return 0, erro.ErrBadField
+	}
+
+	for _, field := range []*string{request.Title, request.Front, request.Back, request.Question} {
+		if field != nil && strings.TrimSpace(*field) == "" {
+			return 0, erro.ErrBadField
+		}
+	}
+	if request.AnswerType != nil && !card.ValidAnswerType(*request.AnswerType) {
+		return 0, erro.ErrBadField
+	}
+
+	access, err := s.collaborators.Authorize(request.DeckID, request.Token, collaborator.ReadDeck)
+	if err != nil {
+		return 0, err
+	}
+	if access.Can(collaborator.WriteCards) {
+		return 0, erro.ErrOwnDeckSuggestion
+	}
+	if !s.repository.Subscribed(request.DeckID, access.AccID) {
+		return 0, erro.ErrNotSubscribed
+	}
+
+	sg := Suggestion{
+		DeckID:     request.DeckID,
+		CardID:     request.CardID,
+		AccID:      &access.AccID,
+		Title:      request.Title,
+		Front:      request.Front,
+		Back:       request.Back,
+		Question:   request.Question,
+		AnswerType: request.AnswerType,
+		Options:    make([]Option, 0, len(request.Options)),
+		Message:    request.Message,
+	}
+	for _, o := range request.Options {
+		if strings.TrimSpace(o.Answer) == "" {
+			return 0, erro.ErrBadField
+		}
+		sg.Options = append(sg.Options, Option{OptionID: o.OptionID, Answer: o.Answer, Correct: o.Correct})
+	}
+
+	if request.CardID == nil {
+		if sg.AnswerType == nil {
+			answerType := card.AnswerSingle
+			sg.AnswerType = &answerType
+		}
+		if sg.Title == nil || sg.Front == nil || sg.Back == nil || sg.Question == nil {
+			return 0, erro.ErrBadField
+		}
+		if card.ValidateOptions(*sg.AnswerType, proposed(card.Card{}, sg).Options) != nil {
+			return 0, erro.ErrBadField
+		}
+		for _, o := range sg.Options {
+			if o.OptionID != 0 {
+				return 0, erro.ErrBadField
+			}
+		}
+		return s.repository.Create(sg)
+	}
+
+	// Edits must target options of the card, leave them fitting the
+	// answer type and change something
+	current, err := s.cards.Card(*request.CardID, request.DeckID, request.Token)
+	if err != nil {
+		return 0, err
+	}
+
+	seen := make(map[int64]bool, len(sg.Options))
+	for _, o := range sg.Options {
+		exists := slices.ContainsFunc(current.Options, func(co card.Option) bool { return co.OptionID == o.OptionID })
+		if !exists || seen[o.OptionID] {
+			return 0, erro.ErrBadField
+		}
+		seen[o.OptionID] = true
+	}
+
+	edited := proposed(current, sg)
+	if card.ValidateOptions(edited.AnswerType, edited.Options) != nil {
+		return 0, erro.ErrBadField
+	}
+
+	if len(changes(current, sg)) == 0 {
+		return 0, erro.ErrBadField
+	}
+
+	return s.repository.Create(sg)
+}
+
+// Lists the suggestions of a deck. Only for those who can edit its cards
+func (s *SuggestionServiceImpl) Suggestions(deckID int64, status string, token string) ([]Suggestion, error) {
+	if status == "" {
+		status = StatusPending
+	}
+	if !slices.Contains(Statuses, status) {
+		return nil, erro.ErrBadField
+	}
+
+	if _, err := s.collaborators.Authorize(deckID, token, collaborator.WriteCards); err != nil {
+		return nil, err
+	}
+
+	return s.repository.ByDeck(deckID, status)
+}
+
+// Retrieves a suggestion with the diff against the card as it is now.
+// Only for its author and those who can edit the cards
+func (s *SuggestionServiceImpl) Suggestion(deckID int64, suggestionID int64, token string) (Suggestion, error) {
+	access, err := s.collaborators.Authorize(deckID, token, collaborator.ReadDeck)
+	if err != nil {
+		return Suggestion{}, err
+	}
+
+	sg, err := s.repository.ById(suggestionID)
+	if err != nil {
+		return Suggestion{}, err
+	}
+	if sg.DeckID != deckID {
+		return Suggestion{}, erro.ErrSuggestionNotFound
+	}
+	if !access.Can(collaborator.WriteCards) && (sg.AccID == nil || *sg.AccID != access.AccID) {
+		return Suggestion{}, erro.ErrForbidden
+	}
+
+	sg.Diff, err = s.diff(sg, token)
+	if err != nil {
+		return Suggestion{}, err
+	}
+
+	return sg, nil
+}
+
+// Applies a suggestion through the card service. If that fails the
+// suggestion goes back to pending
+func (s *SuggestionServiceImpl) Accept(deckID int64, suggestionID int64, request suggestion.ReviewRequest, token string) (Suggestion, error) {
+	request.Comment = strings.TrimSpace(request.Comment)
+	if len(request.Comment) > maxComment {
+		return Suggestion{}, erro.ErrBadField
+	}
+
+	sg, err := s.reviewable(deckID, suggestionID, token)
+	if err != nil {
+		return Suggestion{}, err
+	}
+
+	if err := s.repository.Claim(suggestionID, deckID, request.Comment); err != nil {
+		return Suggestion{}, err
+	}
+
+	cardID, err := s.apply(sg, token)
+	if err != nil {
+		if rerr := s.repository.Release(suggestionID); rerr != nil {
+			log.Println(rerr)
+		}
+		return Suggestion{}, err
+	}
+
+	if sg.CardID == nil {
+		if err := s.repository.Result(suggestionID, cardID); err != nil {
+			return Suggestion{}, err
+		}
+	}
+
+	accepted, err := s.repository.ById(suggestionID)
+	if err != nil {
+		return Suggestion{}, err
+	}
+
+	s.notify(accepted)
+	return accepted, nil
+}
+
+// Rejects a suggestion. The author is told why
+func (s *SuggestionServiceImpl) Reject(deckID int64, suggestionID int64, request suggestion.ReviewRequest, token string) error {
+	request.Comment = strings.TrimSpace(request.Comment)
+	if request.Comment == "" || len(request.Comment) > maxComment {
+		return erro.ErrBadField
+	}
+
+	if _, err := s.reviewable(deckID, suggestionID, token); err != nil {
+		return err
+	}
+
+	if err := s.repository.Reject(suggestionID, deckID, request.Comment); err != nil {
+		return err
+	}
+
+	rejected, err := s.repository.ById(suggestionID)
+	if err != nil {
+		return err
+	}
+
+	s.notify(rejected)
+	return nil
+}
+
+// Withdraws a pending suggestion of the caller
+func (s *SuggestionServiceImpl) Withdraw(deckID int64, suggestionID int64, token string) error {
+	access, err := s.collaborators.Authorize(deckID, token, collaborator.ReadDeck)
+	if err != nil {
+		return err
+	}
+
+	sg, err := s.repository.ById(suggestionID)
+	if err != nil {
+		return err
+	}
+	if sg.DeckID != deckID {
+		return erro.ErrSuggestionNotFound
+	}
+
+	return s.repository.Withdraw(suggestionID, access.AccID)
+}
+
+// Loads a pending suggestion of a deck whose cards the caller can edit
+func (s *SuggestionServiceImpl) reviewable(deckID int64, suggestionID int64, token string) (Suggestion, error) {
+	if _, err := s.collaborators.Authorize(deckID, token, collaborator.WriteCards); err != nil {
+		return Suggestion{}, err
+	}
+
+	sg, err := s.repository.ById(suggestionID)
+	if err != nil {
+		return Suggestion{}, err
+	}
+	if sg.DeckID != deckID {
+		return Suggestion{}, erro.ErrSuggestionNotFound
+	}
+	if sg.Status != StatusPending {
+		return Suggestion{}, erro.ErrSuggestionClosed
+	}
+
+	return sg, nil
+}
+
+// Creates or updates the card as the reviewer. Returns its id
+func (s *SuggestionServiceImpl) apply(sg Suggestion, token string) (int64, error) {
+	if sg.CardID == nil {
+		request := carddto.CreateRequest{
+			Token:      token,
+			DeckID:     sg.DeckID,
+			Title:      *sg.Title,
+			Front:      *sg.Front,
+			Back:       *sg.Back,
+			Question:   *sg.Question,
+			AnswerType: value(sg.AnswerType),
+			Options:    make([]carddto.CreateOptionRequest, 0, len(sg.Options)),
+		}
+		for _, o := range sg.Options {
+			request.Options = append(request.Options, carddto.CreateOptionRequest{Answer: o.Answer, Correct: o.Correct})
+		}
+		return s.cards.Create(request)
+	}
+
+	request := carddto.UpdateRequest{
+		Token:      token,
+		DeckID:     sg.DeckID,
+		CardID:     *sg.CardID,
+		Title:      field(sg.Title),
+		Front:      field(sg.Front),
+		Back:       field(sg.Back),
+		Question:   field(sg.Question),
+		AnswerType: field(sg.AnswerType),
+	}
+
+	// Options of an update are the whole list, so the ones the suggestion
+	// leaves alone are kept as they are
+	if len(sg.Options) > 0 {
+		current, err := s.cards.Card(*sg.CardID, sg.DeckID, token)
+		if err != nil {
+			return 0, err
+		}
+
+		patches := make([]carddto.OptionPatch, 0, len(current.Options))
+		for _, co := range current.Options {
+			patch := carddto.OptionPatch{OptionID: co.OptionID}
+			if i := slices.IndexFunc(sg.Options, func(o Option) bool { return o.OptionID == co.OptionID }); i >= 0 {
+				patch.Answer = carddto.Value(sg.Options[i].Answer)
+				patch.Correct = carddto.Value(sg.Options[i].Correct)
+			}
+			patches = append(patches, patch)
+		}
+		request.Options = carddto.Value(patches)
+	}
+	return *sg.CardID, s.cards.Update(request)
+}
+
+// Compares the suggestion with the card as it is now. Every field of a
+// new card is a change
+func (s *SuggestionServiceImpl) diff(sg Suggestion, token string) ([]Change, error) {
+	if sg.CardID == nil {
+		return changes(card.Card{}, sg), nil
+	}
+
+	current, err := s.cards.Card(*sg.CardID, sg.DeckID, token)
+	if err != nil {
+		return nil, err
+	}
+
+	return changes(current, sg), nil
+}
+
+// Mails the author the outcome of the review. Failures are only logged
+func (s *SuggestionServiceImpl) notify(sg Suggestion) {
+	if sg.AccID == nil {
+		return
+	}
+
+	email, name, err := s.repository.Contact(*sg.AccID)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	body := fmt.Sprintf("Hi %s,\n\nYour suggestion for deck %d was %s.\n", name, sg.DeckID, sg.Status)
+	if sg.Comment != "" {
+		body += fmt.Sprintf("\nThe owner said:\n\n%s\n", sg.Comment)
+	}
+
+	err = s.mailer.Send(mailer.Message{
+		To:      email,
+		Subject: "Your suggestion was " + sg.Status,
+		Body:    body,
+	})
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+// Fields the suggestion would change
+func changes(current card.Card, sg Suggestion) []Change {
+	diff := []Change{}
+	fields := []struct {
+		name     string
+		old      string
+		proposed *string
+	}{
+		{"title", current.Title, sg.Title},
+		{"front", current.Front, sg.Front},
+		{"back", current.Back, sg.Back},
+		{"question", current.Question, sg.Question},
+		{"answer_type", current.AnswerType, sg.AnswerType},
+	}
+	for _, f := range fields {
+		if f.proposed != nil && *f.proposed != f.old {
+			diff = append(diff, Change{Field: f.name, Old: f.old, New: *f.proposed})
+		}
+	}
+
+	for i, o := range sg.Options {
+		if o.OptionID == 0 {
+			field := "option:" + strconv.Itoa(i)
+			diff = append(diff, Change{Field: field, New: o.Answer})
+			diff = append(diff, Change{Field: field + ":correct", New: strconv.FormatBool(o.Correct)})
+			continue
+		}
+		for _, co := range current.Options {
+			if co.OptionID != o.OptionID {
+				continue
+			}
+			field := "option:" + strconv.FormatInt(o.OptionID, 10)
+			if co.Answer != o.Answer {
+				diff = append(diff, Change{Field: field, Old: co.Answer, New: o.Answer})
+			}
+			if co.Correct != o.Correct {
+				diff = append(diff, Change{Field: field + ":correct", Old: strconv.FormatBool(co.Correct), New: strconv.FormatBool(o.Correct)})
+			}
+		}
+	}
+
+	return diff
+}
+
+// Answer type and options the card would have once the suggestion is
+// applied
+func proposed(current card.Card, sg Suggestion) card.Card {
+	if sg.AnswerType != nil {
+		current.AnswerType = *sg.AnswerType
+	}
+	if sg.CardID == nil {
+		current.Options = make([]card.Option, 0, len(sg.Options))
+		for _, o := range sg.Options {
+			current.Options = append(current.Options, card.Option{Answer: o.Answer, Correct: o.Correct})
+		}
+		return current
+	}
+
+	options := slices.Clone(current.Options)
+	for _, o := range sg.Options {
+		if i := slices.IndexFunc(options, func(co card.Option) bool { return co.OptionID == o.OptionID }); i >= 0 {
+			options[i].Answer, options[i].Correct = o.Answer, o.Correct
+		}
+	}
+	current.Options = options
+	return current
+}
+
+// Member of an update for a field the suggestion may leave out
+func field(s *string) carddto.Field[string] {
+	if s == nil {
+		return carddto.Field[string]{}
+	}
+	return carddto.Value(*s)
+}
+
+func value(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
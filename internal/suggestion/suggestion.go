@@ -0,0 +1,49 @@
+package suggestion
+
+import "time"
+
+const (
+	StatusPending   = "pending"
+	StatusAccepted  = "accepted"
+	StatusRejected  = "rejected"
+	StatusWithdrawn = "withdrawn"
+)
+
+var Statuses = []string{StatusPending, StatusAccepted, StatusRejected, StatusWithdrawn}
+
+// Change to a card, or a new card when CardID is nil. Nil fields of an
+// edit are left as they are
+type Suggestion struct {
+	ID         int64      `json:"suggestion_id"`
+	DeckID     int64      `json:"deck_id"`
+	CardID     *int64     `json:"card_id"`
+	AccID      *int64     `json:"acc_id"`
+	Username   string     `json:"username"`
+	Title      *string    `json:"title,omitempty"`
+	Front      *string    `json:"front,omitempty"`
+	Back       *string    `json:"back,omitempty"`
+	Question   *string    `json:"question,omitempty"`
+	AnswerType *string    `json:"answer_type,omitempty"`
+	Options    []Option   `json:"options,omitempty"`
+	Message    string     `json:"message"`
+	Status     string     `json:"status"`
+	Comment    string     `json:"comment"`
+	ResultCard *int64     `json:"result_card,omitempty"`
+	ReviewedAt *time.Time `json:"reviewed_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+	Diff       []Change   `json:"diff,omitempty"`
+}
+
+// Proposed option. OptionID is 0 for new cards
+type Option struct {
+	OptionID int64  `json:"option_id,omitempty"`
+	Answer   string `json:"answer"`
+	Correct  bool   `json:"correct"`
+}
+
+// Field of the card as it is now and as it would be
+type Change struct {
+	Field string `json:"field"` // title, front... or option:<option_id>[:correct]
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
@@ -0,0 +1,82 @@
+package totp
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// Seed of the SHA1 vectors of RFC 6238, "12345678901234567890"
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+// RFC 6238 appendix B, with the last 6 of its 8 digits
+func TestCode(t *testing.T) {
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+
+	for _, tt := range tests {
+		for _, secret := range []string{rfcSecret, strings.ToLower(rfcSecret)} {
+			got, err := Code(secret, Step(time.Unix(tt.unix, 0)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("code at %d = %s, want %s", tt.unix, got, tt.want)
+			}
+		}
+	}
+
+	if _, err := Code("not base32!", 1); err == nil {
+		t.Error("invalid secret gave a code")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1111111111, 0) // Code 050471, step 37037037
+
+	tests := []struct {
+		name string
+		code string
+		at   time.Time
+		ok   bool
+	}{
+		{"current step", "050471", now, true},
+		{"spaces", "050 471", now, true},
+		{"a step later", "050471", now.Add(Period * time.Second), true},
+		{"a step earlier", "050471", now.Add(-Period * time.Second), true},
+		{"two steps later", "050471", now.Add(2 * Period * time.Second), false},
+		{"wrong code", "050472", now, false},
+		{"8 digits", "14050471", now, false},
+		{"empty", "", now, false},
+	}
+
+	for _, tt := range tests {
+		step, ok := Validate(rfcSecret, tt.code, tt.at)
+		if ok != tt.ok {
+			t.Errorf("%s: valid %v, want %v", tt.name, ok, tt.ok)
+		}
+		if ok && step != Step(now) {
+			t.Errorf("%s: matched step %d, want %d", tt.name, step, Step(now))
+		}
+	}
+}
+
+func TestGenerateSecret(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := encoding.DecodeString(secret)
+	if err != nil || len(key) != 20 {
+		t.Errorf("secret %q decodes to %d bytes, %v", secret, len(key), err)
+	}
+}
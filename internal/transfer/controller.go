@@ -0,0 +1,175 @@
+package transfer
+
+import (
+	"errors"
+	"learn-swiping-api/erro"
+	transfer "learn-swiping-api/internal/transfer/dto"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TransferController interface {
+	Transfer(*gin.Context) // GET
+	Create(*gin.Context)   // POST
+	Cancel(*gin.Context)   // DELETE
+	Incoming(*gin.Context) // GET
+	Accept(*gin.Context)   // POST
+	Decline(*gin.Context)  // POST
+}
+
+type TransferControllerImpl struct {
+	service TransferService
+}
+
+func NewTransferController(service TransferService) TransferController {
+	return &TransferControllerImpl{service: service}
+}
+
+// Retrieves the pending transfer of a deck
+// Method: GET
+func (c *TransferControllerImpl) Transfer(ctx *gin.Context) {
+	token, deckID, ok := deckParams(ctx)
+	if !ok {
+		return
+	}
+
+	pending, err := c.service.Transfer(deckID, token)
+	if err != nil {
+		transferError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, pending)
+}
+
+// Offers the ownership of a deck to another account
+// Method: POST
+func (c *TransferControllerImpl) Create(ctx *gin.Context) {
+	token, deckID, ok := deckParams(ctx)
+	if !ok {
+		return
+	}
+
+	var request transfer.CreateRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrBadField.Error()})
+		return
+	}
+	request.Token = token
+	request.DeckID = deckID
+
+	created, err := c.service.Create(request)
+	if err != nil {
+		transferError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, created)
+}
+
+// Cancels the pending transfer of a deck
+// Method: DELETE
+func (c *TransferControllerImpl) Cancel(ctx *gin.Context) {
+	token, deckID, ok := deckParams(ctx)
+	if !ok {
+		return
+	}
+
+	if err := c.service.Cancel(deckID, token); err != nil {
+		transferError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{})
+}
+
+// Lists the decks offered to the caller
+// Method: GET
+func (c *TransferControllerImpl) Incoming(ctx *gin.Context) {
+	token := ctx.GetHeader("Token")
+	if token == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrInvalidToken.Error()})
+		return
+	}
+
+	transfers, err := c.service.Incoming(token)
+	if err != nil {
+		transferError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, transfers)
+}
+
+// Accepts the ownership of a deck
+// Method: POST
+func (c *TransferControllerImpl) Accept(ctx *gin.Context) {
+	token, deckID, ok := deckParams(ctx)
+	if !ok {
+		return
+	}
+
+	accepted, err := c.service.Accept(deckID, token)
+	if err != nil {
+		transferError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, accepted)
+}
+
+// Declines the ownership of a deck
+// Method: POST
+func (c *TransferControllerImpl) Decline(ctx *gin.Context) {
+	token, deckID, ok := deckParams(ctx)
+	if !ok {
+		return
+	}
+
+	if err := c.service.Decline(deckID, token); err != nil {
+		transferError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{})
+}
+
+func deckParams(ctx *gin.Context) (string, int64, bool) {
+	token := ctx.GetHeader("Token")
+	if token == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrInvalidToken.Error()})
+		return "", 0, false
+	}
+
+	deckID, err := strconv.Atoi(ctx.Param("deckID"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": erro.ErrBadField.Error()})
+		return "", 0, false
+	}
+
+	return token, int64(deckID), true
+}
+
+func transferError(ctx *gin.Context, err error) {
+	if errors.Is(err, erro.ErrBadField) || errors.Is(err, erro.ErrInvalidToken) ||
+		errors.Is(err, erro.ErrTransferExpired) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, erro.ErrForbidden) {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, erro.ErrDeckNotFound) || errors.Is(err, erro.ErrAccountNotFound) ||
+		errors.Is(err, erro.ErrTransferNotFound) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, erro.ErrTransferExists) {
+		ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
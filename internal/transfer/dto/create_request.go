@@ -0,0 +1,8 @@
+package transfer
+
+type CreateRequest struct {
+	Token    string
+	DeckID   int64
+	Username string `json:"username" binding:"required"`
+	KeepRole string `json:"keep_role"` // Collaborator role kept by the current owner, empty to leave the deck
+}
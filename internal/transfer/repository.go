@@ -0,0 +1,304 @@
+package transfer
+
+import (
+	"database/sql"
+	"learn-swiping-api/erro"
+	"log"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+type TransferRepository interface {
+	ByToken(token string) (int64, error)
+	Account(username string) (int64, error)
+	Create(Transfer) (int64, error)
+	ById(transferID int64) (Transfer, error)
+	Pending(deckID int64) (Transfer, error)
+	Incoming(accID int64) ([]Transfer, error)
+	Accept(Transfer) error
+	Resolve(transferID int64, status string) error
+	Expire(deckID int64) error
+	Contact(accID int64) (string, string, error) // Email and name
+}
+
+type TransferRepositoryImpl struct {
+	db           *sql.DB
+	ByTokenStmt  *sql.Stmt
+	AccountStmt  *sql.Stmt
+	CreateStmt   *sql.Stmt
+	ByIdStmt     *sql.Stmt
+	PendingStmt  *sql.Stmt
+	IncomingStmt *sql.Stmt
+	ResolveStmt  *sql.Stmt
+	ExpireStmt   *sql.Stmt
+	ContactStmt  *sql.Stmt
+}
+
+func NewTransferRepository(db *sql.DB) *TransferRepositoryImpl {
+	repo := &TransferRepositoryImpl{db: db}
+	err := repo.InitStatements()
+	if err != nil {
+		log.Fatalln(err)
+	}
+	return repo
+}
+
+// Columns read by scanTransfer
+const transferColumns = `t.transfer_id, t.deck_id, d.title, t.from_acc, COALESCE(f.username, ''),
+							t.to_acc, r.username, t.keep_role, t.status, t.expires_at, t.created_at, t.resolved_at
+						FROM DECK_TRANSFER t
+						JOIN DECK d ON t.deck_id = d.deck_id
+						JOIN ACCOUNT r ON t.to_acc = r.acc_id
+						LEFT JOIN ACCOUNT f ON t.from_acc = f.acc_id`
+
+func (r *TransferRepositoryImpl) InitStatements() error {
+	var err error
+	r.ByTokenStmt, err = r.db.Prepare("SELECT acc_id FROM ACCOUNT WHERE token = ? AND token_expire >= NOW()")
+	if err != nil {
+		return err
+	}
+
+	r.AccountStmt, err = r.db.Prepare("SELECT acc_id FROM ACCOUNT WHERE username = ?")
+	if err != nil {
+		return err
+	}
+
+	r.CreateStmt, err = r.db.Prepare(`INSERT INTO DECK_TRANSFER (deck_id, from_acc, to_acc, keep_role, expires_at)
+										VALUES (?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+
+	r.ByIdStmt, err = r.db.Prepare("SELECT " + transferColumns + " WHERE t.transfer_id = ?")
+	if err != nil {
+		return err
+	}
+
+	r.PendingStmt, err = r.db.Prepare("SELECT " + transferColumns + " WHERE t.deck_id = ? AND t.pending = 1")
+	if err != nil {
+		return err
+	}
+
+	r.IncomingStmt, err = r.db.Prepare("SELECT " + transferColumns + " WHERE t.to_acc = ? AND t.pending = 1 AND t.expires_at > NOW() ORDER BY t.created_at")
+	if err != nil {
+		return err
+	}
+
+	r.ResolveStmt, err = r.db.Prepare(`UPDATE DECK_TRANSFER SET status = ?, pending = NULL, resolved_at = NOW()
+										WHERE transfer_id = ? AND pending = 1`)
+	if err != nil {
+		return err
+	}
+
+	// Frees the pending slot of a deck whose transfer wasn't accepted in time
+	r.ExpireStmt, err = r.db.Prepare(`UPDATE DECK_TRANSFER SET status = 'expired', pending = NULL, resolved_at = NOW()
+										WHERE deck_id = ? AND pending = 1 AND expires_at <= NOW()`)
+	if err != nil {
+		return err
+	}
+
+	r.ContactStmt, err = r.db.Prepare("SELECT email, name FROM ACCOUNT WHERE acc_id = ?")
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *TransferRepositoryImpl) ByToken(token string) (int64, error) {
+	var accID int64
+	if err := r.ByTokenStmt.QueryRow(token).Scan(&accID); err != nil {
+		if err == sql.ErrNoRows {
+			return 0, erro.ErrInvalidToken
+		}
+		return 0, err
+	}
+	return accID, nil
+}
+
+func (r *TransferRepositoryImpl) Account(username string) (int64, error) {
+	var accID int64
+	if err := r.AccountStmt.QueryRow(username).Scan(&accID); err != nil {
+		if err == sql.ErrNoRows {
+			return 0, erro.ErrAccountNotFound
+		}
+		return 0, err
+	}
+	return accID, nil
+}
+
+func (r *TransferRepositoryImpl) Create(transfer Transfer) (int64, error) {
+	result, err := r.CreateStmt.Exec(transfer.DeckID, transfer.FromID, transfer.ToID, transfer.KeepRole, transfer.ExpiresAt)
+	if err != nil {
+		if mysqlErr, ok := err.(*mysql.MySQLError); ok {
+			switch mysqlErr.Number {
+			case 1062:
+				return 0, erro.ErrTransferExists
+			case 1452:
+				return 0, erro.ErrDeckNotFound
+			}
+		}
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+func (r *TransferRepositoryImpl) ById(transferID int64) (Transfer, error) {
+	return r.one(r.ByIdStmt, transferID)
+}
+
+// The unresolved transfer of a deck, even if it already expired
+func (r *TransferRepositoryImpl) Pending(deckID int64) (Transfer, error) {
+	return r.one(r.PendingStmt, deckID)
+}
+
+// Transfers waiting for the account to accept them, oldest first
+func (r *TransferRepositoryImpl) Incoming(accID int64) ([]Transfer, error) {
+	rows, err := r.IncomingStmt.Query(accID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	transfers := []Transfer{}
+	for rows.Next() {
+		transfer, err := scanTransfer(rows)
+		if err != nil {
+			return nil, err
+		}
+		transfers = append(transfers, transfer)
+	}
+
+	return transfers, rows.Err()
+}
+
+// Hands the deck over. Pictures belong to the deck row and subscriptions
+// and ratings are left untouched, only the owner and the roles of both
+// accounts change
+func (r *TransferRepositoryImpl) Accept(transfer Transfer) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	result, err := tx.Exec(`UPDATE DECK_TRANSFER SET status = 'accepted', pending = NULL, resolved_at = NOW()
+								WHERE transfer_id = ? AND pending = 1 AND expires_at > NOW()`, transfer.ID)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := affected(result); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	// A previous owner that deleted the account left the deck to the
+	// system account, which can't object to the transfer
+	result, err = tx.Exec(`UPDATE DECK SET acc_id = ?, updated_at = ?
+							WHERE deck_id = ? AND (acc_id = ? OR (? IS NULL AND acc_id IN (SELECT acc_id FROM ACCOUNT_ROLE WHERE role = 'system')))`,
+		transfer.ToID, time.Now(), transfer.DeckID, transfer.FromID, transfer.FromID)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := affected(result); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	// The new owner doesn't need a collaborator role anymore
+	if _, err := tx.Exec("DELETE FROM DECK_COLLABORATOR WHERE deck_id = ? AND acc_id = ?", transfer.DeckID, transfer.ToID); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if transfer.FromID != nil && transfer.KeepRole != "" {
+		_, err := tx.Exec(`INSERT INTO DECK_COLLABORATOR (deck_id, acc_id, role, invited_by, accepted_at)
+								VALUES (?, ?, ?, ?, NOW())`, transfer.DeckID, *transfer.FromID, transfer.KeepRole, transfer.ToID)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	if _, err := tx.Exec("INSERT IGNORE INTO ACC_DECK (acc_id, deck_id) VALUES (?, ?)", transfer.ToID, transfer.DeckID); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Closes a pending transfer as declined, cancelled or expired
+func (r *TransferRepositoryImpl) Resolve(transferID int64, status string) error {
+	result, err := r.ResolveStmt.Exec(status, transferID)
+	if err != nil {
+		return err
+	}
+	return affected(result)
+}
+
+func (r *TransferRepositoryImpl) Expire(deckID int64) error {
+	_, err := r.ExpireStmt.Exec(deckID)
+	return err
+}
+
+func (r *TransferRepositoryImpl) Contact(accID int64) (string, string, error) {
+	var email, name string
+	if err := r.ContactStmt.QueryRow(accID).Scan(&email, &name); err != nil {
+		if err == sql.ErrNoRows {
+			return "", "", erro.ErrAccountNotFound
+		}
+		return "", "", err
+	}
+	return email, name, nil
+}
+
+func (r *TransferRepositoryImpl) one(stmt *sql.Stmt, id int64) (Transfer, error) {
+	transfer, err := scanTransfer(stmt.QueryRow(id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return Transfer{}, erro.ErrTransferNotFound
+		}
+		return Transfer{}, err
+	}
+	return transfer, nil
+}
+
+// Checks an update changed the pending transfer or the deck
+func affected(result sql.Result) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return erro.ErrTransferNotFound
+	}
+
+	return nil
+}
+
+// Scans from either *sql.Row or *sql.Rows
+func scanTransfer(row interface{ Scan(...any) error }) (Transfer, error) {
+	var transfer Transfer
+	err := row.Scan(
+		&transfer.ID,
+		&transfer.DeckID,
+		&transfer.DeckTitle,
+		&transfer.FromID,
+		&transfer.FromUsername,
+		&transfer.ToID,
+		&transfer.ToUsername,
+		&transfer.KeepRole,
+		&transfer.Status,
+		&transfer.ExpiresAt,
+		&transfer.CreatedAt,
+		&transfer.ResolvedAt,
+	)
+	if err != nil {
+		return Transfer{}, err
+	}
+	return transfer, nil
+}
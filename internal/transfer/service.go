@@ -0,0 +1,193 @@
+package transfer
+
+import (
+	"fmt"
+	"learn-swiping-api/erro"
+	"learn-swiping-api/internal/collaborator"
+	"learn-swiping-api/internal/mailer"
+	transfer "learn-swiping-api/internal/transfer/dto"
+	"log"
+	"slices"
+	"time"
+)
+
+// Time the recipient has to accept a transfer
+const transferTTL = 7 * 24 * time.Hour
+
+type TransferService interface {
+	Create(transfer.CreateRequest) (Transfer, error)
+	Transfer(deckID int64, token string) (Transfer, error)
+	Cancel(deckID int64, token string) error
+	Incoming(token string) ([]Transfer, error)
+	Accept(deckID int64, token string) (Transfer, error)
+	Decline(deckID int64, token string) error
+	incoming(deckID int64, token string) (Transfer, error)
+	notify(accID int64, subject string, body string)
+}
+
+type TransferServiceImpl struct {
+	repository    TransferRepository
+	collaborators collaborator.CollaboratorService
+	mailer        mailer.Mailer
+}
+
+func NewTransferService(repository TransferRepository, collaborators collaborator.CollaboratorService, mailer mailer.Mailer) TransferService {
+	return &TransferServiceImpl{repository: repository, collaborators: collaborators, mailer: mailer}
+}
+
+// Offers the deck to another account. Only the owner can do it and a
+// deck can only have one pending transfer
+func (s *TransferServiceImpl) Create(request transfer.CreateRequest) (Transfer, error) {
+	if request.KeepRole != "" && !slices.Contains(collaborator.Roles, request.KeepRole) {
+		return Transfer{}, erro.ErrBadField
+	}
+
+	access, err := s.collaborators.Authorize(request.DeckID, request.Token, collaborator.TransferDeck)
+	if err != nil {
+		return Transfer{}, err
+	}
+
+	accID, err := s.repository.Account(request.Username)
+	if err != nil {
+		return Transfer{}, err
+	}
+	if accID == access.AccID {
+		return Transfer{}, erro.ErrBadField
+	}
+
+	if err := s.repository.Expire(request.DeckID); err != nil {
+		return Transfer{}, err
+	}
+
+	transferID, err := s.repository.Create(Transfer{
+		DeckID:    request.DeckID,
+		FromID:    &access.AccID,
+		ToID:      accID,
+		KeepRole:  request.KeepRole,
+		ExpiresAt: time.Now().Add(transferTTL),
+	})
+	if err != nil {
+		return Transfer{}, err
+	}
+
+	created, err := s.repository.ById(transferID)
+	if err != nil {
+		return Transfer{}, err
+	}
+
+	s.notify(accID, fmt.Sprintf("%s wants to give you a deck", created.FromUsername),
+		fmt.Sprintf("%s offered you the ownership of the deck %q. Accept it from the app before %s.",
+			created.FromUsername, created.DeckTitle, created.ExpiresAt.Format(time.RFC1123)))
+	return created, nil
+}
+
+// The pending transfer of a deck. Only for the owner
+func (s *TransferServiceImpl) Transfer(deckID int64, token string) (Transfer, error) {
+	if _, err := s.collaborators.Authorize(deckID, token, collaborator.TransferDeck); err != nil {
+		return Transfer{}, err
+	}
+
+	if err := s.repository.Expire(deckID); err != nil {
+		return Transfer{}, err
+	}
+
+	return s.repository.Pending(deckID)
+}
+
+func (s *TransferServiceImpl) Cancel(deckID int64, token string) error {
+	pending, err := s.Transfer(deckID, token)
+	if err != nil {
+		return err
+	}
+
+	return s.repository.Resolve(pending.ID, StatusCancelled)
+}
+
+func (s *TransferServiceImpl) Incoming(token string) ([]Transfer, error) {
+	accID, err := s.repository.ByToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.repository.Incoming(accID)
+}
+
+// Takes the ownership of the deck
+func (s *TransferServiceImpl) Accept(deckID int64, token string) (Transfer, error) {
+	pending, err := s.incoming(deckID, token)
+	if err != nil {
+		return Transfer{}, err
+	}
+
+	if err := s.repository.Accept(pending); err != nil {
+		return Transfer{}, err
+	}
+
+	if pending.FromID != nil {
+		s.notify(*pending.FromID, fmt.Sprintf("%s accepted your deck", pending.ToUsername),
+			fmt.Sprintf("%s is now the owner of the deck %q.", pending.ToUsername, pending.DeckTitle))
+	}
+
+	return s.repository.ById(pending.ID)
+}
+
+func (s *TransferServiceImpl) Decline(deckID int64, token string) error {
+	pending, err := s.incoming(deckID, token)
+	if err != nil {
+		return err
+	}
+
+	if err := s.repository.Resolve(pending.ID, StatusDeclined); err != nil {
+		return err
+	}
+
+	if pending.FromID != nil {
+		s.notify(*pending.FromID, fmt.Sprintf("%s declined your deck", pending.ToUsername),
+			fmt.Sprintf("%s didn't accept the ownership of the deck %q. You are still its owner.", pending.ToUsername, pending.DeckTitle))
+	}
+	return nil
+}
+
+// Loads the pending transfer of the deck addressed to the caller. A
+// transfer that wasn't accepted in time is closed on the way
+func (s *TransferServiceImpl) incoming(deckID int64, token string) (Transfer, error) {
+	accID, err := s.repository.ByToken(token)
+	if err != nil {
+		return Transfer{}, err
+	}
+
+	pending, err := s.repository.Pending(deckID)
+	if err != nil {
+		return Transfer{}, err
+	}
+	if pending.ToID != accID {
+		return Transfer{}, erro.ErrTransferNotFound
+	}
+
+	if !pending.ExpiresAt.After(time.Now()) {
+		if err := s.repository.Expire(deckID); err != nil {
+			return Transfer{}, err
+		}
+		return Transfer{}, erro.ErrTransferExpired
+	}
+
+	return pending, nil
+}
+
+// Mails one of the accounts involved. Failures are only logged
+func (s *TransferServiceImpl) notify(accID int64, subject string, body string) {
+	email, name, err := s.repository.Contact(accID)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	err = s.mailer.Send(mailer.Message{
+		To:      email,
+		Subject: subject,
+		Body:    fmt.Sprintf("Hi %s,\n\n%s\n", name, body),
+	})
+	if err != nil {
+		log.Println(err)
+	}
+}
@@ -0,0 +1,26 @@
+package transfer
+
+import "time"
+
+const (
+	StatusPending   = "pending"
+	StatusAccepted  = "accepted"
+	StatusDeclined  = "declined"
+	StatusCancelled = "cancelled"
+	StatusExpired   = "expired"
+)
+
+type Transfer struct {
+	ID           int64      `json:"transfer_id"`
+	DeckID       int64      `json:"deck_id"`
+	DeckTitle    string     `json:"deck_title"`
+	FromID       *int64     `json:"from_acc"`
+	FromUsername string     `json:"from_username"`
+	ToID         int64      `json:"to_acc"`
+	ToUsername   string     `json:"to_username"`
+	KeepRole     string     `json:"keep_role"`
+	Status       string     `json:"status"`
+	ExpiresAt    time.Time  `json:"expires_at"`
+	CreatedAt    time.Time  `json:"created_at"`
+	ResolvedAt   *time.Time `json:"resolved_at"`
+}
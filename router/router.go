@@ -2,7 +2,14 @@ package router
 
 import (
 	"learn-swiping-api/config"
+	"learn-swiping-api/internal/apikey"
+	"learn-swiping-api/internal/ratelimit"
+	"learn-swiping-api/internal/role"
+	"log"
 	"net/http"
+	"os"
+	"strings"
+	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
@@ -11,23 +18,59 @@ import (
 func NewRouter(init *config.Initialization) *gin.Engine {
 	router := gin.Default()
 
+	// Only the proxies in TRUSTED_PROXIES, comma separated IPs or CIDRs,
+	// can tell the client IP with X-Forwarded-For. None by default, so
+	// clients can't pick the IP they're limited and locked out by
+	if err := router.SetTrustedProxies(trustedProxies(os.Getenv("TRUSTED_PROXIES"))); err != nil {
+		log.Fatalln(err)
+	}
+
 	config := cors.DefaultConfig()
 	config.AllowAllOrigins = true
 	config.AllowMethods = append(config.AllowMethods, "OPTIONS")
+	config.AllowHeaders = append(config.AllowHeaders, "Api-Key")
 
 	router.Use(cors.New(config))
 
+	// Limits can be changed with RATE_LIMIT_<NAME>, e.g. RATE_LIMIT_AUTH=20/1m
+	limit := init.Limiter.Limit
+	globalPolicy := ratelimit.PolicyFromEnv(ratelimit.Policy{Name: "global", Limit: 300, Period: time.Minute})
+	authPolicy := ratelimit.PolicyFromEnv(ratelimit.Policy{Name: "auth", Limit: 10, Period: time.Minute})
+	registerPolicy := ratelimit.PolicyFromEnv(ratelimit.Policy{Name: "register", Limit: 5, Period: time.Hour})
+	ratingPolicy := ratelimit.PolicyFromEnv(ratelimit.Policy{Name: "rating", Limit: 30, Period: time.Minute})
+	cardPolicy := ratelimit.PolicyFromEnv(ratelimit.Policy{Name: "cards", Limit: 120, Period: time.Minute})
+	reportPolicy := ratelimit.PolicyFromEnv(ratelimit.Policy{Name: "report", Limit: 20, Period: time.Hour})
+	commentPolicy := ratelimit.PolicyFromEnv(ratelimit.Policy{Name: "comment", Limit: 30, Period: time.Minute})
+	suggestionPolicy := ratelimit.PolicyFromEnv(ratelimit.Policy{Name: "suggestion", Limit: 30, Period: time.Hour})
+	sharePolicy := ratelimit.PolicyFromEnv(ratelimit.Policy{Name: "share", Limit: 60, Period: time.Minute})
+	importPolicy := ratelimit.PolicyFromEnv(ratelimit.Policy{Name: "import", Limit: 10, Period: time.Hour})
+	cardImportPolicy := ratelimit.PolicyFromEnv(ratelimit.Policy{Name: "card_import", Limit: 60, Period: time.Hour})
+
+	router.Use(limit(globalPolicy, ratelimit.ByAPIKey))
+
 	router.GET("/ping", ping)
 
+	// Routes with a scope can also be called with an API key
+	scope := init.APIKeyCtrl.Scope
+	authLimit := limit(authPolicy, ratelimit.ByIP)
+	reportLimit := limit(reportPolicy, ratelimit.ByAPIKey)
+
 	// CHAOS ZONE
 	// Proceed with caution
 	authGroup := router.Group("/auth")
 	{
 		authGroup.GET("", init.UserCtrl.Token)
-		authGroup.POST("register", init.UserCtrl.Register)
-		authGroup.POST("login", init.UserCtrl.Login)
+		authGroup.POST("register", limit(registerPolicy, ratelimit.ByIP), init.UserCtrl.Register)
+		authGroup.POST("login", authLimit, init.UserCtrl.Login)
+		authGroup.POST("login/2fa", authLimit, init.UserCtrl.LoginTOTP)
 		authGroup.GET("token", init.UserCtrl.Token) // TODO: Migrate to Account fn
 		authGroup.DELETE("logout", init.UserCtrl.Logout)
+		authGroup.POST("verify", authLimit, init.UserCtrl.VerifyEmail)
+		authGroup.POST("verify/resend", authLimit, init.UserCtrl.ResendVerification)
+		authGroup.POST("password/forgot", authLimit, init.UserCtrl.ForgotPassword)
+		authGroup.POST("password/reset", authLimit, init.UserCtrl.ResetPassword)
+		authGroup.GET("oidc/:provider", authLimit, init.UserCtrl.OIDCLogin)
+		authGroup.GET("oidc/:provider/callback", init.UserCtrl.OIDCCallback)
 	}
 
 	accountGroup := router.Group("account")
@@ -35,35 +78,115 @@ func NewRouter(init *config.Initialization) *gin.Engine {
 		accountGroup.GET("", init.UserCtrl.Account)
 		accountGroup.PUT("", init.UserCtrl.Update)
 		accountGroup.DELETE("", init.UserCtrl.Delete)
+
+		accountGroup.POST("2fa", init.UserCtrl.EnrolTOTP)
+		accountGroup.POST("2fa/confirm", init.UserCtrl.ConfirmTOTP)
+		accountGroup.DELETE("2fa", init.UserCtrl.DisableTOTP)
+		accountGroup.POST("2fa/recovery-codes", init.UserCtrl.RegenerateRecoveryCodes)
+
+		accountGroup.GET("identities", init.UserCtrl.Identities)
+		accountGroup.POST("identities/:provider", init.UserCtrl.LinkIdentity)
+		accountGroup.DELETE("identities/:identityID", init.UserCtrl.UnlinkIdentity)
+
+		accountGroup.GET("keys", init.APIKeyCtrl.Keys)
+		accountGroup.POST("keys", init.APIKeyCtrl.Create)
+		accountGroup.DELETE("keys/:keyID", init.APIKeyCtrl.Delete)
+
+		accountGroup.GET("permissions", init.RoleCtrl.Permissions)
+
+		accountGroup.GET("invitations", init.CollaboratorCtrl.Invitations)
+		accountGroup.GET("transfers", init.TransferCtrl.Incoming)
 	}
 
 	userGroup := router.Group("users")
 	{
 		userGroup.GET(":username", init.UserCtrl.AccountPublic)
-		userGroup.GET(":username/decks", init.DeckCtrl.OwnedDecks)
-		userGroup.GET(":username/subscribed", init.DeckCtrl.Subscriptions)
+		userGroup.GET(":username/decks", scope(apikey.DecksRead), init.DeckCtrl.OwnedDecks)
+		userGroup.GET(":username/subscribed", scope(apikey.DecksRead), init.DeckCtrl.Subscriptions)
+		userGroup.POST(":username/report", reportLimit, init.ReportCtrl.ReportAccount)
 	}
 
 	deckGroup := router.Group("decks")
 	{
-		deckGroup.POST("", init.DeckCtrl.Create)
-		deckGroup.PUT(":deckID", init.DeckCtrl.Update)
-		deckGroup.DELETE(":deckID", init.DeckCtrl.Delete)
-		deckGroup.GET(":deckID", init.DeckCtrl.DeckDetails)
-
-		deckGroup.POST("subs/:deckID", init.DeckCtrl.AddDeckSubscription)
-		deckGroup.DELETE("subs/:deckID", init.DeckCtrl.RemoveDeckSubscription)
-		deckGroup.GET("subs/:username/:deckID", init.DeckCtrl.DeckDetails)
-
-		deckGroup.POST(":deckID/rating/:rating", init.DeckCtrl.SaveRating)
-		deckGroup.GET(":deckID/rating", init.DeckCtrl.Rating)
-		deckGroup.DELETE(":deckID/rating", init.DeckCtrl.DeleteRating)
-
-		deckGroup.POST(":deckID", init.CardCtrl.Create)
-		deckGroup.GET(":deckID/:cardID", init.CardCtrl.Card)
-		deckGroup.GET(":deckID/cards", init.CardCtrl.Cards)
-		deckGroup.PUT(":deckID/:cardID", init.CardCtrl.Update)
-		deckGroup.DELETE(":deckID/:cardID", init.CardCtrl.Delete)
+		deckGroup.POST("", scope(apikey.DecksWrite), init.DeckCtrl.Create)
+		deckGroup.PUT(":deckID", scope(apikey.DecksWrite), init.DeckCtrl.Update)
+		deckGroup.DELETE(":deckID", scope(apikey.DecksWrite), init.DeckCtrl.Delete)
+		deckGroup.GET(":deckID", scope(apikey.DecksRead), init.DeckCtrl.DeckDetails)
+
+		deckGroup.POST("subs/:deckID", scope(apikey.DecksWrite), init.DeckCtrl.AddDeckSubscription)
+		deckGroup.DELETE("subs/:deckID", scope(apikey.DecksWrite), init.DeckCtrl.RemoveDeckSubscription)
+		deckGroup.GET("subs/:username/:deckID", scope(apikey.DecksRead), init.DeckCtrl.DeckDetails)
+
+		deckGroup.POST("import/apkg", limit(importPolicy, ratelimit.ByAPIKey), scope(apikey.DecksWrite), init.AnkiCtrl.Import)
+		deckGroup.GET(":deckID/export", scope(apikey.DecksRead), init.ExportCtrl.Export)
+		deckGroup.GET(":deckID/export/apkg", scope(apikey.DecksRead), init.AnkiCtrl.Export)
+		deckGroup.POST(":deckID/import/preview", limit(cardImportPolicy, ratelimit.ByAPIKey), scope(apikey.DecksWrite), init.ImporterCtrl.Preview)
+		deckGroup.POST(":deckID/import", limit(cardImportPolicy, ratelimit.ByAPIKey), scope(apikey.DecksWrite), init.ImporterCtrl.Import)
+
+		deckGroup.POST(":deckID/rating/:rating", limit(ratingPolicy, ratelimit.ByAPIKey), scope(apikey.DecksWrite), init.DeckCtrl.SaveRating)
+		deckGroup.GET(":deckID/rating", scope(apikey.DecksRead), init.DeckCtrl.Rating)
+		deckGroup.DELETE(":deckID/rating", scope(apikey.DecksWrite), init.DeckCtrl.DeleteRating)
+
+		deckGroup.PUT(":deckID/review", limit(ratingPolicy, ratelimit.ByAPIKey), scope(apikey.DecksWrite), init.DeckCtrl.SaveReview)
+		deckGroup.GET(":deckID/reviews", scope(apikey.DecksRead), init.DeckCtrl.Reviews)
+		deckGroup.POST(":deckID/reviews/:ratingID/helpful", limit(ratingPolicy, ratelimit.ByAPIKey), scope(apikey.DecksWrite), init.DeckCtrl.VoteHelpful)
+		deckGroup.DELETE(":deckID/reviews/:ratingID/helpful", scope(apikey.DecksWrite), init.DeckCtrl.RemoveHelpful)
+		deckGroup.PUT(":deckID/reviews/:ratingID/reply", scope(apikey.DecksWrite), init.DeckCtrl.Reply)
+		deckGroup.DELETE(":deckID/reviews/:ratingID/reply", scope(apikey.DecksWrite), init.DeckCtrl.DeleteReply)
+
+		deckGroup.POST(":deckID", limit(cardPolicy, ratelimit.ByAPIKey), scope(apikey.DecksWrite), init.CardCtrl.Create)
+		deckGroup.GET(":deckID/:cardID", scope(apikey.DecksRead), init.CardCtrl.Card)
+		deckGroup.GET(":deckID/cards", scope(apikey.DecksRead), init.CardCtrl.Cards)
+		deckGroup.PUT(":deckID/:cardID", scope(apikey.DecksWrite), init.CardCtrl.Update)
+		deckGroup.PATCH(":deckID/:cardID", scope(apikey.DecksWrite), init.CardCtrl.Update)
+		deckGroup.DELETE(":deckID/:cardID", scope(apikey.DecksWrite), init.CardCtrl.Delete)
+		deckGroup.POST(":deckID/:cardID/grade", scope(apikey.DecksRead), init.CardCtrl.Grade)
+		deckGroup.POST(":deckID/pictures", limit(cardPolicy, ratelimit.ByAPIKey), scope(apikey.DecksWrite), init.CardCtrl.Picture)
+		// gin can't escape the colon, so :batch is a param the handler checks
+		deckGroup.POST(":deckID/cards:batch", limit(cardImportPolicy, ratelimit.ByAPIKey), scope(apikey.DecksWrite), init.CardCtrl.Batch)
+		deckGroup.GET(":deckID/queue", scope(apikey.DecksRead), init.CardCtrl.Pending)
+		deckGroup.GET(":deckID/order", scope(apikey.DecksRead), init.CardCtrl.Outline)
+		deckGroup.PUT(":deckID/order", limit(cardPolicy, ratelimit.ByAPIKey), scope(apikey.DecksWrite), init.CardCtrl.Reorder)
+		deckGroup.POST(":deckID/sections", limit(cardPolicy, ratelimit.ByAPIKey), scope(apikey.DecksWrite), init.CardCtrl.CreateSection)
+		deckGroup.PUT(":deckID/sections/:sectionID", scope(apikey.DecksWrite), init.CardCtrl.RenameSection)
+		deckGroup.DELETE(":deckID/sections/:sectionID", scope(apikey.DecksWrite), init.CardCtrl.DeleteSection)
+
+		deckGroup.GET(":deckID/comments", scope(apikey.DecksRead), init.CommentCtrl.DeckComments)
+		deckGroup.POST(":deckID/comments", limit(commentPolicy, ratelimit.ByAPIKey), scope(apikey.DecksWrite), init.CommentCtrl.CommentDeck)
+		deckGroup.GET(":deckID/:cardID/comments", scope(apikey.DecksRead), init.CommentCtrl.CardComments)
+		deckGroup.POST(":deckID/:cardID/comments", limit(commentPolicy, ratelimit.ByAPIKey), scope(apikey.DecksWrite), init.CommentCtrl.CommentCard)
+		deckGroup.PUT(":deckID/comments/:commentID", scope(apikey.DecksWrite), init.CommentCtrl.Update)
+		deckGroup.DELETE(":deckID/comments/:commentID", scope(apikey.DecksWrite), init.CommentCtrl.Delete)
+		deckGroup.PUT(":deckID/comments/:commentID/pin", scope(apikey.DecksWrite), init.CommentCtrl.Pin)
+		deckGroup.DELETE(":deckID/comments/:commentID/pin", scope(apikey.DecksWrite), init.CommentCtrl.Unpin)
+
+		deckGroup.GET(":deckID/collaborators", scope(apikey.DecksRead), init.CollaboratorCtrl.Collaborators)
+		deckGroup.POST(":deckID/collaborators", scope(apikey.DecksWrite), init.CollaboratorCtrl.Invite)
+		deckGroup.PUT(":deckID/collaborators/:username", scope(apikey.DecksWrite), init.CollaboratorCtrl.SetRole)
+		deckGroup.DELETE(":deckID/collaborators/:username", scope(apikey.DecksWrite), init.CollaboratorCtrl.Remove)
+		deckGroup.POST(":deckID/invitation", scope(apikey.DecksWrite), init.CollaboratorCtrl.Accept)
+		deckGroup.DELETE(":deckID/invitation", scope(apikey.DecksWrite), init.CollaboratorCtrl.Decline)
+
+		deckGroup.GET(":deckID/transfer", scope(apikey.DecksRead), init.TransferCtrl.Transfer)
+		deckGroup.POST(":deckID/transfer", scope(apikey.DecksWrite), init.TransferCtrl.Create)
+		deckGroup.DELETE(":deckID/transfer", scope(apikey.DecksWrite), init.TransferCtrl.Cancel)
+		deckGroup.POST(":deckID/transfer/accept", scope(apikey.DecksWrite), init.TransferCtrl.Accept)
+		deckGroup.POST(":deckID/transfer/decline", scope(apikey.DecksWrite), init.TransferCtrl.Decline)
+
+		deckGroup.GET(":deckID/shares", scope(apikey.DecksRead), init.ShareCtrl.Links)
+		deckGroup.POST(":deckID/shares", scope(apikey.DecksWrite), init.ShareCtrl.Create)
+		deckGroup.DELETE(":deckID/shares/:shareID", scope(apikey.DecksWrite), init.ShareCtrl.Revoke)
+
+		deckGroup.GET(":deckID/suggestions", scope(apikey.DecksRead), init.SuggestionCtrl.Suggestions)
+		deckGroup.POST(":deckID/suggestions", limit(suggestionPolicy, ratelimit.ByAPIKey), scope(apikey.DecksWrite), init.SuggestionCtrl.SuggestCard)
+		deckGroup.POST(":deckID/:cardID/suggestions", limit(suggestionPolicy, ratelimit.ByAPIKey), scope(apikey.DecksWrite), init.SuggestionCtrl.SuggestEdit)
+		deckGroup.GET(":deckID/suggestions/:suggestionID", scope(apikey.DecksRead), init.SuggestionCtrl.Suggestion)
+		deckGroup.POST(":deckID/suggestions/:suggestionID/accept", scope(apikey.DecksWrite), init.SuggestionCtrl.Accept)
+		deckGroup.POST(":deckID/suggestions/:suggestionID/reject", scope(apikey.DecksWrite), init.SuggestionCtrl.Reject)
+		deckGroup.DELETE(":deckID/suggestions/:suggestionID", scope(apikey.DecksWrite), init.SuggestionCtrl.Withdraw)
+
+		deckGroup.POST(":deckID/report", reportLimit, init.ReportCtrl.ReportDeck)
+		deckGroup.POST(":deckID/:cardID/report", reportLimit, init.ReportCtrl.ReportCard)
 	}
 
 	shopGroup := router.Group("shop")
@@ -71,12 +194,59 @@ func NewRouter(init *config.Initialization) *gin.Engine {
 		shopGroup.GET(":deckID", init.DeckCtrl.DeckDetailsShop)
 	}
 
+	// Slugs are random, the limit keeps them from being guessed
+	shareGroup := router.Group("share")
+	{
+		shareGroup.GET(":slug", limit(sharePolicy, ratelimit.ByIP), init.ShareCtrl.Open)
+		shareGroup.POST(":slug/subscribe", limit(sharePolicy, ratelimit.ByIP), scope(apikey.DecksWrite), init.ShareCtrl.Subscribe)
+	}
+
 	progressGroup := router.Group("progress")
 	{
-		progressGroup.POST("", init.ProgressCtrl.Create)
-		progressGroup.GET(":cardID", init.ProgressCtrl.Progress)
-		progressGroup.PUT("", init.ProgressCtrl.Update)
-		progressGroup.DELETE("", init.ProgressCtrl.Delete)
+		progressGroup.POST("", scope(apikey.ProgressWrite), init.ProgressCtrl.Create)
+		progressGroup.GET(":cardID", scope(apikey.ProgressRead), init.ProgressCtrl.Progress)
+		progressGroup.PUT("", scope(apikey.ProgressWrite), init.ProgressCtrl.Update)
+		progressGroup.DELETE("", scope(apikey.ProgressWrite), init.ProgressCtrl.Delete)
+	}
+
+	// Every admin route requires a permission of the caller's role and
+	// every change made through them is written to the audit log
+	require := init.RoleCtrl.Require
+
+	adminGroup := router.Group("admin")
+	adminGroup.Use(init.AuditCtrl.Record)
+	{
+		adminGroup.GET("accounts", require(role.ManageAccounts), init.AdminCtrl.Accounts)
+		adminGroup.GET("accounts/:username", require(role.ManageAccounts), init.AdminCtrl.Account)
+		adminGroup.POST("accounts/:username/suspend", require(role.ManageAccounts), init.AdminCtrl.Suspend)
+		adminGroup.POST("accounts/:username/ban", require(role.ManageAccounts), init.AdminCtrl.Ban)
+		adminGroup.DELETE("accounts/:username/suspension", require(role.ManageAccounts), init.AdminCtrl.Unsuspend)
+		adminGroup.POST("accounts/:username/logout", require(role.ManageAccounts), init.AdminCtrl.Logout)
+		adminGroup.POST("accounts/:username/password-reset", require(role.ManageAccounts), init.AdminCtrl.ResetPassword)
+
+		adminGroup.PUT("decks/:deckID/visibility", require(role.ManageDecks), init.AdminCtrl.SetDeckVisibility)
+		adminGroup.PUT("decks/:deckID/owner", require(role.ManageDecks), init.AdminCtrl.TransferDeck)
+
+		adminGroup.GET("audit", require(role.ReadAudit), init.AuditCtrl.Entries)
+
+		adminGroup.GET("lockouts", require(role.ManageLockouts), init.LockoutCtrl.Lockouts)
+		adminGroup.DELETE("lockouts/:scope/:subject", require(role.ManageLockouts), init.LockoutCtrl.Clear)
+
+		adminGroup.GET("roles", require(role.ManageRoles), init.RoleCtrl.Privileged)
+		adminGroup.PUT("accounts/:username/role", require(role.ManageRoles), init.RoleCtrl.Assign)
+	}
+
+	moderationGroup := router.Group("moderation")
+	moderationGroup.Use(init.AuditCtrl.Record)
+	{
+		moderationGroup.GET("reports", require(role.ModerateContent), init.ReportCtrl.Reports)
+		moderationGroup.GET("reports/:reportID", require(role.ModerateContent), init.ReportCtrl.Report)
+		moderationGroup.POST("reports/:reportID/triage", require(role.ModerateContent), init.ReportCtrl.Triage)
+		moderationGroup.POST("reports/:reportID/escalate", require(role.ModerateContent), init.ReportCtrl.Escalate)
+		moderationGroup.POST("reports/:reportID/dismiss", require(role.ModerateContent), init.ReportCtrl.Dismiss)
+		moderationGroup.POST("reports/:reportID/resolve", require(role.ModerateContent), init.ReportCtrl.Resolve)
+
+		moderationGroup.DELETE("comments/:commentID", require(role.ModerateContent), init.CommentCtrl.Remove)
 	}
 
 	pictureGroup := router.Group("pics")
@@ -91,3 +261,13 @@ func NewRouter(init *config.Initialization) *gin.Engine {
 func ping(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, gin.H{"message": "pong"})
 }
+
+func trustedProxies(value string) []string {
+	var proxies []string
+	for _, proxy := range strings.Split(value, ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			proxies = append(proxies, proxy)
+		}
+	}
+	return proxies
+}